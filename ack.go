@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ackMode acknowledges the failing checks of a node so they get no more
+// reminders until their status changes.
+func ackMode(arguments map[string]interface{}) {
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	node := arguments["<node>"].(string)
+	check := arguments["<check>"].(string)
+	checks, err := consulClient.Acknowledge(node, check, cliUser(), stringOption(arguments, "--comment", ""), time.Now())
+	for _, acknowledged := range checks {
+		fmt.Printf("Acknowledged %s:%s:%s (%s).\n", acknowledged.Node, acknowledged.ServiceID, acknowledged.CheckID, acknowledged.Status)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to acknowledge the check:", err)
+		os.Exit(1)
+	}
+}
+
+// cliUser is the user recorded as the author of the acknowledgements and
+// silences created from the command line.
+func cliUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "cli"
+}
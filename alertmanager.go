@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// alertmanagerPayload is the body of the Prometheus Alertmanager webhook
+// notifications, version 4.
+type alertmanagerPayload struct {
+	Version  string              `json:"version"`
+	GroupKey string              `json:"groupKey"`
+	Receiver string              `json:"receiver"`
+	Alerts   []alertmanagerAlert `json:"alerts"`
+}
+
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// alertmanagerHandler receives the Alertmanager webhook notifications at
+// /v1/alertmanager and sends the alerts through the routes, profiles and
+// silences like the check alerts.
+func alertmanagerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(405)
+		return
+	}
+	if !consulClient.AlertmanagerEnabled() {
+		writeJson(w, 404, map[string]string{"error": "the alertmanager receiver is disabled"})
+		return
+	}
+	var payload alertmanagerPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJson(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var alerts []consul.Check
+	for _, alert := range payload.Alerts {
+		check := alertmanagerCheck(alert)
+		if consulClient.IsBlacklisted(&check) || consulClient.IsSilenced(&check) {
+			log.Infof("%s:%s:%s from alertmanager is silenced or blacklisted.", check.Node, check.ServiceID, check.CheckID)
+			continue
+		}
+		alerts = append(alerts, check)
+	}
+	log.Infof("Received %d alert(s) from alertmanager group %s, %d to notify.", len(payload.Alerts), payload.GroupKey, len(alerts))
+	if len(alerts) > 0 {
+		pipelineBegin()
+		go func() {
+			defer pipelineEnd()
+			notify(alerts)
+		}()
+	}
+	w.WriteHeader(200)
+}
+
+// alertmanagerCheck turns an Alertmanager alert into a check alert. The node
+// is the instance label, the service the service or job label and the check
+// the alertname. The labels are the node metadata the routes can match. A
+// resolved alert is passing, a firing one is critical unless its severity is
+// warning or info.
+func alertmanagerCheck(alert alertmanagerAlert) consul.Check {
+	labels := alert.Labels
+	service := firstLabel(labels, "", "service", "job")
+	check := consul.Check{
+		Node:        firstLabel(labels, "alertmanager", "instance", "node"),
+		ServiceID:   service,
+		ServiceName: service,
+		Name:        firstLabel(labels, "alertmanager", "alertname"),
+		Status:      "critical",
+		Notes:       alert.GeneratorURL,
+		NodeMeta:    labels,
+		Type:        "alertmanager",
+	}
+	check.CheckID = "alertmanager:" + check.Name
+	if alert.Fingerprint != "" {
+		check.CheckID += ":" + alert.Fingerprint
+	}
+	switch {
+	case alert.Status == "resolved":
+		check.Status = "passing"
+	case labels["severity"] == "warning" || labels["severity"] == "info":
+		check.Status = "warning"
+	}
+	var output []string
+	for _, annotation := range []string{"summary", "description", "message"} {
+		if text := alert.Annotations[annotation]; text != "" {
+			output = append(output, text)
+		}
+	}
+	check.Output = strings.Join(output, "\n")
+	return check
+}
+
+func firstLabel(labels map[string]string, fallback string, names ...string) string {
+	for _, name := range names {
+		if value := labels[name]; value != "" {
+			return value
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAlertmanagerCheck(t *testing.T) {
+	check := alertmanagerCheck(alertmanagerAlert{
+		Status:       "firing",
+		Labels:       map[string]string{"alertname": "HighLatency", "instance": "api-1:9090", "job": "api", "severity": "warning", "team": "web"},
+		Annotations:  map[string]string{"summary": "p99 latency above 1s", "description": "p99 is 1.4s"},
+		GeneratorURL: "http://prometheus/graph",
+		Fingerprint:  "c6d1e3",
+	})
+	if check.Node != "api-1:9090" || check.ServiceName != "api" || check.Name != "HighLatency" || check.CheckID != "alertmanager:HighLatency:c6d1e3" {
+		t.Errorf("unexpected check %+v", check)
+	}
+	if check.Status != "warning" || check.Output != "p99 latency above 1s\np99 is 1.4s" || check.NodeMeta["team"] != "web" {
+		t.Errorf("unexpected check %+v", check)
+	}
+
+	if resolved := alertmanagerCheck(alertmanagerAlert{Status: "resolved", Labels: map[string]string{"alertname": "Down"}}); resolved.Status != "passing" || resolved.Node != "alertmanager" {
+		t.Errorf("unexpected resolved check %+v", resolved)
+	}
+	if firing := alertmanagerCheck(alertmanagerAlert{Status: "firing", Labels: map[string]string{"alertname": "Down", "severity": "page"}}); firing.Status != "critical" {
+		t.Errorf("expected a critical check, got %s", firing.Status)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+type alertState struct {
+	Datacenter      string     `json:"datacenter,omitempty"`
+	Namespace       string     `json:"namespace,omitempty"`
+	Node            string     `json:"node"`
+	ServiceId       string     `json:"serviceId"`
+	Service         string     `json:"service"`
+	CheckId         string     `json:"checkId"`
+	Check           string     `json:"check"`
+	Status          string     `json:"status"`
+	OriginalStatus  string     `json:"originalStatus,omitempty"`
+	StatusSince     *time.Time `json:"statusSince,omitempty"`
+	PendingStatus   string     `json:"pendingStatus,omitempty"`
+	PendingSince    *time.Time `json:"pendingSince,omitempty"`
+	LastNotified    *time.Time `json:"lastNotified,omitempty"`
+	ForNotification bool       `json:"forNotification"`
+	Acknowledged    *time.Time `json:"acknowledged,omitempty"`
+	AcknowledgedBy  string     `json:"acknowledgedBy,omitempty"`
+	Output          string     `json:"output"`
+	Notes           string     `json:"notes"`
+}
+
+// alertsHandler serves the tracked check states at /v1/alerts and the states
+// of a single node at /v1/alerts/{node}.
+func alertsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(405)
+		return
+	}
+
+	node := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/alerts"), "/")
+	statuses, err := checkStatuses(node)
+	if err != nil {
+		writeJson(w, 503, map[string]string{"error": err.Error()})
+		return
+	}
+	if node != "" && len(statuses) == 0 {
+		writeJson(w, 404, map[string]string{"error": "unknown node " + node})
+		return
+	}
+
+	alerts := make([]alertState, len(statuses))
+	for i, status := range statuses {
+		alerts[i] = toAlertState(status)
+	}
+	writeJson(w, 200, alerts)
+}
+
+func toAlertState(status consul.Status) alertState {
+	check := status.HealthCheck
+	return alertState{
+		Datacenter:      check.Datacenter,
+		Namespace:       check.Namespace,
+		Node:            check.Node,
+		ServiceId:       check.ServiceID,
+		Service:         check.ServiceName,
+		CheckId:         check.CheckID,
+		Check:           check.Name,
+		Status:          status.Current,
+		OriginalStatus:  check.OriginalStatus,
+		StatusSince:     timeOrNil(status.CurrentTimestamp),
+		PendingStatus:   status.Pending,
+		PendingSince:    timeOrNil(status.PendingTimestamp),
+		LastNotified:    timeOrNil(status.NotifiedTimestamp),
+		ForNotification: status.ForNotification,
+		Acknowledged:    timeOrNil(status.AcknowledgedTimestamp),
+		AcknowledgedBy:  status.AcknowledgedBy,
+		Output:          check.Output,
+		Notes:           check.Notes,
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+)
+
+// apiAuth protects the consul-alerts API with a shared token and/or basic
+// auth credentials. When neither is configured every request is allowed.
+type apiAuth struct {
+	token    string
+	username string
+	password string
+}
+
+func (a apiAuth) enabled() bool {
+	return a.token != "" || a.username != ""
+}
+
+func (a apiAuth) authorized(r *http.Request) bool {
+	if !a.enabled() {
+		return true
+	}
+	if a.token != "" {
+		token := r.Header.Get("X-Consul-Alerts-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token != "" && secureCompare(token, a.token) {
+			return true
+		}
+	}
+	if a.username != "" {
+		if username, password, ok := r.BasicAuth(); ok {
+			return secureCompare(username, a.username) && secureCompare(password, a.password)
+		}
+	}
+	return false
+}
+
+// wrap rejects unauthorized requests before calling the handler.
+func (a apiAuth) wrap(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			if a.username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="consul-alerts"`)
+			}
+			w.WriteHeader(401)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// alertApiClient returns the scheme and http client used to call the
+// consul-alerts API, trusting caFile if given.
+func alertApiClient(useTls bool, caFile string, skipVerify bool) (string, *http.Client, error) {
+	if !useTls {
+		return "http", http.DefaultClient, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipVerify}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return "", nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return "https", client, nil
+}
+
+// stringOption returns the value of a docopt option, falling back to the
+// environment variable when the option is not set.
+func stringOption(arguments map[string]interface{}, option, env string) string {
+	if value, ok := arguments[option].(string); ok && value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// intOption returns the value of a docopt option as an int, falling back to the
+// environment variable when the option is not set. It returns 0 if neither is
+// set.
+func intOption(arguments map[string]interface{}, option, env string) (int, error) {
+	value := stringOption(arguments, option, env)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// boolOption returns true if a docopt flag is set or the environment variable
+// is "true".
+func boolOption(arguments map[string]interface{}, option, env string) bool {
+	if value, ok := arguments[option].(bool); ok && value {
+		return true
+	}
+	return os.Getenv(env) == "true"
+}
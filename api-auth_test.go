@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"net/http"
+)
+
+func TestApiAuthToken(t *testing.T) {
+	auth := apiAuth{token: "secret"}
+	req, _ := http.NewRequest("POST", "/v1/process/checks", nil)
+	if auth.authorized(req) {
+		t.Error("request without token should be rejected")
+	}
+	req.Header.Set("X-Consul-Alerts-Token", "secret")
+	if !auth.authorized(req) {
+		t.Error("request with token should be allowed")
+	}
+}
+
+func TestApiAuthBasic(t *testing.T) {
+	auth := apiAuth{username: "ops", password: "pass"}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("ops", "wrong")
+	if auth.authorized(req) {
+		t.Error("request with wrong password should be rejected")
+	}
+	req.SetBasicAuth("ops", "pass")
+	if !auth.authorized(req) {
+		t.Error("request with credentials should be allowed")
+	}
+}
+
+func TestApiAuthDisabled(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	if !(apiAuth{}).authorized(req) {
+		t.Error("requests should be allowed when auth is disabled")
+	}
+}
@@ -0,0 +1,1269 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: alerts.proto
+
+package api
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/golang/protobuf/proto"
+	duration "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/golang/protobuf/ptypes/timestamp"
+	grpc "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc"
+	codes "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/codes"
+	status "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Alert struct {
+	Datacenter           string               `protobuf:"bytes,1,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Namespace            string               `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Node                 string               `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	ServiceId            string               `protobuf:"bytes,4,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	Service              string               `protobuf:"bytes,5,opt,name=service,proto3" json:"service,omitempty"`
+	CheckId              string               `protobuf:"bytes,6,opt,name=check_id,json=checkId,proto3" json:"check_id,omitempty"`
+	Check                string               `protobuf:"bytes,7,opt,name=check,proto3" json:"check,omitempty"`
+	Status               string               `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	StatusSince          *timestamp.Timestamp `protobuf:"bytes,9,opt,name=status_since,json=statusSince,proto3" json:"status_since,omitempty"`
+	PendingStatus        string               `protobuf:"bytes,10,opt,name=pending_status,json=pendingStatus,proto3" json:"pending_status,omitempty"`
+	PendingSince         *timestamp.Timestamp `protobuf:"bytes,11,opt,name=pending_since,json=pendingSince,proto3" json:"pending_since,omitempty"`
+	LastNotified         *timestamp.Timestamp `protobuf:"bytes,12,opt,name=last_notified,json=lastNotified,proto3" json:"last_notified,omitempty"`
+	ForNotification      bool                 `protobuf:"varint,13,opt,name=for_notification,json=forNotification,proto3" json:"for_notification,omitempty"`
+	Acknowledged         *timestamp.Timestamp `protobuf:"bytes,14,opt,name=acknowledged,proto3" json:"acknowledged,omitempty"`
+	AcknowledgedBy       string               `protobuf:"bytes,15,opt,name=acknowledged_by,json=acknowledgedBy,proto3" json:"acknowledged_by,omitempty"`
+	AcknowledgedComment  string               `protobuf:"bytes,16,opt,name=acknowledged_comment,json=acknowledgedComment,proto3" json:"acknowledged_comment,omitempty"`
+	Output               string               `protobuf:"bytes,17,opt,name=output,proto3" json:"output,omitempty"`
+	Notes                string               `protobuf:"bytes,18,opt,name=notes,proto3" json:"notes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Alert) Reset()         { *m = Alert{} }
+func (m *Alert) String() string { return proto.CompactTextString(m) }
+func (*Alert) ProtoMessage()    {}
+func (*Alert) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{0}
+}
+
+func (m *Alert) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Alert.Unmarshal(m, b)
+}
+func (m *Alert) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Alert.Marshal(b, m, deterministic)
+}
+func (m *Alert) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Alert.Merge(m, src)
+}
+func (m *Alert) XXX_Size() int {
+	return xxx_messageInfo_Alert.Size(m)
+}
+func (m *Alert) XXX_DiscardUnknown() {
+	xxx_messageInfo_Alert.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Alert proto.InternalMessageInfo
+
+func (m *Alert) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *Alert) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *Alert) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *Alert) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *Alert) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *Alert) GetCheckId() string {
+	if m != nil {
+		return m.CheckId
+	}
+	return ""
+}
+
+func (m *Alert) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+func (m *Alert) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Alert) GetStatusSince() *timestamp.Timestamp {
+	if m != nil {
+		return m.StatusSince
+	}
+	return nil
+}
+
+func (m *Alert) GetPendingStatus() string {
+	if m != nil {
+		return m.PendingStatus
+	}
+	return ""
+}
+
+func (m *Alert) GetPendingSince() *timestamp.Timestamp {
+	if m != nil {
+		return m.PendingSince
+	}
+	return nil
+}
+
+func (m *Alert) GetLastNotified() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastNotified
+	}
+	return nil
+}
+
+func (m *Alert) GetForNotification() bool {
+	if m != nil {
+		return m.ForNotification
+	}
+	return false
+}
+
+func (m *Alert) GetAcknowledged() *timestamp.Timestamp {
+	if m != nil {
+		return m.Acknowledged
+	}
+	return nil
+}
+
+func (m *Alert) GetAcknowledgedBy() string {
+	if m != nil {
+		return m.AcknowledgedBy
+	}
+	return ""
+}
+
+func (m *Alert) GetAcknowledgedComment() string {
+	if m != nil {
+		return m.AcknowledgedComment
+	}
+	return ""
+}
+
+func (m *Alert) GetOutput() string {
+	if m != nil {
+		return m.Output
+	}
+	return ""
+}
+
+func (m *Alert) GetNotes() string {
+	if m != nil {
+		return m.Notes
+	}
+	return ""
+}
+
+type ListAlertsRequest struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAlertsRequest) Reset()         { *m = ListAlertsRequest{} }
+func (m *ListAlertsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAlertsRequest) ProtoMessage()    {}
+func (*ListAlertsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{1}
+}
+
+func (m *ListAlertsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAlertsRequest.Unmarshal(m, b)
+}
+func (m *ListAlertsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAlertsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAlertsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAlertsRequest.Merge(m, src)
+}
+func (m *ListAlertsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAlertsRequest.Size(m)
+}
+func (m *ListAlertsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAlertsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAlertsRequest proto.InternalMessageInfo
+
+func (m *ListAlertsRequest) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *ListAlertsRequest) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+type ListAlertsResponse struct {
+	Alerts               []*Alert `protobuf:"bytes,1,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAlertsResponse) Reset()         { *m = ListAlertsResponse{} }
+func (m *ListAlertsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAlertsResponse) ProtoMessage()    {}
+func (*ListAlertsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{2}
+}
+
+func (m *ListAlertsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAlertsResponse.Unmarshal(m, b)
+}
+func (m *ListAlertsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAlertsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListAlertsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAlertsResponse.Merge(m, src)
+}
+func (m *ListAlertsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListAlertsResponse.Size(m)
+}
+func (m *ListAlertsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAlertsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAlertsResponse proto.InternalMessageInfo
+
+func (m *ListAlertsResponse) GetAlerts() []*Alert {
+	if m != nil {
+		return m.Alerts
+	}
+	return nil
+}
+
+type AcknowledgeRequest struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Check                string   `protobuf:"bytes,2,opt,name=check,proto3" json:"check,omitempty"`
+	By                   string   `protobuf:"bytes,3,opt,name=by,proto3" json:"by,omitempty"`
+	Comment              string   `protobuf:"bytes,4,opt,name=comment,proto3" json:"comment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcknowledgeRequest) Reset()         { *m = AcknowledgeRequest{} }
+func (m *AcknowledgeRequest) String() string { return proto.CompactTextString(m) }
+func (*AcknowledgeRequest) ProtoMessage()    {}
+func (*AcknowledgeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{3}
+}
+
+func (m *AcknowledgeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcknowledgeRequest.Unmarshal(m, b)
+}
+func (m *AcknowledgeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcknowledgeRequest.Marshal(b, m, deterministic)
+}
+func (m *AcknowledgeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcknowledgeRequest.Merge(m, src)
+}
+func (m *AcknowledgeRequest) XXX_Size() int {
+	return xxx_messageInfo_AcknowledgeRequest.Size(m)
+}
+func (m *AcknowledgeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcknowledgeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcknowledgeRequest proto.InternalMessageInfo
+
+func (m *AcknowledgeRequest) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *AcknowledgeRequest) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+func (m *AcknowledgeRequest) GetBy() string {
+	if m != nil {
+		return m.By
+	}
+	return ""
+}
+
+func (m *AcknowledgeRequest) GetComment() string {
+	if m != nil {
+		return m.Comment
+	}
+	return ""
+}
+
+type Check struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	ServiceId            string   `protobuf:"bytes,2,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	CheckId              string   `protobuf:"bytes,3,opt,name=check_id,json=checkId,proto3" json:"check_id,omitempty"`
+	Status               string   `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Check) Reset()         { *m = Check{} }
+func (m *Check) String() string { return proto.CompactTextString(m) }
+func (*Check) ProtoMessage()    {}
+func (*Check) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{4}
+}
+
+func (m *Check) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Check.Unmarshal(m, b)
+}
+func (m *Check) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Check.Marshal(b, m, deterministic)
+}
+func (m *Check) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Check.Merge(m, src)
+}
+func (m *Check) XXX_Size() int {
+	return xxx_messageInfo_Check.Size(m)
+}
+func (m *Check) XXX_DiscardUnknown() {
+	xxx_messageInfo_Check.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Check proto.InternalMessageInfo
+
+func (m *Check) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *Check) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *Check) GetCheckId() string {
+	if m != nil {
+		return m.CheckId
+	}
+	return ""
+}
+
+func (m *Check) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+type AcknowledgeResponse struct {
+	Checks               []*Check `protobuf:"bytes,1,rep,name=checks,proto3" json:"checks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AcknowledgeResponse) Reset()         { *m = AcknowledgeResponse{} }
+func (m *AcknowledgeResponse) String() string { return proto.CompactTextString(m) }
+func (*AcknowledgeResponse) ProtoMessage()    {}
+func (*AcknowledgeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{5}
+}
+
+func (m *AcknowledgeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AcknowledgeResponse.Unmarshal(m, b)
+}
+func (m *AcknowledgeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AcknowledgeResponse.Marshal(b, m, deterministic)
+}
+func (m *AcknowledgeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AcknowledgeResponse.Merge(m, src)
+}
+func (m *AcknowledgeResponse) XXX_Size() int {
+	return xxx_messageInfo_AcknowledgeResponse.Size(m)
+}
+func (m *AcknowledgeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AcknowledgeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AcknowledgeResponse proto.InternalMessageInfo
+
+func (m *AcknowledgeResponse) GetChecks() []*Check {
+	if m != nil {
+		return m.Checks
+	}
+	return nil
+}
+
+type Silence struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Node                 string               `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Service              string               `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	Check                string               `protobuf:"bytes,4,opt,name=check,proto3" json:"check,omitempty"`
+	StartsAt             *timestamp.Timestamp `protobuf:"bytes,5,opt,name=starts_at,json=startsAt,proto3" json:"starts_at,omitempty"`
+	EndsAt               *timestamp.Timestamp `protobuf:"bytes,6,opt,name=ends_at,json=endsAt,proto3" json:"ends_at,omitempty"`
+	CreatedBy            string               `protobuf:"bytes,7,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Comment              string               `protobuf:"bytes,8,opt,name=comment,proto3" json:"comment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Silence) Reset()         { *m = Silence{} }
+func (m *Silence) String() string { return proto.CompactTextString(m) }
+func (*Silence) ProtoMessage()    {}
+func (*Silence) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{6}
+}
+
+func (m *Silence) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Silence.Unmarshal(m, b)
+}
+func (m *Silence) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Silence.Marshal(b, m, deterministic)
+}
+func (m *Silence) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Silence.Merge(m, src)
+}
+func (m *Silence) XXX_Size() int {
+	return xxx_messageInfo_Silence.Size(m)
+}
+func (m *Silence) XXX_DiscardUnknown() {
+	xxx_messageInfo_Silence.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Silence proto.InternalMessageInfo
+
+func (m *Silence) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Silence) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *Silence) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *Silence) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+func (m *Silence) GetStartsAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.StartsAt
+	}
+	return nil
+}
+
+func (m *Silence) GetEndsAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.EndsAt
+	}
+	return nil
+}
+
+func (m *Silence) GetCreatedBy() string {
+	if m != nil {
+		return m.CreatedBy
+	}
+	return ""
+}
+
+func (m *Silence) GetComment() string {
+	if m != nil {
+		return m.Comment
+	}
+	return ""
+}
+
+type ListSilencesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListSilencesRequest) Reset()         { *m = ListSilencesRequest{} }
+func (m *ListSilencesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSilencesRequest) ProtoMessage()    {}
+func (*ListSilencesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{7}
+}
+
+func (m *ListSilencesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSilencesRequest.Unmarshal(m, b)
+}
+func (m *ListSilencesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSilencesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListSilencesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSilencesRequest.Merge(m, src)
+}
+func (m *ListSilencesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListSilencesRequest.Size(m)
+}
+func (m *ListSilencesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSilencesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSilencesRequest proto.InternalMessageInfo
+
+type ListSilencesResponse struct {
+	Silences             []*Silence `protobuf:"bytes,1,rep,name=silences,proto3" json:"silences,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListSilencesResponse) Reset()         { *m = ListSilencesResponse{} }
+func (m *ListSilencesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSilencesResponse) ProtoMessage()    {}
+func (*ListSilencesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{8}
+}
+
+func (m *ListSilencesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListSilencesResponse.Unmarshal(m, b)
+}
+func (m *ListSilencesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListSilencesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListSilencesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListSilencesResponse.Merge(m, src)
+}
+func (m *ListSilencesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListSilencesResponse.Size(m)
+}
+func (m *ListSilencesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListSilencesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListSilencesResponse proto.InternalMessageInfo
+
+func (m *ListSilencesResponse) GetSilences() []*Silence {
+	if m != nil {
+		return m.Silences
+	}
+	return nil
+}
+
+type CreateSilenceRequest struct {
+	Silence              *Silence           `protobuf:"bytes,1,opt,name=silence,proto3" json:"silence,omitempty"`
+	Duration             *duration.Duration `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *CreateSilenceRequest) Reset()         { *m = CreateSilenceRequest{} }
+func (m *CreateSilenceRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateSilenceRequest) ProtoMessage()    {}
+func (*CreateSilenceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{9}
+}
+
+func (m *CreateSilenceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateSilenceRequest.Unmarshal(m, b)
+}
+func (m *CreateSilenceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateSilenceRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateSilenceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateSilenceRequest.Merge(m, src)
+}
+func (m *CreateSilenceRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateSilenceRequest.Size(m)
+}
+func (m *CreateSilenceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateSilenceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateSilenceRequest proto.InternalMessageInfo
+
+func (m *CreateSilenceRequest) GetSilence() *Silence {
+	if m != nil {
+		return m.Silence
+	}
+	return nil
+}
+
+func (m *CreateSilenceRequest) GetDuration() *duration.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+type DeleteSilenceRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSilenceRequest) Reset()         { *m = DeleteSilenceRequest{} }
+func (m *DeleteSilenceRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteSilenceRequest) ProtoMessage()    {}
+func (*DeleteSilenceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{10}
+}
+
+func (m *DeleteSilenceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSilenceRequest.Unmarshal(m, b)
+}
+func (m *DeleteSilenceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSilenceRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteSilenceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSilenceRequest.Merge(m, src)
+}
+func (m *DeleteSilenceRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteSilenceRequest.Size(m)
+}
+func (m *DeleteSilenceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSilenceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSilenceRequest proto.InternalMessageInfo
+
+func (m *DeleteSilenceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteSilenceResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSilenceResponse) Reset()         { *m = DeleteSilenceResponse{} }
+func (m *DeleteSilenceResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteSilenceResponse) ProtoMessage()    {}
+func (*DeleteSilenceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{11}
+}
+
+func (m *DeleteSilenceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSilenceResponse.Unmarshal(m, b)
+}
+func (m *DeleteSilenceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSilenceResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteSilenceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSilenceResponse.Merge(m, src)
+}
+func (m *DeleteSilenceResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteSilenceResponse.Size(m)
+}
+func (m *DeleteSilenceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSilenceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSilenceResponse proto.InternalMessageInfo
+
+type WatchTransitionsRequest struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Service              string   `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Check                string   `protobuf:"bytes,3,opt,name=check,proto3" json:"check,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchTransitionsRequest) Reset()         { *m = WatchTransitionsRequest{} }
+func (m *WatchTransitionsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchTransitionsRequest) ProtoMessage()    {}
+func (*WatchTransitionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{12}
+}
+
+func (m *WatchTransitionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchTransitionsRequest.Unmarshal(m, b)
+}
+func (m *WatchTransitionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchTransitionsRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchTransitionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchTransitionsRequest.Merge(m, src)
+}
+func (m *WatchTransitionsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchTransitionsRequest.Size(m)
+}
+func (m *WatchTransitionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchTransitionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchTransitionsRequest proto.InternalMessageInfo
+
+func (m *WatchTransitionsRequest) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *WatchTransitionsRequest) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *WatchTransitionsRequest) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+type Transition struct {
+	Time                 *timestamp.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Datacenter           string               `protobuf:"bytes,2,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Node                 string               `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	ServiceId            string               `protobuf:"bytes,4,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	Service              string               `protobuf:"bytes,5,opt,name=service,proto3" json:"service,omitempty"`
+	CheckId              string               `protobuf:"bytes,6,opt,name=check_id,json=checkId,proto3" json:"check_id,omitempty"`
+	Check                string               `protobuf:"bytes,7,opt,name=check,proto3" json:"check,omitempty"`
+	From                 string               `protobuf:"bytes,8,opt,name=from,proto3" json:"from,omitempty"`
+	Status               string               `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	Output               string               `protobuf:"bytes,10,opt,name=output,proto3" json:"output,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Transition) Reset()         { *m = Transition{} }
+func (m *Transition) String() string { return proto.CompactTextString(m) }
+func (*Transition) ProtoMessage()    {}
+func (*Transition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{13}
+}
+
+func (m *Transition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Transition.Unmarshal(m, b)
+}
+func (m *Transition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Transition.Marshal(b, m, deterministic)
+}
+func (m *Transition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Transition.Merge(m, src)
+}
+func (m *Transition) XXX_Size() int {
+	return xxx_messageInfo_Transition.Size(m)
+}
+func (m *Transition) XXX_DiscardUnknown() {
+	xxx_messageInfo_Transition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Transition proto.InternalMessageInfo
+
+func (m *Transition) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *Transition) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *Transition) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *Transition) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *Transition) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *Transition) GetCheckId() string {
+	if m != nil {
+		return m.CheckId
+	}
+	return ""
+}
+
+func (m *Transition) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+func (m *Transition) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *Transition) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Transition) GetOutput() string {
+	if m != nil {
+		return m.Output
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Alert)(nil), "consulalerts.Alert")
+	proto.RegisterType((*ListAlertsRequest)(nil), "consulalerts.ListAlertsRequest")
+	proto.RegisterType((*ListAlertsResponse)(nil), "consulalerts.ListAlertsResponse")
+	proto.RegisterType((*AcknowledgeRequest)(nil), "consulalerts.AcknowledgeRequest")
+	proto.RegisterType((*Check)(nil), "consulalerts.Check")
+	proto.RegisterType((*AcknowledgeResponse)(nil), "consulalerts.AcknowledgeResponse")
+	proto.RegisterType((*Silence)(nil), "consulalerts.Silence")
+	proto.RegisterType((*ListSilencesRequest)(nil), "consulalerts.ListSilencesRequest")
+	proto.RegisterType((*ListSilencesResponse)(nil), "consulalerts.ListSilencesResponse")
+	proto.RegisterType((*CreateSilenceRequest)(nil), "consulalerts.CreateSilenceRequest")
+	proto.RegisterType((*DeleteSilenceRequest)(nil), "consulalerts.DeleteSilenceRequest")
+	proto.RegisterType((*DeleteSilenceResponse)(nil), "consulalerts.DeleteSilenceResponse")
+	proto.RegisterType((*WatchTransitionsRequest)(nil), "consulalerts.WatchTransitionsRequest")
+	proto.RegisterType((*Transition)(nil), "consulalerts.Transition")
+}
+
+func init() { proto.RegisterFile("alerts.proto", fileDescriptor_20493709c38b81dc) }
+
+var fileDescriptor_20493709c38b81dc = []byte{
+	// 907 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x55, 0x5d, 0x6f, 0xe4, 0x34,
+	0x14, 0xd5, 0x64, 0x32, 0x5f, 0x77, 0x3e, 0xda, 0x75, 0xa7, 0xac, 0x37, 0x82, 0xdd, 0xd9, 0xa0,
+	0x85, 0x22, 0xa4, 0x29, 0xdb, 0x15, 0xe2, 0x09, 0xaa, 0x69, 0xf7, 0xa5, 0x08, 0x2d, 0x52, 0xba,
+	0x08, 0x84, 0x84, 0x46, 0x9e, 0xc4, 0xd3, 0x46, 0x9d, 0x89, 0x87, 0xd8, 0x03, 0xea, 0x0b, 0x12,
+	0x12, 0xbf, 0x83, 0xdf, 0xc1, 0xcf, 0x43, 0xb1, 0x9d, 0xc4, 0xce, 0x7c, 0x3d, 0xee, 0x5b, 0x7c,
+	0x7d, 0xee, 0x75, 0x7c, 0xee, 0x39, 0xd7, 0xd0, 0x23, 0x0b, 0x9a, 0x0a, 0x3e, 0x5e, 0xa5, 0x4c,
+	0x30, 0xd4, 0x0b, 0x59, 0xc2, 0xd7, 0x0b, 0x15, 0xf3, 0x9e, 0xdf, 0x31, 0x76, 0xb7, 0xa0, 0xe7,
+	0x72, 0x6f, 0xb6, 0x9e, 0x9f, 0x47, 0xeb, 0x94, 0x88, 0x98, 0x25, 0x0a, 0xed, 0xbd, 0xa8, 0xee,
+	0x8b, 0x78, 0x49, 0xb9, 0x20, 0xcb, 0x95, 0x02, 0xf8, 0xff, 0x35, 0xa0, 0x31, 0xc9, 0x6a, 0xa1,
+	0xe7, 0x00, 0x11, 0x11, 0x24, 0xa4, 0x89, 0xa0, 0x29, 0xae, 0x8d, 0x6a, 0x67, 0x9d, 0xc0, 0x88,
+	0xa0, 0x8f, 0xa1, 0x93, 0x90, 0x25, 0xe5, 0x2b, 0x12, 0x52, 0xec, 0xc8, 0xed, 0x32, 0x80, 0x10,
+	0xb8, 0x09, 0x8b, 0x28, 0xae, 0xcb, 0x0d, 0xf9, 0x8d, 0x3e, 0x01, 0xe0, 0x34, 0xfd, 0x23, 0x0e,
+	0xe9, 0x34, 0x8e, 0xb0, 0xab, 0x52, 0x74, 0xe4, 0x26, 0x42, 0x18, 0x5a, 0x7a, 0x81, 0x1b, 0x72,
+	0x2f, 0x5f, 0xa2, 0x67, 0xd0, 0x0e, 0xef, 0x69, 0xf8, 0x90, 0xa5, 0x35, 0xd5, 0x96, 0x5c, 0xdf,
+	0x44, 0x68, 0x08, 0x0d, 0xf9, 0x89, 0x5b, 0x32, 0xae, 0x16, 0xe8, 0x23, 0x68, 0x72, 0x41, 0xc4,
+	0x9a, 0xe3, 0xb6, 0x0c, 0xeb, 0x15, 0xfa, 0x16, 0x7a, 0xea, 0x6b, 0xca, 0xe3, 0x24, 0xa4, 0xb8,
+	0x33, 0xaa, 0x9d, 0x75, 0x2f, 0xbc, 0xb1, 0x62, 0x65, 0x9c, 0xb3, 0x32, 0x7e, 0x9f, 0xb3, 0x12,
+	0x74, 0x15, 0xfe, 0x36, 0x83, 0xa3, 0x57, 0x30, 0x58, 0xd1, 0x24, 0x8a, 0x93, 0xbb, 0xa9, 0x2e,
+	0x0f, 0xb2, 0x7c, 0x5f, 0x47, 0x6f, 0xd5, 0x29, 0x97, 0xd0, 0x2f, 0x60, 0xf2, 0x98, 0xee, 0xc1,
+	0x63, 0x7a, 0x79, 0x05, 0x79, 0xce, 0x25, 0xf4, 0x17, 0x84, 0x8b, 0x69, 0xc2, 0x44, 0x3c, 0x8f,
+	0x69, 0x84, 0x7b, 0x87, 0x0b, 0x64, 0x09, 0xef, 0x34, 0x1e, 0x7d, 0x01, 0xc7, 0x73, 0x96, 0xea,
+	0xfc, 0x50, 0x0a, 0x00, 0xf7, 0x47, 0xb5, 0xb3, 0x76, 0x70, 0x34, 0x67, 0xe9, 0x3b, 0x23, 0x8c,
+	0xbe, 0x83, 0x1e, 0x09, 0x1f, 0x12, 0xf6, 0xe7, 0x82, 0x46, 0x77, 0x34, 0xc2, 0x83, 0xc3, 0x47,
+	0x99, 0x78, 0xf4, 0x39, 0x1c, 0x99, 0xeb, 0xe9, 0xec, 0x11, 0x1f, 0x49, 0x52, 0x06, 0x66, 0xf8,
+	0xea, 0x11, 0xbd, 0x86, 0xa1, 0x05, 0x0c, 0xd9, 0x72, 0x49, 0x13, 0x81, 0x8f, 0x25, 0xfa, 0xc4,
+	0xdc, 0xbb, 0x56, 0x5b, 0x59, 0x1b, 0xd9, 0x5a, 0xac, 0xd6, 0x02, 0x3f, 0x51, 0x6d, 0x54, 0xab,
+	0xac, 0xe9, 0x09, 0x13, 0x94, 0x63, 0xa4, 0x9a, 0x2e, 0x17, 0xfe, 0x25, 0x3c, 0xf9, 0x21, 0xe6,
+	0x42, 0xaa, 0x97, 0x07, 0xf4, 0xf7, 0x35, 0xe5, 0xa2, 0xd0, 0x61, 0xcd, 0xd0, 0x61, 0xa9, 0x0e,
+	0xc7, 0x54, 0x87, 0x3f, 0x01, 0x64, 0x16, 0xe0, 0x2b, 0x96, 0x70, 0x8a, 0xbe, 0x84, 0xa6, 0x32,
+	0x17, 0xae, 0x8d, 0xea, 0x67, 0xdd, 0x8b, 0x93, 0xb1, 0xe9, 0xb8, 0xb1, 0x44, 0x07, 0x1a, 0xe2,
+	0xdf, 0x03, 0x9a, 0x94, 0x17, 0xd9, 0xf7, 0x13, 0x85, 0x70, 0x1d, 0x53, 0xb8, 0x03, 0x70, 0x66,
+	0x8f, 0xda, 0x34, 0xce, 0xec, 0x31, 0xf3, 0x44, 0xce, 0x93, 0xab, 0x85, 0xaf, 0x96, 0xfe, 0x12,
+	0x1a, 0xd7, 0x32, 0x65, 0x5b, 0x71, 0xdb, 0x69, 0x4e, 0xd5, 0x69, 0xa6, 0x9f, 0xea, 0xb6, 0x9f,
+	0x4a, 0x6e, 0x5c, 0x8b, 0x9b, 0x2b, 0x38, 0xb1, 0x2e, 0x56, 0x92, 0x23, 0x33, 0x77, 0x90, 0x23,
+	0xff, 0x30, 0xd0, 0x10, 0xff, 0x1f, 0x07, 0x5a, 0xb7, 0xf1, 0x82, 0x66, 0x12, 0x1f, 0x80, 0x13,
+	0x47, 0xfa, 0x9f, 0x9d, 0x38, 0x2a, 0x6e, 0xe1, 0x18, 0xb7, 0x30, 0x06, 0x42, 0xdd, 0x1e, 0x08,
+	0x05, 0x79, 0xae, 0x49, 0xde, 0x37, 0xd0, 0xe1, 0x82, 0xa4, 0x82, 0x4f, 0x89, 0xc0, 0x8d, 0x83,
+	0x3a, 0x6e, 0x2b, 0xf0, 0x44, 0xa0, 0x37, 0xd0, 0xa2, 0x49, 0x24, 0xd3, 0x9a, 0x07, 0xd3, 0x9a,
+	0x19, 0x74, 0x22, 0x32, 0x8e, 0xc3, 0x94, 0x12, 0xa1, 0x34, 0xaf, 0xc6, 0x4f, 0x47, 0x47, 0xae,
+	0xac, 0xce, 0xb5, 0xed, 0xce, 0x9d, 0xc2, 0x49, 0x26, 0x33, 0xcd, 0x44, 0xae, 0x54, 0xff, 0x06,
+	0x86, 0x76, 0x58, 0x53, 0xfc, 0x1a, 0xda, 0x5c, 0xc7, 0x34, 0xc9, 0xa7, 0x36, 0xc9, 0x3a, 0x23,
+	0x28, 0x60, 0xfe, 0x5f, 0x30, 0xbc, 0x96, 0x3f, 0x92, 0x6f, 0x69, 0x1d, 0x9e, 0x43, 0x4b, 0x63,
+	0x24, 0xf3, 0x3b, 0x2b, 0xe5, 0x28, 0xf4, 0x35, 0xb4, 0xf3, 0x07, 0x44, 0x76, 0xa6, 0x7b, 0xf1,
+	0x6c, 0x83, 0x99, 0xb7, 0x1a, 0x10, 0x14, 0x50, 0xff, 0x33, 0x18, 0xbe, 0xa5, 0x0b, 0xba, 0x71,
+	0x7e, 0xa5, 0xe9, 0xfe, 0x53, 0x38, 0xad, 0xe0, 0xd4, 0x9d, 0xfd, 0xdf, 0xe0, 0xe9, 0xcf, 0x44,
+	0x84, 0xf7, 0xef, 0x53, 0x92, 0xf0, 0x38, 0xab, 0xb9, 0xd7, 0xd0, 0x86, 0x50, 0x9c, 0x1d, 0x42,
+	0xa9, 0x1b, 0x42, 0xf1, 0xff, 0x75, 0x00, 0xca, 0xd2, 0x68, 0x0c, 0x6e, 0xf6, 0x0c, 0xe2, 0xda,
+	0xc1, 0xde, 0x4b, 0x5c, 0xe5, 0x65, 0x74, 0x36, 0x5e, 0xc6, 0x0f, 0xfb, 0xf6, 0x21, 0x70, 0xe7,
+	0x29, 0x5b, 0x6a, 0xd5, 0xc9, 0x6f, 0xc3, 0xd5, 0x1d, 0xeb, 0x3d, 0x2c, 0x07, 0x2c, 0x98, 0x03,
+	0xf6, 0xe2, 0x6f, 0x17, 0x9a, 0x6a, 0x0c, 0xa2, 0x1f, 0x01, 0xca, 0xa1, 0x88, 0x5e, 0xd8, 0x82,
+	0xd9, 0x98, 0xb7, 0xde, 0x68, 0x37, 0x40, 0xeb, 0x39, 0x80, 0xae, 0x31, 0x49, 0x50, 0x25, 0x61,
+	0x73, 0x7a, 0x7a, 0x2f, 0xf7, 0x20, 0x74, 0xcd, 0x9f, 0xa0, 0x67, 0x7a, 0x07, 0xbd, 0xdc, 0xfc,
+	0x8b, 0x8a, 0xdd, 0x3c, 0x7f, 0x1f, 0x44, 0x97, 0xfd, 0x1e, 0xfa, 0x96, 0x8f, 0x50, 0x25, 0x69,
+	0x9b, 0xc9, 0xbc, 0xed, 0x9e, 0x42, 0xbf, 0x40, 0xdf, 0xd2, 0x7a, 0xb5, 0xd6, 0x36, 0xc3, 0x78,
+	0x9f, 0xee, 0xc5, 0x14, 0x97, 0x3f, 0xae, 0x9a, 0x05, 0xbd, 0xb2, 0x13, 0x77, 0x98, 0xc9, 0xc3,
+	0x36, 0xac, 0x44, 0x7c, 0x55, 0xbb, 0x6a, 0xfc, 0x5a, 0x27, 0xab, 0x78, 0xd6, 0x94, 0x36, 0x78,
+	0xf3, 0xff, 0x00, 0xe9, 0xfd, 0x6b, 0x38, 0x76, 0x0a, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AlertsClient is the client API for Alerts service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AlertsClient interface {
+	ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error)
+	Acknowledge(ctx context.Context, in *AcknowledgeRequest, opts ...grpc.CallOption) (*AcknowledgeResponse, error)
+	ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error)
+	CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error)
+	DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error)
+	WatchTransitions(ctx context.Context, in *WatchTransitionsRequest, opts ...grpc.CallOption) (Alerts_WatchTransitionsClient, error)
+}
+
+type alertsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAlertsClient(cc *grpc.ClientConn) AlertsClient {
+	return &alertsClient{cc}
+}
+
+func (c *alertsClient) ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error) {
+	out := new(ListAlertsResponse)
+	err := c.cc.Invoke(ctx, "/consulalerts.Alerts/ListAlerts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertsClient) Acknowledge(ctx context.Context, in *AcknowledgeRequest, opts ...grpc.CallOption) (*AcknowledgeResponse, error) {
+	out := new(AcknowledgeResponse)
+	err := c.cc.Invoke(ctx, "/consulalerts.Alerts/Acknowledge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertsClient) ListSilences(ctx context.Context, in *ListSilencesRequest, opts ...grpc.CallOption) (*ListSilencesResponse, error) {
+	out := new(ListSilencesResponse)
+	err := c.cc.Invoke(ctx, "/consulalerts.Alerts/ListSilences", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertsClient) CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error) {
+	out := new(Silence)
+	err := c.cc.Invoke(ctx, "/consulalerts.Alerts/CreateSilence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertsClient) DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error) {
+	out := new(DeleteSilenceResponse)
+	err := c.cc.Invoke(ctx, "/consulalerts.Alerts/DeleteSilence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertsClient) WatchTransitions(ctx context.Context, in *WatchTransitionsRequest, opts ...grpc.CallOption) (Alerts_WatchTransitionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Alerts_serviceDesc.Streams[0], "/consulalerts.Alerts/WatchTransitions", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &alertsWatchTransitionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Alerts_WatchTransitionsClient interface {
+	Recv() (*Transition, error)
+	grpc.ClientStream
+}
+
+type alertsWatchTransitionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *alertsWatchTransitionsClient) Recv() (*Transition, error) {
+	m := new(Transition)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AlertsServer is the server API for Alerts service.
+type AlertsServer interface {
+	ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error)
+	Acknowledge(context.Context, *AcknowledgeRequest) (*AcknowledgeResponse, error)
+	ListSilences(context.Context, *ListSilencesRequest) (*ListSilencesResponse, error)
+	CreateSilence(context.Context, *CreateSilenceRequest) (*Silence, error)
+	DeleteSilence(context.Context, *DeleteSilenceRequest) (*DeleteSilenceResponse, error)
+	WatchTransitions(*WatchTransitionsRequest, Alerts_WatchTransitionsServer) error
+}
+
+// UnimplementedAlertsServer can be embedded to have forward compatible implementations.
+type UnimplementedAlertsServer struct {
+}
+
+func (*UnimplementedAlertsServer) ListAlerts(ctx context.Context, req *ListAlertsRequest) (*ListAlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (*UnimplementedAlertsServer) Acknowledge(ctx context.Context, req *AcknowledgeRequest) (*AcknowledgeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acknowledge not implemented")
+}
+func (*UnimplementedAlertsServer) ListSilences(ctx context.Context, req *ListSilencesRequest) (*ListSilencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSilences not implemented")
+}
+func (*UnimplementedAlertsServer) CreateSilence(ctx context.Context, req *CreateSilenceRequest) (*Silence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSilence not implemented")
+}
+func (*UnimplementedAlertsServer) DeleteSilence(ctx context.Context, req *DeleteSilenceRequest) (*DeleteSilenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSilence not implemented")
+}
+func (*UnimplementedAlertsServer) WatchTransitions(req *WatchTransitionsRequest, srv Alerts_WatchTransitionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTransitions not implemented")
+}
+
+func RegisterAlertsServer(s *grpc.Server, srv AlertsServer) {
+	s.RegisterService(&_Alerts_serviceDesc, srv)
+}
+
+func _Alerts_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertsServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/consulalerts.Alerts/ListAlerts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertsServer).ListAlerts(ctx, req.(*ListAlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Alerts_Acknowledge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcknowledgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertsServer).Acknowledge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/consulalerts.Alerts/Acknowledge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertsServer).Acknowledge(ctx, req.(*AcknowledgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Alerts_ListSilences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSilencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertsServer).ListSilences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/consulalerts.Alerts/ListSilences",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertsServer).ListSilences(ctx, req.(*ListSilencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Alerts_CreateSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertsServer).CreateSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/consulalerts.Alerts/CreateSilence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertsServer).CreateSilence(ctx, req.(*CreateSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Alerts_DeleteSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertsServer).DeleteSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/consulalerts.Alerts/DeleteSilence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertsServer).DeleteSilence(ctx, req.(*DeleteSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Alerts_WatchTransitions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTransitionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AlertsServer).WatchTransitions(m, &alertsWatchTransitionsServer{stream})
+}
+
+type Alerts_WatchTransitionsServer interface {
+	Send(*Transition) error
+	grpc.ServerStream
+}
+
+type alertsWatchTransitionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *alertsWatchTransitionsServer) Send(m *Transition) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Alerts_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "consulalerts.Alerts",
+	HandlerType: (*AlertsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAlerts",
+			Handler:    _Alerts_ListAlerts_Handler,
+		},
+		{
+			MethodName: "Acknowledge",
+			Handler:    _Alerts_Acknowledge_Handler,
+		},
+		{
+			MethodName: "ListSilences",
+			Handler:    _Alerts_ListSilences_Handler,
+		},
+		{
+			MethodName: "CreateSilence",
+			Handler:    _Alerts_CreateSilence_Handler,
+		},
+		{
+			MethodName: "DeleteSilence",
+			Handler:    _Alerts_DeleteSilence_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTransitions",
+			Handler:       _Alerts_WatchTransitions_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "alerts.proto",
+}
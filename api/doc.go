@@ -0,0 +1,6 @@
+// Package api is the consul-alerts gRPC service generated from alerts.proto
+// by protoc 3 and protoc-gen-go v1.3.2, with the imports rewritten to the
+// Godeps packages.
+package api
+
+//go:generate protoc --go_out=plugins=grpc:. alerts.proto
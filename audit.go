@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// auditLog is the file the delivery attempts are appended to. It is nil
+// unless --audit-file is set.
+var auditLog = struct {
+	sync.Mutex
+	file *os.File
+}{}
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Started   time.Time    `json:"started"`
+	Finished  time.Time    `json:"finished"`
+	Notifier  string       `json:"notifier"`
+	Receivers []string     `json:"receivers"`
+	Alerts    []auditAlert `json:"alerts"`
+	Success   bool         `json:"success"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type auditAlert struct {
+	Datacenter string `json:"datacenter,omitempty"`
+	Node       string `json:"node"`
+	Service    string `json:"service,omitempty"`
+	CheckId    string `json:"checkId"`
+	Check      string `json:"check"`
+	Status     string `json:"status"`
+}
+
+// openAuditLog appends the delivery attempts to path, one JSON object per
+// line. The file is never rotated so no record is lost.
+func openAuditLog(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	auditLog.file = file
+	return nil
+}
+
+func closeAuditLog() {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file != nil {
+		auditLog.file.Close()
+		auditLog.file = nil
+	}
+}
+
+// auditDelivery appends a delivery attempt of the messages to the audit log,
+// failed when err is set.
+func auditDelivery(name string, receivers []string, messages notifier.Messages, started time.Time, err error) {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+
+	entry := auditEntry{
+		Started:   started,
+		Finished:  time.Now(),
+		Notifier:  name,
+		Receivers: receivers,
+		Alerts:    make([]auditAlert, len(messages)),
+		Success:   err == nil,
+	}
+	if entry.Receivers == nil {
+		entry.Receivers = []string{}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	for i, message := range messages {
+		entry.Alerts[i] = auditAlert{
+			Datacenter: message.Datacenter,
+			Node:       message.Node,
+			Service:    message.Service,
+			CheckId:    message.CheckId,
+			Check:      message.Check,
+			Status:     message.Status,
+		}
+	}
+	data, _ := json.Marshal(entry)
+	if _, err := auditLog.file.Write(append(data, '\n')); err != nil {
+		log.Errorln("Unable to write the audit log:", err)
+	}
+}
+
+// notifierReceivers returns where a notifier delivers the messages: the email
+// addresses, the slack channel, the hipchat room, the victorops routing key,
+// the zulip stream, the nats subject, the mqtt broker, the splunk index, the
+// nrdp endpoint, the zabbix server, the sqs queue and the eventbridge bus, the
+// pubsub topic, the log file or the influxdb database.
+func notifierReceivers(n notifier.Notifier, messages notifier.Messages) []string {
+	switch n := n.(type) {
+	case *notifier.EmailNotifier:
+		return n.Recipients(messages)
+	case *notifier.SlackNotifier:
+		if n.Channel != "" {
+			return []string{n.Channel}
+		}
+	case *notifier.HipChatNotifier:
+		return []string{n.RoomId}
+	case *notifier.VictorOpsNotifier:
+		return []string{n.RoutingKey}
+	case *notifier.ZulipNotifier:
+		return []string{n.Stream}
+	case *notifier.NatsNotifier:
+		return []string{n.Subject}
+	case *notifier.MqttNotifier:
+		return []string{n.Url}
+	case *notifier.SplunkNotifier:
+		return []string{n.Url + "/" + n.Index}
+	case *notifier.LogNotifier:
+		return []string{n.LogFile}
+	case *notifier.InfluxdbNotifier:
+		return []string{n.Host + "/" + n.Database}
+	case *notifier.NrdpNotifier:
+		return []string{n.Url}
+	case *notifier.ZabbixNotifier:
+		return []string{n.Server}
+	case *notifier.AwsNotifier:
+		var receivers []string
+		for _, receiver := range []string{n.QueueUrl, n.EventBus} {
+			if receiver != "" {
+				receivers = append(receivers, receiver)
+			}
+		}
+		return receivers
+	case *notifier.PubsubNotifier:
+		return []string{n.Project + "/" + n.Topic}
+	}
+	return nil
+}
+
+// customReceivers returns the program of a custom notifier, its arguments may
+// hold secrets.
+func customReceivers(command string) []string {
+	args, err := parseCommand(command)
+	if err != nil {
+		return nil
+	}
+	return []string{args[0]}
+}
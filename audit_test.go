@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+func TestAuditDelivery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := openAuditLog(path); err != nil {
+		t.Fatal(err)
+	}
+	defer closeAuditLog()
+
+	messages := notifier.Messages{{Node: "web-1", Service: "nginx", CheckId: "service:nginx", Check: "HTTP", Status: "critical"}}
+	email := &notifier.EmailNotifier{Receivers: []string{"ops@example.com"}, Bcc: []string{"audit@example.com"}}
+	auditDelivery("email", notifierReceivers(email, messages), messages, time.Now(), errors.New("dial tcp: connection refused"))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %s", data, err)
+	}
+	if entry.Notifier != "email" || entry.Success || entry.Error != "dial tcp: connection refused" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if len(entry.Receivers) != 2 || entry.Receivers[0] != "audit@example.com" || entry.Receivers[1] != "ops@example.com" {
+		t.Errorf("expected the email and bcc receivers, got %v", entry.Receivers)
+	}
+	if len(entry.Alerts) != 1 || entry.Alerts[0].Node != "web-1" || entry.Alerts[0].Status != "critical" {
+		t.Errorf("unexpected alerts: %+v", entry.Alerts)
+	}
+}
+
+func TestCustomReceivers(t *testing.T) {
+	receivers := customReceivers(`/usr/local/bin/notify.sh --token secret`)
+	if len(receivers) != 1 || receivers[0] != "/usr/local/bin/notify.sh" {
+		t.Errorf("expected only the program, got %v", receivers)
+	}
+}
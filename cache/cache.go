@@ -0,0 +1,120 @@
+// Package cache is a local store keeping the last known check states and the
+// notifications that couldn't be completed while consul was unreachable.
+package cache
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	bolt "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/go.etcd.io/bbolt"
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+var (
+	checksBucket = []byte("checks")
+	queueBucket  = []byte("queue")
+)
+
+// Store is a bolt database holding the cached check states and the queued
+// notifications.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the store at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{checksBucket, queueBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveChecks replaces the cached check states.
+func (s *Store) SaveChecks(statuses []consul.Status) error {
+	data, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checksBucket).Put([]byte("statuses"), data)
+	})
+}
+
+// Checks returns the cached check states.
+func (s *Store) Checks() ([]consul.Status, error) {
+	var statuses []consul.Status
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(checksBucket).Get([]byte("statuses"))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &statuses)
+	})
+	return statuses, err
+}
+
+// Queue stores alerts to be notified later.
+func (s *Store) Queue(alerts []consul.Check) error {
+	data, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, data)
+	})
+}
+
+// Flush removes and returns the queued alerts in the order they were queued.
+func (s *Store) Flush() ([][]consul.Check, error) {
+	var queued [][]consul.Check
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		cursor := bucket.Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			var alerts []consul.Check
+			if err := json.Unmarshal(data, &alerts); err == nil {
+				queued = append(queued, alerts)
+			}
+		}
+		if err := tx.DeleteBucket(queueBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(queueBucket)
+		return err
+	})
+	return queued, err
+}
+
+// QueueSize returns the number of queued notifications.
+func (s *Store) QueueSize() int {
+	size := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		size = tx.Bucket(queueBucket).Stats().KeyN
+		return nil
+	})
+	return size
+}
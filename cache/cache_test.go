@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func openTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "consul-alerts-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := Open(filepath.Join(dir, "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSaveChecks(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+
+	statuses := []consul.Status{{Current: "critical", HealthCheck: &consul.Check{Node: "node1"}}}
+	if err := store.SaveChecks(statuses); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := store.Checks()
+	if err != nil || len(cached) != 1 || cached[0].HealthCheck.Node != "node1" {
+		t.Errorf("unexpected cached checks: %v, %v", cached, err)
+	}
+}
+
+func TestQueueAndFlush(t *testing.T) {
+	store, cleanup := openTestStore(t)
+	defer cleanup()
+
+	store.Queue([]consul.Check{{Node: "node1"}})
+	store.Queue([]consul.Check{{Node: "node2"}, {Node: "node3"}})
+	if size := store.QueueSize(); size != 2 {
+		t.Errorf("expected 2 queued notifications, got %d", size)
+	}
+
+	queued, err := store.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 || queued[0][0].Node != "node1" || len(queued[1]) != 2 {
+		t.Errorf("unexpected queued notifications: %v", queued)
+	}
+	if size := store.QueueSize(); size != 0 {
+		t.Errorf("queue should be empty after flush, got %d", size)
+	}
+}
@@ -2,44 +2,64 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"encoding/json"
 	"net/http"
-	"os/exec"
 
 	"github.com/AcalephStorage/consul-alerts/consul"
 	"github.com/AcalephStorage/consul-alerts/notifier"
 
-	log "github.com/Sirupsen/logrus"
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
 var checksChannel = make(chan []consul.Check, 1)
 var firstCheckRun = true
 
 func checkHandler(w http.ResponseWriter, r *http.Request) {
+	var checks []consul.Check
+	toWatchObject(r.Body, &checks)
+	handleChecks(checks)
+	w.WriteHeader(200)
+}
+
+// handleChecks queues the latest checks for processing. Checks still waiting
+// to be processed are replaced as only the latest state matters.
+func handleChecks(checks []consul.Check) {
+	if stopping() {
+		log.Debugln("Shutting down. Checks ignored.")
+		return
+	}
 	consulClient.LoadConfig()
 	if firstCheckRun {
-		log.Println("Now watching for health changes.")
+		log.Infoln("Now watching for health changes.")
 		firstCheckRun = false
-		w.WriteHeader(200)
 		return
 	}
 
 	if !consulClient.ChecksEnabled() {
-		log.Println("Checks handling disabled. Checks ignored.")
-		w.WriteHeader(200)
+		log.Debugln("Checks handling disabled. Checks ignored.")
 		return
 	}
 
 	if len(checksChannel) == 1 {
 		<-checksChannel
+		pipelineEnd()
 	}
 
-	var checks []consul.Check
-	toWatchObject(r.Body, &checks)
+	queueChecks(checks)
+}
+
+// queueChecks queues the checks for processing without blocking. They are
+// counted in the pipeline until processed.
+func queueChecks(checks []consul.Check) {
+	pipelineBegin()
 	go startProcess(checks)
-	w.WriteHeader(200)
 }
 
 func startProcess(checks []consul.Check) {
@@ -48,81 +68,457 @@ func startProcess(checks []consul.Check) {
 
 func processChecks() {
 	for {
-		<-checksChannel
+		loopAlive("checks processing")
+		select {
+		case <-checksChannel:
+		case <-time.After(loopIdleInterval):
+			continue
+		}
+		window := time.Duration(consulClient.CheckCoalesceWindow()) * time.Second
+		if merged := coalesceChecks(window); merged > 0 {
+			log.Debugf("Coalesced %d check update(s).", merged)
+		}
+		runChecks()
+		pipelineEnd()
+	}
+}
+
+// coalesceChecks waits until no check update has been queued for the window,
+// so a burst of updates is processed once, and returns the number of updates
+// merged. The wait is bounded to 5 windows so a steady flow of updates is
+// still processed.
+func coalesceChecks(window time.Duration) int {
+	merged := 0
+	deadline := time.Now().Add(5 * window)
+	for !stopping() {
+		wait := window
+		if remaining := deadline.Sub(time.Now()); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			break
+		}
+		select {
+		case <-checksChannel:
+			pipelineEnd()
+			merged++
+			checkUpdatesCoalesced.Inc()
+		case <-time.After(wait):
+			return merged
+		}
+	}
+	return merged
+}
+
+// runChecks updates the check states, notifies the new alerts and runs their
+// remediation handlers.
+func runChecks() {
+	// the changes are processed once consul is back
+	for consulClient.CircuitOpen() && !stopping() {
+		loopAlive("checks processing")
+		log.Debugln("Consul is failing, delaying the health check processing.")
+		time.Sleep(5 * time.Second)
+	}
 
+	// shard members process the checks of their own nodes
+	if shard == nil {
 		for leaderCandidate.Leader() == "" {
-			log.Println("There is current no consul-alerts leader... waiting for one.")
+			if stopping() {
+				return
+			}
+			loopAlive("checks processing")
+			log.Warnln("There is current no consul-alerts leader... waiting for one.")
 			time.Sleep(5 * time.Second)
 		}
 
 		if !leaderCandidate.IsLeader() {
-			log.Println("Currently not the leader. Ignoring checks.")
-			continue
+			log.Debugln("Currently not the leader. Ignoring checks.")
+			return
 		}
+	}
 
-		log.Println("Running health check.")
-		changeThreshold := consulClient.CheckChangeThreshold()
-		for elapsed := 0; elapsed < changeThreshold; elapsed += 10 {
-			consulClient.UpdateCheckData()
-			time.Sleep(10 * time.Second)
-		}
+	// when shutting down, the changes still under the threshold are left
+	// pending in consul for the next leader
+	log.Debugln("Running health check.")
+	changeThreshold := consulClient.CheckChangeThreshold()
+	for elapsed := 0; elapsed < changeThreshold && !stopping(); elapsed += 10 {
+		loopAlive("checks processing")
 		consulClient.UpdateCheckData()
-		log.Println("Processing health checks for notification.")
-		alerts := consulClient.NewAlerts()
-		if len(alerts) > 0 {
-			notify(alerts)
-		}
+		time.Sleep(10 * time.Second)
+	}
+	started := time.Now()
+	defer func() { checksProcessingDuration.Observe(time.Since(started).Seconds()) }()
+	consulClient.UpdateCheckData()
+	notifyMaintenance()
+	log.Debugln("Processing health checks for notification.")
+	alerts := consulClient.NewAlerts()
+	if len(alerts) > 0 {
+		deliver(alerts)
+		remediate(alerts)
+	}
+}
+
+// resumeChecks runs the check processing when this instance becomes the
+// leader or the shard members change. The pending status changes and the
+// alerts the previous leader didn't send are stored in consul so they are
+// picked up where it left off.
+func resumeChecks() {
+	if !consulClient.ChecksEnabled() {
+		return
+	}
+	log.Infoln("Resuming pending health check notifications.")
+	if len(checksChannel) == 0 {
+		queueChecks(nil)
 	}
 }
 
+// notify sends the alerts through the routes, limited to the notifiers of
+// their profile.
 func notify(alerts []consul.Check) {
-	messages := make([]notifier.Message, len(alerts))
+	messages := make(notifier.Messages, len(alerts))
+	profileNotifiers := make([][]string, len(alerts))
 	for i, alert := range alerts {
+		if profile := consulClient.CheckProfile(&alert); profile != nil {
+			profileNotifiers[i] = profile.Notifiers
+		}
 		messages[i] = notifier.Message{
-			Node:      alert.Node,
-			ServiceId: alert.ServiceID,
-			Service:   alert.ServiceName,
-			CheckId:   alert.CheckID,
-			Check:     alert.Name,
-			Status:    alert.Status,
-			Output:    alert.Output,
-			Notes:     alert.Notes,
-			Timestamp: time.Now(),
+			Datacenter:     alert.Datacenter,
+			Namespace:      alert.Namespace,
+			Node:           alert.Node,
+			NodeMeta:       alert.NodeMeta,
+			ServiceId:      alert.ServiceID,
+			Service:        alert.ServiceName,
+			Tags:           alert.ServiceTags,
+			CheckId:        alert.CheckID,
+			Check:          alert.Name,
+			Status:         alert.Status,
+			NotifiedStatus: alert.NotifiedStatus,
+			Output:         alert.Output,
+			Notes:          alert.Notes,
+			Timestamp:      time.Now(),
 		}
 	}
 
 	if len(messages) == 0 {
-		log.Println("Nothing to notify.")
+		log.Debugln("Nothing to notify.")
+		return
+	}
+	addCatalogInfo(messages)
+	addLinks(messages, consulClient.Links())
+	for _, group := range groupByNotifiers(messages, profileNotifiers) {
+		routeMessages(group.messages, group.notifiers)
+	}
+}
+
+// addCatalogInfo sets the node and service addresses and the service port of
+// the messages from the catalog, looked up once per node.
+func addCatalogInfo(messages notifier.Messages) {
+	nodes := make(map[string]*consul.CatalogNode)
+	for i := range messages {
+		key := messages[i].Datacenter + "/" + messages[i].Node
+		node, found := nodes[key]
+		if !found {
+			var err error
+			node, err = consulClient.CatalogNode(messages[i].Datacenter, messages[i].Node)
+			if err != nil {
+				log.Warnf("Unable to retrieve the catalog entry of %s: %s", messages[i].Node, err)
+			}
+			nodes[key] = node
+		}
+		setCatalogInfo(&messages[i], node)
+	}
+}
+
+// addLinks expands the link templates for each message. The links that fail
+// to expand or expand to an empty url are left out.
+func addLinks(messages notifier.Messages, links []consul.LinkTemplate) {
+	for i := range messages {
+		for _, link := range links {
+			url, err := link.Expand(messages[i])
+			if err != nil {
+				log.Warnf("Unable to expand the %s link: %s", link.Name, err)
+				continue
+			}
+			if url != "" {
+				messages[i].Links = append(messages[i].Links, notifier.Link{Name: link.Name, Url: url})
+			}
+		}
+	}
+}
+
+func setCatalogInfo(message *notifier.Message, node *consul.CatalogNode) {
+	if node == nil {
 		return
 	}
+	message.NodeAddress = node.Address
+	if message.NodeMeta == nil {
+		message.NodeMeta = node.Meta
+	}
+	if service, found := node.Services[message.ServiceId]; found {
+		message.ServiceAddress = service.Address
+		if message.ServiceAddress == "" {
+			message.ServiceAddress = node.Address
+		}
+		message.ServicePort = service.Port
+	}
+}
+
+// sendMessages runs every enabled notifier.
+func sendMessages(messages notifier.Messages) {
+	sendMessagesTo(messages, consul.Route{})
+}
+
+// sendMessagesTo runs the enabled notifiers named in the route, all of them
+// when it has none, concurrently. "custom" selects the custom notifiers. The
+// route receivers, slack channel and zulip stream and topic replace the
+// configured ones.
+func sendMessagesTo(messages notifier.Messages, route consul.Route) {
+	selected := func(name string) bool {
+		if len(route.Notifiers) == 0 {
+			return true
+		}
+		for _, n := range route.Notifiers {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
 
+	for _, message := range messages {
+		alertsProcessed.Inc(message.Status)
+	}
+	recordNotifications(messages)
+
+	var deliveries deliveryResults
 	for _, n := range builtinNotifiers() {
-		n.Notify(messages)
+		if !selected(n.name) {
+			continue
+		}
+		if now := digest(n.name, route, messages); len(now) > 0 {
+			name, n := n.name, routedNotifier(n.Notifier, route)
+			deliveries.run(func() []delivery { return []delivery{runNotifier(name, n, now)} })
+		}
+	}
+	if selected("custom") {
+		if now := digest("custom", route, messages); len(now) > 0 {
+			deliveries.run(func() []delivery { return runCustomNotifiers(now) })
+		}
+	}
+	deliveries.wait()
+	if len(deliveries.results) > 0 {
+		log.Infof("Dispatched %d alert(s): %s", len(messages), &deliveries)
 	}
+}
+
+// delivery is the result of a notifier run: sent, failed or timeout.
+type delivery struct {
+	notifier string
+	result   string
+	duration time.Duration
+}
+
+// deliveryResults runs notifiers concurrently and collects their results.
+type deliveryResults struct {
+	sync.Mutex
+	wg      sync.WaitGroup
+	results []delivery
+}
+
+func (d *deliveryResults) run(f func() []delivery) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		results := f()
+		d.Lock()
+		defer d.Unlock()
+		d.results = append(d.results, results...)
+	}()
+}
+
+// wait waits for the notifiers and sorts their results by notifier.
+func (d *deliveryResults) wait() {
+	d.wg.Wait()
+	sort.SliceStable(d.results, func(i, j int) bool { return d.results[i].notifier < d.results[j].notifier })
+}
+
+func (d *deliveryResults) String() string {
+	parts := make([]string, len(d.results))
+	for i, result := range d.results {
+		parts[i] = fmt.Sprintf("%s %s in %s", result.notifier, result.result, result.duration.Round(time.Millisecond))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// notifyWithin returns the result of send, or false and true if it doesn't
+// return within timeout. The notifiers stop sending at their deadline, see
+// notifier.SetDeadline, this only guards against one that doesn't.
+func notifyWithin(timeout time.Duration, send func() bool) (success, timedOut bool) {
+	done := make(chan bool, 1)
+	go func() {
+		done <- send()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case success := <-done:
+		return success, false
+	case <-timer.C:
+		return false, true
+	}
+}
+
+// routedNotifier applies the receivers, slack channel and zulip stream and
+// topic of a route to a builtin notifier.
+func routedNotifier(n notifier.Notifier, route consul.Route) notifier.Notifier {
+	if email, ok := n.(*notifier.EmailNotifier); ok {
+		if len(route.Receivers) > 0 {
+			email.Receivers = route.Receivers
+			email.ServiceReceivers = nil
+			email.NodeReceivers = nil
+			email.DatacenterReceivers = nil
+		}
+		resolveEmailReceivers(email)
+	}
+	if slack, ok := n.(*notifier.SlackNotifier); ok && route.SlackChannel != "" {
+		slack.Channel = route.SlackChannel
+	}
+	if zulip, ok := n.(*notifier.ZulipNotifier); ok {
+		if route.ZulipStream != "" {
+			zulip.Stream = route.ZulipStream
+		}
+		if route.ZulipTopic != "" {
+			zulip.Topic = route.ZulipTopic
+		}
+	}
+	return n
+}
+
+// runNotifier sends the messages through a builtin notifier within its
+// timeout and records the delivery.
+func runNotifier(name string, n notifier.Notifier, messages notifier.Messages) delivery {
+	start := time.Now()
+	limited := outputLimits(name).Apply(messages)
+	timeout := consulClient.NotifierTimeout(name)
+	notifier.SetDeadline(n, start.Add(timeout))
+	success, timedOut := notifyWithin(timeout, func() bool { return n.Notify(limited) })
+	result := resultLabel(success)
+	var failure error
+	if timedOut {
+		result = "timeout"
+		log.Errorf("The %s notifier didn't deliver the alerts within %s.", name, timeout)
+		failure = fmt.Errorf("timed out after %s", timeout)
+	} else if !success {
+		if failure = notifier.Failure(n); failure == nil {
+			failure = errors.New("delivery failed")
+		}
+	}
+	notificationDuration.Observe(time.Since(start).Seconds(), name)
+	notificationsSent.Inc(name, result)
+	recordDeliveries(name, limited, success)
+	auditDelivery(name, notifierReceivers(n, limited), limited, start, failure)
+	return delivery{name, result, time.Since(start)}
+}
+
+// runCustomNotifiers runs the custom notifiers concurrently, each one is
+// killed if it runs longer than the custom timeout.
+func runCustomNotifiers(messages notifier.Messages) []delivery {
+	customMessages := outputLimits("custom").Apply(messages)
+	var deliveries deliveryResults
 	for _, n := range consulClient.CustomNotifiers() {
-		executeHealthNotifier(messages, n)
+		n := n
+		deliveries.run(func() []delivery {
+			start := time.Now()
+			err := executeHealthNotifier(customMessages, n, consulClient.NotifierTimeout("custom"))
+			_, timedOut := err.(errCommandTimeout)
+			success := err == nil
+			result := resultLabel(success)
+			if timedOut {
+				result = "timeout"
+			}
+			notificationDuration.Observe(time.Since(start).Seconds(), "custom")
+			notificationsSent.Inc("custom", result)
+			recordDeliveries("custom", customMessages, success)
+			auditDelivery("custom", customReceivers(n), customMessages, start, err)
+			return []delivery{{"custom", result, time.Since(start)}}
+		})
+	}
+	deliveries.wait()
+	return deliveries.results
+}
+
+// resolveEmailReceivers replaces the on-call sources of the email receivers
+// with the current on-call contacts. The receiver maps are shared with the
+// config so they are copied.
+func resolveEmailReceivers(email *notifier.EmailNotifier) {
+	resolve := func(receivers map[string][]string) map[string][]string {
+		resolved := make(map[string][]string, len(receivers))
+		for key, list := range receivers {
+			resolved[key] = resolveReceivers(list)
+		}
+		return resolved
+	}
+	email.Receivers = resolveReceivers(email.Receivers)
+	email.ServiceReceivers = resolve(email.ServiceReceivers)
+	email.NodeReceivers = resolve(email.NodeReceivers)
+	email.DatacenterReceivers = resolve(email.DatacenterReceivers)
+}
+
+// outputLimits returns the output limits configured for a notifier.
+func outputLimits(name string) notifier.OutputLimits {
+	output := consulClient.NotifierOutput(name)
+	return notifier.OutputLimits{
+		MaxBytes: output.MaxBytes,
+		MaxLines: output.MaxLines,
+		Sanitize: output.Sanitize,
 	}
 }
 
-func executeHealthNotifier(messages []notifier.Message, notifCmd string) {
+// executeHealthNotifier runs a custom notifier with the messages on stdin. It
+// returns why it failed, an errCommandTimeout when it was killed after
+// running longer than timeout, a zero timeout waits for it to exit.
+func executeHealthNotifier(messages []notifier.Message, notifCmd string, timeout time.Duration) error {
 	data, err := json.Marshal(&messages)
 	if err != nil {
-		log.Println("Unable to read messages: ", err)
-		return
+		log.Errorln("Unable to read messages:", err)
+		return err
+	}
+
+	if dryRun() {
+		log.WithField("notifier", notifCmd).Infof("Dry run, notification not sent:\n%s", data)
+		return nil
+	}
+
+	cmd, err := newCommand(notifCmd)
+	if err != nil {
+		log.WithField("notifier", notifCmd).Errorln("Unable to run notifier:", err)
+		return err
 	}
 
 	input := bytes.NewReader(data)
 	output := new(bytes.Buffer)
-	cmd := exec.Command(notifCmd)
 	cmd.Stdin = input
 	cmd.Stdout = output
 	cmd.Stderr = output
 
-	if err := cmd.Run(); err != nil {
-		log.Println("error running notifier: ", err)
+	err = runCommand(cmd, timeout)
+	if err != nil {
+		log.WithField("notifier", notifCmd).Errorln("Error running notifier:", err)
 	} else {
-		log.Println(">>> notification sent to:", notifCmd)
+		log.WithField("notifier", notifCmd).Infoln("Notification sent.")
 	}
-	log.Println(output)
+	log.WithField("notifier", notifCmd).Debugln(output)
+	return commandFailure(err, output.String())
+}
 
+// commandFailure adds the last line of the output of a failed command, which
+// usually tells why it failed, to its error.
+func commandFailure(err error, output string) error {
+	if _, timedOut := err.(errCommandTimeout); err == nil || timedOut {
+		return err
+	}
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); lines[len(lines)-1] != "" {
+		return fmt.Errorf("%s: %s", err, lines[len(lines)-1])
+	}
+	return err
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+func TestNotifyWithin(t *testing.T) {
+	if success, timedOut := notifyWithin(time.Second, func() bool { return true }); !success || timedOut {
+		t.Errorf("expected a success, got %v %v", success, timedOut)
+	}
+	release := make(chan bool)
+	defer close(release)
+	if success, timedOut := notifyWithin(10*time.Millisecond, func() bool { return <-release }); success || !timedOut {
+		t.Errorf("expected a timeout, got %v %v", success, timedOut)
+	}
+}
+
+func TestCommandFailure(t *testing.T) {
+	if err := commandFailure(nil, "sent\n"); err != nil {
+		t.Errorf("expected no failure, got %s", err)
+	}
+	if err := commandFailure(errors.New("exit status 3"), "sending\ninvalid token\n"); err == nil || err.Error() != "exit status 3: invalid token" {
+		t.Errorf("expected the exit status and the last output line, got %v", err)
+	}
+	if err := commandFailure(errors.New("exit status 1"), ""); err == nil || err.Error() != "exit status 1" {
+		t.Errorf("expected the exit status, got %v", err)
+	}
+}
+
+func TestDeliveryResults(t *testing.T) {
+	var deliveries deliveryResults
+	start := time.Now()
+	for _, d := range []delivery{{"slack", "sent", time.Millisecond}, {"email", "timeout", 50 * time.Millisecond}} {
+		d := d
+		deliveries.run(func() []delivery {
+			time.Sleep(d.duration)
+			return []delivery{d}
+		})
+	}
+	deliveries.wait()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the notifiers to run concurrently, took %s", elapsed)
+	}
+	if s := deliveries.String(); s != "email timeout in 50ms, slack sent in 1ms" {
+		t.Errorf("unexpected results %q", s)
+	}
+}
+
+func TestCoalesceChecks(t *testing.T) {
+	queueChecks(nil)
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		queueChecks(nil)
+	}()
+	if merged := coalesceChecks(100 * time.Millisecond); merged != 2 {
+		t.Errorf("expected 2 updates merged, got %d", merged)
+	}
+	if active := pipelineActive(); active != 0 {
+		t.Errorf("expected the merged updates out of the pipeline, got %d", active)
+	}
+	if merged := coalesceChecks(0); merged != 0 {
+		t.Errorf("expected no wait without a window, got %d", merged)
+	}
+}
+
+func TestSetCatalogInfo(t *testing.T) {
+	node := &consul.CatalogNode{
+		Address: "10.0.0.5",
+		Meta:    map[string]string{"team": "web"},
+		Services: map[string]consul.CatalogService{
+			"web":   {Port: 8080},
+			"proxy": {Address: "10.0.1.5", Port: 443},
+		},
+	}
+	web := notifier.Message{Node: "web1", ServiceId: "web"}
+	setCatalogInfo(&web, node)
+	if web.NodeAddress != "10.0.0.5" || web.ServiceAddress != "10.0.0.5" || web.ServicePort != 8080 || web.NodeMeta["team"] != "web" {
+		t.Errorf("unexpected web message %+v", web)
+	}
+	proxy := notifier.Message{Node: "web1", ServiceId: "proxy", NodeMeta: map[string]string{}}
+	setCatalogInfo(&proxy, node)
+	if proxy.ServiceAddress != "10.0.1.5" || proxy.ServicePort != 443 || proxy.NodeMeta["team"] != "" {
+		t.Errorf("unexpected proxy message %+v", proxy)
+	}
+	unknown := notifier.Message{Node: "db1"}
+	setCatalogInfo(&unknown, nil)
+	if unknown.NodeAddress != "" {
+		t.Errorf("unexpected unknown message %+v", unknown)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// clusterState is the raft leader and peer count of the Consul servers. peers
+// is -1 when unknown.
+type clusterState struct {
+	leader string
+	peers  int
+}
+
+// runClusterWatcher polls the raft leader and peers of the Consul servers and
+// alerts when the leader is lost or changes, or when the peers drop below the
+// quorum.
+func runClusterWatcher(hostname string) {
+	setWatcherRunning("cluster", true)
+	defer setWatcherRunning("cluster", false)
+
+	log.Infoln("Starting cluster watcher.")
+	var previous *clusterState
+	maxPeers := 0
+	wasLeader := false
+	for {
+		config := consulClient.ClusterConfig()
+		interval := time.Duration(config.Interval) * time.Second
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		if !config.Enabled {
+			previous = nil
+			time.Sleep(interval)
+			continue
+		}
+
+		// an unreachable agent is reported by the connectivity alerts
+		leader, err := consulClient.ConsulLeader()
+		if err != nil {
+			log.Warnf("Unable to get the Consul leader, retrying in %s: %s", interval, err)
+			time.Sleep(interval)
+			continue
+		}
+		current := clusterState{leader: leader, peers: -1}
+		if peers, err := consulClient.ConsulPeers(); err == nil {
+			current.peers = len(peers)
+		} else if previous != nil {
+			current.peers = previous.peers
+		}
+		if current.peers > maxPeers {
+			maxPeers = current.peers
+		}
+		expected := config.ExpectedPeers
+		if expected <= 0 {
+			expected = maxPeers
+		}
+
+		// the leadership is kept in the KV which can't be read without a
+		// Consul leader, the last known leader notifies until it's back
+		if leader != "" {
+			wasLeader = leaderCandidate == nil || leaderCandidate.IsLeader()
+		}
+		if previous != nil && wasLeader {
+			messages := clusterMessages(hostname, *previous, current, expected)
+			for _, message := range messages {
+				log.WithField("check", message.CheckId).Infoln(message.Output)
+			}
+			if len(messages) > 0 {
+				sendMessages(messages)
+			}
+		}
+		previous = &current
+		time.Sleep(interval)
+	}
+}
+
+// clusterMessages returns the alerts for the leader and quorum changes
+// between the previous and current states.
+func clusterMessages(hostname string, previous, current clusterState, expected int) notifier.Messages {
+	var messages notifier.Messages
+	if current.leader != previous.leader {
+		switch {
+		case current.leader == "":
+			messages = append(messages, clusterMessage(hostname, "consul-leader", "Consul leader", "critical",
+				"The Consul cluster has no leader."))
+		case previous.leader == "":
+			messages = append(messages, clusterMessage(hostname, "consul-leader", "Consul leader", "passing",
+				fmt.Sprintf("The Consul cluster elected %s as leader.", current.leader)))
+		default:
+			messages = append(messages, clusterMessage(hostname, "consul-leader", "Consul leader", "warning",
+				fmt.Sprintf("The Consul leader changed from %s to %s.", previous.leader, current.leader)))
+		}
+	}
+
+	if expected > 0 && previous.peers >= 0 && current.peers >= 0 {
+		quorum := expected/2 + 1
+		switch {
+		case current.peers < quorum && previous.peers >= quorum:
+			messages = append(messages, clusterMessage(hostname, "consul-peers", "Consul raft peers", "critical",
+				fmt.Sprintf("The Consul cluster has %d raft peer(s), below the quorum of %d for %d servers.", current.peers, quorum, expected)))
+		case current.peers >= quorum && previous.peers < quorum:
+			messages = append(messages, clusterMessage(hostname, "consul-peers", "Consul raft peers", "passing",
+				fmt.Sprintf("The Consul cluster has %d raft peer(s), the quorum for %d servers is %d.", current.peers, expected, quorum)))
+		}
+	}
+	return messages
+}
+
+func clusterMessage(hostname, checkId, check, status, output string) notifier.Message {
+	return notifier.Message{
+		Node:      hostname,
+		ServiceId: "consul",
+		Service:   "consul",
+		CheckId:   checkId,
+		Check:     check,
+		Status:    status,
+		Output:    output,
+		Timestamp: time.Now(),
+	}
+}
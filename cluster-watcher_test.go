@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestClusterMessages(t *testing.T) {
+	cases := []struct {
+		previous, current clusterState
+		expected          int
+		messages          []string
+	}{
+		{clusterState{"10.0.0.1:8300", 3}, clusterState{"10.0.0.1:8300", 3}, 3, nil},
+		{clusterState{"10.0.0.1:8300", 3}, clusterState{"", 3}, 3, []string{"critical: The Consul cluster has no leader."}},
+		{clusterState{"", 3}, clusterState{"10.0.0.2:8300", 3}, 3, []string{"passing: The Consul cluster elected 10.0.0.2:8300 as leader."}},
+		{clusterState{"10.0.0.1:8300", 3}, clusterState{"10.0.0.2:8300", 2}, 3, []string{"warning: The Consul leader changed from 10.0.0.1:8300 to 10.0.0.2:8300."}},
+		{clusterState{"10.0.0.1:8300", 2}, clusterState{"10.0.0.1:8300", 1}, 3, []string{"critical: The Consul cluster has 1 raft peer(s), below the quorum of 2 for 3 servers."}},
+		{clusterState{"10.0.0.1:8300", 2}, clusterState{"10.0.0.1:8300", 3}, 5, []string{"passing: The Consul cluster has 3 raft peer(s), the quorum for 5 servers is 3."}},
+		{clusterState{"10.0.0.1:8300", -1}, clusterState{"10.0.0.1:8300", 1}, 3, nil},
+	}
+	for i, c := range cases {
+		messages := clusterMessages("host", c.previous, c.current, c.expected)
+		if len(messages) != len(c.messages) {
+			t.Errorf("case %d: expected %d messages, got %v", i, len(c.messages), messages)
+			continue
+		}
+		for j, m := range messages {
+			if got := m.Status + ": " + m.Output; got != c.messages[j] {
+				t.Errorf("case %d: unexpected message %q", i, got)
+			}
+		}
+	}
+}
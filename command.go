@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"encoding/json"
+)
+
+// newCommand builds the command of an event handler or custom notifier. The
+// command is either a JSON array like ["handler.sh", "--flag"] or a command
+// line split like a shell does, with single quotes, double quotes and
+// backslash escapes. Pipes, redirections and variables are not interpreted,
+// use ["sh", "-c", "..."] for those.
+func newCommand(command string) (*exec.Cmd, error) {
+	args, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
+
+func parseCommand(command string) ([]string, error) {
+	command = strings.TrimSpace(command)
+	var args []string
+	if strings.HasPrefix(command, "[") {
+		if err := json.Unmarshal([]byte(command), &args); err != nil {
+			return nil, fmt.Errorf("invalid command %s: %s", command, err)
+		}
+	} else {
+		var err error
+		if args, err = splitCommand(command); err != nil {
+			return nil, fmt.Errorf("invalid command %s: %s", command, err)
+		}
+	}
+	if len(args) == 0 || args[0] == "" {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
+
+// splitCommand splits a command line into its arguments.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// runCommand runs cmd in its own process group and kills the whole group if
+// it is still running after timeout, so the children of a shell script don't
+// outlive it. A zero timeout waits for the command to exit.
+func runCommand(cmd *exec.Cmd, timeout time.Duration) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	if timeout <= 0 {
+		return <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return errCommandTimeout{timeout}
+	}
+}
+
+type errCommandTimeout struct {
+	timeout time.Duration
+}
+
+func (e errCommandTimeout) Error() string {
+	return fmt.Sprintf("killed after running for %s", e.timeout)
+}
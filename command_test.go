@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	if err := runCommand(exec.Command("true"), time.Second); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := runCommand(exec.Command("false"), time.Second); err == nil {
+		t.Error("a failing command should return an error")
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	// the background sleep keeps the output pipe open unless the whole
+	// process group is killed
+	cmd := exec.Command("sh", "-c", "sleep 10 & sleep 10")
+	cmd.Stdout = new(bytes.Buffer)
+	start := time.Now()
+	err := runCommand(cmd, 100*time.Millisecond)
+	if _, ok := err.(errCommandTimeout); !ok {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the command was not killed, ran for %s", elapsed)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	commands := map[string][]string{
+		"handler.sh":                           {"handler.sh"},
+		"  handler.sh --flag value ":           {"handler.sh", "--flag", "value"},
+		`notify.sh "two words" 'single $HOME'`: {"notify.sh", "two words", "single $HOME"},
+		`notify.sh escaped\ space "a \"b\""`:   {"notify.sh", "escaped space", `a "b"`},
+		`notify.sh ""`:                         {"notify.sh", ""},
+		`["sh", "-c", "echo $1 | tee log"]`:    {"sh", "-c", "echo $1 | tee log"},
+	}
+	for command, expected := range commands {
+		args, err := parseCommand(command)
+		if err != nil {
+			t.Errorf("%s: %s", command, err)
+			continue
+		}
+		if strings.Join(args, "|") != strings.Join(expected, "|") || len(args) != len(expected) {
+			t.Errorf("%s: expected %q, got %q", command, expected, args)
+		}
+	}
+
+	for _, command := range []string{"", "   ", `notify.sh "unterminated`, `notify.sh \`, `["sh", `, "[]"} {
+		if _, err := parseCommand(command); err == nil {
+			t.Errorf("%q should be invalid", command)
+		}
+	}
+}
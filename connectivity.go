@@ -0,0 +1,153 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/cache"
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// localCache keeps the check states and the alerts that couldn't be completed
+// while consul is unreachable. It is nil unless --cache-file is set.
+var localCache *cache.Store
+
+var connectivity = struct {
+	sync.Mutex
+	reachable bool
+}{reachable: true}
+
+func consulReachable() bool {
+	connectivity.Lock()
+	defer connectivity.Unlock()
+	return connectivity.reachable
+}
+
+func setConsulReachable(reachable bool) {
+	connectivity.Lock()
+	defer connectivity.Unlock()
+	connectivity.reachable = reachable
+}
+
+// monitorConsul pings the consul agent, notifies when the connectivity is lost
+// or restored, and keeps the cached check states up to date. Only the leader
+// notifies, as it was before consul became unreachable.
+func monitorConsul(hostname string) {
+	ticks := 0
+	for range time.Tick(10 * time.Second) {
+		err := consulClient.Ping()
+		switch {
+		case err != nil && consulReachable():
+			setConsulReachable(false)
+			log.Errorln("Consul connectivity lost:", err)
+			if leading() {
+				sendMessages(notifier.Messages{connectivityMessage(hostname, "critical", err.Error())})
+			}
+		case err == nil && !consulReachable():
+			setConsulReachable(true)
+			log.Infoln("Consul connectivity restored.")
+			if leading() {
+				sendMessages(notifier.Messages{connectivityMessage(hostname, "passing", "The consul agent is reachable.")})
+			}
+			flushQueuedAlerts()
+		}
+
+		// the check states are saved every minute
+		if err == nil && ticks%6 == 0 {
+			if statuses, err := consulClient.CheckStatuses(""); err == nil {
+				if err := localCache.SaveChecks(statuses); err != nil {
+					log.Warnln("Unable to cache check states:", err)
+				}
+			}
+		}
+		ticks++
+	}
+}
+
+// circuitChanged notifies once when the consul circuit breaker opens and once
+// when it closes, instead of an error per failed call. Only the leader
+// notifies, and with --cache-file the connectivity monitor already does.
+func circuitChanged(hostname string) func(bool, error) {
+	return func(open bool, err error) {
+		var message notifier.Message
+		if open {
+			log.Errorln("Consul keeps failing, the consul calls are paused:", err)
+			message = connectivityMessage(hostname, "critical", "The consul API keeps failing: "+err.Error())
+		} else {
+			log.Infoln("Consul recovered, the consul calls are resumed.")
+			message = connectivityMessage(hostname, "passing", "The consul API is answering.")
+		}
+		if localCache == nil && leading() {
+			go sendMessages(notifier.Messages{message})
+		}
+	}
+}
+
+// leading returns true if this instance was the leader when last known,
+// without asking consul, for the notifications about consul itself.
+func leading() bool {
+	return leaderCandidate == nil || leaderCandidate.Leading()
+}
+
+func connectivityMessage(hostname, status, output string) notifier.Message {
+	return notifier.Message{
+		Node:      hostname,
+		ServiceId: "consul-alerts",
+		Service:   "consul-alerts",
+		CheckId:   "consul-connectivity",
+		Check:     "Consul connectivity",
+		Status:    status,
+		Output:    output,
+		Timestamp: time.Now(),
+	}
+}
+
+// deliver notifies the alerts and records them as sent. When consul is
+// unreachable the alerts are queued in the local cache until it is back.
+func deliver(alerts []consul.Check) {
+	if localCache != nil && !consulReachable() {
+		err := localCache.Queue(alerts)
+		if err == nil {
+			log.Warnf("Consul is unreachable, %d alerts queued.", len(alerts))
+			return
+		}
+		log.Errorln("Unable to queue alerts:", err)
+	}
+	notify(alerts)
+	consulClient.MarkNotified(alerts)
+}
+
+func flushQueuedAlerts() {
+	queued, err := localCache.Flush()
+	if err != nil {
+		log.Errorln("Unable to read queued alerts:", err)
+		return
+	}
+	for _, alerts := range queued {
+		deliver(alerts)
+	}
+}
+
+// checkStatuses returns the check states from consul, or the cached states if
+// consul is unreachable.
+func checkStatuses(node string) ([]consul.Status, error) {
+	statuses, err := consulClient.CheckStatuses(node)
+	if err == nil || localCache == nil {
+		return statuses, err
+	}
+	cached, cacheErr := localCache.Checks()
+	if cacheErr != nil || cached == nil {
+		return nil, err
+	}
+	log.Warnln("Consul is unreachable, serving cached check states.")
+	statuses = make([]consul.Status, 0, len(cached))
+	for _, status := range cached {
+		if node == "" || status.HealthCheck.Node == node {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
@@ -5,16 +5,19 @@ package main
 import (
 	"fmt"
 	"os"
+	"sort"
 	"syscall"
+	"time"
 
 	"net/http"
 	"os/signal"
 
+	"github.com/AcalephStorage/consul-alerts/cache"
 	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/metrics"
 	"github.com/AcalephStorage/consul-alerts/notifier"
 
-	log "github.com/Sirupsen/logrus"
-	"github.com/darkcrux/consul-skipper"
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/docopt/docopt-go"
 )
 
@@ -22,24 +25,86 @@ const version = "Consul Alerts 0.2.0"
 const usage = `Consul Alerts.
 
 Usage:
-  consul-alerts start [--alert-addr=<addr>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--watch-checks] [--watch-events]
-  consul-alerts watch (checks|event) [--alert-addr=<addr>]
+  consul-alerts start [--alert-addr=<addr>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--vault-addr=<addr>] [--vault-token=<token>] [--vault-ca-file=<file>] [--watch-checks] [--watch-events] [--watch-keys] [--shard] [--shard-id=<id>] [--register] [--register-id=<id>] [--cache-file=<file>] [--audit-file=<file>] [--statsd-addr=<addr>] [--statsd-prefix=<prefix>] [--dogstatsd] [--shutdown-timeout=<seconds>] [--dry-run] [--log-level=<level>] [--log-format=<format>] [--log-file=<file>] [--log-max-size=<mb>] [--log-max-age=<hours>] [--log-max-backups=<count>] [--alert-tls-cert=<file>] [--alert-tls-key=<file>] [--alert-token=<token>] [--alert-user=<user>] [--alert-password=<password>] [--grpc-addr=<addr>]
+  consul-alerts watch (checks|event) [--alert-addr=<addr>] [--alert-tls] [--alert-tls-ca=<file>] [--alert-tls-skip-verify] [--alert-token=<token>] [--alert-user=<user>] [--alert-password=<password>]
+  consul-alerts test-notify [--node=<node>] [--service=<service>] [--check=<check>] [--status=<status>] [--output=<output>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>] [--vault-addr=<addr>] [--vault-token=<token>] [--vault-ca-file=<file>]
+  consul-alerts validate [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>] [--vault-addr=<addr>] [--vault-token=<token>] [--vault-ca-file=<file>]
+  consul-alerts history [--type=<type>] [--node=<node>] [--service=<service>] [--check=<check>] [--since=<time>] [--until=<time>] [--limit=<count>] [--json] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>]
+  consul-alerts ack <node> <check> [--comment=<comment>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>]
+  consul-alerts silence --duration=<duration> [--node=<node>] [--service=<service>] [--check=<check>] [--comment=<comment>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>]
+  consul-alerts export-state [<file>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>]
+  consul-alerts import-state [<file>] [--config-file=<file>] [--consul-addr=<consuladdr>] [--consul-dc=<dc>] [--consul-namespace=<ns>] [--consul-partition=<partition>] [--consul-tls] [--consul-ca-file=<file>] [--consul-cert-file=<file>] [--consul-key-file=<file>] [--consul-tls-server-name=<name>] [--consul-tls-skip-verify] [--consul-acl-token=<token>] [--consul-acl-token-key=<key>]
   consul-alerts --help
   consul-alerts --version
 
 Options:
   --alert-addr=<addr>          The address for the consul-alert api [default: localhost:9000].
-  --consul-addr=<consuladdr>   The consul api address [default: localhost:8500].
-  --consul-dc=<dc>             The consul datacenter [default: dc1].
-  --watch-checks               Run check watcher.
-  --watch-events               Run event watcher.
+  --alert-tls-cert=<file>      Serve the consul-alert api over TLS using this certificate.
+  --alert-tls-key=<file>       The private key of the TLS certificate.
+  --alert-tls                  Connect to the consul-alert api over TLS.
+  --alert-tls-ca=<file>        The CA used to verify the consul-alert api certificate.
+  --alert-tls-skip-verify      Skip verification of the consul-alert api certificate.
+  --alert-token=<token>        Shared token required to call the consul-alert api.
+  --alert-user=<user>          Basic auth user allowed to call the consul-alert api.
+  --alert-password=<password>  Basic auth password of --alert-user.
+  --grpc-addr=<addr>           Also serve the consul-alert api over gRPC on this address.
+  --config-file=<file>         Load the configuration from this YAML, TOML or JSON file, consul KV values override it.
+  --consul-addr=<consuladdr>   The consul api address, https:// or unix:// addresses are supported, defaults to CONSUL_HTTP_ADDR or localhost:8500.
+  --consul-dc=<dc>             The consul datacenter, defaults to dc1.
+  --consul-namespace=<ns>      The consul enterprise namespace.
+  --consul-partition=<partition>  The consul enterprise admin partition.
+  --consul-acl-token=<token>   The consul ACL token used for every consul api call.
+  --consul-acl-token-key=<key> Read the consul ACL token from this consul KV key.
+  --consul-tls                 Connect to the consul api over HTTPS.
+  --consul-ca-file=<file>      The CA used to verify the consul api certificate.
+  --consul-cert-file=<file>    The client certificate presented to consul.
+  --consul-key-file=<file>     The private key of the client certificate.
+  --consul-tls-server-name=<name>  The server name used to verify the consul api certificate.
+  --consul-tls-skip-verify     Skip verification of the consul api certificate.
+  --vault-addr=<addr>          The vault address used to resolve the vault:<path>#<key> config values.
+  --vault-token=<token>        The vault token, it is renewed while consul-alerts runs.
+  --vault-ca-file=<file>       The CA used to verify the vault certificate.
+  --watch-checks               Watch the health checks using consul blocking queries.
+  --watch-events               Watch the events using consul blocking queries.
+  --watch-keys                 Watch the KV prefixes of the key handlers using consul blocking queries.
+  --shard                      Split the nodes with the other instances started with --shard.
+  --shard-id=<id>              The unique id of this instance among the shard members, defaults to the hostname.
+  --register                   Register as a consul service with a TTL check that fails when a main loop stalls.
+  --register-id=<id>           The service id used by --register, defaults to consul-alerts-<hostname>.
+  --cache-file=<file>          Cache the check states and queue the alerts in this file while consul is unreachable.
+  --audit-file=<file>          Append every notification delivery attempt to this file as JSON lines.
+  --statsd-addr=<addr>         Also send the metrics to the StatsD agent at this host:port.
+  --statsd-prefix=<prefix>     The prefix of the StatsD metric names.
+  --dogstatsd                  Send the metric labels as DogStatsD tags.
+  --shutdown-timeout=<seconds> How long the shutdown waits for the queued checks, events and notifications. Defaults to 30.
+  --dry-run                    Log the notifications instead of sending them.
+  --log-level=<level>          The log level: debug, info, warn or error. Defaults to info.
+  --log-format=<format>        The log format: text or json. Defaults to text.
+  --log-file=<file>            Write the logs to this file instead of stderr.
+  --log-max-size=<mb>          Rotate the log file when it reaches this size in megabytes.
+  --log-max-age=<hours>        Rotate the log file after this number of hours.
+  --log-max-backups=<count>    The number of rotated log files to keep, all are kept by default.
+  --node=<node>                The node of the test alert, defaults to the hostname. The node pattern of the history and the silence.
+  --service=<service>          The service of the test alert. The service pattern of the history and the silence.
+  --check=<check>              The check of the test alert, defaults to "consul-alerts test". The check pattern of the history and the silence.
+  --status=<status>            The status of the test alert: passing, warning or critical. Defaults to critical.
+  --output=<output>            The output of the test alert.
+  --type=<type>                Only show the history entries of this type: transition or notification.
+  --since=<time>               Only show the history since this RFC3339 time or duration ago, like 24h.
+  --until=<time>               Only show the history until this RFC3339 time or duration ago.
+  --limit=<count>              Only show the latest history entries.
+  --json                       Print the history as JSON.
+  --duration=<duration>        How long the silence lasts, like 30m or 2h.
+  --comment=<comment>          The comment of the acknowledgement or the silence.
   --help                       Show this screen.
   --version                    Show version.
 
 `
 
 var consulClient consul.Consul
-var leaderCandidate *skipper.Candidate
+var leaderCandidate *consul.LeaderCandidate
+var shard *consul.Shard
+var dryRunFlag bool
 
 func main() {
 	log.SetLevel(log.InfoLevel)
@@ -49,66 +114,259 @@ func main() {
 		daemonMode(args)
 	case args["watch"].(bool):
 		watchMode(args)
+	case args["test-notify"].(bool):
+		testNotifyMode(args)
+	case args["validate"].(bool):
+		validateMode(args)
+	case args["history"].(bool):
+		historyMode(args)
+	case args["ack"].(bool):
+		ackMode(args)
+	case args["silence"].(bool):
+		silenceMode(args)
+	case args["export-state"].(bool):
+		exportStateMode(args)
+	case args["import-state"].(bool):
+		importStateMode(args)
 	}
 }
 
 func daemonMode(arguments map[string]interface{}) {
+	logLevel := stringOption(arguments, "--log-level", "CONSUL_ALERTS_LOG_LEVEL")
+	logFormat := stringOption(arguments, "--log-format", "CONSUL_ALERTS_LOG_FORMAT")
+	if err := configureLogging(logLevel, logFormat); err != nil {
+		log.Errorln("Invalid logging configuration:", err)
+		os.Exit(1)
+	}
+	if logFile := stringOption(arguments, "--log-file", "CONSUL_ALERTS_LOG_FILE"); logFile != "" {
+		maxSize, sizeErr := intOption(arguments, "--log-max-size", "CONSUL_ALERTS_LOG_MAX_SIZE")
+		maxAge, ageErr := intOption(arguments, "--log-max-age", "CONSUL_ALERTS_LOG_MAX_AGE")
+		maxBackups, backupsErr := intOption(arguments, "--log-max-backups", "CONSUL_ALERTS_LOG_MAX_BACKUPS")
+		if sizeErr != nil || ageErr != nil || backupsErr != nil {
+			log.Errorln("Invalid log rotation settings, numbers are expected.")
+			os.Exit(1)
+		}
+		if err := configureLogFile(logFile, maxSize, maxAge, maxBackups); err != nil {
+			log.Errorln("Unable to open the log file:", err)
+			os.Exit(1)
+		}
+	}
+
 	addr := arguments["--alert-addr"].(string)
+	tlsCert := stringOption(arguments, "--alert-tls-cert", "CONSUL_ALERTS_TLS_CERT")
+	tlsKey := stringOption(arguments, "--alert-tls-key", "CONSUL_ALERTS_TLS_KEY")
+	auth := apiAuth{
+		token:    stringOption(arguments, "--alert-token", "CONSUL_ALERTS_TOKEN"),
+		username: stringOption(arguments, "--alert-user", "CONSUL_ALERTS_USER"),
+		password: stringOption(arguments, "--alert-password", "CONSUL_ALERTS_PASSWORD"),
+	}
 
-	url := fmt.Sprintf("http://%s/v1/info", addr)
-	resp, err := http.Get(url)
+	scheme, client, _ := alertApiClient(tlsCert != "", "", true)
+	url := fmt.Sprintf("%s://%s/v1/info", scheme, addr)
+	resp, err := client.Get(url)
 	if err == nil && resp.StatusCode == 200 {
 		version := resp.Header.Get("version")
 		resp.Body.Close()
-		log.Printf("consul-alert daemon already running version: %s", version)
+		log.Errorf("consul-alert daemon already running version: %s", version)
 		os.Exit(1)
 	}
 
-	consulAddr := arguments["--consul-addr"].(string)
-	consulDc := arguments["--consul-dc"].(string)
+	dryRunFlag = boolOption(arguments, "--dry-run", "CONSUL_ALERTS_DRY_RUN")
+	consulAddr, consulDc := consulAgent(arguments)
 	watchChecks := arguments["--watch-checks"].(bool)
 	watchEvents := arguments["--watch-events"].(bool)
+	watchKeys := arguments["--watch-keys"].(bool)
 
-	consulClient, err = consul.NewClient(consulAddr, consulDc)
+	alertClient, err := connectConsul(arguments)
 	if err != nil {
-		log.Println("Cluster has no leader or is unreacheable.", err)
+		log.Errorln("Cluster has no leader or is unreacheable.", err)
 		os.Exit(3)
 	}
+	alertClient.UpdateCheckData()
+	consulClient = alertClient
+	alertClient.OnTransition(transitions.publish)
+	alertClient.ValidateConfig(configProblems)
+	useHistoryBackend()
 
 	hostname, _ := os.Hostname()
+	alertClient.OnCircuitChange(circuitChanged(hostname))
 
-	log.Println("Consul Alerts daemon started")
-	log.Println("Consul Alerts Host:", hostname)
-	log.Println("Consul Agent:", consulAddr)
-	log.Println("Consul Datacenter:", consulDc)
+	log.Infoln("Consul Alerts daemon started")
+	log.Infoln("Consul Alerts Host:", hostname)
+	log.Infoln("Consul Agent:", consulAddr)
+	log.Infoln("Consul Datacenter:", consulDc)
 
-	leaderCandidate = &skipper.Candidate{
-		ConsulAddress:    consulAddr,
-		ConsulDatacenter: consulDc,
-		LeadershipKey:    "consul-alerts/leader",
+	if boolOption(arguments, "--register", "CONSUL_ALERTS_REGISTER") {
+		serviceId := stringOption(arguments, "--register-id", "CONSUL_ALERTS_REGISTER_ID")
+		if serviceId == "" {
+			serviceId = "consul-alerts-" + hostname
+		}
+		if err := registerSelf(serviceId, addr); err != nil {
+			log.Errorln("Unable to register the consul-alerts service:", err)
+			os.Exit(1)
+		}
 	}
+
+	leaderCandidate = alertClient.NewLeaderCandidate("consul-alerts/leader")
+	leaderCandidate.OnElected = resumeChecks
 	leaderCandidate.RunForElection()
 
+	if boolOption(arguments, "--shard", "CONSUL_ALERTS_SHARD") {
+		shardId := stringOption(arguments, "--shard-id", "CONSUL_ALERTS_SHARD_ID")
+		if shardId == "" {
+			shardId = hostname
+		}
+		log.Infoln("Shard Id:", shardId)
+		shard = alertClient.EnableSharding(shardId)
+		shard.OnChange = resumeChecks
+		shard.Join()
+	}
+
+	go runWatcher("config")
+	go reloadOnHangup()
+	go runNodeWatcher()
+	go runServiceWatcher()
+	go runClusterWatcher(hostname)
+	go runHeartbeat()
+	go runReminders()
+	go runHistoryRetention()
 	if watchChecks {
-		go runWatcher(consulAddr, consulDc, "checks")
+		go runChecksWatchers()
 	}
 	if watchEvents {
-		go runWatcher(consulAddr, consulDc, "event")
+		go runWatcher("event")
+	}
+	if watchKeys {
+		go runKeyWatchers()
+	}
+
+	if cacheFile := stringOption(arguments, "--cache-file", "CONSUL_ALERTS_CACHE_FILE"); cacheFile != "" {
+		localCache, err = cache.Open(cacheFile)
+		if err != nil {
+			log.Errorln("Unable to open the cache file:", err)
+			os.Exit(1)
+		}
+		go monitorConsul(hostname)
+	}
+
+	if auditFile := stringOption(arguments, "--audit-file", "CONSUL_ALERTS_AUDIT_FILE"); auditFile != "" {
+		if err := openAuditLog(auditFile); err != nil {
+			log.Errorln("Unable to open the audit file:", err)
+			os.Exit(1)
+		}
+	}
+
+	if statsdAddr := stringOption(arguments, "--statsd-addr", "CONSUL_ALERTS_STATSD_ADDR"); statsdAddr != "" {
+		prefix := stringOption(arguments, "--statsd-prefix", "CONSUL_ALERTS_STATSD_PREFIX")
+		dogstatsd := boolOption(arguments, "--dogstatsd", "CONSUL_ALERTS_DOGSTATSD")
+		if err := metrics.EnableStatsd(statsdAddr, prefix, dogstatsd); err != nil {
+			log.Errorln("Unable to reach the statsd agent:", err)
+			os.Exit(1)
+		}
+	}
+
+	shutdownTimeout := defaultShutdownTimeout
+	if seconds, err := intOption(arguments, "--shutdown-timeout", "CONSUL_ALERTS_SHUTDOWN_TIMEOUT"); err != nil || seconds < 0 {
+		log.Errorln("Invalid shutdown timeout, a number of seconds is expected.")
+		os.Exit(1)
+	} else if seconds > 0 {
+		shutdownTimeout = time.Duration(seconds) * time.Second
 	}
 
 	go processEvents()
 	go processChecks()
 
 	http.HandleFunc("/v1/info", infoHandler)
-	http.HandleFunc("/v1/process/events", eventHandler)
-	http.HandleFunc("/v1/process/checks", checkHandler)
-	http.HandleFunc("/v1/health", healthHandler)
-	go http.ListenAndServe(addr, nil)
+	http.HandleFunc("/v1/process/events", auth.wrap(refuseWhenStopping(eventHandler)))
+	http.HandleFunc("/v1/process/checks", auth.wrap(refuseWhenStopping(checkHandler)))
+	http.HandleFunc("/v1/health", auth.wrap(healthHandler))
+	http.HandleFunc("/v1/alerts", auth.wrap(alertsHandler))
+	http.HandleFunc("/v1/alerts/", auth.wrap(alertsHandler))
+	http.HandleFunc("/v1/history", auth.wrap(historyHandler))
+	http.HandleFunc("/v1/silences", auth.wrap(silencesHandler))
+	http.HandleFunc("/v1/silences/", auth.wrap(silencesHandler))
+	http.HandleFunc("/v1/alertmanager", auth.wrap(refuseWhenStopping(alertmanagerHandler)))
+	http.HandleFunc("/", auth.wrap(dashboardHandler))
+	http.HandleFunc("/health", selfHealthHandler)
+	http.HandleFunc("/ready", readyHandler)
+	http.HandleFunc("/readyz", readyHandler)
+	http.HandleFunc("/livez", livezHandler)
+	http.HandleFunc("/metrics", auth.wrap(metrics.Handler))
+	if tlsCert != "" {
+		go func() {
+			if err := http.ListenAndServeTLS(addr, tlsCert, tlsKey, nil); err != nil {
+				log.Errorln("Unable to start the consul-alerts api:", err)
+				os.Exit(1)
+			}
+		}()
+	} else {
+		go http.ListenAndServe(addr, nil)
+	}
+	if grpcAddr := stringOption(arguments, "--grpc-addr", "CONSUL_ALERTS_GRPC_ADDR"); grpcAddr != "" {
+		if err := serveGrpc(grpcAddr, tlsCert, tlsKey, auth); err != nil {
+			log.Errorln("Unable to start the consul-alerts gRPC api:", err)
+			os.Exit(1)
+		}
+		log.Infoln("gRPC API:", grpcAddr)
+	}
 
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
 	<-ch
-	cleanup()
+	cleanup(shutdownTimeout)
+}
+
+// consulAgent returns the consul address and datacenter options, the address
+// falls back to CONSUL_HTTP_ADDR.
+func consulAgent(arguments map[string]interface{}) (string, string) {
+	consulAddr := stringOption(arguments, "--consul-addr", "CONSUL_HTTP_ADDR")
+	if consulAddr == "" {
+		consulAddr = "localhost:8500"
+	}
+	consulDc := stringOption(arguments, "--consul-dc", "")
+	if consulDc == "" {
+		consulDc = "dc1"
+	}
+	return consulAddr, consulDc
+}
+
+// connectConsul creates the consul client from the consul options.
+func connectConsul(arguments map[string]interface{}) (*consul.ConsulAlertClient, error) {
+	consulAddr, consulDc := consulAgent(arguments)
+
+	client, err := consul.NewClient(consul.ClientConfig{
+		Address:    consulAddr,
+		Datacenter: consulDc,
+		Token:      stringOption(arguments, "--consul-acl-token", "CONSUL_HTTP_TOKEN"),
+		Namespace:  stringOption(arguments, "--consul-namespace", "CONSUL_NAMESPACE"),
+		Partition:  stringOption(arguments, "--consul-partition", "CONSUL_PARTITION"),
+		ConfigFile: stringOption(arguments, "--config-file", "CONSUL_ALERTS_CONFIG_FILE"),
+		Vault: consul.VaultConfig{
+			Address: stringOption(arguments, "--vault-addr", "VAULT_ADDR"),
+			Token:   stringOption(arguments, "--vault-token", "VAULT_TOKEN"),
+			TLS: consul.TLSConfig{
+				CAFile:             stringOption(arguments, "--vault-ca-file", "VAULT_CACERT"),
+				InsecureSkipVerify: os.Getenv("VAULT_SKIP_VERIFY") == "true",
+			},
+		},
+		TLS: consul.TLSConfig{
+			Enabled:            boolOption(arguments, "--consul-tls", "CONSUL_HTTP_SSL"),
+			CAFile:             stringOption(arguments, "--consul-ca-file", "CONSUL_CACERT"),
+			CertFile:           stringOption(arguments, "--consul-cert-file", "CONSUL_CLIENT_CERT"),
+			KeyFile:            stringOption(arguments, "--consul-key-file", "CONSUL_CLIENT_KEY"),
+			ServerName:         stringOption(arguments, "--consul-tls-server-name", "CONSUL_TLS_SERVER_NAME"),
+			InsecureSkipVerify: boolOption(arguments, "--consul-tls-skip-verify", "") || os.Getenv("CONSUL_HTTP_SSL_VERIFY") == "false",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tokenKey := stringOption(arguments, "--consul-acl-token-key", "CONSUL_ALERTS_ACL_TOKEN_KEY"); tokenKey != "" {
+		if err := client.UseTokenFromKV(tokenKey); err != nil {
+			return nil, fmt.Errorf("unable to read the consul ACL token: %s", err)
+		}
+	}
+	return client, nil
 }
 
 func watchMode(arguments map[string]interface{}) {
@@ -124,13 +382,35 @@ func watchMode(arguments map[string]interface{}) {
 		watchType = "events"
 	}
 
-	url := fmt.Sprintf("http://%s/v1/process/%s", addr, watchType)
-	resp, err := http.Post(url, "text/json", os.Stdin)
+	scheme, client, err := alertApiClient(
+		boolOption(arguments, "--alert-tls", "CONSUL_ALERTS_TLS"),
+		stringOption(arguments, "--alert-tls-ca", "CONSUL_ALERTS_TLS_CA"),
+		boolOption(arguments, "--alert-tls-skip-verify", "CONSUL_ALERTS_TLS_SKIP_VERIFY"),
+	)
+	if err != nil {
+		log.Errorln("Unable to configure TLS:", err)
+		os.Exit(2)
+	}
+
+	url := fmt.Sprintf("%s://%s/v1/process/%s", scheme, addr, watchType)
+	req, _ := http.NewRequest("POST", url, os.Stdin)
+	req.Header.Set("Content-Type", "text/json")
+	if token := stringOption(arguments, "--alert-token", "CONSUL_ALERTS_TOKEN"); token != "" {
+		req.Header.Set("X-Consul-Alerts-Token", token)
+	}
+	if user := stringOption(arguments, "--alert-user", "CONSUL_ALERTS_USER"); user != "" {
+		req.SetBasicAuth(user, stringOption(arguments, "--alert-password", "CONSUL_ALERTS_PASSWORD"))
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		log.Println("consul-alert daemon is not running.")
+		log.Errorln("consul-alert daemon is not running.")
 		os.Exit(2)
 	} else {
 		resp.Body.Close()
+		if resp.StatusCode == 401 {
+			log.Errorln("consul-alert daemon rejected the request, check --alert-token or --alert-user and --alert-password.")
+			os.Exit(2)
+		}
 	}
 }
 
@@ -139,21 +419,116 @@ func infoHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("version", version)
 }
 
-func cleanup() {
-	log.Println("Shutting down...")
-	close(checksChannel)
-	close(eventsChannel)
+// cleanup waits for the accepted checks, events and notifications, then
+// releases the leadership and the shard membership so another instance takes
+// over right away. A follower leaves the election first so it can't become
+// the leader while shutting down.
+func cleanup(timeout time.Duration) {
+	log.Infoln("Shutting down...")
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	if !leaderCandidate.IsLeader() {
+		leaderCandidate.Resign()
+	}
+	if remaining := drainPipeline(timeout); remaining > 0 {
+		log.Warnf("Shutdown timed out after %s, %d check processing(s), event(s) or reminder(s) dropped.", timeout, remaining)
+	}
+	leaderCandidate.Resign()
+	deregisterSelf()
+	if shard != nil {
+		shard.Leave()
+	}
+	if localCache != nil {
+		localCache.Close()
+	}
+	closeAuditLog()
+}
+
+// notifierState is the notifier.StateStore of a notifier, kept in consul.
+type notifierState string
+
+func (n notifierState) Get(key string) ([]byte, error) {
+	return consulClient.NotifierState(string(n), key)
 }
 
-func builtinNotifiers() []notifier.Notifier {
+func (n notifierState) Put(key string, value []byte) error {
+	return consulClient.SetNotifierState(string(n), key, value)
+}
 
-	emailConfig := consulClient.EmailConfig()
-	logConfig := consulClient.LogConfig()
-	influxdbConfig := consulClient.InfluxdbConfig()
-	slackConfig := consulClient.SlackConfig()
-	pagerdutyConfig := consulClient.PagerDutyConfig()
+// namedNotifier is a builtin notifier and the name routes, profiles and the
+// per-notifier settings address it by: its type, eg. "email", or the name of
+// a notifier instance.
+type namedNotifier struct {
+	name string
+	notifier.Notifier
+}
 
-	notifiers := []notifier.Notifier{}
+// builtinNotifiers returns the enabled builtin notifiers, then the enabled
+// notifier instances sorted by name.
+func builtinNotifiers() []namedNotifier {
+	notifiers := newNotifiers("", &consul.NotifiersConfig{
+		Email:     consulClient.EmailConfig(),
+		Log:       consulClient.LogConfig(),
+		Influxdb:  consulClient.InfluxdbConfig(),
+		Slack:     consulClient.SlackConfig(),
+		PagerDuty: consulClient.PagerDutyConfig(),
+		HipChat:   consulClient.HipChatConfig(),
+		VictorOps: consulClient.VictorOpsConfig(),
+		Zulip:     consulClient.ZulipConfig(),
+		Discord:   consulClient.DiscordConfig(),
+		Nats:      consulClient.NatsConfig(),
+		Mqtt:      consulClient.MqttConfig(),
+		Splunk:    consulClient.SplunkConfig(),
+		Nrdp:      consulClient.NrdpConfig(),
+		Zabbix:    consulClient.ZabbixConfig(),
+		Sentry:    consulClient.SentryConfig(),
+		Aws:       consulClient.AwsConfig(),
+		Pubsub:    consulClient.PubsubConfig(),
+	})
+	instances := consulClient.NotifierInstances()
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		notifiers = append(notifiers, newNotifiers(name, instances[name].Notifiers)...)
+	}
+	return notifiers
+}
+
+// newNotifiers builds the enabled notifiers of config, named after their type
+// or after instance for the notifiers of an instance.
+func newNotifiers(instance string, config *consul.NotifiersConfig) []namedNotifier {
+	nameOf := func(notifierType string) string {
+		if instance != "" {
+			return instance
+		}
+		return notifierType
+	}
+
+	emailConfig := config.Email
+	logConfig := config.Log
+	influxdbConfig := config.Influxdb
+	slackConfig := config.Slack
+	pagerdutyConfig := config.PagerDuty
+	hipchatConfig := config.HipChat
+	victoropsConfig := config.VictorOps
+	zulipConfig := config.Zulip
+	discordConfig := config.Discord
+	natsConfig := config.Nats
+	mqttConfig := config.Mqtt
+	splunkConfig := config.Splunk
+	nrdpConfig := config.Nrdp
+	zabbixConfig := config.Zabbix
+	sentryConfig := config.Sentry
+	awsConfig := config.Aws
+	pubsubConfig := config.Pubsub
+
+	dryRunMode := dryRun()
+
+	notifiers := []namedNotifier{}
 	if emailConfig.Enabled {
 		emailNotifier := &notifier.EmailNotifier{
 			Url:         emailConfig.Url,
@@ -163,16 +538,42 @@ func builtinNotifiers() []notifier.Notifier {
 			SenderAlias: emailConfig.SenderAlias,
 			SenderEmail: emailConfig.SenderEmail,
 			Receivers:   emailConfig.Receivers,
+			Cc:          emailConfig.Cc,
+			Bcc:         emailConfig.Bcc,
+			ReplyTo:     emailConfig.ReplyTo,
 			Template:    emailConfig.Template,
 			ClusterName: emailConfig.ClusterName,
+
+			SubjectTemplate:  emailConfig.SubjectTemplate,
+			ServiceReceivers: emailConfig.ServiceReceivers,
+			NodeReceivers:    emailConfig.NodeReceivers,
+
+			DatacenterReceivers: emailConfig.DatacenterReceivers,
+			Datacenter:          consulClient.LocalDatacenter(),
+			DeliveryMode:        emailConfig.DeliveryMode,
+			MaxOutputSize:       emailConfig.MaxOutputSize,
+
+			TLSMode:            emailConfig.TLSMode,
+			CACert:             emailConfig.CACert,
+			InsecureSkipVerify: emailConfig.InsecureSkipVerify,
+			AuthMode:           emailConfig.AuthMode,
+			OAuth2: notifier.OAuth2Config{
+				ClientId:     emailConfig.OAuth2ClientId,
+				ClientSecret: emailConfig.OAuth2ClientSecret,
+				RefreshToken: emailConfig.OAuth2RefreshToken,
+				TokenUrl:     emailConfig.OAuth2TokenUrl,
+				TokenCommand: emailConfig.OAuth2TokenCommand,
+				Http:         notifierHttpClient(nameOf("email")),
+			},
+			DryRun: dryRunMode,
 		}
-		notifiers = append(notifiers, emailNotifier)
+		notifiers = append(notifiers, namedNotifier{nameOf("email"), emailNotifier})
 	}
 	if logConfig.Enabled {
 		logNotifier := &notifier.LogNotifier{
 			LogFile: logConfig.Path,
 		}
-		notifiers = append(notifiers, logNotifier)
+		notifiers = append(notifiers, namedNotifier{nameOf("log"), logNotifier})
 	}
 	if influxdbConfig.Enabled {
 		influxdbNotifier := &notifier.InfluxdbNotifier{
@@ -181,8 +582,10 @@ func builtinNotifiers() []notifier.Notifier {
 			Password:   influxdbConfig.Password,
 			Database:   influxdbConfig.Database,
 			SeriesName: influxdbConfig.SeriesName,
+			DryRun:     dryRunMode,
+			Http:       notifierHttpClient(nameOf("influxdb")),
 		}
-		notifiers = append(notifiers, influxdbNotifier)
+		notifiers = append(notifiers, namedNotifier{nameOf("influxdb"), influxdbNotifier})
 	}
 	if slackConfig.Enabled {
 		slackNotifier := &notifier.SlackNotifier{
@@ -192,17 +595,180 @@ func builtinNotifiers() []notifier.Notifier {
 			Username:    slackConfig.Username,
 			IconUrl:     slackConfig.IconUrl,
 			IconEmoji:   slackConfig.IconEmoji,
+			DryRun:      dryRunMode,
+
+			Token:        slackConfig.Token,
+			Threads:      notifierState(nameOf("slack")),
+			ThreadWindow: time.Duration(slackConfig.ThreadWindow) * time.Second,
+			Http:         notifierHttpClient(nameOf("slack")),
 		}
-		notifiers = append(notifiers, slackNotifier)
+		notifiers = append(notifiers, namedNotifier{nameOf("slack"), slackNotifier})
 	}
 	if pagerdutyConfig.Enabled {
 		pagerdutyNotifier := &notifier.PagerDutyNotifier{
 			ServiceKey: pagerdutyConfig.ServiceKey,
 			ClientName: pagerdutyConfig.ClientName,
 			ClientUrl:  pagerdutyConfig.ClientUrl,
+			DryRun:     dryRunMode,
+			Http:       notifierHttpClient(nameOf("pagerduty")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("pagerduty"), pagerdutyNotifier})
+	}
+	if hipchatConfig.Enabled {
+		hipchatNotifier := &notifier.HipChatNotifier{
+			ClusterName:  hipchatConfig.ClusterName,
+			Url:          hipchatConfig.Url,
+			RoomId:       hipchatConfig.RoomId,
+			AuthToken:    hipchatConfig.AuthToken,
+			From:         hipchatConfig.From,
+			PassingColor: hipchatConfig.PassingColor,
+			WarningColor: hipchatConfig.WarningColor,
+			FailColor:    hipchatConfig.FailColor,
+			DryRun:       dryRunMode,
+			Http:         notifierHttpClient(nameOf("hipchat")),
 		}
-		notifiers = append(notifiers, pagerdutyNotifier)
+		notifiers = append(notifiers, namedNotifier{nameOf("hipchat"), hipchatNotifier})
+	}
+	if victoropsConfig.Enabled {
+		victoropsNotifier := &notifier.VictorOpsNotifier{
+			Url:        victoropsConfig.Url,
+			ApiKey:     victoropsConfig.ApiKey,
+			RoutingKey: victoropsConfig.RoutingKey,
+			DryRun:     dryRunMode,
+			Http:       notifierHttpClient(nameOf("victorops")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("victorops"), victoropsNotifier})
+	}
+	if zulipConfig.Enabled {
+		zulipNotifier := &notifier.ZulipNotifier{
+			ClusterName: zulipConfig.ClusterName,
+			Url:         zulipConfig.Url,
+			BotEmail:    zulipConfig.BotEmail,
+			ApiKey:      zulipConfig.ApiKey,
+			Stream:      zulipConfig.Stream,
+			Topic:       zulipConfig.Topic,
+			DryRun:      dryRunMode,
+			Http:        notifierHttpClient(nameOf("zulip")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("zulip"), zulipNotifier})
+	}
+	if discordConfig.Enabled {
+		discordNotifier := &notifier.DiscordNotifier{
+			ClusterName: discordConfig.ClusterName,
+			Url:         discordConfig.Url,
+			Username:    discordConfig.Username,
+			AvatarUrl:   discordConfig.AvatarUrl,
+			DryRun:      dryRunMode,
+			Http:        notifierHttpClient(nameOf("discord")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("discord"), discordNotifier})
+	}
+	if natsConfig.Enabled {
+		natsNotifier := &notifier.NatsNotifier{
+			Url:         natsConfig.Url,
+			Subject:     natsConfig.Subject,
+			JetStream:   natsConfig.JetStream,
+			Credentials: natsConfig.Credentials,
+			Token:       natsConfig.Token,
+			Username:    natsConfig.Username,
+			Password:    natsConfig.Password,
+			CACert:      natsConfig.CACert,
+			TLSCert:     natsConfig.TLSCert,
+			TLSKey:      natsConfig.TLSKey,
+			DryRun:      dryRunMode,
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("nats"), natsNotifier})
+	}
+	if mqttConfig.Enabled {
+		mqttNotifier := &notifier.MqttNotifier{
+			Url:      mqttConfig.Url,
+			ClientId: mqttConfig.ClientId,
+			Topic:    mqttConfig.Topic,
+			Qos:      mqttConfig.Qos,
+			Retain:   mqttConfig.Retain,
+			Username: mqttConfig.Username,
+			Password: mqttConfig.Password,
+			CACert:   mqttConfig.CACert,
+			TLSCert:  mqttConfig.TLSCert,
+			TLSKey:   mqttConfig.TLSKey,
+			DryRun:   dryRunMode,
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("mqtt"), mqttNotifier})
+	}
+	if splunkConfig.Enabled {
+		splunkNotifier := &notifier.SplunkNotifier{
+			Url:        splunkConfig.Url,
+			Token:      splunkConfig.Token,
+			Index:      splunkConfig.Index,
+			Source:     splunkConfig.Source,
+			SourceType: splunkConfig.SourceType,
+			DryRun:     dryRunMode,
+			Http:       notifierHttpClient(nameOf("splunk")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("splunk"), splunkNotifier})
+	}
+	if nrdpConfig.Enabled {
+		nrdpNotifier := &notifier.NrdpNotifier{
+			Url:     nrdpConfig.Url,
+			Token:   nrdpConfig.Token,
+			Host:    nrdpConfig.Host,
+			Service: nrdpConfig.Service,
+			DryRun:  dryRunMode,
+			Http:    notifierHttpClient(nameOf("nrdp")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("nrdp"), nrdpNotifier})
+	}
+	if zabbixConfig.Enabled {
+		zabbixNotifier := &notifier.ZabbixNotifier{
+			Server:  zabbixConfig.Server,
+			Host:    zabbixConfig.Host,
+			Key:     zabbixConfig.Key,
+			Numeric: zabbixConfig.Numeric,
+			DryRun:  dryRunMode,
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("zabbix"), zabbixNotifier})
+	}
+	if sentryConfig.Enabled {
+		sentryNotifier := &notifier.SentryNotifier{
+			Dsn:         sentryConfig.Dsn,
+			Environment: sentryConfig.Environment,
+			Level:       sentryConfig.Level,
+			DryRun:      dryRunMode,
+			Http:        notifierHttpClient(nameOf("sentry")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("sentry"), sentryNotifier})
+	}
+	if awsConfig.Enabled {
+		awsNotifier := &notifier.AwsNotifier{
+			Region:         awsConfig.Region,
+			Credentials:    notifier.AwsCredentials{AccessKeyId: awsConfig.AccessKeyId, SecretAccessKey: awsConfig.SecretAccessKey, SessionToken: awsConfig.SessionToken},
+			QueueUrl:       awsConfig.QueueUrl,
+			EventBus:       awsConfig.EventBus,
+			EventsEndpoint: awsConfig.EventsEndpoint,
+			Source:         awsConfig.Source,
+			DetailType:     awsConfig.DetailType,
+			DryRun:         dryRunMode,
+			Http:           notifierHttpClient(nameOf("aws")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("aws"), awsNotifier})
+	}
+	if pubsubConfig.Enabled {
+		pubsubNotifier := &notifier.PubsubNotifier{
+			Project:         pubsubConfig.Project,
+			Topic:           pubsubConfig.Topic,
+			CredentialsFile: pubsubConfig.CredentialsFile,
+			Endpoint:        pubsubConfig.Endpoint,
+			DryRun:          dryRunMode,
+			Http:            notifierHttpClient(nameOf("pubsub")),
+		}
+		notifiers = append(notifiers, namedNotifier{nameOf("pubsub"), pubsubNotifier})
 	}
 
 	return notifiers
 }
+
+// dryRun returns true if the notifications must be logged instead of sent,
+// either with --dry-run or the notifiers/dry-run KV toggle.
+func dryRun() bool {
+	return dryRunFlag || consulClient.DryRun()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docopt/docopt-go"
+)
+
+func TestConsulAgentDefaults(t *testing.T) {
+	defer os.Setenv("CONSUL_HTTP_ADDR", os.Getenv("CONSUL_HTTP_ADDR"))
+	os.Setenv("CONSUL_HTTP_ADDR", "")
+
+	args, err := docopt.Parse(usage, []string{"validate", "--consul-tls", "--consul-ca-file=ca.pem"}, true, version, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !args["--consul-tls"].(bool) || args["--consul-ca-file"] != "ca.pem" {
+		t.Errorf("expected the consul TLS options, got %v", args)
+	}
+	if addr, dc := consulAgent(args); addr != "localhost:8500" || dc != "dc1" {
+		t.Errorf("expected the default agent, got %s and %s", addr, dc)
+	}
+
+	os.Setenv("CONSUL_HTTP_ADDR", "consul.example.com:8500")
+	if addr, _ := consulAgent(args); addr != "consul.example.com:8500" {
+		t.Errorf("expected CONSUL_HTTP_ADDR, got %s", addr)
+	}
+	args["--consul-addr"] = "localhost:18500"
+	if addr, _ := consulAgent(args); addr != "localhost:18500" {
+		t.Errorf("expected --consul-addr to override CONSUL_HTTP_ADDR, got %s", addr)
+	}
+}
@@ -0,0 +1,86 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// ackRetries is the number of times an acknowledgement is retried when the
+// check processing updates the status meanwhile.
+const ackRetries = 3
+
+// Acknowledge acknowledges the failing checks of a node with the check id or
+// name. An acknowledged check gets no reminders and no output change alerts
+// until its status changes. It returns the acknowledged checks.
+func (c *ConsulAlertClient) Acknowledge(node, check, by, comment string, now time.Time) ([]Check, error) {
+	kvPairs, _, err := c.api.KV().List("consul-alerts/checks/", nil)
+	if err != nil {
+		apiErrors.Inc("list_checks")
+		return nil, err
+	}
+	var acknowledged []Check
+	for _, kvPair := range kvPairs {
+		if strings.HasSuffix(kvPair.Key, "/") {
+			continue
+		}
+		var status Status
+		if err := json.Unmarshal(kvPair.Value, &status); err != nil || status.HealthCheck == nil {
+			continue
+		}
+		health := status.HealthCheck
+		if health.Node != node || (health.CheckID != check && health.Name != check) {
+			continue
+		}
+		updated, err := c.acknowledge(kvPair, by, comment, now)
+		if err != nil {
+			return acknowledged, err
+		}
+		if updated != nil {
+			acknowledged = append(acknowledged, *updated)
+		}
+	}
+	if len(acknowledged) == 0 {
+		return nil, fmt.Errorf("no failing check %s on node %s", check, node)
+	}
+	return acknowledged, nil
+}
+
+// acknowledge acknowledges the status of a check if it is failing, it returns
+// nil otherwise.
+func (c *ConsulAlertClient) acknowledge(kvPair *consulapi.KVPair, by, comment string, now time.Time) (*Check, error) {
+	key := kvPair.Key
+	for attempt := 0; ; attempt++ {
+		var status Status
+		json.Unmarshal(kvPair.Value, &status)
+		if status.Current == "" || status.Current == "passing" {
+			return nil, nil
+		}
+		status.AcknowledgedTimestamp = now
+		status.AcknowledgedBy = by
+		status.AcknowledgedComment = comment
+		data, _ := json.Marshal(status)
+		updated, _, err := c.api.KV().CAS(&consulapi.KVPair{Key: key, Value: data, ModifyIndex: kvPair.ModifyIndex}, nil)
+		if err != nil {
+			apiErrors.Inc("acknowledge")
+			return nil, err
+		}
+		if updated {
+			return status.HealthCheck, nil
+		}
+		if attempt >= ackRetries {
+			return nil, fmt.Errorf("%s kept changing, try again", key)
+		}
+		if kvPair, _, err = c.api.KV().Get(key, nil); err != nil {
+			apiErrors.Inc("acknowledge")
+			return nil, err
+		}
+		if kvPair == nil {
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package consul
+
+// AlertmanagerConfig enables the endpoint receiving the Prometheus
+// Alertmanager webhook notifications.
+type AlertmanagerConfig struct {
+	Enabled bool
+}
+
+func (c *ConsulAlertClient) AlertmanagerEnabled() bool {
+	return c.currentConfig().Alertmanager.Enabled
+}
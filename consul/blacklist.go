@@ -0,0 +1,80 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"encoding/json"
+)
+
+const blacklistPatternPrefix = "consul-alerts/config/checks/blacklist/patterns/"
+
+// BlacklistPattern disables the notifications of the checks matching its
+// node, service and check patterns. A pattern between slashes like
+// /^chef-client.*/ is a regular expression, otherwise it uses path.Match
+// syntax like build-*. An empty pattern matches everything.
+type BlacklistPattern struct {
+	Name    string `json:"-"`
+	Node    string `json:"node"`
+	Service string `json:"service"`
+	Check   string `json:"check"`
+
+	node, service, check func(string) bool
+}
+
+// Matches returns true if the patterns match the check. Like silences, the
+// service pattern is matched against the service id and name, and the check
+// pattern against the check id and name.
+func (p *BlacklistPattern) Matches(check *Check) bool {
+	return p.node(check.Node) &&
+		(p.service(check.ServiceID) || p.service(check.ServiceName)) &&
+		(p.check(check.CheckID) || p.check(check.Name))
+}
+
+func (p *BlacklistPattern) compile() (err error) {
+	if p.Node == "" && p.Service == "" && p.Check == "" {
+		return errors.New("at least one of node, service or check is required")
+	}
+	if p.node, err = patternMatcher(p.Node); err != nil {
+		return err
+	}
+	if p.service, err = patternMatcher(p.Service); err != nil {
+		return err
+	}
+	p.check, err = patternMatcher(p.Check)
+	return err
+}
+
+func patternMatcher(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %s", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %s", pattern, err)
+	}
+	return func(value string) bool {
+		return matchPattern(pattern, value)
+	}, nil
+}
+
+// loadBlacklistPattern loads the JSON pattern stored at
+// consul-alerts/config/checks/blacklist/patterns/{{ name }}.
+func loadBlacklistPattern(patterns *[]BlacklistPattern, key string, data []byte) error {
+	var pattern BlacklistPattern
+	if err := json.Unmarshal(data, &pattern); err != nil {
+		return fmt.Errorf(`expected a JSON object like {"node": "build-*", "check": "/^chef-client.*/"}, got %q`, data)
+	}
+	pattern.Name = strings.TrimPrefix(key, blacklistPatternPrefix)
+	if err := pattern.compile(); err != nil {
+		return err
+	}
+	*patterns = append(*patterns, pattern)
+	return nil
+}
@@ -0,0 +1,41 @@
+package consul
+
+import "testing"
+
+func TestBlacklistPatternMatches(t *testing.T) {
+	var patterns []BlacklistPattern
+	err := loadBlacklistPattern(&patterns, blacklistPatternPrefix+"chef", []byte(`{"node": "build-*", "check": "/^chef-client.*/"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := patterns[0]
+	if pattern.Name != "chef" {
+		t.Errorf("unexpected name %s", pattern.Name)
+	}
+	cases := []struct {
+		check   Check
+		matches bool
+	}{
+		{Check{Node: "build-01", CheckID: "chef-client-run"}, true},
+		{Check{Node: "build-01", CheckID: "service:chef", Name: "chef-client"}, true},
+		{Check{Node: "build-01", CheckID: "disk"}, false},
+		{Check{Node: "web-01", CheckID: "chef-client-run"}, false},
+	}
+	for _, c := range cases {
+		if pattern.Matches(&c.check) != c.matches {
+			t.Errorf("expected %v for %+v", c.matches, c.check)
+		}
+	}
+}
+
+func TestLoadBlacklistPatternInvalid(t *testing.T) {
+	var patterns []BlacklistPattern
+	for _, data := range []string{`{}`, `{"node": "/[/"}`, `{"service": "[x"}`, `build-*`} {
+		if err := loadBlacklistPattern(&patterns, blacklistPatternPrefix+"bad", []byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+	if len(patterns) != 0 {
+		t.Errorf("invalid patterns shouldn't be loaded: %v", patterns)
+	}
+}
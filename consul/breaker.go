@@ -0,0 +1,192 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/metrics"
+)
+
+// breakerThreshold is the number of consecutive failed consul calls that open
+// the circuit breaker.
+const breakerThreshold = 5
+
+// breakerCooldown is how long the open circuit breaker fails the calls before
+// letting one through to probe consul.
+const breakerCooldown = 30 * time.Second
+
+// breakerRetries is the number of retries of a failed read, the first one
+// after breakerBackoff which doubles on every retry.
+const breakerRetries = 2
+
+const breakerBackoff = 250 * time.Millisecond
+
+// ErrCircuitOpen is returned by the consul calls while the circuit breaker is
+// open.
+var ErrCircuitOpen = errors.New("consul is failing, circuit breaker open")
+
+var breakerOpened = metrics.NewCounterVec(
+	"consul_alerts_consul_circuit_breaker_opened_total",
+	"Number of times the Consul API circuit breaker opened.",
+)
+
+// circuitBreaker stops calling consul once it keeps failing. While open, a
+// single call per cooldown goes through and the first one that succeeds
+// closes it.
+type circuitBreaker struct {
+	sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	onChange func(open bool, err error)
+}
+
+// allow returns ErrCircuitOpen while the breaker is open and the cooldown
+// since it opened or since the last probe hasn't elapsed.
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.Lock()
+	defer b.Unlock()
+	if !b.open {
+		return nil
+	}
+	if now.Sub(b.openedAt) < breakerCooldown {
+		return ErrCircuitOpen
+	}
+	b.openedAt = now
+	return nil
+}
+
+// record counts the result of a call, err is nil when consul answered.
+func (b *circuitBreaker) record(err error, now time.Time) {
+	b.Lock()
+	changed := false
+	if err == nil {
+		b.failures = 0
+		changed = b.open
+		b.open = false
+	} else {
+		b.failures++
+		if !b.open && b.failures >= breakerThreshold {
+			b.open = true
+			b.openedAt = now
+			changed = true
+			breakerOpened.Inc()
+		}
+	}
+	open, onChange := b.open, b.onChange
+	b.Unlock()
+
+	if changed && onChange != nil {
+		onChange(open, err)
+	}
+}
+
+func (b *circuitBreaker) isOpen() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.open
+}
+
+// remoteBreakers are the circuit breakers of the remote datacenters, by name.
+// A failing remote datacenter only fails the calls to this datacenter.
+type remoteBreakers struct {
+	sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func (r *remoteBreakers) get(dc string) *circuitBreaker {
+	r.Lock()
+	defer r.Unlock()
+	if r.breakers == nil {
+		r.breakers = make(map[string]*circuitBreaker)
+	}
+	breaker := r.breakers[dc]
+	if breaker == nil {
+		breaker = &circuitBreaker{}
+		r.breakers[dc] = breaker
+	}
+	return breaker
+}
+
+// breakerTransport retries the failed reads with a backoff and counts the
+// results in the circuit breakers. Connection errors to the agent are
+// failures of the local breaker while 5xx responses are failures of the
+// breaker of the queried datacenter. The other responses, like the 409 of a
+// conflict, mean consul answered. Blocking queries aren't retried, the
+// watchers retry them.
+type breakerTransport struct {
+	breaker   *circuitBreaker
+	remote    *remoteBreakers
+	local     string
+	transport http.RoundTripper
+}
+
+// datacenterBreaker returns the breaker of the remote datacenter queried by
+// req, nil for the local datacenter.
+func (t *breakerTransport) datacenterBreaker(req *http.Request) *circuitBreaker {
+	dc := req.URL.Query().Get("dc")
+	if dc == "" || dc == t.local || t.remote == nil {
+		return nil
+	}
+	return t.remote.get(dc)
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	remote := t.datacenterBreaker(req)
+	backoff := breakerBackoff
+	for attempt := 0; ; attempt++ {
+		if err := t.breaker.allow(time.Now()); err != nil {
+			return nil, err
+		}
+		if remote != nil {
+			if err := remote.allow(time.Now()); err != nil {
+				return nil, err
+			}
+		}
+		res, err := t.transport.RoundTrip(req)
+		failure := err
+		if err == nil && res.StatusCode >= 500 {
+			failure = fmt.Errorf("unexpected response code: %d", res.StatusCode)
+		}
+		if remote != nil && err == nil {
+			t.breaker.record(nil, time.Now())
+			remote.record(failure, time.Now())
+		} else {
+			t.breaker.record(failure, time.Now())
+		}
+		if failure == nil || attempt == breakerRetries || !retryable(req) {
+			return res, err
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(req *http.Request) bool {
+	return req.Method == "GET" && req.URL.Query().Get("index") == ""
+}
+
+// CircuitOpen returns true while the consul calls to the local datacenter
+// fail fast because consul keeps failing.
+func (c *ConsulAlertClient) CircuitOpen() bool {
+	return c.breaker.isOpen()
+}
+
+// OnCircuitChange calls f when the circuit breaker opens, with the last
+// error, and when it closes again.
+func (c *ConsulAlertClient) OnCircuitChange(f func(open bool, err error)) {
+	c.breaker.Lock()
+	defer c.breaker.Unlock()
+	c.breaker.onChange = f
+}
@@ -0,0 +1,102 @@
+package consul
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	var changes []bool
+	breaker := &circuitBreaker{onChange: func(open bool, err error) { changes = append(changes, open) }}
+	now := time.Now()
+	failure := errors.New("connection refused")
+
+	for i := 0; i < breakerThreshold; i++ {
+		if err := breaker.allow(now); err != nil {
+			t.Fatalf("call %d should be allowed: %s", i, err)
+		}
+		breaker.record(failure, now)
+	}
+	if !breaker.isOpen() || breaker.allow(now) != ErrCircuitOpen {
+		t.Fatal("the breaker should be open after the threshold")
+	}
+
+	probe := now.Add(breakerCooldown)
+	if err := breaker.allow(probe); err != nil {
+		t.Fatal("a probe should be allowed after the cooldown:", err)
+	}
+	if breaker.allow(probe) != ErrCircuitOpen {
+		t.Error("only one probe should be allowed per cooldown")
+	}
+	breaker.record(nil, probe)
+	if breaker.isOpen() {
+		t.Error("a successful probe should close the breaker")
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("expected an open then a close change, got %v", changes)
+	}
+}
+
+func TestBreakerTransportRetriesReads(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &breakerTransport{breaker: &circuitBreaker{}, transport: http.DefaultTransport}}
+	res, err := client.Get(server.URL + "/v1/kv/test")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 || calls != 2 {
+		t.Errorf("expected a successful retry, got %d after %d calls", res.StatusCode, calls)
+	}
+
+	calls = 0
+	res, err = client.Get(server.URL + "/v1/health/state/any?index=10")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	res.Body.Close()
+	if calls != 1 {
+		t.Errorf("blocking queries should not be retried, got %d calls", calls)
+	}
+}
+
+func TestBreakerTransportIsolatesRemoteDatacenters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("dc") == "dc2" {
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	local := &circuitBreaker{}
+	transport := &breakerTransport{breaker: local, remote: &remoteBreakers{}, local: "dc1", transport: http.DefaultTransport}
+	client := &http.Client{Transport: transport}
+	for i := 0; i < breakerThreshold; i++ {
+		if res, err := client.Post(server.URL+"/v1/txn?dc=dc2", "application/json", nil); err == nil {
+			res.Body.Close()
+		}
+	}
+	if local.isOpen() {
+		t.Error("a failing remote datacenter should not open the local breaker")
+	}
+	if _, err := client.Post(server.URL+"/v1/txn?dc=dc2", "application/json", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the dc2 breaker to be open, got %v", err)
+	}
+	res, err := client.Get(server.URL + "/v1/kv/test?dc=dc1")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	res.Body.Close()
+}
@@ -1,17 +1,31 @@
 package consul
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/AcalephStorage/consul-alerts/metrics"
 
 	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
 )
 
+var apiErrors = metrics.NewCounterVec(
+	"consul_alerts_consul_api_errors_total",
+	"Number of failed Consul API calls.",
+	"operation",
+)
+
 const (
 	ConfigTypeBool = iota
 	ConfigTypeString
@@ -22,139 +36,858 @@ const (
 type configType int
 
 type ConsulAlertClient struct {
-	api    *consulapi.Client
-	config *ConsulAlertConfig
+	api          *consulapi.Client
+	watchApi     *consulapi.Client
+	state        *configState
+	clientConfig ClientConfig
+	datacenter   string
+	httpClient   *http.Client
+	httpAddress  string
+	shard        *Shard
+
+	// the nodes and services in maintenance, updated with the check data
+	maintenance        map[string]Maintenance
+	maintenanceLoaded  map[string]bool
+	maintenanceChanges []MaintenanceChange
+
+	// the checks, besides serfHealth, that invalidate the sessions of this
+	// instance when they turn critical
+	sessionChecks []string
+
+	// breaker fails the consul calls fast while consul keeps failing, and
+	// remoteBreakers the calls to a failing remote datacenter. They are kept
+	// when the clients are recreated with a new token.
+	breaker        *circuitBreaker
+	remoteBreakers *remoteBreakers
+
+	// history keeps the history instead of the KV ring buffer when its store
+	// is set
+	history *historyState
+
+	// onTransition is called with each check status change
+	onTransition func(entry HistoryEntry)
+
+	// validate reports the problems of a reloaded config before it's used
+	validate func(client Consul) []string
 }
 
-func NewClient(address, dc string) (*ConsulAlertClient, error) {
-	config := consulapi.DefaultConfig()
-	config.Address = address
-	config.Datacenter = dc
-	config.HttpClient.Timeout = 5 * time.Second
-	api, _ := consulapi.NewClient(config)
+// ClientConfig holds the settings used to connect to the consul agent.
+type ClientConfig struct {
+	Address    string
+	Datacenter string
+	Token      string
+	TLS        TLSConfig
+
+	// Namespace and Partition select the consul enterprise namespace and
+	// admin partition used for the KV config and the health queries.
+	Namespace string
+	Partition string
+
+	// ConfigFile is a YAML, TOML or JSON file loaded before the KV config.
+	ConfigFile string
+
+	// Vault resolves the vault:<path>#<key> config values when its Address
+	// is set.
+	Vault VaultConfig
+}
+
+func NewClient(clientConfig ClientConfig) (*ConsulAlertClient, error) {
 	alertConfig := DefaultAlertConfig()
 
 	client := &ConsulAlertClient{
-		api:    api,
-		config: alertConfig,
+		state:      &configState{config: alertConfig},
+		datacenter: clientConfig.Datacenter,
+		history:    &historyState{},
+	}
+	if err := client.connect(clientConfig); err != nil {
+		return nil, err
+	}
+	if clientConfig.Vault.Address != "" {
+		vaultClient, err := newVaultClient(clientConfig.Vault)
+		if err != nil {
+			return nil, err
+		}
+		vault = vaultClient
+		go vault.renewToken()
 	}
 
-	log.Println("Checking consul agent connection...")
+	log.Infoln("Checking consul agent connection...")
 	if _, err := client.api.Status().Leader(); err != nil {
 		return nil, err
 	}
+	// the checks of the agent's datacenter are told apart by its name
+	if client.datacenter == "" {
+		if self, err := client.api.Agent().Self(); err == nil {
+			client.datacenter, _ = self["Config"]["Datacenter"].(string)
+		}
+	}
 
 	client.LoadConfig()
-	client.UpdateCheckData()
 	return client, nil
 }
 
+// connect creates the consul api clients. The same ACL token and TLS settings
+// are used for every call, including blocking queries and leader election.
+func (c *ConsulAlertClient) connect(clientConfig ClientConfig) error {
+	address, transport, err := newTransport(clientConfig.Address, clientConfig.TLS)
+	if err != nil {
+		return err
+	}
+	transport.params = url.Values{}
+	if clientConfig.Namespace != "" {
+		transport.params.Set("ns", clientConfig.Namespace)
+	}
+	if clientConfig.Partition != "" {
+		transport.params.Set("partition", clientConfig.Partition)
+	}
+
+	if c.breaker == nil {
+		c.breaker = &circuitBreaker{}
+		c.remoteBreakers = &remoteBreakers{}
+	}
+	breaker := &breakerTransport{breaker: c.breaker, remote: c.remoteBreakers, local: clientConfig.Datacenter, transport: transport}
+
+	config := consulapi.DefaultConfig()
+	config.Address = address
+	config.Datacenter = clientConfig.Datacenter
+	config.Token = clientConfig.Token
+	config.HttpClient = &http.Client{Transport: breaker, Timeout: 5 * time.Second}
+	c.api, _ = consulapi.NewClient(config)
+
+	// blocking queries need a timeout longer than the query wait time
+	watchConfig := *config
+	watchConfig.HttpClient = &http.Client{Transport: breaker, Timeout: watchWaitTime + 30*time.Second}
+	c.watchApi, _ = consulapi.NewClient(&watchConfig)
+
+	c.clientConfig = clientConfig
+	c.httpClient = config.HttpClient
+	c.httpAddress = address
+	return nil
+}
+
+// request calls a consul http endpoint that the consul api package doesn't
+// support. body and out are encoded as json when not nil.
+func (c *ConsulAlertClient) request(method, path string, body, out interface{}) (int, error) {
+	return c.requestIn(c.clientConfig.Datacenter, method, path, body, out)
+}
+
+// requestIn is request for a given datacenter.
+func (c *ConsulAlertClient) requestIn(dc, method, path string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	params := url.Values{}
+	if dc != "" {
+		params.Set("dc", dc)
+	}
+	if c.clientConfig.Token != "" {
+		params.Set("token", c.clientConfig.Token)
+	}
+	requestUrl := fmt.Sprintf("http://%s%s?%s", c.httpAddress, path, params.Encode())
+	req, err := http.NewRequest(method, requestUrl, reader)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return resp.StatusCode, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode, nil
+}
+
+// UseTokenFromKV reads an ACL token stored in the given KV key and uses it for
+// all further calls. The key is read with the current token.
+func (c *ConsulAlertClient) UseTokenFromKV(key string) error {
+	kvPair, _, err := c.api.KV().Get(key, nil)
+	if err != nil {
+		return err
+	}
+	if kvPair == nil {
+		return fmt.Errorf("acl token key %s not found", key)
+	}
+	clientConfig := c.clientConfig
+	clientConfig.Token = strings.TrimSpace(string(kvPair.Value))
+	if err := c.connect(clientConfig); err != nil {
+		return err
+	}
+	c.LoadConfig()
+	return nil
+}
+
+// Ping checks that the consul agent is reachable and the cluster has a leader.
+func (c *ConsulAlertClient) Ping() error {
+	leader, err := c.api.Status().Leader()
+	if err != nil {
+		return err
+	}
+	if leader == "" {
+		return fmt.Errorf("cluster has no leader")
+	}
+	return nil
+}
+
+// LoadConfig loads the config file and the KV config over the defaults and
+// makes it the active config.
 func (c *ConsulAlertClient) LoadConfig() {
-	if kvPairs, _, err := c.api.KV().List("consul-alerts/config", nil); err == nil {
-
-		config := c.config
-
-		for _, kvPair := range kvPairs {
-
-			key := kvPair.Key
-			val := kvPair.Value
-
-			var valErr error
-			switch key {
-			// checks config
-			case "consul-alerts/config/checks/enabled":
-				valErr = loadCustomValue(&config.Checks.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/checks/change-threshold":
-				valErr = loadCustomValue(&config.Checks.ChangeThreshold, val, ConfigTypeInt)
-
-			// events config
-			case "consul-alerts/config/events/enabled":
-				valErr = loadCustomValue(&config.Events.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/events/handlers":
-				valErr = loadCustomValue(&config.Events.Handlers, val, ConfigTypeStrArray)
-
-			// notifiers config
-			case "consul-alerts/config/notifiers/custom":
-				valErr = loadCustomValue(&config.Notifiers.Custom, val, ConfigTypeStrArray)
-
-			// email notifier config
-			case "consul-alerts/config/notifiers/email/cluster-name":
-				valErr = loadCustomValue(&config.Notifiers.Email.ClusterName, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/template":
-				valErr = loadCustomValue(&config.Notifiers.Email.Template, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/enabled":
-				valErr = loadCustomValue(&config.Notifiers.Email.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/notifiers/email/password":
-				valErr = loadCustomValue(&config.Notifiers.Email.Password, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/port":
-				valErr = loadCustomValue(&config.Notifiers.Email.Port, val, ConfigTypeInt)
-			case "consul-alerts/config/notifiers/email/receivers":
-				valErr = loadCustomValue(&config.Notifiers.Email.Receivers, val, ConfigTypeStrArray)
-			case "consul-alerts/config/notifiers/email/sender-alias":
-				valErr = loadCustomValue(&config.Notifiers.Email.SenderAlias, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/sender-email":
-				valErr = loadCustomValue(&config.Notifiers.Email.SenderEmail, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/url":
-				valErr = loadCustomValue(&config.Notifiers.Email.Url, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/email/username":
-				valErr = loadCustomValue(&config.Notifiers.Email.Username, val, ConfigTypeString)
-
-			// log notifier config
-			case "consul-alerts/config/notifiers/log/enabled":
-				valErr = loadCustomValue(&config.Notifiers.Log.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/notifiers/log/path":
-				valErr = loadCustomValue(&config.Notifiers.Log.Path, val, ConfigTypeString)
-
-			// influxdb notifier config
-			case "consul-alerts/config/notifiers/influxdb/enabled":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/notifiers/influxdb/host":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.Host, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/influxdb/username":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.Username, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/influxdb/password":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.Password, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/influxdb/database":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.Database, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/influxdb/series-name":
-				valErr = loadCustomValue(&config.Notifiers.Influxdb.SeriesName, val, ConfigTypeString)
-
-			// slack notfier config
-			case "consul-alerts/config/notifiers/slack/enabled":
-				valErr = loadCustomValue(&config.Notifiers.Slack.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/notifiers/slack/cluster-name":
-				valErr = loadCustomValue(&config.Notifiers.Slack.ClusterName, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/slack/url":
-				valErr = loadCustomValue(&config.Notifiers.Slack.Url, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/slack/channel":
-				valErr = loadCustomValue(&config.Notifiers.Slack.Channel, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/slack/username":
-				valErr = loadCustomValue(&config.Notifiers.Slack.Username, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/slack/icon-url":
-				valErr = loadCustomValue(&config.Notifiers.Slack.IconUrl, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/slack/icon-emoji":
-				valErr = loadCustomValue(&config.Notifiers.Slack.IconEmoji, val, ConfigTypeString)
-
-			case "consul-alerts/config/notifiers/pagerduty/enabled":
-				valErr = loadCustomValue(&config.Notifiers.PagerDuty.Enabled, val, ConfigTypeBool)
-			case "consul-alerts/config/notifiers/pagerduty/service-key":
-				valErr = loadCustomValue(&config.Notifiers.PagerDuty.ServiceKey, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/pagerduty/client-name":
-				valErr = loadCustomValue(&config.Notifiers.PagerDuty.ClientName, val, ConfigTypeString)
-			case "consul-alerts/config/notifiers/pagerduty/client-url":
-				valErr = loadCustomValue(&config.Notifiers.PagerDuty.ClientUrl, val, ConfigTypeString)
+	c.ReloadConfig()
+}
 
-			}
+// configState is the active config, with the errors found while loading it
+// and its raw values by key to report the changes of the next load. The
+// config is replaced as a whole under the lock and never modified once
+// active, so the readers only need the lock to get it.
+type configState struct {
+	sync.RWMutex
+	config *ConsulAlertConfig
+	errors []error
+	values map[string]string
+	loaded bool
+
+	// reload serializes the reloads
+	reload sync.Mutex
+}
+
+// ConfigProblemsError is returned by ReloadConfig when the validator found
+// problems in the new config. The current config is kept.
+type ConfigProblemsError struct {
+	Problems []string
+}
 
-			if valErr != nil {
-				log.Printf(`unable to load custom value for "%s". Using default instead. Error: %s`, key, valErr.Error())
+func (e *ConfigProblemsError) Error() string {
+	return fmt.Sprintf("%d problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// currentConfig returns the active config.
+func (c *ConsulAlertClient) currentConfig() *ConsulAlertConfig {
+	c.state.RLock()
+	defer c.state.RUnlock()
+	return c.state.config
+}
+
+// ValidateConfig sets the validator of the reloaded configs. It gets a client
+// using the new config and returns its problems.
+func (c *ConsulAlertClient) ValidateConfig(validate func(client Consul) []string) {
+	c.state.reload.Lock()
+	defer c.state.reload.Unlock()
+	c.validate = validate
+}
+
+// ReloadConfig loads the config like LoadConfig and returns the config keys
+// that were added, changed or removed since the last load, they are also
+// logged. The new config is built and validated aside and swapped in at once,
+// so it is never seen half loaded. The current config is kept while the KV
+// can't be read, and when the validator finds problems in the new config, a
+// ConfigProblemsError is then returned.
+func (c *ConsulAlertClient) ReloadConfig() ([]ConfigChange, error) {
+	c.state.reload.Lock()
+	defer c.state.reload.Unlock()
+	c.state.RLock()
+	loaded, previousValues := c.state.loaded, c.state.values
+	c.state.RUnlock()
+
+	pairs, _, kvErr := c.api.KV().List("consul-alerts/config", nil)
+	if kvErr != nil {
+		apiErrors.Inc("load_config")
+		if loaded {
+			log.Debugln("Unable to load custom config from consul KV, keeping the current config:", kvErr)
+			return nil, kvErr
+		}
+		log.Warnln("Unable to load custom config from consul KV:", kvErr)
+	}
+	var configErrors []error
+
+	// the config file values are loaded first so the KV values override them
+	var kvPairs consulapi.KVPairs
+	if c.clientConfig.ConfigFile != "" {
+		filePairs, err := loadConfigFile(c.clientConfig.ConfigFile)
+		if err != nil {
+			configErrors = append(configErrors, fmt.Errorf("%s: %s", c.clientConfig.ConfigFile, err))
+			log.Warnln("Unable to load the config file:", err)
+		}
+		kvPairs = append(kvPairs, filePairs...)
+	}
+	kvPairs = append(kvPairs, pairs...)
+
+	config, loadErrors := loadConfigPairs(kvPairs)
+	configErrors = append(configErrors, loadErrors...)
+	instances, instanceErrors := loadNotifierInstances(kvPairs)
+	configErrors = append(configErrors, instanceErrors...)
+	config.Notifiers.Instances = instances
+
+	values := configValues(kvPairs)
+	changes := configChanges(previousValues, values)
+
+	// the first config is used even with problems, there is no other one
+	if loaded && c.validate != nil {
+		candidate := *c
+		candidate.state = &configState{config: config, errors: configErrors, values: values, loaded: true}
+		if problems := c.validate(&candidate); len(problems) > 0 {
+			return changes, &ConfigProblemsError{Problems: problems}
+		}
+	}
+	if previousValues != nil && len(changes) > 0 {
+		log.Infoln("Configuration changes:", describeChanges(changes))
+	}
+	c.state.Lock()
+	c.state.config, c.state.errors, c.state.values = config, configErrors, values
+	c.state.loaded = c.state.loaded || kvErr == nil
+	c.state.Unlock()
+	return changes, kvErr
+}
+
+// loadConfigPairs loads the config values over the defaults.
+func loadConfigPairs(kvPairs consulapi.KVPairs) (*ConsulAlertConfig, []error) {
+	var configErrors []error
+	config := DefaultAlertConfig()
+	serviceReceivers := make(map[string][]string)
+	nodeReceivers := make(map[string][]string)
+	datacenterReceivers := make(map[string][]string)
+	outputs := make(map[string]OutputConfig)
+	digests := make(map[string]DigestConfig)
+	quietHours := make(map[string]QuietHoursConfig)
+	timeouts := make(map[string]int)
+	https := make(map[string]HttpConfig)
+	serviceMinHealthy := make(map[string]int)
+	var blacklistPatterns []BlacklistPattern
+	profiles := &ProfilesConfig{
+		Profiles: make(map[string]Profile),
+		Checks:   make(map[string]string),
+		Services: make(map[string]string),
+		Nodes:    make(map[string]string),
+	}
+
+	for _, kvPair := range kvPairs {
+
+		key := kvPair.Key
+		val := kvPair.Value
+
+		var valErr error
+		switch key {
+		// checks config
+		case "consul-alerts/config/checks/enabled":
+			valErr = loadCustomValue(&config.Checks.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/checks/change-threshold":
+			valErr = loadCustomValue(&config.Checks.ChangeThreshold, val, ConfigTypeInt)
+		case "consul-alerts/config/checks/dependencies":
+			valErr = loadDependencies(&config.Checks.Dependencies, val)
+		case "consul-alerts/config/checks/inhibit-rules":
+			valErr = loadInhibitRules(&config.Checks.InhibitRules, val)
+		case "consul-alerts/config/checks/coalesce-window":
+			valErr = loadCustomValue(&config.Checks.CoalesceWindow, val, ConfigTypeInt)
+		case "consul-alerts/config/checks/maintenance-notices":
+			valErr = loadCustomValue(&config.Checks.MaintenanceNotices, val, ConfigTypeBool)
+		case "consul-alerts/config/checks/stale-after":
+			valErr = loadStaleAfter(&config.Checks.StaleAfter, val)
+		case "consul-alerts/config/checks/datacenters":
+			valErr = loadCustomValue(&config.Checks.Datacenters, val, ConfigTypeStrArray)
+		case "consul-alerts/config/checks/whitelist/enabled":
+			valErr = loadCustomValue(&config.Checks.Whitelist.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/checks/whitelist/services":
+			valErr = loadWhitelistPatterns(&config.Checks.Whitelist.Services, &config.Checks.Whitelist.services, val)
+		case "consul-alerts/config/checks/whitelist/checks":
+			valErr = loadWhitelistPatterns(&config.Checks.Whitelist.Checks, &config.Checks.Whitelist.checks, val)
+		case "consul-alerts/config/checks/whitelist/tags":
+			valErr = loadWhitelistPatterns(&config.Checks.Whitelist.Tags, &config.Checks.Whitelist.tags, val)
+
+		// leader election config
+		case "consul-alerts/config/leader/session-ttl":
+			valErr = loadCustomValue(&config.Leader.SessionTTL, val, ConfigTypeInt)
+		case "consul-alerts/config/leader/lock-delay":
+			valErr = loadCustomValue(&config.Leader.LockDelay, val, ConfigTypeInt)
+		case "consul-alerts/config/leader/retry-interval":
+			valErr = loadCustomValue(&config.Leader.RetryInterval, val, ConfigTypeInt)
+		case "consul-alerts/config/leader/session-behavior":
+			valErr = loadCustomValue(&config.Leader.SessionBehavior, val, ConfigTypeString)
+
+		// events config
+		case "consul-alerts/config/events/enabled":
+			valErr = loadCustomValue(&config.Events.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/events/handlers":
+			valErr = loadEventHandlers(&config.Events.Handlers, val)
+		case "consul-alerts/config/events/handler-timeout":
+			valErr = loadCustomValue(&config.Events.HandlerTimeout, val, ConfigTypeInt)
+		case "consul-alerts/config/events/timeout":
+			valErr = loadCustomValue(&config.Events.Timeout, val, ConfigTypeInt)
+		case "consul-alerts/config/events/concurrency":
+			valErr = loadCustomValue(&config.Events.Concurrency, val, ConfigTypeInt)
+		case "consul-alerts/config/events/webhook-retries":
+			valErr = loadCustomValue(&config.Events.WebhookRetries, val, ConfigTypeInt)
+		case "consul-alerts/config/events/webhook-headers":
+			valErr = loadHeaders(&config.Events.WebhookHeaders, val)
+		case "consul-alerts/config/events/failure-alerts":
+			valErr = loadCustomValue(&config.Events.FailureAlerts, val, ConfigTypeBool)
+
+		// key handlers config
+		case "consul-alerts/config/keys/handlers":
+			valErr = loadKeyHandlers(&config.Keys.Handlers, val)
+
+		// remediation config
+		case "consul-alerts/config/remediation/handlers":
+			valErr = loadRemediationHandlers(&config.Remediation.Handlers, val)
+		case "consul-alerts/config/remediation/cooldown":
+			valErr = loadCustomValue(&config.Remediation.Cooldown, val, ConfigTypeInt)
+		case "consul-alerts/config/remediation/max-attempts":
+			valErr = loadCustomValue(&config.Remediation.MaxAttempts, val, ConfigTypeInt)
+		case "consul-alerts/config/remediation/timeout":
+			valErr = loadCustomValue(&config.Remediation.Timeout, val, ConfigTypeInt)
+		case "consul-alerts/config/remediation/notify":
+			valErr = loadCustomValue(&config.Remediation.Notify, val, ConfigTypeBool)
+
+		// node membership alerts config
+		case "consul-alerts/config/nodes/enabled":
+			valErr = loadCustomValue(&config.Nodes.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/nodes/interval":
+			valErr = loadCustomValue(&config.Nodes.Interval, val, ConfigTypeInt)
+		case "consul-alerts/config/nodes/notifiers":
+			valErr = loadCustomValue(&config.Nodes.Notifiers, val, ConfigTypeStrArray)
+		case "consul-alerts/config/nodes/receivers":
+			valErr = loadCustomValue(&config.Nodes.Receivers, val, ConfigTypeStrArray)
+
+		// service registration alerts config
+		case "consul-alerts/config/services/enabled":
+			valErr = loadCustomValue(&config.Services.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/services/interval":
+			valErr = loadCustomValue(&config.Services.Interval, val, ConfigTypeInt)
+		case "consul-alerts/config/services/monitored":
+			valErr = loadCustomValue(&config.Services.Monitored, val, ConfigTypeStrArray)
+		case "consul-alerts/config/services/min-healthy":
+			valErr = loadMinHealthy(&config.Services.MinHealthy, val)
+
+		// consul cluster alerts config
+		case "consul-alerts/config/cluster/enabled":
+			valErr = loadCustomValue(&config.Cluster.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/cluster/interval":
+			valErr = loadCustomValue(&config.Cluster.Interval, val, ConfigTypeInt)
+		case "consul-alerts/config/cluster/expected-peers":
+			valErr = loadCustomValue(&config.Cluster.ExpectedPeers, val, ConfigTypeInt)
+
+		// alert routing config
+		case "consul-alerts/config/routes":
+			valErr = loadRoutes(&config.Routes, val)
+
+		// heartbeat config
+		case "consul-alerts/config/heartbeat/url":
+			valErr = loadCustomValue(&config.Heartbeat.URL, val, ConfigTypeString)
+		case "consul-alerts/config/heartbeat/interval":
+			valErr = loadCustomValue(&config.Heartbeat.Interval, val, ConfigTypeInt)
+		case "consul-alerts/config/heartbeat/headers":
+			valErr = loadHeaders(&config.Heartbeat.Headers, val)
+
+		// history config
+		case "consul-alerts/config/history/enabled":
+			valErr = loadCustomValue(&config.History.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/history/size":
+			valErr = loadHistorySize(&config.History.Size, val)
+		case "consul-alerts/config/history/backend":
+			valErr = loadHistoryBackend(&config.History.Backend, val)
+		case "consul-alerts/config/history/dsn":
+			valErr = loadCustomValue(&config.History.DSN, val, ConfigTypeString)
+		case "consul-alerts/config/history/retention":
+			valErr = loadCustomValue(&config.History.Retention, val, ConfigTypeInt)
+
+		// alertmanager config
+		case "consul-alerts/config/alertmanager/enabled":
+			valErr = loadCustomValue(&config.Alertmanager.Enabled, val, ConfigTypeBool)
+
+		// notifiers config
+		case "consul-alerts/config/notifiers/custom":
+			valErr = loadCustomValue(&config.Notifiers.Custom, val, ConfigTypeStrArray)
+		case "consul-alerts/config/notifiers/dry-run":
+			valErr = loadCustomValue(&config.Notifiers.DryRun, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/schedule-jitter":
+			valErr = loadCustomValue(&config.Notifiers.ScheduleJitter, val, ConfigTypeInt)
+		case "consul-alerts/config/notifiers/links":
+			valErr = loadLinks(&config.Notifiers.Links, val)
+
+		// email notifier config
+		case "consul-alerts/config/notifiers/email/cluster-name":
+			valErr = loadCustomValue(&config.Notifiers.Email.ClusterName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/template":
+			valErr = loadCustomValue(&config.Notifiers.Email.Template, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/subject-template":
+			valErr = loadCustomValue(&config.Notifiers.Email.SubjectTemplate, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Email.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/email/password":
+			valErr = loadCustomValue(&config.Notifiers.Email.Password, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/port":
+			valErr = loadCustomValue(&config.Notifiers.Email.Port, val, ConfigTypeInt)
+		case "consul-alerts/config/notifiers/email/receivers":
+			valErr = loadCustomValue(&config.Notifiers.Email.Receivers, val, ConfigTypeStrArray)
+		case "consul-alerts/config/notifiers/email/cc":
+			valErr = loadCustomValue(&config.Notifiers.Email.Cc, val, ConfigTypeStrArray)
+		case "consul-alerts/config/notifiers/email/bcc":
+			valErr = loadCustomValue(&config.Notifiers.Email.Bcc, val, ConfigTypeStrArray)
+		case "consul-alerts/config/notifiers/email/reply-to":
+			valErr = loadCustomValue(&config.Notifiers.Email.ReplyTo, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/sender-alias":
+			valErr = loadCustomValue(&config.Notifiers.Email.SenderAlias, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/sender-email":
+			valErr = loadCustomValue(&config.Notifiers.Email.SenderEmail, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/url":
+			valErr = loadCustomValue(&config.Notifiers.Email.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/username":
+			valErr = loadCustomValue(&config.Notifiers.Email.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/delivery-mode":
+			valErr = loadCustomValue(&config.Notifiers.Email.DeliveryMode, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/max-output-size":
+			valErr = loadCustomValue(&config.Notifiers.Email.MaxOutputSize, val, ConfigTypeInt)
+		case "consul-alerts/config/notifiers/email/tls-mode":
+			valErr = loadCustomValue(&config.Notifiers.Email.TLSMode, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/ca-cert":
+			valErr = loadCustomValue(&config.Notifiers.Email.CACert, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/insecure-skip-verify":
+			valErr = loadCustomValue(&config.Notifiers.Email.InsecureSkipVerify, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/email/auth-mode":
+			valErr = loadCustomValue(&config.Notifiers.Email.AuthMode, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/oauth2/client-id":
+			valErr = loadCustomValue(&config.Notifiers.Email.OAuth2ClientId, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/oauth2/client-secret":
+			valErr = loadCustomValue(&config.Notifiers.Email.OAuth2ClientSecret, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/oauth2/refresh-token":
+			valErr = loadCustomValue(&config.Notifiers.Email.OAuth2RefreshToken, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/oauth2/token-url":
+			valErr = loadCustomValue(&config.Notifiers.Email.OAuth2TokenUrl, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/email/oauth2/token-command":
+			valErr = loadCustomValue(&config.Notifiers.Email.OAuth2TokenCommand, val, ConfigTypeString)
+
+		// log notifier config
+		case "consul-alerts/config/notifiers/log/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Log.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/log/path":
+			valErr = loadCustomValue(&config.Notifiers.Log.Path, val, ConfigTypeString)
+
+		// influxdb notifier config
+		case "consul-alerts/config/notifiers/influxdb/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/influxdb/host":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.Host, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/influxdb/username":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/influxdb/password":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.Password, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/influxdb/database":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.Database, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/influxdb/series-name":
+			valErr = loadCustomValue(&config.Notifiers.Influxdb.SeriesName, val, ConfigTypeString)
+
+		// slack notfier config
+		case "consul-alerts/config/notifiers/slack/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Slack.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/slack/cluster-name":
+			valErr = loadCustomValue(&config.Notifiers.Slack.ClusterName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/url":
+			valErr = loadCustomValue(&config.Notifiers.Slack.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/channel":
+			valErr = loadCustomValue(&config.Notifiers.Slack.Channel, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/username":
+			valErr = loadCustomValue(&config.Notifiers.Slack.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/icon-url":
+			valErr = loadCustomValue(&config.Notifiers.Slack.IconUrl, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/icon-emoji":
+			valErr = loadCustomValue(&config.Notifiers.Slack.IconEmoji, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/token":
+			valErr = loadCustomValue(&config.Notifiers.Slack.Token, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/slack/thread-window":
+			valErr = loadCustomValue(&config.Notifiers.Slack.ThreadWindow, val, ConfigTypeInt)
+
+		case "consul-alerts/config/notifiers/pagerduty/enabled":
+			valErr = loadCustomValue(&config.Notifiers.PagerDuty.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/pagerduty/service-key":
+			valErr = loadCustomValue(&config.Notifiers.PagerDuty.ServiceKey, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/pagerduty/client-name":
+			valErr = loadCustomValue(&config.Notifiers.PagerDuty.ClientName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/pagerduty/client-url":
+			valErr = loadCustomValue(&config.Notifiers.PagerDuty.ClientUrl, val, ConfigTypeString)
+
+		// hipchat notifier config
+		case "consul-alerts/config/notifiers/hipchat/enabled":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/hipchat/cluster-name":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.ClusterName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/url":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/room-id":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.RoomId, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/auth-token":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.AuthToken, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/from":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.From, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/passing-color":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.PassingColor, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/warning-color":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.WarningColor, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/hipchat/fail-color":
+			valErr = loadCustomValue(&config.Notifiers.HipChat.FailColor, val, ConfigTypeString)
+
+		// victorops notifier config
+		case "consul-alerts/config/notifiers/victorops/enabled":
+			valErr = loadCustomValue(&config.Notifiers.VictorOps.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/victorops/url":
+			valErr = loadCustomValue(&config.Notifiers.VictorOps.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/victorops/api-key":
+			valErr = loadCustomValue(&config.Notifiers.VictorOps.ApiKey, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/victorops/routing-key":
+			valErr = loadCustomValue(&config.Notifiers.VictorOps.RoutingKey, val, ConfigTypeString)
+
+		// zulip notifier config
+		case "consul-alerts/config/notifiers/zulip/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/zulip/cluster-name":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.ClusterName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zulip/url":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zulip/bot-email":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.BotEmail, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zulip/api-key":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.ApiKey, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zulip/stream":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.Stream, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zulip/topic":
+			valErr = loadCustomValue(&config.Notifiers.Zulip.Topic, val, ConfigTypeString)
+
+		// discord notifier config
+		case "consul-alerts/config/notifiers/discord/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Discord.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/discord/cluster-name":
+			valErr = loadCustomValue(&config.Notifiers.Discord.ClusterName, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/discord/url":
+			valErr = loadCustomValue(&config.Notifiers.Discord.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/discord/username":
+			valErr = loadCustomValue(&config.Notifiers.Discord.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/discord/avatar-url":
+			valErr = loadCustomValue(&config.Notifiers.Discord.AvatarUrl, val, ConfigTypeString)
+
+		// nats notifier config
+		case "consul-alerts/config/notifiers/nats/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/nats/url":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/subject":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Subject, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/jetstream":
+			valErr = loadCustomValue(&config.Notifiers.Nats.JetStream, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/nats/credentials":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Credentials, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/token":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Token, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/username":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/password":
+			valErr = loadCustomValue(&config.Notifiers.Nats.Password, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/ca-cert":
+			valErr = loadCustomValue(&config.Notifiers.Nats.CACert, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/tls-cert":
+			valErr = loadCustomValue(&config.Notifiers.Nats.TLSCert, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nats/tls-key":
+			valErr = loadCustomValue(&config.Notifiers.Nats.TLSKey, val, ConfigTypeString)
+
+		// mqtt notifier config
+		case "consul-alerts/config/notifiers/mqtt/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/mqtt/url":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/client-id":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.ClientId, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/topic":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Topic, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/qos":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Qos, val, ConfigTypeInt)
+		case "consul-alerts/config/notifiers/mqtt/retain":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Retain, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/mqtt/username":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Username, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/password":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.Password, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/ca-cert":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.CACert, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/tls-cert":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.TLSCert, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/mqtt/tls-key":
+			valErr = loadCustomValue(&config.Notifiers.Mqtt.TLSKey, val, ConfigTypeString)
+
+		// splunk notifier config
+		case "consul-alerts/config/notifiers/splunk/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/splunk/url":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/splunk/token":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.Token, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/splunk/index":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.Index, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/splunk/source":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.Source, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/splunk/sourcetype":
+			valErr = loadCustomValue(&config.Notifiers.Splunk.SourceType, val, ConfigTypeString)
+
+		// nrdp notifier config
+		case "consul-alerts/config/notifiers/nrdp/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Nrdp.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/nrdp/url":
+			valErr = loadCustomValue(&config.Notifiers.Nrdp.Url, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nrdp/token":
+			valErr = loadCustomValue(&config.Notifiers.Nrdp.Token, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nrdp/host":
+			valErr = loadCustomValue(&config.Notifiers.Nrdp.Host, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/nrdp/service":
+			valErr = loadCustomValue(&config.Notifiers.Nrdp.Service, val, ConfigTypeString)
+
+		// zabbix notifier config
+		case "consul-alerts/config/notifiers/zabbix/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Zabbix.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/zabbix/server":
+			valErr = loadCustomValue(&config.Notifiers.Zabbix.Server, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zabbix/host":
+			valErr = loadCustomValue(&config.Notifiers.Zabbix.Host, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zabbix/key":
+			valErr = loadCustomValue(&config.Notifiers.Zabbix.Key, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/zabbix/numeric":
+			valErr = loadCustomValue(&config.Notifiers.Zabbix.Numeric, val, ConfigTypeBool)
+
+		// sentry notifier config
+		case "consul-alerts/config/notifiers/sentry/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Sentry.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/sentry/dsn":
+			valErr = loadCustomValue(&config.Notifiers.Sentry.Dsn, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/sentry/environment":
+			valErr = loadCustomValue(&config.Notifiers.Sentry.Environment, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/sentry/level":
+			valErr = loadCustomValue(&config.Notifiers.Sentry.Level, val, ConfigTypeString)
+
+		// aws notifier config
+		case "consul-alerts/config/notifiers/aws/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Aws.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/aws/region":
+			valErr = loadCustomValue(&config.Notifiers.Aws.Region, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/access-key-id":
+			valErr = loadCustomValue(&config.Notifiers.Aws.AccessKeyId, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/secret-access-key":
+			valErr = loadCustomValue(&config.Notifiers.Aws.SecretAccessKey, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/session-token":
+			valErr = loadCustomValue(&config.Notifiers.Aws.SessionToken, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/queue-url":
+			valErr = loadCustomValue(&config.Notifiers.Aws.QueueUrl, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/event-bus":
+			valErr = loadCustomValue(&config.Notifiers.Aws.EventBus, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/events-endpoint":
+			valErr = loadCustomValue(&config.Notifiers.Aws.EventsEndpoint, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/source":
+			valErr = loadCustomValue(&config.Notifiers.Aws.Source, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/aws/detail-type":
+			valErr = loadCustomValue(&config.Notifiers.Aws.DetailType, val, ConfigTypeString)
+
+		// pubsub notifier config
+		case "consul-alerts/config/notifiers/pubsub/enabled":
+			valErr = loadCustomValue(&config.Notifiers.Pubsub.Enabled, val, ConfigTypeBool)
+		case "consul-alerts/config/notifiers/pubsub/project":
+			valErr = loadCustomValue(&config.Notifiers.Pubsub.Project, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/pubsub/topic":
+			valErr = loadCustomValue(&config.Notifiers.Pubsub.Topic, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/pubsub/credentials-file":
+			valErr = loadCustomValue(&config.Notifiers.Pubsub.CredentialsFile, val, ConfigTypeString)
+		case "consul-alerts/config/notifiers/pubsub/endpoint":
+			valErr = loadCustomValue(&config.Notifiers.Pubsub.Endpoint, val, ConfigTypeString)
+
+		default:
+			if strings.HasPrefix(key, minHealthyPrefix) {
+				valErr = loadServiceMinHealthy(serviceMinHealthy, key, val)
+				break
+			}
+			if strings.HasPrefix(key, blacklistPatternPrefix) {
+				valErr = loadBlacklistPattern(&blacklistPatterns, key, val)
+				break
 			}
+			if loaded, err := loadOutputValue(key, val, outputs); loaded {
+				valErr = err
+				break
+			}
+			if loaded, err := loadDigestValue(key, val, digests); loaded {
+				valErr = err
+				break
+			}
+			if loaded, err := loadQuietHoursValue(key, val, quietHours); loaded {
+				valErr = err
+				break
+			}
+			if loaded, err := loadTimeoutValue(key, val, timeouts); loaded {
+				valErr = err
+				break
+			}
+			if loaded, err := loadHttpValue(key, val, https); loaded {
+				valErr = err
+				break
+			}
+			if loaded, err := loadProfileValue(key, val, profiles); loaded {
+				valErr = err
+				break
+			}
+			valErr = loadPrefixedValue(key, val, map[string]map[string][]string{
+				"consul-alerts/config/notifiers/email/receivers/services/": serviceReceivers,
+				"consul-alerts/config/notifiers/email/receivers/nodes/":    nodeReceivers,
 
+				"consul-alerts/config/notifiers/email/receivers/datacenters/": datacenterReceivers,
+			})
 		}
-	} else {
-		log.Println("Unable to load custom config, using default instead:", err)
+
+		if valErr != nil {
+			configErrors = append(configErrors, fmt.Errorf("%s: %s", key, valErr))
+			log.Warnf(`unable to load custom value for "%s". Using default instead. Error: %s`, key, valErr.Error())
+		}
+
 	}
 
+	config.Notifiers.Email.ServiceReceivers = serviceReceivers
+	config.Notifiers.Email.NodeReceivers = nodeReceivers
+	config.Notifiers.Email.DatacenterReceivers = datacenterReceivers
+	config.Notifiers.Outputs = outputs
+	config.Notifiers.Digests = digests
+	config.Notifiers.QuietHours = quietHours
+	config.Notifiers.Timeouts = timeouts
+	config.Notifiers.Http = https
+	for service, minimum := range serviceMinHealthy {
+		config.Services.MinHealthy[service] = minimum
+	}
+	config.Checks.BlacklistPatterns = blacklistPatterns
+	config.Profiles = profiles
+	configErrors = append(configErrors, profileProblems(profiles)...)
+	return config, configErrors
+}
+
+// outputKey matches the output limits of a notifier.
+var outputKey = regexp.MustCompile(`^consul-alerts/config/notifiers/([^/]+)/output/(max-bytes|max-lines|sanitize)$`)
+
+// loadOutputValue loads an output limit of a notifier and returns true if the
+// key is one.
+func loadOutputValue(key string, data []byte, outputs map[string]OutputConfig) (bool, error) {
+	match := outputKey.FindStringSubmatch(key)
+	if match == nil {
+		return false, nil
+	}
+	output := outputs[match[1]]
+	var err error
+	switch match[2] {
+	case "max-bytes":
+		err = loadCustomValue(&output.MaxBytes, data, ConfigTypeInt)
+	case "max-lines":
+		err = loadCustomValue(&output.MaxLines, data, ConfigTypeInt)
+	case "sanitize":
+		err = loadCustomValue(&output.Sanitize, data, ConfigTypeBool)
+	}
+	outputs[match[1]] = output
+	return true, err
 }
 
 func loadCustomValue(configVariable interface{}, data []byte, cType configType) (err error) {
@@ -164,63 +897,272 @@ func loadCustomValue(configVariable interface{}, data []byte, cType configType)
 		if val, err = strconv.ParseBool(string(data)); err == nil {
 			boolConfig := configVariable.(*bool)
 			*boolConfig = val
+		} else {
+			err = fmt.Errorf("expected true or false, got %q", data)
 		}
 	case ConfigTypeString:
-		strConfig := configVariable.(*string)
-		*strConfig = string(data)
+		var val string
+		if val, err = resolveSecrets(string(data)); err == nil {
+			strConfig := configVariable.(*string)
+			*strConfig = val
+		}
 	case ConfigTypeInt:
 		var val int
 		if val, err = strconv.Atoi(string(data)); err == nil {
 			intConfig := configVariable.(*int)
 			*intConfig = int(val)
+		} else {
+			err = fmt.Errorf("expected a number, got %q", data)
 		}
 	case ConfigTypeStrArray:
+		var val []string
+		if err = json.Unmarshal(data, &val); err != nil {
+			return fmt.Errorf(`expected a JSON array of strings like ["a", "b"], got %q`, data)
+		}
+		for i := range val {
+			if val[i], err = resolveSecrets(val[i]); err != nil {
+				return err
+			}
+		}
 		arrConfig := configVariable.(*[]string)
-		err = json.Unmarshal(data, arrConfig)
+		*arrConfig = val
 	}
 	return err
 }
 
+// ConfigErrors returns the KV values that couldn't be loaded by the last
+// LoadConfig. The defaults are used for these settings.
+func (c *ConsulAlertClient) ConfigErrors() []error {
+	c.state.RLock()
+	defer c.state.RUnlock()
+	return c.state.errors
+}
+
+// loadPrefixedValue loads a string array stored under one of the given key
+// prefixes into the matching map, keyed by the remainder of the key.
+func loadPrefixedValue(key string, data []byte, prefixes map[string]map[string][]string) error {
+	for prefix, values := range prefixes {
+		if !strings.HasPrefix(key, prefix) || key == prefix {
+			continue
+		}
+		var arr []string
+		if err := loadCustomValue(&arr, data, ConfigTypeStrArray); err != nil {
+			return err
+		}
+		values[strings.TrimPrefix(key, prefix)] = arr
+	}
+	return nil
+}
+
 func (c *ConsulAlertClient) EventsEnabled() bool {
-	return c.config.Events.Enabled
+	return c.currentConfig().Events.Enabled
 }
 
 func (c *ConsulAlertClient) ChecksEnabled() bool {
-	return c.config.Checks.Enabled
+	return c.currentConfig().Checks.Enabled
+}
+
+func (c *ConsulAlertClient) EventHandlers(eventName string) []EventHandler {
+	return c.currentConfig().Events.Handlers
+}
+
+func (c *ConsulAlertClient) EventHandlerTimeout() time.Duration {
+	return time.Duration(c.currentConfig().Events.HandlerTimeout) * time.Second
+}
+
+func (c *ConsulAlertClient) EventTimeout() time.Duration {
+	return time.Duration(c.currentConfig().Events.Timeout) * time.Second
+}
+
+func (c *ConsulAlertClient) KeyHandlers() []KeyHandler {
+	return c.currentConfig().Keys.Handlers
+}
+
+func (c *ConsulAlertClient) RemediationConfig() *RemediationConfig {
+	return c.currentConfig().Remediation
+}
+
+func (c *ConsulAlertClient) NodesConfig() *NodesConfig {
+	return c.currentConfig().Nodes
+}
+
+func (c *ConsulAlertClient) ServicesConfig() *ServicesConfig {
+	return c.currentConfig().Services
+}
+
+func (c *ConsulAlertClient) ClusterConfig() *ClusterConfig {
+	return c.currentConfig().Cluster
+}
+
+// NotifierOutput returns the output limits of a notifier, "custom" for the
+// custom notifiers.
+func (c *ConsulAlertClient) NotifierOutput(name string) OutputConfig {
+	return c.currentConfig().Notifiers.Outputs[name]
+}
+
+func (c *ConsulAlertClient) Routes() []Route {
+	return c.currentConfig().Routes
+}
+
+func (c *ConsulAlertClient) HeartbeatConfig() *HeartbeatConfig {
+	return c.currentConfig().Heartbeat
+}
+
+func (c *ConsulAlertClient) EventFailureAlerts() bool {
+	return c.currentConfig().Events.FailureAlerts
+}
+
+func (c *ConsulAlertClient) EventWebhookRetries() int {
+	return c.currentConfig().Events.WebhookRetries
 }
 
-func (c *ConsulAlertClient) EventHandlers(eventName string) []string {
-	return c.config.Events.Handlers
+func (c *ConsulAlertClient) EventWebhookHeaders() map[string]string {
+	return c.currentConfig().Events.WebhookHeaders
+}
+
+func (c *ConsulAlertClient) EventConcurrency() int {
+	if c.currentConfig().Events.Concurrency < 1 {
+		return 1
+	}
+	return c.currentConfig().Events.Concurrency
+}
+
+func (c *ConsulAlertClient) MaintenanceNotices() bool {
+	return c.currentConfig().Checks.MaintenanceNotices
 }
 
 func (c *ConsulAlertClient) CheckChangeThreshold() int {
-	return c.config.Checks.ChangeThreshold
+	return c.currentConfig().Checks.ChangeThreshold
+}
+
+func (c *ConsulAlertClient) CheckCoalesceWindow() int {
+	return c.currentConfig().Checks.CoalesceWindow
+}
+
+// Datacenters returns the datacenters to monitor. This is the datacenter of
+// the agent unless other datacenters are configured, "*" meaning every known
+// datacenter.
+func (c *ConsulAlertClient) Datacenters() []string {
+	datacenters := c.currentConfig().Checks.Datacenters
+	if len(datacenters) == 0 {
+		return []string{c.datacenter}
+	}
+	for _, dc := range datacenters {
+		if dc == "*" {
+			all, err := c.api.Catalog().Datacenters()
+			if err != nil {
+				apiErrors.Inc("datacenters")
+				log.Errorln("Unable to discover datacenters:", err)
+				return []string{c.datacenter}
+			}
+			return all
+		}
+	}
+	return datacenters
+}
+
+// checkKey returns the KV key of a check status. Checks of the agent's
+// datacenter keep the original layout while checks of other datacenters are
+// stored under consul-alerts/checks/_dc/{{ dc }}/.
+func (c *ConsulAlertClient) checkKey(dc, node, serviceId, checkId string) string {
+	if serviceId == "" {
+		serviceId = "_"
+	}
+	if dc == "" || dc == c.datacenter {
+		return fmt.Sprintf("consul-alerts/checks/%s/%s/%s", node, serviceId, checkId)
+	}
+	return fmt.Sprintf("consul-alerts/checks/_dc/%s/%s/%s/%s", dc, node, serviceId, checkId)
+}
+
+// LocalDatacenter returns the datacenter of the agent.
+func (c *ConsulAlertClient) LocalDatacenter() string {
+	return c.datacenter
+}
+
+// checkDatacenter returns the datacenter of the checks of dc, empty for the
+// agent's datacenter so its checks keep the incident keys and thread keys
+// they had before other datacenters were monitored.
+func (c *ConsulAlertClient) checkDatacenter(dc string) string {
+	if dc == c.datacenter {
+		return ""
+	}
+	return dc
 }
 
 func (c *ConsulAlertClient) UpdateCheckData() {
+	for _, dc := range c.Datacenters() {
+		c.updateDatacenterCheckData(dc)
+	}
+}
+
+func (c *ConsulAlertClient) updateDatacenterCheckData(dc string) {
 	healthApi := c.api.Health()
 	kvApi := c.api.KV()
 
-	healths, _, _ := healthApi.State("any", nil)
+	healths, _, err := healthApi.State("any", &consulapi.QueryOptions{Datacenter: dc})
+	if err != nil {
+		apiErrors.Inc("health_state")
+		log.Errorf("Unable to retrieve health checks of %s: %s", dc, err)
+		return
+	}
+	// the checks aren't updated without their overrides, they would
+	// notify the overridden statuses
+	overrides, err := c.overrides(time.Now())
+	if err != nil {
+		log.Errorf("Unable to retrieve the check overrides, skipping the checks of %s: %s", dc, err)
+		return
+	}
 
+	var owned []*consulapi.HealthCheck
 	for _, health := range healths {
+		if c.ownsNode(health.Node) {
+			owned = append(owned, health)
+		}
+	}
+	checkDc := c.checkDatacenter(dc)
+	c.updateMaintenance(checkDc, findMaintenance(checkDc, owned))
+
+	serviceTags := c.serviceTags(dc)
+	nodeMeta := c.nodeMeta(dc)
+	var checkTypes map[string]string
+	if len(c.currentConfig().Checks.StaleAfter) > 0 {
+		checkTypes = c.checkTypes(dc)
+	}
+	for _, health := range owned {
+		if isMaintenanceCheck(health.CheckID) {
+			continue
+		}
 
 		node := health.Node
 		service := health.ServiceID
 		check := health.CheckID
-		if service == "" {
-			service = "_"
-		}
-		key := fmt.Sprintf("consul-alerts/checks/%s/%s/%s", node, service, check)
+		key := c.checkKey(dc, node, service, check)
 
 		status, _, _ := kvApi.Get(key, nil)
 		existing := status != nil
 
-		localHealth := Check(*health)
+		localHealth := toCheck(health, checkDc, c.clientConfig.Namespace)
+		if health.ServiceName != "" {
+			localHealth.ServiceTags = serviceTags[health.ServiceName]
+		}
+		localHealth.NodeMeta = nodeMeta[health.Node]
+		localHealth.Type = checkTypes[health.Node+"/"+health.CheckID]
 
 		if c.IsBlacklisted(&localHealth) {
-			log.Printf("%s:%s:%s is blacklisted.", node, service, check)
-			return
+			log.Debugf("%s:%s:%s is blacklisted.", node, service, check)
+			continue
+		}
+		if !c.isWhitelisted(&localHealth) {
+			log.Debugf("%s:%s:%s is not whitelisted.", node, service, check)
+			continue
+		}
+		if c.inMaintenance(&localHealth) {
+			log.Debugf("%s:%s:%s is in maintenance.", node, service, check)
+			continue
+		}
+		if override, found := overrides[c.overrideKey(&localHealth)]; found {
+			override.Apply(&localHealth)
+			log.Debugf("%s:%s:%s is overridden to %s.", node, service, check, override.Status)
 		}
 
 		if !existing {
@@ -233,63 +1175,351 @@ func (c *ConsulAlertClient) UpdateCheckData() {
 
 }
 
+// serviceTags returns the tags of the services of a datacenter. A service has
+// the tags of all its instances.
+func (c *ConsulAlertClient) serviceTags(dc string) map[string][]string {
+	tags, _, err := c.api.Catalog().Services(&consulapi.QueryOptions{Datacenter: dc})
+	if err != nil {
+		apiErrors.Inc("services")
+		log.Errorf("Unable to retrieve the service tags of %s: %s", dc, err)
+	}
+	return tags
+}
+
+// nodeMeta returns the metadata of the nodes of a datacenter, by node.
+func (c *ConsulAlertClient) nodeMeta(dc string) map[string]map[string]string {
+	var nodes []struct {
+		Node string
+		Meta map[string]string
+	}
+	if _, err := c.requestIn(dc, "GET", "/v1/catalog/nodes", nil, &nodes); err != nil {
+		apiErrors.Inc("nodes")
+		log.Errorf("Unable to retrieve the node metadata of %s: %s", dc, err)
+		return nil
+	}
+	meta := make(map[string]map[string]string, len(nodes))
+	for _, node := range nodes {
+		meta[node.Node] = node.Meta
+	}
+	return meta
+}
+
+// CatalogNode is the catalog entry of a node and its services by id.
+type CatalogNode struct {
+	Address  string
+	Meta     map[string]string
+	Services map[string]CatalogService
+}
+
+// CatalogService is a service instance registered in the catalog. Address is
+// empty when the service uses the address of its node.
+type CatalogService struct {
+	Address string
+	Port    int
+	Tags    []string
+}
+
+// CatalogNode returns the catalog entry of a node of a datacenter, nil if the
+// node isn't registered.
+func (c *ConsulAlertClient) CatalogNode(dc, node string) (*CatalogNode, error) {
+	var entry *struct {
+		Node     CatalogNode
+		Services map[string]CatalogService
+	}
+	if _, err := c.requestIn(dc, "GET", "/v1/catalog/node/"+url.PathEscape(node), nil, &entry); err != nil {
+		apiErrors.Inc("catalog_node")
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	entry.Node.Services = entry.Services
+	return &entry.Node, nil
+}
+
+func toCheck(health *consulapi.HealthCheck, dc, namespace string) Check {
+	return Check{
+		Node:        health.Node,
+		CheckID:     health.CheckID,
+		Name:        health.Name,
+		Status:      health.Status,
+		Notes:       health.Notes,
+		Output:      health.Output,
+		ServiceID:   health.ServiceID,
+		ServiceName: health.ServiceName,
+		Datacenter:  dc,
+		Namespace:   namespace,
+	}
+}
+
 func (c *ConsulAlertClient) NewAlerts() []Check {
-	allChecks, _, _ := c.api.KV().List("consul-alerts/checks", nil)
+	allChecks, _, err := c.api.KV().List("consul-alerts/checks", nil)
+	if err != nil {
+		apiErrors.Inc("list_checks")
+		log.Errorln("Unable to retrieve check statuses:", err)
+	}
 	alerts := make([]Check, 0)
+	silences, _ := c.Silences()
+	now := time.Now()
+	keys := make([]string, 0, len(allChecks))
+	statuses := make([]Status, 0, len(allChecks))
 	for _, kvpair := range allChecks {
-		key := kvpair.Key
-		if strings.HasSuffix(key, "/") {
+		if strings.HasSuffix(kvpair.Key, "/") {
 			continue
 		}
 		var status Status
 		json.Unmarshal(kvpair.Value, &status)
-		if status.ForNotification {
-			status.ForNotification = false
-			data, _ := json.Marshal(status)
-			c.api.KV().Put(&consulapi.KVPair{Key: key, Value: data}, nil)
-			// check if blacklisted
-
-			if !c.IsBlacklisted(status.HealthCheck) {
-				alerts = append(alerts, *status.HealthCheck)
+		if status.HealthCheck == nil {
+			continue
+		}
+		keys = append(keys, kvpair.Key)
+		statuses = append(statuses, status)
+	}
+	// the inhibiting and the failing dependency checks are the checks of
+	// any node, even the ones of another shard member
+	checks := make([]*Check, len(statuses))
+	var sent []*Check
+	for i, status := range statuses {
+		checks[i] = status.HealthCheck
+		if !status.ForNotification && status.Current == "critical" && status.NotifiedStatus == "critical" {
+			sent = append(sent, status.HealthCheck)
+		}
+	}
+	dependencies := c.currentConfig().Checks.Dependencies
+	var failing map[string]map[string]bool
+	if len(dependencies) > 0 {
+		failing = failingServices(checks, c.currentConfig().Services.MinHealthy)
+	}
+	rolledUp := make(map[string]string)
+	for i, status := range statuses {
+		key := keys[i]
+		if status.ForNotification && c.ownsNode(status.HealthCheck.Node) {
+			// blacklisted, not whitelisted, in maintenance and silenced checks
+			// are dropped, the recoveries of failures that were never sent
+			// too. The inhibited checks stay pending and are checked again on
+			// the next run, like the others until MarkNotified so a new
+			// leader can send them.
+			if c.IsBlacklisted(status.HealthCheck) || !c.isWhitelisted(status.HealthCheck) || c.inMaintenance(status.HealthCheck) {
+				c.markNotified(key, now, false)
+				continue
+			}
+			if isSilenced(silences, status.HealthCheck, now) {
+				log.Infof("%s:%s:%s is silenced.", status.HealthCheck.Node, status.HealthCheck.ServiceID, status.HealthCheck.CheckID)
+				c.markNotified(key, now, false)
+				continue
+			}
+			if unsentRecovery(&status) {
+				log.Infof("%s:%s:%s recovered from a failure that was not sent.", status.HealthCheck.Node, status.HealthCheck.ServiceID, status.HealthCheck.CheckID)
+				c.markNotified(key, now, false)
+				continue
+			}
+			if source := inhibitedBy(c.currentConfig().Checks.InhibitRules, checks, status.HealthCheck); source != nil {
+				log.Debugf("%s:%s:%s is inhibited by %s:%s:%s.", status.HealthCheck.Node, status.HealthCheck.ServiceID, status.HealthCheck.CheckID,
+					source.Node, source.ServiceID, source.CheckID)
+				continue
+			}
+			// the checks already listed in the alert of their failing
+			// dependency stay pending until it recovers
+			if status.RolledUpInto != "" && status.RolledUpInto == rootCause(dependencies, failing[status.HealthCheck.Datacenter], status.HealthCheck.ServiceName) {
+				continue
+			}
+			rolledUp[c.checkKey(status.HealthCheck.Datacenter, status.HealthCheck.Node, status.HealthCheck.ServiceID, status.HealthCheck.CheckID)] = status.RolledUpInto
+			alert := *status.HealthCheck
+			alert.NotifiedStatus = status.NotifiedStatus
+			alerts = append(alerts, alert)
+		}
+	}
+	if len(dependencies) > 0 {
+		var rolled []rolledAlert
+		alerts, rolled = rollupDependents(dependencies, failing, alerts, sent)
+		for _, alert := range rolled {
+			log.Infof("%s:%s:%s is rolled up into the alert of its failing dependency %s.", alert.Node, alert.ServiceID, alert.CheckID, alert.Root)
+			key := c.checkKey(alert.Datacenter, alert.Node, alert.ServiceID, alert.CheckID)
+			if rolledUp[key] != alert.Root {
+				c.markRolledUp(key, alert.Root)
 			}
 		}
 	}
 	return alerts
 }
 
+// unsentRecovery returns true if the check passes again since the last alert
+// sent, which was already passing: the failure in between was dropped or
+// never sent, so is its recovery.
+func unsentRecovery(status *Status) bool {
+	return status.Current == "passing" && status.NotifiedStatus == "passing" && status.CurrentTimestamp.After(status.NotifiedTimestamp)
+}
+
+// MarkNotified records that the alerts have been sent.
+func (c *ConsulAlertClient) MarkNotified(alerts []Check) {
+	now := time.Now()
+	for _, alert := range alerts {
+		c.markNotified(c.checkKey(alert.Datacenter, alert.Node, alert.ServiceID, alert.CheckID), now, true)
+	}
+}
+
+// markNotified clears the pending alert of a check, sent records its status
+// as the last one notified.
+func (c *ConsulAlertClient) markNotified(key string, now time.Time, sent bool) {
+	err := c.updateStatus(key, func(status *Status) {
+		status.ForNotification = false
+		status.NotifiedTimestamp = now
+		status.RolledUpInto = ""
+		if sent {
+			status.NotifiedStatus = status.Current
+		}
+	})
+	if err != nil {
+		apiErrors.Inc("mark_notified")
+		log.Errorf("Unable to update check status %s: %s", key, err)
+	}
+}
+
+// markRolledUp records that the pending alert of a check was listed in the
+// alert of its failing dependency root.
+func (c *ConsulAlertClient) markRolledUp(key, root string) {
+	err := c.updateStatus(key, func(status *Status) {
+		status.RolledUpInto = root
+	})
+	if err != nil {
+		apiErrors.Inc("mark_notified")
+		log.Errorf("Unable to update check status %s: %s", key, err)
+	}
+}
+
+// statusRetries is the number of times a check status update is retried when
+// the status is changed meanwhile.
+const statusRetries = 5
+
+// updateStatus applies update to the stored status of a check, the update is
+// applied again on the latest status if it changed meanwhile, like by the
+// check processing. Nothing is written when the update changes nothing.
+func (c *ConsulAlertClient) updateStatus(key string, update func(status *Status)) error {
+	for attempt := 0; ; attempt++ {
+		kvpair, _, err := c.api.KV().Get(key, nil)
+		if err != nil {
+			return err
+		}
+		if kvpair == nil {
+			return fmt.Errorf("no status stored")
+		}
+		var status Status
+		json.Unmarshal(kvpair.Value, &status)
+		update(&status)
+		data, _ := json.Marshal(status)
+		if bytes.Equal(data, kvpair.Value) {
+			return nil
+		}
+		updated, _, err := c.api.KV().CAS(&consulapi.KVPair{Key: key, Value: data, ModifyIndex: kvpair.ModifyIndex}, nil)
+		if err != nil {
+			return err
+		}
+		if updated {
+			return nil
+		}
+		if attempt >= statusRetries {
+			return fmt.Errorf("the status kept changing")
+		}
+	}
+}
+
+func (c *ConsulAlertClient) LeaderConfig() *LeaderConfig {
+	return c.currentConfig().Leader
+}
+
 func (c *ConsulAlertClient) CustomNotifiers() []string {
-	return c.config.Notifiers.Custom
+	return c.currentConfig().Notifiers.Custom
+}
+
+func (c *ConsulAlertClient) DryRun() bool {
+	return c.currentConfig().Notifiers.DryRun
+}
+
+func (c *ConsulAlertClient) ScheduleJitter() int {
+	return c.currentConfig().Notifiers.ScheduleJitter
 }
 
 func (c *ConsulAlertClient) EmailConfig() *EmailNotifierConfig {
-	return c.config.Notifiers.Email
+	return c.currentConfig().Notifiers.Email
 }
 
 func (c *ConsulAlertClient) LogConfig() *LogNotifierConfig {
-	return c.config.Notifiers.Log
+	return c.currentConfig().Notifiers.Log
 }
 
 func (c *ConsulAlertClient) InfluxdbConfig() *InfluxdbNotifierConfig {
-	return c.config.Notifiers.Influxdb
+	return c.currentConfig().Notifiers.Influxdb
 }
 
 func (c *ConsulAlertClient) SlackConfig() *SlackNotifierConfig {
-	return c.config.Notifiers.Slack
+	return c.currentConfig().Notifiers.Slack
 }
 
 func (c *ConsulAlertClient) PagerDutyConfig() *PagerDutyNotifierConfig {
-	return c.config.Notifiers.PagerDuty
+	return c.currentConfig().Notifiers.PagerDuty
+}
+
+func (c *ConsulAlertClient) HipChatConfig() *HipChatNotifierConfig {
+	return c.currentConfig().Notifiers.HipChat
+}
+
+func (c *ConsulAlertClient) VictorOpsConfig() *VictorOpsNotifierConfig {
+	return c.currentConfig().Notifiers.VictorOps
+}
+
+func (c *ConsulAlertClient) ZulipConfig() *ZulipNotifierConfig {
+	return c.currentConfig().Notifiers.Zulip
+}
+
+func (c *ConsulAlertClient) DiscordConfig() *DiscordNotifierConfig {
+	return c.currentConfig().Notifiers.Discord
+}
+
+func (c *ConsulAlertClient) NatsConfig() *NatsNotifierConfig {
+	return c.currentConfig().Notifiers.Nats
+}
+
+func (c *ConsulAlertClient) MqttConfig() *MqttNotifierConfig {
+	return c.currentConfig().Notifiers.Mqtt
+}
+
+func (c *ConsulAlertClient) SplunkConfig() *SplunkNotifierConfig {
+	return c.currentConfig().Notifiers.Splunk
+}
+
+func (c *ConsulAlertClient) NrdpConfig() *NrdpNotifierConfig {
+	return c.currentConfig().Notifiers.Nrdp
+}
+
+func (c *ConsulAlertClient) ZabbixConfig() *ZabbixNotifierConfig {
+	return c.currentConfig().Notifiers.Zabbix
+}
+
+func (c *ConsulAlertClient) SentryConfig() *SentryNotifierConfig {
+	return c.currentConfig().Notifiers.Sentry
+}
+
+func (c *ConsulAlertClient) AwsConfig() *AwsNotifierConfig {
+	return c.currentConfig().Notifiers.Aws
+}
+
+func (c *ConsulAlertClient) PubsubConfig() *PubsubNotifierConfig {
+	return c.currentConfig().Notifiers.Pubsub
+}
+
+// checkLog returns a logger tagged with the check identity.
+func checkLog(health *Check) *log.Entry {
+	return log.WithFields(log.Fields{
+		"dc":      health.Datacenter,
+		"node":    health.Node,
+		"service": health.ServiceName,
+		"check":   health.Name,
+	})
 }
 
 func (c *ConsulAlertClient) registerHealthCheck(key string, health *Check) {
 
-	log.Printf(
-		"Registering new health check: node=%s, service=%s, check=%s, status=%s",
-		health.Node,
-		health.ServiceName,
-		health.Name,
-		health.Status,
-	)
+	checkLog(health).Infof("Registering new health check with status %s.", health.Status)
 
 	var newStatus Status
 	if health.Status == "passing" {
@@ -305,96 +1535,92 @@ func (c *ConsulAlertClient) registerHealthCheck(key string, health *Check) {
 			HealthCheck:      health,
 		}
 	}
+	newStatus.OutputTimestamp = time.Now()
+	// a new check is assumed to be passing so the recovery of a failure that
+	// wasn't sent isn't either
+	newStatus.NotifiedStatus = "passing"
 
 	statusData, _ := json.Marshal(newStatus)
 	c.api.KV().Put(&consulapi.KVPair{Key: key, Value: statusData}, nil)
 }
 
+// updateHealthCheck updates the stored status of a check with its latest
+// health. The update is applied again if the status was changed meanwhile,
+// like when marked notified or stale, so these changes aren't overwritten.
 func (c *ConsulAlertClient) updateHealthCheck(key string, health *Check) {
+	var transition []string
+	err := c.updateStatus(key, func(storedStatus *Status) {
+		transition = nil
+
+		// no status change if the stored status and latest status is the same
+		noStatusChange := storedStatus.Current == health.Status
+
+		// new pending status if it's a new status and it's not the same as the pending status
+		newPendingStatus := storedStatus.Current != health.Status && storedStatus.Pending != health.Status
+
+		// status is still pending for change. will change if it reaches threshold
+		stillPendingStatus := storedStatus.Current != health.Status && storedStatus.Pending == health.Status
+
+		switch {
+
+		case noStatusChange:
+			if storedStatus.Pending != "" {
+				storedStatus.Pending = ""
+				storedStatus.PendingTimestamp = time.Time{}
+				checkLog(health).Infof("Check is now back to %s.", storedStatus.Current)
+			}
+			if storedStatus.AcknowledgedTimestamp.IsZero() && c.outputChanged(storedStatus.HealthCheck, health) {
+				checkLog(health).Infof("Check output has changed while %s.", health.Status)
+				storedStatus.ForNotification = true
+			}
+
+		case newPendingStatus:
+			storedStatus.Pending = health.Status
+			storedStatus.PendingTimestamp = time.Now()
+			checkLog(health).Infof("Check is now pending status change from %s to %s.", storedStatus.Current, storedStatus.Pending)
+
+		case stillPendingStatus:
+			duration := time.Since(storedStatus.PendingTimestamp)
+			if int(duration.Seconds()) >= c.changeThreshold(health) {
+
+				checkLog(health).Infof("Check has changed status from %s to %s.", storedStatus.Current, storedStatus.Pending)
+				transition = []string{storedStatus.Current, storedStatus.Pending}
+
+				storedStatus.Current = storedStatus.Pending
+				storedStatus.CurrentTimestamp = time.Now()
+				storedStatus.Pending = ""
+				storedStatus.PendingTimestamp = time.Time{}
+				storedStatus.ForNotification = true
+				storedStatus.RolledUpInto = ""
+				storedStatus.AcknowledgedTimestamp = time.Time{}
+				storedStatus.AcknowledgedBy = ""
+				storedStatus.AcknowledgedComment = ""
+			} else {
+				checkLog(health).Debugf("Check is pending status change from %s to %s for %s.", storedStatus.Current, storedStatus.Pending, duration)
+			}
 
-	kvpair, _, _ := c.api.KV().Get(key, nil)
-	val := kvpair.Value
-	var storedStatus Status
-	json.Unmarshal(val, &storedStatus)
-
-	// no status change if the stored status and latest status is the same
-	noStatusChange := storedStatus.Current == health.Status
-
-	// new pending status if it's a new status and it's not the same as the pending status
-	newPendingStatus := storedStatus.Current != health.Status && storedStatus.Pending != health.Status
-
-	// status is still pending for change. will change if it reaches threshold
-	stillPendingStatus := storedStatus.Current != health.Status && storedStatus.Pending == health.Status
-
-	switch {
-
-	case noStatusChange:
-		if storedStatus.Pending != "" {
-			storedStatus.Pending = ""
-			storedStatus.PendingTimestamp = time.Time{}
-			log.Printf(
-				"%s:%s:%s is now back to %s.",
-				health.Node,
-				health.ServiceName,
-				health.Name,
-				storedStatus.Current,
-			)
-		}
-
-	case newPendingStatus:
-		storedStatus.Pending = health.Status
-		storedStatus.PendingTimestamp = time.Now()
-		log.Printf(
-			"%s:%s:%s is now pending status change from %s to %s.",
-			health.Node,
-			health.ServiceName,
-			health.Name,
-			storedStatus.Current,
-			storedStatus.Pending,
-		)
-
-	case stillPendingStatus:
-		duration := time.Since(storedStatus.PendingTimestamp)
-		if int(duration.Seconds()) >= c.config.Checks.ChangeThreshold {
-
-			log.Printf(
-				"%s:%s:%s has changed status from %s to %s.",
-				health.Node,
-				health.ServiceName,
-				health.Name,
-				storedStatus.Current,
-				storedStatus.Pending,
-			)
-
-			storedStatus.Current = storedStatus.Pending
-			storedStatus.CurrentTimestamp = time.Now()
-			storedStatus.Pending = ""
-			storedStatus.PendingTimestamp = time.Time{}
-			storedStatus.ForNotification = true
-		} else {
-			log.Printf(
-				"%s:%s:%s is pending status change from %s to %s for %s.",
-				health.Node,
-				health.ServiceName,
-				health.Name,
-				storedStatus.Current,
-				storedStatus.Pending,
-				duration,
-			)
 		}
 
+		// the stale detection needs to know when the check last reported
+		// something different
+		previous := storedStatus.HealthCheck
+		if previous == nil || previous.Status != health.Status || previous.Output != health.Output || storedStatus.OutputTimestamp.IsZero() {
+			storedStatus.OutputTimestamp = time.Now()
+		}
+		storedStatus.HealthCheck = health
+	})
+	if err != nil {
+		apiErrors.Inc("update_check")
+		checkLog(health).Errorln("Unable to update the check status:", err)
+		return
+	}
+	if transition != nil {
+		c.recordTransition(health, transition[0], transition[1])
 	}
-	storedStatus.HealthCheck = health
-
-	data, _ := json.Marshal(storedStatus)
-	c.api.KV().Put(&consulapi.KVPair{Key: key, Value: data}, nil)
 }
 
-func (c *ConsulAlertClient) CheckStatus(node, serviceId, checkId string) (status, output string) {
-	if serviceId == "" {
-		serviceId = "_"
-	}
-	key := fmt.Sprintf("consul-alerts/checks/%s/%s/%s", node, serviceId, checkId)
+func (c *ConsulAlertClient) CheckStatus(dc, node, serviceId, checkId string) (status, output string) {
+	key := c.checkKey(dc, node, serviceId, checkId)
 	kvPair, _, _ := c.api.KV().Get(key, nil)
 
 	if kvPair == nil {
@@ -411,6 +1637,31 @@ func (c *ConsulAlertClient) CheckStatus(node, serviceId, checkId string) (status
 	return
 }
 
+// CheckStatuses returns the tracked status of every check, or of the checks of
+// a single node if node is not empty.
+func (c *ConsulAlertClient) CheckStatuses(node string) ([]Status, error) {
+	kvPairs, _, err := c.api.KV().List("consul-alerts/checks/", nil)
+	if err != nil {
+		apiErrors.Inc("list_checks")
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		if strings.HasSuffix(kvPair.Key, "/") {
+			continue
+		}
+		var status Status
+		if err := json.Unmarshal(kvPair.Value, &status); err != nil || status.HealthCheck == nil {
+			continue
+		}
+		if node != "" && status.HealthCheck.Node != node {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
 func (c *ConsulAlertClient) IsBlacklisted(check *Check) bool {
 	node := check.Node
 	nodeCheckKey := fmt.Sprintf("consul-alerts/config/checks/blacklist/nodes/%s", node)
@@ -431,7 +1682,15 @@ func (c *ConsulAlertClient) IsBlacklisted(check *Check) bool {
 	singleKey := fmt.Sprintf("consul-alerts/config/checks/blacklist/single/%s/%s/%s", node, service, checkId)
 	singleBlacklisted := c.checkKeyExists(singleKey)
 
-	return nodeBlacklisted || serviceBlacklisted || checkBlacklisted || singleBlacklisted
+	if nodeBlacklisted || serviceBlacklisted || checkBlacklisted || singleBlacklisted {
+		return true
+	}
+	for _, pattern := range c.currentConfig().Checks.BlacklistPatterns {
+		if pattern.Matches(check) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *ConsulAlertClient) checkKeyExists(key string) bool {
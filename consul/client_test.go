@@ -1,8 +1,18 @@
 package consul
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
+
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 )
 
 func TestLoadCustomValueForString(t *testing.T) {
@@ -45,3 +55,185 @@ func TestLoadCustomValueForInt(t *testing.T) {
 		t.Errorf("unable to parse %s to int", input)
 	}
 }
+
+func TestLoadCustomValueInvalid(t *testing.T) {
+	port := 25
+	err := loadCustomValue(&port, []byte("smtp"), ConfigTypeInt)
+	if err == nil || err.Error() != `expected a number, got "smtp"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if port != 25 {
+		t.Errorf("an invalid value should keep the default, got %d", port)
+	}
+}
+
+func TestLoadPrefixedValue(t *testing.T) {
+	services := make(map[string][]string)
+	prefixes := map[string]map[string][]string{
+		"consul-alerts/config/notifiers/email/receivers/services/": services,
+	}
+	loadPrefixedValue("consul-alerts/config/notifiers/email/receivers/services/redis", []byte(`["ops@example.com"]`), prefixes)
+	loadPrefixedValue("consul-alerts/config/notifiers/email/receivers", []byte(`["all@example.com"]`), prefixes)
+	if len(services) != 1 || services["redis"][0] != "ops@example.com" {
+		t.Errorf("unable to load prefixed value: %v", services)
+	}
+}
+
+func TestLoadOutputValue(t *testing.T) {
+	outputs := make(map[string]OutputConfig)
+	loadOutputValue("consul-alerts/config/notifiers/slack/output/max-lines", []byte("20"), outputs)
+	loadOutputValue("consul-alerts/config/notifiers/slack/output/sanitize", []byte("true"), outputs)
+	if loaded, _ := loadOutputValue("consul-alerts/config/notifiers/slack/url", []byte("http://example.com"), outputs); loaded {
+		t.Error("a notifier setting shouldn't be loaded as an output limit")
+	}
+	if _, err := loadOutputValue("consul-alerts/config/notifiers/email/output/max-bytes", []byte("big"), outputs); err == nil {
+		t.Error("expected an error for an invalid max-bytes")
+	}
+	if output := outputs["slack"]; output.MaxLines != 20 || !output.Sanitize || output.MaxBytes != 0 {
+		t.Errorf("unexpected slack output limits: %+v", output)
+	}
+}
+
+func TestReloadConfigKeepsInvalidConfigAside(t *testing.T) {
+	threshold := "30"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := base64.StdEncoding.EncodeToString([]byte(threshold))
+		w.Write([]byte(`[{"Key": "consul-alerts/config/checks/change-threshold", "Value": "` + value + `"}]`))
+	}))
+	defer server.Close()
+
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	if err := client.connect(ClientConfig{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client.ValidateConfig(func(candidate Consul) []string {
+		if candidate.CheckChangeThreshold() > 60 {
+			return []string{"change-threshold too high"}
+		}
+		return nil
+	})
+	if _, err := client.ReloadConfig(); err != nil || client.CheckChangeThreshold() != 30 {
+		t.Fatalf("unexpected first load %d %v", client.CheckChangeThreshold(), err)
+	}
+
+	threshold = "600"
+	_, err := client.ReloadConfig()
+	var problems *ConfigProblemsError
+	if !errors.As(err, &problems) || len(problems.Problems) != 1 {
+		t.Fatalf("expected the config problems, got %v", err)
+	}
+	if client.CheckChangeThreshold() != 30 {
+		t.Errorf("the invalid config should not be used, got %d", client.CheckChangeThreshold())
+	}
+
+	threshold = "45"
+	if changes, err := client.ReloadConfig(); err != nil || len(changes) != 1 || client.CheckChangeThreshold() != 45 {
+		t.Errorf("unexpected reload %v %v %d", changes, err, client.CheckChangeThreshold())
+	}
+}
+
+func TestUnsentRecovery(t *testing.T) {
+	failed, recovered := time.Now().Add(-time.Hour), time.Now()
+	cases := []struct {
+		status Status
+		unsent bool
+	}{
+		{Status{Current: "passing", CurrentTimestamp: recovered, NotifiedStatus: "critical", NotifiedTimestamp: failed}, false},
+		{Status{Current: "passing", CurrentTimestamp: recovered, NotifiedStatus: "passing", NotifiedTimestamp: failed}, true},
+		{Status{Current: "passing", CurrentTimestamp: failed, NotifiedStatus: "passing", NotifiedTimestamp: recovered}, false},
+		{Status{Current: "passing", CurrentTimestamp: recovered, NotifiedTimestamp: failed}, false},
+		{Status{Current: "critical", CurrentTimestamp: recovered, NotifiedStatus: "passing", NotifiedTimestamp: failed}, false},
+	}
+	for _, c := range cases {
+		if unsentRecovery(&c.status) != c.unsent {
+			t.Errorf("expected %v for %+v", c.unsent, c.status)
+		}
+	}
+}
+
+func TestMarkNotifiedRetriesOnConflict(t *testing.T) {
+	var lock sync.Mutex
+	stored, _ := json.Marshal(Status{Current: "warning", ForNotification: true})
+	index := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == "GET" {
+			value := base64.StdEncoding.EncodeToString(stored)
+			fmt.Fprintf(w, `[{"Key": "consul-alerts/checks/node/svc/check", "Value": "%s", "ModifyIndex": %d}]`, value, index)
+			return
+		}
+		if r.URL.Query().Get("cas") != strconv.Itoa(index) {
+			w.Write([]byte("false"))
+			return
+		}
+		if index == 1 {
+			// the check processing updates the status first
+			stored, _ = json.Marshal(Status{Current: "critical", ForNotification: true})
+			index++
+			w.Write([]byte("false"))
+			return
+		}
+		stored, _ = ioutil.ReadAll(r.Body)
+		index++
+		w.Write([]byte("true"))
+	}))
+	defer server.Close()
+
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	if err := client.connect(ClientConfig{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client.markNotified("consul-alerts/checks/node/svc/check", time.Now(), true)
+	var status Status
+	json.Unmarshal(stored, &status)
+	if status.ForNotification || status.Current != "critical" || status.NotifiedStatus != "critical" {
+		t.Errorf("expected the latest status marked notified, got %+v", status)
+	}
+}
+
+func TestLocalChecksHaveNoDatacenter(t *testing.T) {
+	client := &ConsulAlertClient{datacenter: "dc1"}
+	if dc := client.checkDatacenter("dc1"); dc != "" {
+		t.Errorf("expected no datacenter for the local checks, got %q", dc)
+	}
+	if key := client.checkKey(client.checkDatacenter("dc1"), "node", "", "serfHealth"); key != "consul-alerts/checks/node/_/serfHealth" {
+		t.Errorf("unexpected local key %s", key)
+	}
+	if key := client.checkKey(client.checkDatacenter("dc2"), "node", "web", "http"); key != "consul-alerts/checks/_dc/dc2/node/web/http" {
+		t.Errorf("unexpected remote key %s", key)
+	}
+}
+
+func TestUpdateHealthCheckSkipsUnchanged(t *testing.T) {
+	health := Check{Node: "node", ServiceID: "svc", CheckID: "check", Status: "passing", Output: "ok"}
+	stored, _ := json.Marshal(Status{Current: "passing", HealthCheck: &health, OutputTimestamp: time.Now(), NotifiedStatus: "passing"})
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			value := base64.StdEncoding.EncodeToString(stored)
+			fmt.Fprintf(w, `[{"Key": "consul-alerts/checks/node/svc/check", "Value": "%s", "ModifyIndex": 1}]`, value)
+			return
+		}
+		puts++
+		stored, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("true"))
+	}))
+	defer server.Close()
+
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	if err := client.connect(ClientConfig{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	same := health
+	client.updateHealthCheck("consul-alerts/checks/node/svc/check", &same)
+	if puts != 0 {
+		t.Errorf("an unchanged check shouldn't be written, got %d writes", puts)
+	}
+	changed := health
+	changed.Output = "slow"
+	client.updateHealthCheck("consul-alerts/checks/node/svc/check", &changed)
+	if puts != 1 {
+		t.Errorf("expected the changed output written, got %d writes", puts)
+	}
+}
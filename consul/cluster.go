@@ -0,0 +1,20 @@
+package consul
+
+// ConsulLeader returns the address of the raft leader, empty when the cluster
+// has no leader.
+func (c *ConsulAlertClient) ConsulLeader() (string, error) {
+	leader, err := c.api.Status().Leader()
+	if err != nil {
+		apiErrors.Inc("status_leader")
+	}
+	return leader, err
+}
+
+// ConsulPeers returns the addresses of the raft peers.
+func (c *ConsulAlertClient) ConsulPeers() ([]string, error) {
+	peers, err := c.api.Status().Peers()
+	if err != nil {
+		apiErrors.Inc("status_peers")
+	}
+	return peers, err
+}
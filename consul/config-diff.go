@@ -0,0 +1,58 @@
+package consul
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// ConfigChange is a config key added, changed or removed by a reload. The
+// values are left out as they may be secrets.
+type ConfigChange struct {
+	Key    string
+	Change string
+}
+
+func (c ConfigChange) String() string {
+	return c.Change + " " + c.Key
+}
+
+// configValues returns the raw config values by key, the KV values override
+// the config file ones.
+func configValues(kvPairs consulapi.KVPairs) map[string]string {
+	values := make(map[string]string, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		values[kvPair.Key] = string(kvPair.Value)
+	}
+	return values
+}
+
+// configChanges compares the config values of two loads, sorted by key.
+func configChanges(previous, current map[string]string) []ConfigChange {
+	var changes []ConfigChange
+	for key, value := range current {
+		previousValue, found := previous[key]
+		switch {
+		case !found:
+			changes = append(changes, ConfigChange{Key: key, Change: "added"})
+		case previousValue != value:
+			changes = append(changes, ConfigChange{Key: key, Change: "changed"})
+		}
+	}
+	for key := range previous {
+		if _, found := current[key]; !found {
+			changes = append(changes, ConfigChange{Key: key, Change: "removed"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func describeChanges(changes []ConfigChange) string {
+	described := make([]string, len(changes))
+	for i, change := range changes {
+		described[i] = change.String()
+	}
+	return strings.Join(described, ", ")
+}
@@ -0,0 +1,147 @@
+package consul
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/BurntSushi/toml"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/gopkg.in/yaml.v2"
+)
+
+// loadConfigFile reads a YAML, TOML or JSON config file and returns its values
+// as the KV pairs they would be stored in under consul-alerts/config. Nested
+// tables are joined with "/" so the file follows the KV layout:
+//
+//	notifiers:
+//	  email:
+//	    port: 587
+//	    receivers: ["ops@example.com"]
+//
+// is loaded as consul-alerts/config/notifiers/email/port and
+// consul-alerts/config/notifiers/email/receivers.
+func loadConfigFile(path string) (consulapi.KVPairs, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		var table map[string]interface{}
+		_, err = toml.Decode(string(data), &table)
+		tree = table
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q, use .yaml, .toml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var kvPairs consulapi.KVPairs
+	if err := flattenConfig("consul-alerts/config", tree, &kvPairs); err != nil {
+		return nil, err
+	}
+	return kvPairs, nil
+}
+
+// flattenConfig adds the values of tree to kvPairs, using the path of each
+// value as its key. Lists are stored as JSON arrays like in the KV config.
+func flattenConfig(key string, tree interface{}, kvPairs *consulapi.KVPairs) error {
+	var value string
+	switch v := tree.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return flattenTable(key, v, kvPairs)
+	case map[interface{}]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			table[fmt.Sprint(k)] = val
+		}
+		return flattenTable(key, table, kvPairs)
+	case []interface{}:
+		// lists of values are string arrays while lists holding tables, like
+		// the event handlers, are stored as they are
+		var items interface{} = normalizeTables(v)
+		if isValueList(v) {
+			values := make([]string, len(v))
+			for i, item := range v {
+				values[i] = fmt.Sprint(item)
+			}
+			items = values
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			return fmt.Errorf("%s: %s", key, err)
+		}
+		value = string(data)
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	case string, bool, int, int64:
+		value = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("%s: unsupported value %v", key, v)
+	}
+	*kvPairs = append(*kvPairs, &consulapi.KVPair{Key: key, Value: []byte(value)})
+	return nil
+}
+
+func flattenTable(key string, table map[string]interface{}, kvPairs *consulapi.KVPairs) error {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := flattenConfig(key+"/"+k, table[k], kvPairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isValueList(list []interface{}) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTables converts the tables decoded by yaml, which have interface{}
+// keys, so they can be encoded to JSON.
+func normalizeTables(tree interface{}) interface{} {
+	switch v := tree.(type) {
+	case map[interface{}]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			table[fmt.Sprint(k)] = normalizeTables(val)
+		}
+		return table
+	case map[string]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			table[k] = normalizeTables(val)
+		}
+		return table
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeTables(item)
+		}
+		return list
+	}
+	return tree
+}
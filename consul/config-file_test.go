@@ -0,0 +1,104 @@
+package consul
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+checks:
+  change-threshold: 30
+notifiers:
+  email:
+    enabled: true
+    receivers: [ops@example.com, dev@example.com]
+    receivers/services:
+      web: [web@example.com]
+events:
+  handlers:
+    - deploy.sh
+    - command: prod.sh
+      match: prod
+`,
+		"config.toml": `
+[checks]
+change-threshold = 30
+
+[notifiers.email]
+enabled = true
+receivers = ["ops@example.com", "dev@example.com"]
+
+[notifiers.email."receivers/services"]
+web = ["web@example.com"]
+
+[events]
+handlers = ["deploy.sh", {command = "prod.sh", match = "prod"}]
+`,
+		"config.json": `{
+  "checks": {"change-threshold": 30},
+  "notifiers": {
+    "email": {
+      "enabled": true,
+      "receivers": ["ops@example.com", "dev@example.com"],
+      "receivers/services": {"web": ["web@example.com"]}
+    }
+  },
+  "events": {"handlers": ["deploy.sh", {"command": "prod.sh", "match": "prod"}]}
+}`,
+	}
+	expected := map[string]string{
+		"consul-alerts/config/checks/change-threshold":                "30",
+		"consul-alerts/config/notifiers/email/enabled":                "true",
+		"consul-alerts/config/notifiers/email/receivers":              `["ops@example.com","dev@example.com"]`,
+		"consul-alerts/config/notifiers/email/receivers/services/web": `["web@example.com"]`,
+		"consul-alerts/config/events/handlers":                        `["deploy.sh",{"command":"prod.sh","match":"prod"}]`,
+	}
+
+	dir, err := ioutil.TempDir("", "consul-alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		kvPairs, err := loadConfigFile(path)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if len(kvPairs) != len(expected) {
+			t.Errorf("%s: expected %d values, got %d", name, len(expected), len(kvPairs))
+		}
+		for _, kvPair := range kvPairs {
+			if expected[kvPair.Key] != string(kvPair.Value) {
+				t.Errorf("%s: unexpected value for %s: %s", name, kvPair.Key, kvPair.Value)
+			}
+		}
+	}
+}
+
+func TestLoadConfigFileUnsupported(t *testing.T) {
+	if _, err := loadConfigFile("config.ini"); err == nil {
+		t.Error("expected an error for an unsupported extension")
+	}
+}
+
+func TestConfigChanges(t *testing.T) {
+	previous := map[string]string{"a": "1", "b": "2", "c": "3"}
+	current := map[string]string{"a": "1", "b": "20", "d": "4"}
+	changes := describeChanges(configChanges(previous, current))
+	if changes != "changed b, removed c, added d" {
+		t.Errorf("unexpected changes %q", changes)
+	}
+	if len(configChanges(current, current)) != 0 {
+		t.Error("the same values should have no changes")
+	}
+}
@@ -0,0 +1,178 @@
+package consul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"encoding/json"
+)
+
+// loadDependencies loads a JSON object of the services each service depends
+// on, like {"web": ["api"], "api": ["postgres", "redis"]}. Circular
+// dependencies are rejected.
+func loadDependencies(dependencies *map[string][]string, data []byte) error {
+	var val map[string][]string
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON object of the services each service depends on like {"web": ["api"]}, got %q`, data)
+	}
+	services := make([]string, 0, len(val))
+	for service := range val {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		if cycle := dependencyCycle(val, service, nil); cycle != nil {
+			return fmt.Errorf("circular dependency %s", strings.Join(cycle, " -> "))
+		}
+	}
+	*dependencies = val
+	return nil
+}
+
+// dependencyCycle returns the dependency cycle reached from service, nil if
+// there is none.
+func dependencyCycle(dependencies map[string][]string, service string, path []string) []string {
+	for i, previous := range path {
+		if previous == service {
+			return append(path[i:], service)
+		}
+	}
+	path = append(path, service)
+	for _, dependency := range dependencies[service] {
+		if cycle := dependencyCycle(dependencies, dependency, path); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// rootCause returns the failing service at the end of the dependency chain
+// of a service, an empty string if none of its dependencies is failing.
+func rootCause(dependencies map[string][]string, failing map[string]bool, service string) string {
+	for _, dependency := range dependencies[service] {
+		if !failing[dependency] {
+			continue
+		}
+		if root := rootCause(dependencies, failing, dependency); root != "" {
+			return root
+		}
+		return dependency
+	}
+	return ""
+}
+
+// failingServices returns the failing services of each datacenter: the
+// services with fewer healthy instances than their minimum, at least one. An
+// instance is unhealthy while one of its checks or of its node is critical.
+func failingServices(checks []*Check, minHealthy map[string]int) map[string]map[string]bool {
+	nodeCritical := make(map[string]bool)
+	for _, check := range checks {
+		if check.ServiceID == "" && check.Status == "critical" {
+			nodeCritical[check.Datacenter+"/"+check.Node] = true
+		}
+	}
+	type instance struct{ dc, service string }
+	instances := make(map[string]instance)
+	unhealthy := make(map[string]bool)
+	for _, check := range checks {
+		if check.ServiceName == "" {
+			continue
+		}
+		key := check.Datacenter + "/" + check.Node + "/" + check.ServiceID
+		instances[key] = instance{check.Datacenter, check.ServiceName}
+		if check.Status == "critical" || nodeCritical[check.Datacenter+"/"+check.Node] {
+			unhealthy[key] = true
+		}
+	}
+	healthy := make(map[instance]int)
+	for key, instance := range instances {
+		if !unhealthy[key] {
+			healthy[instance]++
+		} else if _, found := healthy[instance]; !found {
+			healthy[instance] = 0
+		}
+	}
+	failing := make(map[string]map[string]bool)
+	for instance, count := range healthy {
+		minimum := minHealthy[instance.service]
+		if minimum < 1 {
+			minimum = 1
+		}
+		if count >= minimum {
+			continue
+		}
+		if failing[instance.dc] == nil {
+			failing[instance.dc] = make(map[string]bool)
+		}
+		failing[instance.dc][instance.service] = true
+	}
+	return failing
+}
+
+// rolledAlert is an alert rolled up into the alert of its failing dependency
+// Root.
+type rolledAlert struct {
+	Check
+	Root string
+}
+
+// rollupDependents rolls the alerts of the services depending on a failing
+// service of the same datacenter into the critical alert of this service,
+// listed in its output as affected dependents. It returns the alerts left to
+// send and the rolled up ones. When the failing service has no alert in the
+// batch, the alert of one of its sent checks is sent again listing the new
+// dependents, the others are rolled up without being listed.
+func rollupDependents(dependencies map[string][]string, failing map[string]map[string]bool, alerts []Check, sent []*Check) (kept []Check, rolled []rolledAlert) {
+	roots := make([]string, len(alerts))
+	targets := make(map[string]int)
+	for i, alert := range alerts {
+		if alert.ServiceName == "" {
+			continue
+		}
+		roots[i] = rootCause(dependencies, failing[alert.Datacenter], alert.ServiceName)
+		key := alert.Datacenter + "/" + alert.ServiceName
+		if _, found := targets[key]; !found && roots[i] == "" && alert.Status == "critical" {
+			targets[key] = i
+		}
+	}
+	for i, alert := range alerts {
+		key := alert.Datacenter + "/" + roots[i]
+		if _, found := targets[key]; found || roots[i] == "" {
+			continue
+		}
+		for _, check := range sent {
+			if check.Datacenter == alert.Datacenter && check.ServiceName == roots[i] && check.Status == "critical" {
+				targets[key] = len(alerts)
+				alerts = append(alerts, *check)
+				roots = append(roots, "")
+				break
+			}
+		}
+	}
+
+	dependents := make(map[int][]string)
+	var order []int
+	for i, alert := range alerts {
+		if roots[i] == "" {
+			continue
+		}
+		rolled = append(rolled, rolledAlert{alert, roots[i]})
+		if target, found := targets[alert.Datacenter+"/"+roots[i]]; found {
+			if dependents[target] == nil {
+				order = append(order, target)
+			}
+			dependents[target] = append(dependents[target], fmt.Sprintf("- %s:%s:%s is %s", alert.Node, alert.ServiceID, alert.CheckID, alert.Status))
+		}
+	}
+	for _, target := range order {
+		alerts[target].Output += "\n\nAffected dependents:\n" + strings.Join(dependents[target], "\n")
+	}
+	kept = make([]Check, 0, len(alerts))
+	for i, alert := range alerts {
+		if roots[i] == "" {
+			kept = append(kept, alert)
+		}
+	}
+	return kept, rolled
+}
@@ -0,0 +1,74 @@
+package consul
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadDependencies(t *testing.T) {
+	var dependencies map[string][]string
+	if err := loadDependencies(&dependencies, []byte(`{"web": ["api"], "api": ["postgres", "redis"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	err := loadDependencies(&dependencies, []byte(`{"web": ["api"], "api": ["auth"], "auth": ["web"]}`))
+	if err == nil || !strings.Contains(err.Error(), "api -> auth -> web -> api") {
+		t.Errorf("expected a circular dependency error, got %v", err)
+	}
+}
+
+func TestFailingServices(t *testing.T) {
+	checks := []*Check{
+		{Node: "db1", ServiceID: "postgres", ServiceName: "postgres", CheckID: "pg", Status: "critical"},
+		{Node: "db2", ServiceID: "postgres", ServiceName: "postgres", CheckID: "pg", Status: "passing"},
+		{Node: "app1", ServiceID: "api", ServiceName: "api", CheckID: "http", Status: "passing"},
+		{Node: "app1", CheckID: "serfHealth", Status: "critical"},
+		{Node: "cache1", ServiceID: "redis", ServiceName: "redis", CheckID: "ping", Status: "warning"},
+	}
+	failing := failingServices(checks, nil)
+	if failing[""]["postgres"] || !failing[""]["api"] || failing[""]["redis"] {
+		t.Errorf("expected only api failing with its node, got %v", failing)
+	}
+	failing = failingServices(checks, map[string]int{"postgres": 2})
+	if !failing[""]["postgres"] {
+		t.Errorf("expected postgres failing below its minimum, got %v", failing)
+	}
+}
+
+func TestRollupDependents(t *testing.T) {
+	dependencies := map[string][]string{"web": {"api"}, "api": {"postgres"}, "worker": {"redis"}}
+	postgres := Check{Node: "db1", ServiceID: "postgres", ServiceName: "postgres", CheckID: "pg", Status: "critical", Output: "connection refused"}
+	api := Check{Node: "app1", ServiceID: "api", ServiceName: "api", CheckID: "http", Status: "critical"}
+	web := Check{Node: "app2", ServiceID: "web", ServiceName: "web", CheckID: "http", Status: "warning"}
+	worker := Check{Node: "app3", ServiceID: "worker", ServiceName: "worker", CheckID: "queue", Status: "critical"}
+	redis := Check{Node: "cache1", ServiceID: "redis", ServiceName: "redis", CheckID: "ping", Status: "critical", Output: "timeout"}
+	checks := []*Check{&postgres, &api, &web, &worker, &redis}
+
+	// redis failed earlier, its alert is sent again listing worker
+	kept, rolled := rollupDependents(dependencies, failingServices(checks, nil), []Check{api, postgres, web, worker}, []*Check{&redis})
+	if len(kept) != 2 || kept[0].CheckID != "pg" || kept[1].CheckID != "ping" || len(rolled) != 3 {
+		t.Fatalf("expected the postgres and redis alerts kept, got %+v", kept)
+	}
+	expected := "connection refused\n\nAffected dependents:\n- app1:api:http is critical\n- app2:web:http is warning"
+	if kept[0].Output != expected {
+		t.Errorf("unexpected output %q", kept[0].Output)
+	}
+	if kept[1].Output != "timeout\n\nAffected dependents:\n- app3:worker:queue is critical" {
+		t.Errorf("unexpected output %q", kept[1].Output)
+	}
+	if rolled[1].Root != "postgres" {
+		t.Errorf("expected web rolled into postgres, got %q", rolled[1].Root)
+	}
+
+	// a second postgres instance keeps it healthy
+	healthy := Check{Node: "db2", ServiceID: "postgres", ServiceName: "postgres", CheckID: "pg", Status: "passing"}
+	kept, rolled = rollupDependents(dependencies, failingServices(append(checks, &healthy), nil), []Check{api}, nil)
+	if len(kept) != 1 || len(rolled) != 0 {
+		t.Errorf("expected the api alert sent while postgres has a healthy instance, got %+v %+v", kept, rolled)
+	}
+
+	postgres.Status = "passing"
+	kept, rolled = rollupDependents(dependencies, failingServices(checks, nil), []Check{api}, nil)
+	if len(kept) != 1 || len(rolled) != 0 {
+		t.Errorf("expected the api alert sent once postgres passes, got %+v %+v", kept, rolled)
+	}
+}
@@ -0,0 +1,61 @@
+package consul
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// digestKey matches the digest settings of a notifier.
+var digestKey = regexp.MustCompile(`^consul-alerts/config/notifiers/([^/]+)/digest/(interval|statuses)$`)
+
+// DigestConfig makes a notifier send the alerts of the Statuses, all but
+// critical by default, in a single summary every Interval minutes instead of
+// right away. The digest is disabled when Interval is 0.
+type DigestConfig struct {
+	Interval int
+	Statuses []string
+}
+
+// Digested returns true if the alerts with the status go in the digest.
+func (d DigestConfig) Digested(status string) bool {
+	if d.Interval <= 0 {
+		return false
+	}
+	statuses := d.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{"warning", "passing"}
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDigestValue loads a digest setting of a notifier and returns true if
+// the key is one.
+func loadDigestValue(key string, data []byte, digests map[string]DigestConfig) (bool, error) {
+	match := digestKey.FindStringSubmatch(key)
+	if match == nil {
+		return false, nil
+	}
+	digest := digests[match[1]]
+	var err error
+	switch match[2] {
+	case "interval":
+		if err = loadCustomValue(&digest.Interval, data, ConfigTypeInt); err == nil && digest.Interval < 0 {
+			err = fmt.Errorf("expected a positive number of minutes, got %d", digest.Interval)
+		}
+	case "statuses":
+		err = loadCustomValue(&digest.Statuses, data, ConfigTypeStrArray)
+	}
+	digests[match[1]] = digest
+	return true, err
+}
+
+// NotifierDigest returns the digest settings of a notifier, "custom" for the
+// custom notifiers.
+func (c *ConsulAlertClient) NotifierDigest(name string) DigestConfig {
+	return c.currentConfig().Notifiers.Digests[name]
+}
@@ -0,0 +1,34 @@
+package consul
+
+import "testing"
+
+func TestLoadDigestValue(t *testing.T) {
+	digests := map[string]DigestConfig{}
+	if found, err := loadDigestValue("consul-alerts/config/notifiers/email/digest/interval", []byte("60"), digests); !found || err != nil {
+		t.Fatal("unexpected result:", found, err)
+	}
+	if found, err := loadDigestValue("consul-alerts/config/notifiers/email/digest/statuses", []byte(`["warning"]`), digests); !found || err != nil {
+		t.Fatal("unexpected result:", found, err)
+	}
+	if _, err := loadDigestValue("consul-alerts/config/notifiers/slack/digest/interval", []byte("-1"), digests); err == nil {
+		t.Error("a negative interval should be rejected")
+	}
+	if found, _ := loadDigestValue("consul-alerts/config/notifiers/email/enabled", []byte("true"), digests); found {
+		t.Error("enabled isn't a digest setting")
+	}
+
+	email := digests["email"]
+	if email.Interval != 60 || !email.Digested("warning") || email.Digested("passing") || email.Digested("critical") {
+		t.Errorf("unexpected email digest %+v", email)
+	}
+}
+
+func TestDigested(t *testing.T) {
+	if (DigestConfig{}).Digested("warning") {
+		t.Error("the digest should be disabled without an interval")
+	}
+	digest := DigestConfig{Interval: 1440}
+	if !digest.Digested("warning") || !digest.Digested("passing") || digest.Digested("critical") {
+		t.Error("the criticals should be the only alerts sent right away by default")
+	}
+}
@@ -0,0 +1,177 @@
+package consul
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"encoding/json"
+)
+
+// EventHandler is a command run for the events. It is configured either as a
+// plain command string or as an object with a payload filter:
+//
+//	{"command": "deploy.sh", "path": "$.env", "match": "^production$"}
+//
+// Match is a regular expression tested against the payload, or against the
+// value selected by the JSONPath in Path when the payload is JSON. With only a
+// Path, the handler runs when the payload has a value at this path.
+type EventHandler struct {
+	Command string `json:"command"`
+	Path    string `json:"path,omitempty"`
+	Match   string `json:"match,omitempty"`
+
+	match *regexp.Regexp
+}
+
+func (h *EventHandler) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &h.Command)
+	}
+	type eventHandler EventHandler
+	return json.Unmarshal(data, (*eventHandler)(h))
+}
+
+// compile checks the filter and prepares the regular expression.
+func (h *EventHandler) compile() error {
+	if h.Command == "" {
+		return errors.New("event handler without a command")
+	}
+	if h.Path != "" {
+		if _, err := parseJSONPath(h.Path); err != nil {
+			return err
+		}
+	}
+	if h.Match != "" {
+		match, err := regexp.Compile(h.Match)
+		if err != nil {
+			return err
+		}
+		h.match = match
+	}
+	return nil
+}
+
+// Matches returns true if the handler should run for the payload.
+func (h EventHandler) Matches(payload []byte) bool {
+	value := string(payload)
+	if h.Path != "" {
+		var document interface{}
+		if err := json.Unmarshal(payload, &document); err != nil {
+			return false
+		}
+		selected, found := selectJSONPath(document, h.Path)
+		if !found {
+			return false
+		}
+		switch v := selected.(type) {
+		case string:
+			value = v
+		default:
+			data, _ := json.Marshal(v)
+			value = string(data)
+		}
+	}
+	return h.match == nil || h.match.MatchString(value)
+}
+
+// loadEventHandlers loads a JSON array of event handlers.
+func loadEventHandlers(handlers *[]EventHandler, data []byte) error {
+	var val []EventHandler
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of commands or {"command": ..., "path": ..., "match": ...} objects, got %q`, data)
+	}
+	for i := range val {
+		command, err := resolveSecrets(val[i].Command)
+		if err != nil {
+			return err
+		}
+		val[i].Command = command
+		if err := val[i].compile(); err != nil {
+			return err
+		}
+	}
+	*handlers = val
+	return nil
+}
+
+// jsonPathStep is a child key or an array index of a JSONPath.
+type jsonPathStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath splits a JSONPath like $.a.b[0]['c d'] into its steps. Only
+// child and index selectors are supported.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %s, it should start with $", path)
+	}
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %s, unterminated ['", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[2:end], index: -1})
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %s, unterminated [", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %s, %s is not an index", path, rest[1:end])
+			}
+			steps = append(steps, jsonPathStep{index: index})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSONPath %s, empty key", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[1 : end+1], index: -1})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %s", path)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSONPath returns the value at path in document.
+func selectJSONPath(document interface{}, path string) (interface{}, bool) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+	value := document
+	for _, step := range steps {
+		if step.index >= 0 {
+			list, ok := value.([]interface{})
+			if !ok || step.index >= len(list) {
+				return nil, false
+			}
+			value = list[step.index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
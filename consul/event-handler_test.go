@@ -0,0 +1,64 @@
+package consul
+
+import "testing"
+
+func TestLoadEventHandlers(t *testing.T) {
+	var handlers []EventHandler
+	data := `["deploy.sh", {"command": "prod.sh --now", "path": "$.env", "match": "^prod"}, {"command": "tagged.sh", "path": "$.tags[1]"}]`
+	if err := loadEventHandlers(&handlers, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(handlers) != 3 || handlers[0].Command != "deploy.sh" || handlers[1].Command != "prod.sh --now" {
+		t.Fatalf("unexpected handlers: %+v", handlers)
+	}
+
+	payloads := map[string][3]bool{
+		`{"env": "production", "tags": ["a", "b"]}`: {true, true, true},
+		`{"env": "staging", "tags": ["a"]}`:         {true, false, false},
+		`not json`:                                  {true, false, false},
+	}
+	for payload, expected := range payloads {
+		for i, handler := range handlers {
+			if handler.Matches([]byte(payload)) != expected[i] {
+				t.Errorf("%s: expected handler %d to match %t", payload, i, expected[i])
+			}
+		}
+	}
+
+	invalid := []string{
+		`[{"command": "x.sh", "match": "("}]`,
+		`[{"command": "x.sh", "path": "env"}]`,
+		`[{"match": "prod"}]`,
+		`"deploy.sh"`,
+	}
+	for _, data := range invalid {
+		if err := loadEventHandlers(&handlers, []byte(data)); err == nil {
+			t.Errorf("%s should be invalid", data)
+		}
+	}
+}
+
+func TestPayloadRegexFilter(t *testing.T) {
+	handler := EventHandler{Command: "x.sh", Match: "^v[0-9]+"}
+	if err := handler.compile(); err != nil {
+		t.Fatal(err)
+	}
+	if !handler.Matches([]byte("v12")) || handler.Matches([]byte("latest")) {
+		t.Error("the regex should be matched against the raw payload")
+	}
+}
+
+func TestSelectJSONPath(t *testing.T) {
+	document := map[string]interface{}{
+		"a": map[string]interface{}{"b c": []interface{}{"x", "y"}},
+	}
+	if value, found := selectJSONPath(document, "$.a['b c'][1]"); !found || value != "y" {
+		t.Errorf("unexpected value: %v", value)
+	}
+	if _, found := selectJSONPath(document, "$.a.missing"); found {
+		t.Error("a missing key should not be found")
+	}
+	if _, found := selectJSONPath(document, "$.a['b c'][2]"); found {
+		t.Error("an index out of range should not be found")
+	}
+}
@@ -0,0 +1,39 @@
+package consul
+
+import (
+	"strconv"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const eventStatePrefix = "consul-alerts/events/"
+
+// LastEventLTime returns the Lamport time of the last processed event with the
+// given name, or 0 if none was processed. It is stored in KV so the events
+// aren't handled again after a restart or a leader failover.
+func (c *ConsulAlertClient) LastEventLTime(name string) (uint, error) {
+	kvPair, _, err := c.api.KV().Get(eventStatePrefix+name, nil)
+	if err != nil {
+		apiErrors.Inc("event_state")
+		return 0, err
+	}
+	if kvPair == nil {
+		return 0, nil
+	}
+	ltime, err := strconv.ParseUint(string(kvPair.Value), 10, 64)
+	return uint(ltime), err
+}
+
+// SetLastEventLTime records the Lamport time of the last processed event with
+// the given name.
+func (c *ConsulAlertClient) SetLastEventLTime(name string, ltime uint) error {
+	kvPair := &consulapi.KVPair{
+		Key:   eventStatePrefix + name,
+		Value: []byte(strconv.FormatUint(uint64(ltime), 10)),
+	}
+	if _, err := c.api.KV().Put(kvPair, nil); err != nil {
+		apiErrors.Inc("event_state")
+		return err
+	}
+	return nil
+}
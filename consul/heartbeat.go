@@ -0,0 +1,33 @@
+package consul
+
+import (
+	"fmt"
+
+	"encoding/json"
+)
+
+// HeartbeatConfig configures the request sent every Interval seconds to URL
+// while consul-alerts is healthy, so a dead man's switch service alerts when
+// the requests stop. Headers are added to the requests, eg. for an API key.
+type HeartbeatConfig struct {
+	URL      string
+	Interval int
+	Headers  map[string]string
+}
+
+// loadHeaders loads a JSON object of HTTP headers.
+func loadHeaders(headers *map[string]string, data []byte) error {
+	var val map[string]string
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON object of headers like {"Authorization": "GenieKey ..."}, got %q`, data)
+	}
+	for name, value := range val {
+		value, err := resolveSecrets(value)
+		if err != nil {
+			return err
+		}
+		val[name] = value
+	}
+	*headers = val
+	return nil
+}
@@ -0,0 +1,284 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const historyPrefix = "consul-alerts/history/"
+
+// historyNextKey holds the sequence number of the next history entry. Entry n
+// is stored in slot n % Size so the oldest entries are overwritten.
+const historyNextKey = historyPrefix + "next"
+
+const historyEntriesPrefix = historyPrefix + "entries/"
+
+// HistoryConfig configures the alert history. Size is the number of entries
+// kept in the consul KV ring buffer. Backend is "consul" for the ring buffer,
+// or a SQL backend opened with DSN whose entries are kept Retention days, or
+// forever when it is 0.
+type HistoryConfig struct {
+	Enabled   bool
+	Size      int
+	Backend   string
+	DSN       string
+	Retention int
+}
+
+// HistoryStore stores the history entries outside of consul KV.
+type HistoryStore interface {
+	Record(entries []HistoryEntry) error
+	Query(query HistoryQuery) ([]HistoryEntry, error)
+}
+
+// HistoryEntry is a check status change, with Type "transition", or a
+// notification sent by a notifier, with Type "notification" and its Result:
+// sent or failed.
+type HistoryEntry struct {
+	Sequence   uint64    `json:"sequence"`
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Datacenter string    `json:"datacenter,omitempty"`
+	Node       string    `json:"node"`
+	ServiceId  string    `json:"serviceId,omitempty"`
+	Service    string    `json:"service,omitempty"`
+	CheckId    string    `json:"checkId"`
+	Check      string    `json:"check"`
+	From       string    `json:"from,omitempty"`
+	Status     string    `json:"status"`
+	Output     string    `json:"output,omitempty"`
+	Notifier   string    `json:"notifier,omitempty"`
+	Result     string    `json:"result,omitempty"`
+}
+
+// HistoryQuery filters the history entries. Node, Service and Check are
+// patterns like the silence ones, Service matches the service id or name and
+// Check the check id or name. Limit keeps the most recent entries.
+type HistoryQuery struct {
+	Type    string
+	Node    string
+	Service string
+	Check   string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// Matches returns true if the entry is selected by the query.
+func (q HistoryQuery) Matches(entry HistoryEntry) bool {
+	return (q.Type == "" || q.Type == entry.Type) &&
+		matchPattern(q.Node, entry.Node) &&
+		(matchPattern(q.Service, entry.ServiceId) || matchPattern(q.Service, entry.Service)) &&
+		(matchPattern(q.Check, entry.CheckId) || matchPattern(q.Check, entry.Check)) &&
+		(q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until))
+}
+
+// historyCASRetries is the number of attempts to reserve history sequence
+// numbers when another instance writes at the same time.
+const historyCASRetries = 5
+
+// RecordHistory stores the entries in the history when it is enabled.
+func (c *ConsulAlertClient) RecordHistory(entries []HistoryEntry) error {
+	config := c.currentConfig().History
+	if !config.Enabled || len(entries) == 0 {
+		return nil
+	}
+	c.history.RLock()
+	defer c.history.RUnlock()
+	if store := c.history.current(); store != nil {
+		timed := make([]HistoryEntry, len(entries))
+		for i, entry := range entries {
+			if entry.Time.IsZero() {
+				entry.Time = time.Now()
+			}
+			timed[i] = entry
+		}
+		return store.Record(timed)
+	}
+	first, err := c.reserveHistory(uint64(len(entries)))
+	if err != nil {
+		apiErrors.Inc("history")
+		return err
+	}
+	for i := range entries {
+		entry := entries[i]
+		entry.Sequence = first + uint64(i)
+		if entry.Time.IsZero() {
+			entry.Time = time.Now()
+		}
+		data, _ := json.Marshal(entry)
+		key := fmt.Sprintf("%s%d", historyEntriesPrefix, entry.Sequence%uint64(config.Size))
+		if _, err := c.api.KV().Put(&consulapi.KVPair{Key: key, Value: data}, nil); err != nil {
+			apiErrors.Inc("history")
+			return err
+		}
+	}
+	return nil
+}
+
+// reserveHistory increments the next sequence number by count and returns the
+// first reserved one.
+func (c *ConsulAlertClient) reserveHistory(count uint64) (uint64, error) {
+	for attempt := 0; attempt < historyCASRetries; attempt++ {
+		kvPair, _, err := c.api.KV().Get(historyNextKey, nil)
+		if err != nil {
+			return 0, err
+		}
+		var next, modifyIndex uint64
+		if kvPair != nil {
+			next, _ = strconv.ParseUint(string(kvPair.Value), 10, 64)
+			modifyIndex = kvPair.ModifyIndex
+		}
+		value := []byte(strconv.FormatUint(next+count, 10))
+		ok, _, err := c.api.KV().CAS(&consulapi.KVPair{Key: historyNextKey, Value: value, ModifyIndex: modifyIndex}, nil)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return next, nil
+		}
+	}
+	return 0, errors.New("unable to reserve history entries, too many concurrent writes")
+}
+
+// History returns the stored entries selected by the query, oldest first.
+func (c *ConsulAlertClient) History(query HistoryQuery) ([]HistoryEntry, error) {
+	c.history.RLock()
+	defer c.history.RUnlock()
+	if store := c.history.current(); store != nil {
+		return store.Query(query)
+	}
+	kvPairs, _, err := c.api.KV().List(historyEntriesPrefix, nil)
+	if err != nil {
+		apiErrors.Inc("history")
+		return nil, err
+	}
+	entries := make([]HistoryEntry, 0, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		// slots left over from a larger history size are ignored
+		slot, err := strconv.Atoi(strings.TrimPrefix(kvPair.Key, historyEntriesPrefix))
+		if err != nil || slot >= c.currentConfig().History.Size {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(kvPair.Value, &entry); err != nil || !query.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// recordTransition adds a check status change to the history.
+func (c *ConsulAlertClient) recordTransition(health *Check, from, to string) {
+	entry := HistoryEntry{
+		Type:       "transition",
+		Datacenter: health.Datacenter,
+		Node:       health.Node,
+		ServiceId:  health.ServiceID,
+		Service:    health.ServiceName,
+		CheckId:    health.CheckID,
+		Check:      health.Name,
+		From:       from,
+		Status:     to,
+		Output:     health.Output,
+		Time:       time.Now(),
+	}
+	if c.onTransition != nil {
+		c.onTransition(entry)
+	}
+	if err := c.RecordHistory([]HistoryEntry{entry}); err != nil {
+		checkLog(health).Warnln("Unable to record the status change in the history:", err)
+	}
+}
+
+// HistoryConfig returns the alert history configuration.
+func (c *ConsulAlertClient) HistoryConfig() *HistoryConfig {
+	return c.currentConfig().History
+}
+
+// SetHistoryStore records and reads the history in store instead of the
+// consul KV ring buffer, or in the ring buffer again when store is nil. It
+// returns once the previous store is no longer used, so it can be closed.
+func (c *ConsulAlertClient) SetHistoryStore(store HistoryStore) {
+	c.history.Lock()
+	c.history.store = store
+	c.history.Unlock()
+}
+
+// historyState is the SQL history store, the readers hold the read lock
+// while they use it so it's only swapped once they are done.
+type historyState struct {
+	sync.RWMutex
+	store HistoryStore
+}
+
+// RLock and RUnlock do nothing without a state, for the clients built
+// without NewClient.
+func (h *historyState) RLock() {
+	if h != nil {
+		h.RWMutex.RLock()
+	}
+}
+
+func (h *historyState) RUnlock() {
+	if h != nil {
+		h.RWMutex.RUnlock()
+	}
+}
+
+func (h *historyState) current() HistoryStore {
+	if h == nil {
+		return nil
+	}
+	return h.store
+}
+
+// OnTransition calls f with each check status change processed by this
+// client. It must be set before the checks are processed.
+func (c *ConsulAlertClient) OnTransition(f func(entry HistoryEntry)) {
+	c.onTransition = f
+}
+
+// loadHistoryBackend loads the history backend, "consul" or one of the SQL
+// backends.
+func loadHistoryBackend(backend *string, data []byte) error {
+	var val string
+	if err := loadCustomValue(&val, data, ConfigTypeString); err != nil {
+		return err
+	}
+	switch val {
+	case "consul", "sqlite", "postgres":
+	default:
+		return fmt.Errorf("expected consul, sqlite or postgres, got %q", val)
+	}
+	*backend = val
+	return nil
+}
+
+// loadHistorySize loads the history size, it needs at least one entry.
+func loadHistorySize(size *int, data []byte) error {
+	var val int
+	if err := loadCustomValue(&val, data, ConfigTypeInt); err != nil {
+		return err
+	}
+	if val < 1 {
+		return fmt.Errorf("expected a size of at least 1, got %d", val)
+	}
+	*size = val
+	return nil
+}
@@ -0,0 +1,82 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryQueryMatches(t *testing.T) {
+	now := time.Now()
+	entry := HistoryEntry{Time: now, Type: "notification", Node: "web-1", ServiceId: "nginx-1", Service: "nginx", CheckId: "service:nginx-1", Check: "HTTP"}
+
+	tests := []struct {
+		query   HistoryQuery
+		matches bool
+	}{
+		{HistoryQuery{}, true},
+		{HistoryQuery{Node: "web-*", Service: "nginx", Check: "HTTP"}, true},
+		{HistoryQuery{Type: "transition"}, false},
+		{HistoryQuery{Node: "db-*"}, false},
+		{HistoryQuery{Service: "nginx-1"}, true},
+		{HistoryQuery{Since: now.Add(-time.Hour)}, true},
+		{HistoryQuery{Since: now.Add(time.Minute)}, false},
+		{HistoryQuery{Until: now}, false},
+	}
+	for i, test := range tests {
+		if matches := test.query.Matches(entry); matches != test.matches {
+			t.Errorf("query %d: expected %t, got %t", i, test.matches, matches)
+		}
+	}
+}
+
+func TestLoadHistorySize(t *testing.T) {
+	size := 1000
+	if err := loadHistorySize(&size, []byte("0")); err == nil {
+		t.Error("expected an error for an empty history")
+	}
+	if err := loadHistorySize(&size, []byte("50")); err != nil || size != 50 {
+		t.Errorf("expected 50, got %d: %v", size, err)
+	}
+}
+
+type blockingStore struct {
+	recording chan struct{}
+	release   chan struct{}
+}
+
+func (s blockingStore) Record(entries []HistoryEntry) error {
+	close(s.recording)
+	<-s.release
+	return nil
+}
+
+func (s blockingStore) Query(query HistoryQuery) ([]HistoryEntry, error) {
+	return nil, nil
+}
+
+func TestSetHistoryStoreWaitsForWrites(t *testing.T) {
+	config := DefaultAlertConfig()
+	config.History.Enabled = true
+	client := &ConsulAlertClient{state: &configState{config: config}, history: &historyState{}}
+	store := blockingStore{make(chan struct{}), make(chan struct{})}
+	client.SetHistoryStore(store)
+
+	go client.RecordHistory([]HistoryEntry{{Type: "transition", Node: "web-1", Status: "critical"}})
+	<-store.recording
+	swapped := make(chan struct{})
+	go func() {
+		client.SetHistoryStore(nil)
+		close(swapped)
+	}()
+	select {
+	case <-swapped:
+		t.Fatal("expected the store to be swapped after the write")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(store.release)
+	select {
+	case <-swapped:
+	case <-time.After(time.Second):
+		t.Fatal("expected the store to be swapped once the write is done")
+	}
+}
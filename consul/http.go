@@ -0,0 +1,61 @@
+package consul
+
+import (
+	"regexp"
+)
+
+// httpKey matches the http settings of all the notifiers, without a notifier
+// name, or of one notifier.
+var httpKey = regexp.MustCompile(`^consul-alerts/config/notifiers/(?:([^/]+)/)?http/(proxy|ca-cert|headers)$`)
+
+// HttpConfig sets the proxy, the extra CA bundle and the extra headers the
+// HTTP notifiers use. The header values are templates.
+type HttpConfig struct {
+	Proxy   string
+	CACert  string
+	Headers map[string]string
+}
+
+// loadHttpValue loads an http setting and returns true if the key is one. The
+// settings of all the notifiers are kept under the empty name.
+func loadHttpValue(key string, data []byte, https map[string]HttpConfig) (bool, error) {
+	match := httpKey.FindStringSubmatch(key)
+	if match == nil {
+		return false, nil
+	}
+	config := https[match[1]]
+	var err error
+	switch match[2] {
+	case "proxy":
+		err = loadCustomValue(&config.Proxy, data, ConfigTypeString)
+	case "ca-cert":
+		err = loadCustomValue(&config.CACert, data, ConfigTypeString)
+	case "headers":
+		err = loadHeaders(&config.Headers, data)
+	}
+	https[match[1]] = config
+	return true, err
+}
+
+// NotifierHttp returns the http settings of a notifier. The settings of all
+// the notifiers are used for the ones the notifier doesn't set, the headers
+// of both are sent.
+func (c *ConsulAlertClient) NotifierHttp(name string) HttpConfig {
+	config := c.currentConfig().Notifiers.Http[name]
+	global := c.currentConfig().Notifiers.Http[""]
+	if config.Proxy == "" {
+		config.Proxy = global.Proxy
+	}
+	if config.CACert == "" {
+		config.CACert = global.CACert
+	}
+	headers := make(map[string]string, len(global.Headers)+len(config.Headers))
+	for name, value := range global.Headers {
+		headers[name] = value
+	}
+	for name, value := range config.Headers {
+		headers[name] = value
+	}
+	config.Headers = headers
+	return config
+}
@@ -0,0 +1,36 @@
+package consul
+
+import "testing"
+
+func TestNotifierHttp(t *testing.T) {
+	https := map[string]HttpConfig{}
+	for key, value := range map[string]string{
+		"consul-alerts/config/notifiers/http/proxy":         "http://proxy:3128",
+		"consul-alerts/config/notifiers/http/ca-cert":       "/etc/ssl/corp.pem",
+		"consul-alerts/config/notifiers/slack/http/proxy":   "socks5://proxy:1080",
+		"consul-alerts/config/notifiers/hipchat/http/proxy": "",
+
+		"consul-alerts/config/notifiers/http/headers":       `{"X-Api-Key": "global", "X-Env": "prod"}`,
+		"consul-alerts/config/notifiers/slack/http/headers": `{"X-Api-Key": "slack"}`,
+	} {
+		if loaded, err := loadHttpValue(key, []byte(value), https); !loaded || err != nil {
+			t.Fatal("unexpected result for", key, loaded, err)
+		}
+	}
+	if loaded, _ := loadHttpValue("consul-alerts/config/notifiers/slack/url", []byte("http://slack"), https); loaded {
+		t.Error("url isn't an http setting")
+	}
+
+	config := DefaultAlertConfig()
+	config.Notifiers.Http = https
+	client := &ConsulAlertClient{state: &configState{config: config}}
+	if slack := client.NotifierHttp("slack"); slack.Proxy != "socks5://proxy:1080" || slack.CACert != "/etc/ssl/corp.pem" {
+		t.Errorf("unexpected slack settings %+v", slack)
+	}
+	if headers := client.NotifierHttp("slack").Headers; headers["X-Api-Key"] != "slack" || headers["X-Env"] != "prod" {
+		t.Errorf("unexpected slack headers %v", headers)
+	}
+	if hipchat := client.NotifierHttp("hipchat"); hipchat.Proxy != "http://proxy:3128" {
+		t.Errorf("unexpected hipchat settings %+v", hipchat)
+	}
+}
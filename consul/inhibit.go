@@ -0,0 +1,138 @@
+package consul
+
+import (
+	"fmt"
+
+	"encoding/json"
+)
+
+// InhibitRule suppresses the alerts of the checks matching Target while a
+// check matching Source has the source status, critical by default, and the
+// same values of the Equal fields: "datacenter", "node" and "service". Equal
+// defaults to the node, eg. the failed serfHealth check of a node inhibits
+// the alerts of the other checks of this node.
+type InhibitRule struct {
+	Source InhibitMatcher `json:"source"`
+	Target InhibitMatcher `json:"target"`
+	Equal  []string       `json:"equal"`
+}
+
+// InhibitMatcher selects the checks by node, service and check patterns, and
+// for the sources by status.
+type InhibitMatcher struct {
+	Node    string `json:"node"`
+	Service string `json:"service"`
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+
+	node, service, check func(string) bool
+}
+
+func (m *InhibitMatcher) matches(check *Check) bool {
+	if m.node != nil && !m.node(check.Node) {
+		return false
+	}
+	if m.service != nil && !m.service(check.ServiceID) && !m.service(check.ServiceName) {
+		return false
+	}
+	if m.check != nil && !m.check(check.CheckID) && !m.check(check.Name) {
+		return false
+	}
+	return true
+}
+
+func (m *InhibitMatcher) compile() error {
+	for _, name := range []struct {
+		pattern string
+		matcher *func(string) bool
+	}{{m.Node, &m.node}, {m.Service, &m.service}, {m.Check, &m.check}} {
+		if name.pattern == "" {
+			continue
+		}
+		matcher, err := patternMatcher(name.pattern)
+		if err != nil {
+			return err
+		}
+		*name.matcher = matcher
+	}
+	return nil
+}
+
+// Inhibits returns true if the firing source check inhibits the target
+// check. A check doesn't inhibit itself.
+func (r *InhibitRule) Inhibits(source, target *Check) bool {
+	if source.Datacenter == target.Datacenter && source.Node == target.Node &&
+		source.ServiceID == target.ServiceID && source.CheckID == target.CheckID {
+		return false
+	}
+	if source.Status != r.Source.Status || !r.Source.matches(source) || !r.Target.matches(target) {
+		return false
+	}
+	for _, field := range r.Equal {
+		switch field {
+		case "datacenter":
+			if source.Datacenter != target.Datacenter {
+				return false
+			}
+		case "node":
+			if source.Node != target.Node {
+				return false
+			}
+		case "service":
+			if source.ServiceName != target.ServiceName {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *InhibitRule) compile() error {
+	if r.Source.Status == "" {
+		r.Source.Status = "critical"
+	}
+	if len(r.Equal) == 0 {
+		r.Equal = []string{"node"}
+	}
+	for _, field := range r.Equal {
+		switch field {
+		case "datacenter", "node", "service":
+		default:
+			return fmt.Errorf("expected datacenter, node or service in equal, got %q", field)
+		}
+	}
+	if err := r.Source.compile(); err != nil {
+		return err
+	}
+	return r.Target.compile()
+}
+
+// loadInhibitRules loads a JSON array of inhibit rules.
+func loadInhibitRules(rules *[]InhibitRule, data []byte) error {
+	var val []InhibitRule
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of {"source": {...}, "target": {...}, "equal": [...]} objects, got %q`, data)
+	}
+	for i := range val {
+		if val[i].Source.Node == "" && val[i].Source.Service == "" && val[i].Source.Check == "" {
+			return fmt.Errorf("inhibit rule %d: the source needs a node, service or check pattern", i)
+		}
+		if err := val[i].compile(); err != nil {
+			return fmt.Errorf("inhibit rule %d: %s", i, err)
+		}
+	}
+	*rules = val
+	return nil
+}
+
+// inhibitedBy returns the check inhibiting the target, nil if none does.
+func inhibitedBy(rules []InhibitRule, checks []*Check, target *Check) *Check {
+	for i := range rules {
+		for _, source := range checks {
+			if rules[i].Inhibits(source, target) {
+				return source
+			}
+		}
+	}
+	return nil
+}
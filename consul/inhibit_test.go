@@ -0,0 +1,43 @@
+package consul
+
+import (
+	"testing"
+)
+
+func TestInhibitRules(t *testing.T) {
+	var rules []InhibitRule
+	err := loadInhibitRules(&rules, []byte(`[{"source": {"check": "serfHealth"}}, {"source": {"service": "db", "status": "warning"}, "target": {"service": "web*"}, "equal": ["datacenter"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serfHealth := &Check{Node: "web1", CheckID: "serfHealth", Status: "critical"}
+	db := &Check{Datacenter: "dc1", Node: "db1", ServiceID: "db", ServiceName: "db", CheckID: "replication", Status: "warning"}
+	checks := []*Check{serfHealth, db}
+	cases := []struct {
+		target *Check
+		source *Check
+	}{
+		{&Check{Node: "web1", ServiceID: "web", ServiceName: "web", CheckID: "http"}, serfHealth},
+		{&Check{Node: "web2", ServiceID: "web", ServiceName: "web", CheckID: "http"}, nil},
+		{serfHealth, nil},
+		{&Check{Datacenter: "dc1", Node: "web2", ServiceID: "web-api", ServiceName: "web-api", CheckID: "http"}, db},
+		{&Check{Datacenter: "dc2", Node: "web2", ServiceID: "web-api", ServiceName: "web-api", CheckID: "http"}, nil},
+		{&Check{Datacenter: "dc1", Node: "cache1", ServiceID: "redis", ServiceName: "redis", CheckID: "ping"}, nil},
+	}
+	for _, c := range cases {
+		if source := inhibitedBy(rules, checks, c.target); source != c.source {
+			t.Errorf("expected %+v inhibited by %+v, got %+v", c.target, c.source, source)
+		}
+	}
+
+	serfHealth.Status = "passing"
+	if source := inhibitedBy(rules, checks, cases[0].target); source != nil {
+		t.Errorf("expected no inhibition by a passing check, got %+v", source)
+	}
+
+	for _, invalid := range []string{`[{"target": {"service": "web"}}]`, `[{"source": {"check": "serfHealth"}, "equal": ["rack"]}]`} {
+		if err := loadInhibitRules(&rules, []byte(invalid)); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const notifiersPrefix = "consul-alerts/config/notifiers/"
+
+// NotifierTypes are the builtin notifier types.
+var NotifierTypes = []string{"email", "log", "influxdb", "slack", "pagerduty", "hipchat", "victorops", "zulip", "discord", "nats", "mqtt", "splunk", "nrdp", "zabbix", "sentry", "aws", "pubsub"}
+
+// instanceTypeKey matches the type of a notifier instance.
+var instanceTypeKey = regexp.MustCompile(`^consul-alerts/config/notifiers/([^/]+)/type$`)
+
+// instanceSettings matches the settings kept under the instance name rather
+// than loaded as settings of its type.
+var instanceSettings = regexp.MustCompile(`^(type|timeout|output/.*|digest/.*|quiet-hours|working-hours|http/.*)$`)
+
+// NotifierInstance is a notifier of a builtin type with its own settings,
+// addressed by its name like a builtin notifier. Only the notifier of Type is
+// enabled in Notifiers.
+type NotifierInstance struct {
+	Type      string
+	Notifiers *NotifiersConfig
+}
+
+// loadNotifierInstances loads the notifier instances by name. The settings of
+// an instance are the keys of its type under consul-alerts/config/notifiers/
+// <name>/ instead of consul-alerts/config/notifiers/<type>/, and it is enabled
+// unless its enabled key says otherwise.
+func loadNotifierInstances(kvPairs consulapi.KVPairs) (map[string]NotifierInstance, []error) {
+	types := make(map[string]string)
+	var instanceErrors []error
+	for _, kvPair := range kvPairs {
+		match := instanceTypeKey.FindStringSubmatch(kvPair.Key)
+		if match == nil {
+			continue
+		}
+		name, notifierType := match[1], strings.TrimSpace(string(kvPair.Value))
+		switch {
+		case isNotifierType(name) || name == "custom" || name == "http":
+			instanceErrors = append(instanceErrors, fmt.Errorf("%s: %s is a reserved notifier name", kvPair.Key, name))
+		case !isNotifierType(notifierType):
+			instanceErrors = append(instanceErrors, fmt.Errorf("%s: unknown notifier type %q, expected one of %s", kvPair.Key, notifierType, strings.Join(NotifierTypes, ", ")))
+		default:
+			types[name] = notifierType
+		}
+	}
+
+	instances := make(map[string]NotifierInstance, len(types))
+	for name, notifierType := range types {
+		config, errs := loadConfigPairs(instancePairs(name, notifierType, kvPairs))
+		typePrefix := notifiersPrefix + notifierType + "/"
+		for _, err := range errs {
+			instanceErrors = append(instanceErrors, fmt.Errorf("%s", strings.Replace(err.Error(), typePrefix, notifiersPrefix+name+"/", -1)))
+		}
+		instances[name] = NotifierInstance{Type: notifierType, Notifiers: config.Notifiers}
+	}
+	sort.Slice(instanceErrors, func(i, j int) bool { return instanceErrors[i].Error() < instanceErrors[j].Error() })
+	return instances, instanceErrors
+}
+
+// instancePairs returns the settings of an instance as the settings of its
+// type, after the ones enabling its type only.
+func instancePairs(name, notifierType string, kvPairs consulapi.KVPairs) consulapi.KVPairs {
+	var pairs consulapi.KVPairs
+	for _, t := range NotifierTypes {
+		pairs = append(pairs, &consulapi.KVPair{Key: notifiersPrefix + t + "/enabled", Value: []byte(fmt.Sprint(t == notifierType))})
+	}
+	prefix := notifiersPrefix + name + "/"
+	for _, kvPair := range kvPairs {
+		if !strings.HasPrefix(kvPair.Key, prefix) {
+			continue
+		}
+		setting := strings.TrimPrefix(kvPair.Key, prefix)
+		if instanceSettings.MatchString(setting) {
+			continue
+		}
+		pairs = append(pairs, &consulapi.KVPair{Key: notifiersPrefix + notifierType + "/" + setting, Value: kvPair.Value})
+	}
+	return pairs
+}
+
+func isNotifierType(name string) bool {
+	for _, t := range NotifierTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifierInstances returns the notifier instances by name.
+func (c *ConsulAlertClient) NotifierInstances() map[string]NotifierInstance {
+	return c.currentConfig().Notifiers.Instances
+}
@@ -0,0 +1,48 @@
+package consul
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+func TestLoadNotifierInstances(t *testing.T) {
+	kvPairs := consulapi.KVPairs{
+		{Key: "consul-alerts/config/notifiers/email/url", Value: []byte("smtp.example.com")},
+		{Key: "consul-alerts/config/notifiers/ops-email/type", Value: []byte("email")},
+		{Key: "consul-alerts/config/notifiers/ops-email/url", Value: []byte("smtp.ops.example.com")},
+		{Key: "consul-alerts/config/notifiers/ops-email/receivers", Value: []byte(`["ops@example.com"]`)},
+		{Key: "consul-alerts/config/notifiers/ops-email/port", Value: []byte("many")},
+		{Key: "consul-alerts/config/notifiers/ops-email/output/max-lines", Value: []byte("10")},
+		{Key: "consul-alerts/config/notifiers/db-slack/type", Value: []byte("slack")},
+		{Key: "consul-alerts/config/notifiers/db-slack/channel", Value: []byte("#db")},
+		{Key: "consul-alerts/config/notifiers/db-slack/enabled", Value: []byte("false")},
+		{Key: "consul-alerts/config/notifiers/slack/type", Value: []byte("email")},
+		{Key: "consul-alerts/config/notifiers/pager/type", Value: []byte("sms")},
+	}
+	instances, errs := loadNotifierInstances(kvPairs)
+
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %v", instances)
+	}
+	email := instances["ops-email"]
+	if email.Type != "email" || !email.Notifiers.Email.Enabled || email.Notifiers.Email.Url != "smtp.ops.example.com" || email.Notifiers.Email.Receivers[0] != "ops@example.com" {
+		t.Errorf("unexpected email instance %+v", email.Notifiers.Email)
+	}
+	if email.Notifiers.Log.Enabled || email.Notifiers.Slack.Enabled {
+		t.Error("only the notifier of the instance type should be enabled")
+	}
+	if slack := instances["db-slack"]; slack.Notifiers.Slack.Enabled || slack.Notifiers.Slack.Channel != "#db" {
+		t.Errorf("unexpected slack instance %+v", slack.Notifiers.Slack)
+	}
+
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	for i, expected := range []string{"ops-email/port", "pager/type: unknown notifier type", "slack/type: slack is a reserved"} {
+		if !strings.Contains(errs[i].Error(), expected) {
+			t.Errorf("expected %q in %q", expected, errs[i])
+		}
+	}
+}
@@ -22,22 +22,140 @@ type Check struct {
 	Output      string
 	ServiceID   string
 	ServiceName string
+	ServiceTags []string
+	NodeMeta    map[string]string
+	Datacenter  string
+	Namespace   string
+	Type        string
+
+	// OriginalStatus is the status reported by consul when an override
+	// forced the Status.
+	OriginalStatus string
+
+	// NotifiedStatus is the status of the last alert sent for the check, set
+	// on the new alerts.
+	NotifiedStatus string
 }
 
 type ConsulAlertConfig struct {
-	Checks    *ChecksConfig
-	Events    *EventsConfig
-	Notifiers *NotifiersConfig
+	Checks      *ChecksConfig
+	Events      *EventsConfig
+	Keys        *KeysConfig
+	Remediation *RemediationConfig
+	Nodes       *NodesConfig
+	Services    *ServicesConfig
+	Cluster     *ClusterConfig
+	Routes      []Route
+	Profiles    *ProfilesConfig
+	Heartbeat   *HeartbeatConfig
+	History     *HistoryConfig
+	Notifiers   *NotifiersConfig
+	Leader      *LeaderConfig
+
+	Alertmanager *AlertmanagerConfig
 }
 
 type ChecksConfig struct {
-	Enabled         bool
-	ChangeThreshold int
+	Enabled           bool
+	ChangeThreshold   int
+	Datacenters       []string
+	BlacklistPatterns []BlacklistPattern
+	Whitelist         *WhitelistConfig
+
+	// InhibitRules suppress the alerts of the checks inhibited by a firing
+	// check, eg. the checks of a node whose serfHealth check is critical.
+	InhibitRules []InhibitRule
+
+	// Dependencies are the services each service depends on. The alerts of
+	// a service are rolled up into the alert of a critical dependency.
+	Dependencies map[string][]string
+
+	// MaintenanceNotices sends a notice when a node or service enters or
+	// exits maintenance. Their alerts are suppressed in any case.
+	MaintenanceNotices bool
+
+	// StaleAfter is the number of seconds by check type after which a check
+	// whose output and status haven't changed is notified as stale. The
+	// "default" entry applies to the other types.
+	StaleAfter map[string]int
+
+	// CoalesceWindow is the number of seconds without check updates waited
+	// before processing a burst of updates at once.
+	CoalesceWindow int
+}
+
+// KeysConfig configures the handlers run when the KV values under a prefix
+// change.
+type KeysConfig struct {
+	Handlers []KeyHandler
+}
+
+// NodesConfig configures the alerts sent when a node joins, leaves or fails.
+// Interval is the number of seconds between membership polls. Notifiers
+// limits the alerts to these notifiers, all of them when empty, and Receivers
+// replaces the email receivers of these alerts.
+type NodesConfig struct {
+	Enabled   bool
+	Interval  int
+	Notifiers []string
+	Receivers []string
+}
+
+// ServicesConfig configures the alerts sent when a service is registered or
+// deregistered, or has fewer healthy instances than MinHealthy. Monitored
+// lists the services to watch, all of them when empty. Interval is the number
+// of seconds between catalog polls.
+type ServicesConfig struct {
+	Enabled    bool
+	Interval   int
+	Monitored  []string
+	MinHealthy map[string]int
+}
+
+// ClusterConfig configures the alerts on the health of the Consul servers.
+// Interval is the number of seconds between polls. ExpectedPeers is the size
+// of the raft peer set used to compute the quorum, the largest peer set seen
+// when 0.
+type ClusterConfig struct {
+	Enabled       bool
+	Interval      int
+	ExpectedPeers int
+}
+
+// LeaderConfig configures the leader election session. Durations are in
+// seconds.
+type LeaderConfig struct {
+	SessionTTL      int
+	LockDelay       int
+	RetryInterval   int
+	SessionBehavior string
 }
 
 type EventsConfig struct {
 	Enabled  bool
-	Handlers []string
+	Handlers []EventHandler
+
+	// HandlerTimeout is the number of seconds a handler can run and Timeout
+	// the number of seconds all the handlers of an event can run. 0 disables
+	// the limit.
+	HandlerTimeout int
+	Timeout        int
+
+	// Concurrency is the number of events processed at the same time. The
+	// events with the same name are always processed in order.
+	Concurrency int
+
+	// WebhookRetries is the number of times a failed webhook handler is
+	// retried.
+	WebhookRetries int
+
+	// WebhookHeaders are added to the webhook requests, the values are
+	// templates.
+	WebhookHeaders map[string]string
+
+	// FailureAlerts sends an alert through the notifiers when a handler
+	// fails or times out.
+	FailureAlerts bool
 }
 
 type NotifiersConfig struct {
@@ -46,7 +164,57 @@ type NotifiersConfig struct {
 	Influxdb  *InfluxdbNotifierConfig
 	Slack     *SlackNotifierConfig
 	PagerDuty *PagerDutyNotifierConfig
+	HipChat   *HipChatNotifierConfig
+	VictorOps *VictorOpsNotifierConfig
+	Zulip     *ZulipNotifierConfig
+	Discord   *DiscordNotifierConfig
+	Nats      *NatsNotifierConfig
+	Mqtt      *MqttNotifierConfig
+	Splunk    *SplunkNotifierConfig
+	Nrdp      *NrdpNotifierConfig
+	Zabbix    *ZabbixNotifierConfig
+	Sentry    *SentryNotifierConfig
+	Aws       *AwsNotifierConfig
+	Pubsub    *PubsubNotifierConfig
 	Custom    []string
+	DryRun    bool
+
+	// ScheduleJitter is the maximum random delay in seconds added to each
+	// run of the reminders and the digests.
+	ScheduleJitter int
+
+	// Links are the links to dashboards or runbooks added to every alert.
+	Links []LinkTemplate
+
+	// Outputs holds the output limits of each notifier, by notifier name.
+	Outputs map[string]OutputConfig
+
+	// Digests holds the digest settings of each notifier, by notifier name.
+	Digests map[string]DigestConfig
+
+	// QuietHours holds the quiet and working hours of each notifier, by
+	// notifier name.
+	QuietHours map[string]QuietHoursConfig
+
+	// Timeouts holds the delivery timeout of each notifier in seconds, by
+	// notifier name.
+	Timeouts map[string]int
+
+	// Http holds the http settings of each notifier, by notifier name, and
+	// of all the notifiers under the empty name.
+	Http map[string]HttpConfig
+
+	// Instances holds the notifier instances by name.
+	Instances map[string]NotifierInstance
+}
+
+// OutputConfig limits the check outputs sent by a notifier. MaxBytes and
+// MaxLines keep the end of the output, Sanitize strips the ANSI escape codes
+// and control characters.
+type OutputConfig struct {
+	MaxBytes int
+	MaxLines int
+	Sanitize bool
 }
 
 type EmailNotifierConfig struct {
@@ -59,7 +227,29 @@ type EmailNotifierConfig struct {
 	SenderAlias string
 	SenderEmail string
 	Receivers   []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
 	Template    string
+
+	SubjectTemplate string
+
+	ServiceReceivers    map[string][]string
+	NodeReceivers       map[string][]string
+	DatacenterReceivers map[string][]string
+	DeliveryMode        string
+	MaxOutputSize       int
+
+	TLSMode            string
+	CACert             string
+	InsecureSkipVerify bool
+	AuthMode           string
+
+	OAuth2ClientId     string
+	OAuth2ClientSecret string
+	OAuth2RefreshToken string
+	OAuth2TokenUrl     string
+	OAuth2TokenCommand string
 }
 
 type LogNotifierConfig struct {
@@ -84,6 +274,12 @@ type SlackNotifierConfig struct {
 	Username    string
 	IconUrl     string
 	IconEmoji   string
+
+	// Token is a bot token used to thread the alerts of each check with the
+	// Web API instead of posting them to Url. ThreadWindow is the number of
+	// seconds a thread stays open after the check recovers.
+	Token        string
+	ThreadWindow int
 }
 
 type PagerDutyNotifierConfig struct {
@@ -93,6 +289,124 @@ type PagerDutyNotifierConfig struct {
 	ClientUrl  string
 }
 
+type HipChatNotifierConfig struct {
+	Enabled      bool
+	ClusterName  string
+	Url          string
+	RoomId       string
+	AuthToken    string
+	From         string
+	PassingColor string
+	WarningColor string
+	FailColor    string
+}
+
+type VictorOpsNotifierConfig struct {
+	Enabled    bool
+	Url        string
+	ApiKey     string
+	RoutingKey string
+}
+
+type ZulipNotifierConfig struct {
+	Enabled     bool
+	ClusterName string
+	Url         string
+	BotEmail    string
+	ApiKey      string
+	Stream      string
+	Topic       string
+}
+
+type DiscordNotifierConfig struct {
+	Enabled     bool
+	ClusterName string
+	Url         string
+	Username    string
+	AvatarUrl   string
+}
+
+type NatsNotifierConfig struct {
+	Enabled     bool
+	Url         string
+	Subject     string
+	JetStream   bool
+	Credentials string
+	Token       string
+	Username    string
+	Password    string
+	CACert      string
+	TLSCert     string
+	TLSKey      string
+}
+
+type MqttNotifierConfig struct {
+	Enabled  bool
+	Url      string
+	ClientId string
+	Topic    string
+	Qos      int
+	Retain   bool
+	Username string
+	Password string
+	CACert   string
+	TLSCert  string
+	TLSKey   string
+}
+
+type SplunkNotifierConfig struct {
+	Enabled    bool
+	Url        string
+	Token      string
+	Index      string
+	Source     string
+	SourceType string
+}
+
+type NrdpNotifierConfig struct {
+	Enabled bool
+	Url     string
+	Token   string
+	Host    string
+	Service string
+}
+
+type ZabbixNotifierConfig struct {
+	Enabled bool
+	Server  string
+	Host    string
+	Key     string
+	Numeric bool
+}
+
+type SentryNotifierConfig struct {
+	Enabled     bool
+	Dsn         string
+	Environment string
+	Level       string
+}
+
+type AwsNotifierConfig struct {
+	Enabled         bool
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	QueueUrl        string
+	EventBus        string
+	EventsEndpoint  string
+	Source          string
+	DetailType      string
+}
+
+type PubsubNotifierConfig struct {
+	Enabled         bool
+	Project         string
+	Topic           string
+	CredentialsFile string
+	Endpoint        string
+}
+
 type Status struct {
 	Current          string
 	CurrentTimestamp time.Time
@@ -100,30 +414,141 @@ type Status struct {
 	PendingTimestamp time.Time
 	HealthCheck      *Check
 	ForNotification  bool
+
+	NotifiedTimestamp time.Time
+
+	// NotifiedStatus is the status of the last alert sent for the check, a
+	// recovery is only sent if it isn't passing.
+	NotifiedStatus string
+
+	// RolledUpInto is the failing dependency whose alert listed the pending
+	// alert of the check, it is sent on its own once the dependency recovers.
+	RolledUpInto string
+
+	// OutputTimestamp is when the output or status of the check last changed
+	// and Stale whether it was notified as stale since.
+	OutputTimestamp time.Time
+	Stale           bool
+
+	// AcknowledgedTimestamp is when the current failure was acknowledged by
+	// AcknowledgedBy, it is cleared when the status changes.
+	AcknowledgedTimestamp time.Time
+	AcknowledgedBy        string
+	AcknowledgedComment   string
 }
 
 type Consul interface {
 	LoadConfig()
+	ReloadConfig() ([]ConfigChange, error)
+	ValidateConfig(validate func(client Consul) []string)
+	ConfigErrors() []error
+	Ping() error
+	RegisterSelf(id string, port int, ttl time.Duration) (string, error)
+	UpdateSelfCheck(checkId, status, note string) error
+	DeregisterSelf(id string) error
 
 	EventsEnabled() bool
 	ChecksEnabled() bool
-	EventHandlers(eventName string) []string
+	EventHandlers(eventName string) []EventHandler
+	EventHandlerTimeout() time.Duration
+	EventTimeout() time.Duration
+	EventConcurrency() int
+	EventWebhookRetries() int
+	EventWebhookHeaders() map[string]string
+	EventFailureAlerts() bool
+	LastEventLTime(name string) (uint, error)
+	KeyHandlers() []KeyHandler
+	SetLastEventLTime(name string, ltime uint) error
+	RemediationConfig() *RemediationConfig
+	RemediationAttempts(check *Check) (map[string]RemediationAttempts, error)
+	SetRemediationAttempts(check *Check, attempts map[string]RemediationAttempts) error
+
+	NodesConfig() *NodesConfig
+	Members() ([]Member, error)
+	ServicesConfig() *ServicesConfig
+	Services() ([]string, error)
+	HealthyInstances(service string) (int, error)
+	ClusterConfig() *ClusterConfig
+	HeartbeatConfig() *HeartbeatConfig
+	ConsulLeader() (string, error)
+	ConsulPeers() ([]string, error)
 
 	EmailConfig() *EmailNotifierConfig
 	LogConfig() *LogNotifierConfig
 	InfluxdbConfig() *InfluxdbNotifierConfig
 	SlackConfig() *SlackNotifierConfig
 	PagerDutyConfig() *PagerDutyNotifierConfig
+	HipChatConfig() *HipChatNotifierConfig
+	VictorOpsConfig() *VictorOpsNotifierConfig
+	ZulipConfig() *ZulipNotifierConfig
+	DiscordConfig() *DiscordNotifierConfig
+	NatsConfig() *NatsNotifierConfig
+	MqttConfig() *MqttNotifierConfig
+	SplunkConfig() *SplunkNotifierConfig
+	NrdpConfig() *NrdpNotifierConfig
+	ZabbixConfig() *ZabbixNotifierConfig
+	SentryConfig() *SentryNotifierConfig
+	AwsConfig() *AwsNotifierConfig
+	PubsubConfig() *PubsubNotifierConfig
+
+	WatchChecks(dc string, waitIndex uint64) ([]Check, uint64, error)
+	WatchEvents(waitIndex uint64) ([]Event, uint64, error)
+	WatchConfig(waitIndex uint64) (uint64, error)
+	WatchKeys(prefix string, waitIndex uint64) (map[string]string, uint64, error)
 
 	CheckChangeThreshold() int
+	CheckCoalesceWindow() int
+	Datacenters() []string
+	LocalDatacenter() string
 	UpdateCheckData()
+	MaintenanceNotices() bool
+	MaintenanceChanges() []MaintenanceChange
 	NewAlerts() []Check
+	MarkNotified(alerts []Check)
+	ProfilesConfig() *ProfilesConfig
+	CheckProfile(check *Check) *Profile
+	DueReminders(now time.Time) ([]Check, error)
+	StaleChanges(now time.Time) ([]StaleChange, error)
+
+	CircuitOpen() bool
+	OnCircuitChange(f func(open bool, err error))
 
 	IsBlacklisted(check *Check) bool
 
+	NotifierState(notifier, key string) ([]byte, error)
+	SetNotifierState(notifier, key string, value []byte) error
+
+	RecordHistory(entries []HistoryEntry) error
+	History(query HistoryQuery) ([]HistoryEntry, error)
+	HistoryConfig() *HistoryConfig
+	SetHistoryStore(store HistoryStore)
+	OnTransition(f func(entry HistoryEntry))
+
+	ExportState() (*StateSnapshot, error)
+	ImportState(snapshot *StateSnapshot) error
+
+	Silences() ([]Silence, error)
+	CreateSilence(silence *Silence) error
+	DeleteSilence(id string) error
+	IsSilenced(check *Check) bool
+	Acknowledge(node, check, by, comment string, now time.Time) ([]Check, error)
+
 	CustomNotifiers() []string
+	Routes() []Route
+	NotifierOutput(name string) OutputConfig
+	NotifierDigest(name string) DigestConfig
+	NotifierQuietHours(name string) QuietHoursConfig
+	Links() []LinkTemplate
+	NotifierTimeout(name string) time.Duration
+	NotifierHttp(name string) HttpConfig
+	NotifierInstances() map[string]NotifierInstance
+	AlertmanagerEnabled() bool
+	DryRun() bool
+	ScheduleJitter() int
 
-	CheckStatus(node, statusId, checkId string) (status, output string)
+	CheckStatus(dc, node, statusId, checkId string) (status, output string)
+	CheckStatuses(node string) ([]Status, error)
+	CatalogNode(dc, node string) (*CatalogNode, error)
 }
 
 func DefaultAlertConfig() *ConsulAlertConfig {
@@ -131,18 +556,89 @@ func DefaultAlertConfig() *ConsulAlertConfig {
 	checks := &ChecksConfig{
 		Enabled:         true,
 		ChangeThreshold: 60,
+		Datacenters:     []string{},
+		Whitelist:       &WhitelistConfig{},
+		InhibitRules:    []InhibitRule{},
+		Dependencies:    map[string][]string{},
+		StaleAfter:      map[string]int{},
+		CoalesceWindow:  2,
 	}
 
 	events := &EventsConfig{
-		Enabled:  true,
-		Handlers: []string{},
+		Enabled:        true,
+		Handlers:       []EventHandler{},
+		HandlerTimeout: 60,
+		Timeout:        300,
+		Concurrency:    4,
+		WebhookRetries: 3,
+		WebhookHeaders: map[string]string{},
+		FailureAlerts:  true,
+	}
+
+	keys := &KeysConfig{
+		Handlers: []KeyHandler{},
+	}
+
+	remediation := &RemediationConfig{
+		Handlers:    []RemediationHandler{},
+		Cooldown:    300,
+		MaxAttempts: 3,
+		Timeout:     60,
+		Notify:      true,
+	}
+
+	nodes := &NodesConfig{
+		Enabled:   false,
+		Interval:  30,
+		Notifiers: []string{},
+		Receivers: []string{},
+	}
+
+	services := &ServicesConfig{
+		Enabled:    false,
+		Interval:   30,
+		Monitored:  []string{},
+		MinHealthy: map[string]int{},
+	}
+
+	cluster := &ClusterConfig{
+		Enabled:  false,
+		Interval: 10,
+	}
+
+	heartbeat := &HeartbeatConfig{
+		Interval: 60,
+		Headers:  map[string]string{},
+	}
+
+	profiles := &ProfilesConfig{
+		Profiles: map[string]Profile{},
+		Checks:   map[string]string{},
+		Services: map[string]string{},
+		Nodes:    map[string]string{},
+	}
+
+	history := &HistoryConfig{
+		Enabled: false,
+		Size:    1000,
+		Backend: "consul",
+	}
+
+	alertmanager := &AlertmanagerConfig{
+		Enabled: false,
 	}
 
 	email := &EmailNotifierConfig{
-		ClusterName: "Consul-Alerts",
-		Enabled:     false,
-		SenderAlias: "Consul Alerts",
-		Receivers:   []string{},
+		ClusterName:      "Consul-Alerts",
+		Enabled:          false,
+		SenderAlias:      "Consul Alerts",
+		Receivers:        []string{},
+		Cc:               []string{},
+		Bcc:              []string{},
+		ServiceReceivers: map[string][]string{},
+		NodeReceivers:    map[string][]string{},
+
+		DatacenterReceivers: map[string][]string{},
 	}
 
 	log := &LogNotifierConfig{
@@ -156,26 +652,132 @@ func DefaultAlertConfig() *ConsulAlertConfig {
 	}
 
 	slack := &SlackNotifierConfig{
+		Enabled:      false,
+		ClusterName:  "Consul-Alerts",
+		ThreadWindow: 3600,
+	}
+
+	pagerduty := &PagerDutyNotifierConfig{
+		Enabled: false,
+	}
+
+	hipchat := &HipChatNotifierConfig{
 		Enabled:     false,
 		ClusterName: "Consul-Alerts",
 	}
 
-	pagerduty := &PagerDutyNotifierConfig{
+	victorops := &VictorOpsNotifierConfig{
+		Enabled: false,
+	}
+
+	zulip := &ZulipNotifierConfig{
+		Enabled:     false,
+		ClusterName: "Consul-Alerts",
+	}
+
+	discord := &DiscordNotifierConfig{
+		Enabled:     false,
+		ClusterName: "Consul-Alerts",
+	}
+
+	nats := &NatsNotifierConfig{
+		Enabled: false,
+		Url:     "nats://localhost:4222",
+		Subject: "consul-alerts.alerts",
+	}
+
+	mqtt := &MqttNotifierConfig{
+		Enabled:  false,
+		Url:      "tcp://localhost:1883",
+		ClientId: "consul-alerts",
+		Topic:    "consul-alerts/{datacenter}/{node}/{check}",
+		Qos:      1,
+		Retain:   true,
+	}
+
+	splunk := &SplunkNotifierConfig{
+		Enabled:    false,
+		Source:     "consul-alerts",
+		SourceType: "consul-alerts",
+	}
+
+	nrdp := &NrdpNotifierConfig{
+		Enabled: false,
+		Host:    "{node}",
+		Service: "{check}",
+	}
+
+	zabbix := &ZabbixNotifierConfig{
+		Enabled: false,
+		Server:  "localhost:10051",
+		Host:    "{node}",
+		Key:     "consul.check[{check-id}]",
+	}
+
+	sentry := &SentryNotifierConfig{
+		Enabled: false,
+		Level:   "error",
+	}
+
+	aws := &AwsNotifierConfig{
+		Enabled:    false,
+		Source:     "consul-alerts",
+		DetailType: "Consul Alert",
+	}
+
+	pubsub := &PubsubNotifierConfig{
 		Enabled: false,
 	}
 
 	notifiers := &NotifiersConfig{
-		Email:     email,
-		Log:       log,
-		Influxdb:  influxdb,
-		Slack:     slack,
-		PagerDuty: pagerduty,
-		Custom:    []string{},
+		Email:      email,
+		Log:        log,
+		Influxdb:   influxdb,
+		Slack:      slack,
+		PagerDuty:  pagerduty,
+		HipChat:    hipchat,
+		VictorOps:  victorops,
+		Zulip:      zulip,
+		Discord:    discord,
+		Nats:       nats,
+		Mqtt:       mqtt,
+		Splunk:     splunk,
+		Nrdp:       nrdp,
+		Zabbix:     zabbix,
+		Sentry:     sentry,
+		Aws:        aws,
+		Pubsub:     pubsub,
+		Custom:     []string{},
+		Outputs:    map[string]OutputConfig{},
+		Digests:    map[string]DigestConfig{},
+		QuietHours: map[string]QuietHoursConfig{},
+		Timeouts:   map[string]int{},
+		Http:       map[string]HttpConfig{},
+		Instances:  map[string]NotifierInstance{},
+	}
+
+	leader := &LeaderConfig{
+		SessionTTL:      30,
+		LockDelay:       15,
+		RetryInterval:   15,
+		SessionBehavior: "release",
 	}
 
 	return &ConsulAlertConfig{
-		Checks:    checks,
-		Events:    events,
-		Notifiers: notifiers,
+		Checks:      checks,
+		Events:      events,
+		Keys:        keys,
+		Remediation: remediation,
+		Nodes:       nodes,
+		Services:    services,
+		Cluster:     cluster,
+		Routes:      []Route{},
+		Profiles:    profiles,
+		Heartbeat:   heartbeat,
+		History:     history,
+		Notifiers:   notifiers,
+		Leader:      leader,
+
+		Alertmanager: alertmanager,
 	}
 }
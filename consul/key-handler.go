@@ -0,0 +1,53 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// KeyHandler is a command or webhook run when the KV values under Prefix
+// change.
+type KeyHandler struct {
+	Prefix  string `json:"prefix"`
+	Command string `json:"command"`
+}
+
+// loadKeyHandlers loads a JSON array of key handlers.
+func loadKeyHandlers(handlers *[]KeyHandler, data []byte) error {
+	var val []KeyHandler
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of {"prefix": ..., "command": ...} objects, got %q`, data)
+	}
+	for i := range val {
+		if val[i].Prefix == "" || val[i].Command == "" {
+			return errors.New("key handlers need a prefix and a command")
+		}
+		command, err := resolveSecrets(val[i].Command)
+		if err != nil {
+			return err
+		}
+		val[i].Command = command
+	}
+	*handlers = val
+	return nil
+}
+
+// WatchKeys runs a blocking query on a KV prefix and returns the values under
+// it.
+func (c *ConsulAlertClient) WatchKeys(prefix string, waitIndex uint64) (map[string]string, uint64, error) {
+	options := &consulapi.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime}
+	kvPairs, meta, err := c.watchApi.KV().List(prefix, options)
+	if err != nil {
+		apiErrors.Inc("watch_keys")
+		return nil, waitIndex, err
+	}
+	values := make(map[string]string, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		values[kvPair.Key] = string(kvPair.Value)
+	}
+	return values, meta.LastIndex, nil
+}
@@ -0,0 +1,287 @@
+package consul
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const minSessionTTL = 10 * time.Second
+
+// LeaderCandidate runs a leader election by acquiring a lock on LeadershipKey
+// with a consul session. It uses the client's consul connection so the ACL
+// token is used for every call.
+//
+// The session has a TTL and is renewed while the candidate runs. A shorter
+// TTL and lock delay make failover faster, at the risk of two instances
+// acting as the leader during network hiccups.
+type LeaderCandidate struct {
+	LeadershipKey   string
+	SessionTTL      time.Duration
+	LockDelay       time.Duration
+	RetryInterval   time.Duration
+	SessionBehavior string
+
+	// OnElected is called when the candidate becomes the leader.
+	OnElected func()
+
+	client   *ConsulAlertClient
+	leading  bool
+	lock     sync.Mutex
+	session  string
+	node     string
+	resigned bool
+}
+
+func (c *ConsulAlertClient) NewLeaderCandidate(leadershipKey string) *LeaderCandidate {
+	config := c.currentConfig().Leader
+	candidate := &LeaderCandidate{
+		LeadershipKey:   leadershipKey,
+		SessionTTL:      time.Duration(config.SessionTTL) * time.Second,
+		LockDelay:       time.Duration(config.LockDelay) * time.Second,
+		RetryInterval:   time.Duration(config.RetryInterval) * time.Second,
+		SessionBehavior: config.SessionBehavior,
+		client:          c,
+	}
+	// consul rejects session TTLs below 10s
+	if candidate.SessionTTL < minSessionTTL {
+		candidate.SessionTTL = minSessionTTL
+	}
+	if candidate.RetryInterval < time.Second {
+		candidate.RetryInterval = time.Second
+	}
+	if candidate.SessionBehavior != "delete" {
+		candidate.SessionBehavior = "release"
+	}
+	return candidate
+}
+
+// RunForElection makes the candidate run for leadership in the background.
+func (l *LeaderCandidate) RunForElection() {
+	go l.campaign()
+}
+
+// IsLeader returns true if the current agent is the leader.
+func (l *LeaderCandidate) IsLeader() bool {
+	session := l.currentSession()
+	if session == "" {
+		return false
+	}
+	kv, _, err := l.client.api.KV().Get(l.LeadershipKey, nil)
+	if err != nil {
+		apiErrors.Inc("leader")
+		log.Errorln("Unable to check for leadership:", err)
+		return false
+	}
+	if kv == nil {
+		log.Warnf("Leadership key '%s' is missing in Consul KV.", l.LeadershipKey)
+		return false
+	}
+	return kv.Session == session
+}
+
+// Leading returns true if the candidate was the leader the last time consul
+// answered. Unlike IsLeader it doesn't call consul, so it still answers while
+// consul is failing.
+func (l *LeaderCandidate) Leading() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.leading
+}
+
+// Leader returns the node of the current leader, or an empty string if there
+// is no leader.
+func (l *LeaderCandidate) Leader() string {
+	kv, _, err := l.client.api.KV().Get(l.LeadershipKey, nil)
+	if kv == nil || err != nil || kv.Session == "" {
+		log.Debugln("There is no leader.")
+		return ""
+	}
+	return string(kv.Value)
+}
+
+// Resign releases the leadership lock and destroys the session so another
+// instance can take over without waiting for the session TTL. The candidate
+// stops running for election.
+func (l *LeaderCandidate) Resign() {
+	l.lock.Lock()
+	l.resigned = true
+	l.lock.Unlock()
+
+	session := l.currentSession()
+	if session == "" {
+		return
+	}
+	if l.IsLeader() {
+		kvpair := &consulapi.KVPair{
+			Key:     l.LeadershipKey,
+			Value:   []byte(l.node),
+			Session: session,
+		}
+		if success, _, err := l.client.api.KV().Release(kvpair, nil); !success || err != nil {
+			log.Warnf("%s was unable to step down as a leader", l.node)
+		} else {
+			log.Debugf("%s is no longer the leader.", l.node)
+		}
+	}
+	if _, err := l.client.api.Session().Destroy(session, nil); err != nil {
+		apiErrors.Inc("leader")
+		log.Warnln("Unable to destroy the leader session:", err)
+	}
+	l.setSession("")
+}
+
+func (l *LeaderCandidate) campaign() {
+	l.retrieveNode()
+	l.destroyStaleSessions()
+
+	go l.renew()
+
+	for !l.hasResigned() {
+		session := l.currentSession()
+		if session == "" {
+			// a session created after Resign is destroyed by createSession
+			session = l.createSession()
+			if l.hasResigned() {
+				return
+			}
+		}
+		if session == "" {
+			time.Sleep(l.RetryInterval)
+			continue
+		}
+
+		log.Debugf("%s is running for election with session %s.", l.node, session)
+		kvpair := &consulapi.KVPair{
+			Key:     l.LeadershipKey,
+			Value:   []byte(l.node),
+			Session: session,
+		}
+		acquired, _, err := l.client.api.KV().Acquire(kvpair, nil)
+		if err != nil {
+			apiErrors.Inc("leader")
+			log.Errorln("Failed to run Consul KV Acquire:", err)
+		}
+		// the leadership is only known to be lost when consul answered
+		if err == nil {
+			if acquired && !l.Leading() {
+				log.Infof("%s has become the leader.", l.node)
+				if l.OnElected != nil {
+					go l.OnElected()
+				}
+			}
+			l.lock.Lock()
+			l.leading = acquired
+			l.lock.Unlock()
+		}
+
+		kv, _, err := l.client.api.KV().Get(l.LeadershipKey, nil)
+		if err != nil || kv == nil || kv.Session == "" {
+			time.Sleep(l.RetryInterval)
+			continue
+		}
+		log.Debugf("%s is the current leader.", string(kv.Value))
+		log.Debugf("%s is waiting for changes in '%s'.", l.node, l.LeadershipKey)
+		options := &consulapi.QueryOptions{
+			WaitIndex: kv.ModifyIndex,
+			WaitTime:  l.RetryInterval,
+		}
+		if _, _, err := l.client.watchApi.KV().Get(l.LeadershipKey, options); err != nil {
+			time.Sleep(l.RetryInterval)
+		}
+	}
+}
+
+// renew keeps the session alive until the candidate resigns. A session that
+// is gone is dropped so the campaign creates a new one.
+func (l *LeaderCandidate) renew() {
+	interval := l.SessionTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if l.hasResigned() {
+			return
+		}
+		session := l.currentSession()
+		if session == "" {
+			continue
+		}
+		expired, err := l.client.renewSession(session)
+		switch {
+		case expired:
+			log.Warnf("Leader session %s has expired.", session)
+			l.setSession("")
+		case err != nil:
+			apiErrors.Inc("leader")
+			log.Warnln("Unable to renew the leader session:", err)
+		}
+	}
+}
+
+func (l *LeaderCandidate) createSession() string {
+	session, err := l.client.createSession(l.LeadershipKey, l.SessionTTL, l.LockDelay, l.SessionBehavior)
+	if err != nil {
+		apiErrors.Inc("leader")
+		log.Errorln("Unable to create new sessions:", err)
+		return ""
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.resigned {
+		l.client.api.Session().Destroy(session, nil)
+		return ""
+	}
+	l.session = session
+	return session
+}
+
+// destroyStaleSessions removes the sessions left by a previous run on this
+// node so their locks don't block the election.
+func (l *LeaderCandidate) destroyStaleSessions() {
+	sessions, _, err := l.client.api.Session().List(nil)
+	if err != nil {
+		apiErrors.Inc("leader")
+		log.Warnln("Unable to retrieve list of sessions.")
+		return
+	}
+	for _, session := range sessions {
+		if session.Name == l.LeadershipKey && session.Node == l.node {
+			l.client.api.Session().Destroy(session.ID, nil)
+		}
+	}
+}
+
+func (l *LeaderCandidate) retrieveNode() {
+	agent, err := l.client.api.Agent().Self()
+	if err != nil {
+		apiErrors.Inc("leader")
+		log.Warnln("Unable to retrieve node name.")
+		return
+	}
+	if nodeName, ok := agent["Config"]["NodeName"].(string); ok {
+		l.node = nodeName
+	}
+}
+
+func (l *LeaderCandidate) hasResigned() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.resigned
+}
+
+func (l *LeaderCandidate) currentSession() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.session
+}
+
+func (l *LeaderCandidate) setSession(session string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.session = session
+}
@@ -0,0 +1,38 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLeaderCandidateDefaults(t *testing.T) {
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	candidate := client.NewLeaderCandidate("consul-alerts/leader")
+	if candidate.SessionTTL != 30*time.Second {
+		t.Errorf("expected 30s session ttl, got %s", candidate.SessionTTL)
+	}
+	if candidate.LockDelay != 15*time.Second {
+		t.Errorf("expected 15s lock delay, got %s", candidate.LockDelay)
+	}
+	if candidate.SessionBehavior != "release" {
+		t.Errorf("expected release behavior, got %s", candidate.SessionBehavior)
+	}
+}
+
+func TestNewLeaderCandidateLimits(t *testing.T) {
+	config := DefaultAlertConfig()
+	config.Leader.SessionTTL = 1
+	config.Leader.RetryInterval = 0
+	config.Leader.SessionBehavior = "unknown"
+	client := &ConsulAlertClient{state: &configState{config: config}}
+	candidate := client.NewLeaderCandidate("consul-alerts/leader")
+	if candidate.SessionTTL != minSessionTTL {
+		t.Errorf("session ttl should be at least %s, got %s", minSessionTTL, candidate.SessionTTL)
+	}
+	if candidate.RetryInterval != time.Second {
+		t.Errorf("retry interval should be at least 1s, got %s", candidate.RetryInterval)
+	}
+	if candidate.SessionBehavior != "release" {
+		t.Errorf("unknown behavior should fall back to release, got %s", candidate.SessionBehavior)
+	}
+}
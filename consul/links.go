@@ -0,0 +1,54 @@
+package consul
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"encoding/json"
+)
+
+// LinkTemplate is a link added to every alert, like a dashboard or a runbook
+// page. Url is a text/template of the alert, eg.
+// https://grafana.example.com/d/node?var-node={{ .Node }}.
+type LinkTemplate struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+
+	url *template.Template
+}
+
+// Expand renders the link url for an alert. An empty url means the link
+// doesn't apply to the alert.
+func (l *LinkTemplate) Expand(alert interface{}) (string, error) {
+	var url bytes.Buffer
+	if err := l.url.Execute(&url, alert); err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
+
+// loadLinks loads a JSON array of link templates.
+func loadLinks(links *[]LinkTemplate, data []byte) error {
+	var val []LinkTemplate
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of {"name": ..., "url": ...} objects, got %q`, data)
+	}
+	for i := range val {
+		if val[i].Name == "" || val[i].Url == "" {
+			return fmt.Errorf("link %d: a name and an url are required", i)
+		}
+		tmpl, err := template.New(val[i].Name).Option("missingkey=zero").Parse(val[i].Url)
+		if err != nil {
+			return fmt.Errorf("link %d: %s", i, err)
+		}
+		val[i].url = tmpl
+	}
+	*links = val
+	return nil
+}
+
+// Links returns the link templates added to the alerts.
+func (c *ConsulAlertClient) Links() []LinkTemplate {
+	return c.currentConfig().Notifiers.Links
+}
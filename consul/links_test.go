@@ -0,0 +1,29 @@
+package consul
+
+import "testing"
+
+func TestLoadLinks(t *testing.T) {
+	var links []LinkTemplate
+	data := []byte(`[
+		{"name": "Grafana", "url": "https://grafana.example.com/d/node?var-node={{ .Node | urlquery }}"},
+		{"name": "Runbook", "url": "{{ with .Service }}https://wiki.example.com/runbooks/{{ . }}{{ end }}"}
+	]`)
+	if err := loadLinks(&links, data); err != nil {
+		t.Fatal(err)
+	}
+
+	alert := struct{ Node, Service string }{"web 1", ""}
+	if url, err := links[0].Expand(alert); err != nil || url != "https://grafana.example.com/d/node?var-node=web+1" {
+		t.Errorf("unexpected grafana link %q %v", url, err)
+	}
+	if url, _ := links[1].Expand(alert); url != "" {
+		t.Errorf("the runbook link should be empty for a node check, got %q", url)
+	}
+
+	if err := loadLinks(&links, []byte(`[{"name": "Kibana", "url": "{{ .Node"}]`)); err == nil {
+		t.Error("an invalid template should be rejected")
+	}
+	if err := loadLinks(&links, []byte(`[{"url": "https://kibana.example.com"}]`)); err == nil {
+		t.Error("a link without a name should be rejected")
+	}
+}
@@ -0,0 +1,108 @@
+package consul
+
+import (
+	"strings"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const (
+	nodeMaintenanceCheck    = "_node_maintenance"
+	serviceMaintenanceCheck = "_service_maintenance:"
+)
+
+// Maintenance is a node, or a service when ServiceID is set, in consul
+// maintenance mode. Reason is the reason given when enabling it.
+type Maintenance struct {
+	Datacenter  string
+	Node        string
+	ServiceID   string
+	ServiceName string
+	Reason      string
+}
+
+// MaintenanceChange is a node or service entering or exiting maintenance.
+type MaintenanceChange struct {
+	Maintenance
+	Entered bool
+}
+
+func (m Maintenance) key() string {
+	return m.Datacenter + "/" + m.Node + "/" + m.ServiceID
+}
+
+func isMaintenanceCheck(checkId string) bool {
+	return checkId == nodeMaintenanceCheck || strings.HasPrefix(checkId, serviceMaintenanceCheck)
+}
+
+// findMaintenance returns the nodes and services in maintenance from the
+// maintenance checks, by key.
+func findMaintenance(dc string, healths []*consulapi.HealthCheck) map[string]Maintenance {
+	maintenance := make(map[string]Maintenance)
+	for _, health := range healths {
+		if !isMaintenanceCheck(health.CheckID) {
+			continue
+		}
+		m := Maintenance{
+			Datacenter:  dc,
+			Node:        health.Node,
+			ServiceID:   health.ServiceID,
+			ServiceName: health.ServiceName,
+			Reason:      health.Notes,
+		}
+		maintenance[m.key()] = m
+	}
+	return maintenance
+}
+
+// inMaintenance returns true if the node or the service of the check is in
+// maintenance.
+func (c *ConsulAlertClient) inMaintenance(check *Check) bool {
+	node := Maintenance{Datacenter: check.Datacenter, Node: check.Node}
+	if _, found := c.maintenance[node.key()]; found {
+		return true
+	}
+	if check.ServiceID == "" {
+		return false
+	}
+	service := Maintenance{Datacenter: check.Datacenter, Node: check.Node, ServiceID: check.ServiceID}
+	_, found := c.maintenance[service.key()]
+	return found
+}
+
+// updateMaintenance records the nodes and services in maintenance in a
+// datacenter, and the changes since the last update. The first update of a
+// datacenter has no changes so a restart doesn't report every ongoing
+// maintenance again.
+func (c *ConsulAlertClient) updateMaintenance(dc string, current map[string]Maintenance) {
+	if c.maintenance == nil {
+		c.maintenance = make(map[string]Maintenance)
+		c.maintenanceLoaded = make(map[string]bool)
+	}
+	report := c.maintenanceLoaded[dc]
+	c.maintenanceLoaded[dc] = true
+	for key, m := range current {
+		if _, found := c.maintenance[key]; !found {
+			c.maintenance[key] = m
+			if report {
+				c.maintenanceChanges = append(c.maintenanceChanges, MaintenanceChange{m, true})
+			}
+		}
+	}
+	for key, m := range c.maintenance {
+		if _, found := current[key]; !found && m.Datacenter == dc {
+			delete(c.maintenance, key)
+			if report {
+				c.maintenanceChanges = append(c.maintenanceChanges, MaintenanceChange{m, false})
+			}
+		}
+	}
+}
+
+// MaintenanceChanges returns the nodes and services that entered or exited
+// maintenance since the last call.
+func (c *ConsulAlertClient) MaintenanceChanges() []MaintenanceChange {
+	changes := c.maintenanceChanges
+	c.maintenanceChanges = nil
+	return changes
+}
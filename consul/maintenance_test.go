@@ -0,0 +1,42 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+func TestUpdateMaintenance(t *testing.T) {
+	c := &ConsulAlertClient{}
+	healths := []*consulapi.HealthCheck{
+		{Node: "web-01", CheckID: "_node_maintenance", Notes: "kernel upgrade"},
+		{Node: "db-01", CheckID: "_service_maintenance:redis-1", ServiceID: "redis-1", ServiceName: "redis"},
+		{Node: "db-01", CheckID: "serfHealth"},
+	}
+	c.updateMaintenance("dc1", findMaintenance("dc1", healths))
+	if changes := c.MaintenanceChanges(); len(changes) != 0 {
+		t.Errorf("the first update shouldn't report changes, got %v", changes)
+	}
+	for _, check := range []Check{
+		{Datacenter: "dc1", Node: "web-01", CheckID: "disk"},
+		{Datacenter: "dc1", Node: "db-01", ServiceID: "redis-1", CheckID: "redis"},
+	} {
+		if !c.inMaintenance(&check) {
+			t.Errorf("%+v should be in maintenance", check)
+		}
+	}
+	if c.inMaintenance(&Check{Datacenter: "dc1", Node: "db-01", CheckID: "serfHealth"}) {
+		t.Error("db-01 shouldn't be in maintenance")
+	}
+
+	c.updateMaintenance("dc1", findMaintenance("dc1", healths[1:]))
+	changes := c.MaintenanceChanges()
+	if len(changes) != 1 || changes[0].Node != "web-01" || changes[0].Entered {
+		t.Errorf("expected web-01 to exit maintenance, got %v", changes)
+	}
+	c.updateMaintenance("dc1", findMaintenance("dc1", healths))
+	changes = c.MaintenanceChanges()
+	if len(changes) != 1 || changes[0].Node != "web-01" || !changes[0].Entered || changes[0].Reason != "kernel upgrade" {
+		t.Errorf("expected web-01 to enter maintenance, got %v", changes)
+	}
+}
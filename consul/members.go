@@ -0,0 +1,43 @@
+package consul
+
+// Member is a node of the gossip pool with its serf status: alive, leaving,
+// left or failed.
+type Member struct {
+	Name   string
+	Addr   string
+	Status string
+}
+
+// memberStatus names the serf member status codes.
+func memberStatus(status int) string {
+	switch status {
+	case 1:
+		return "alive"
+	case 2:
+		return "leaving"
+	case 3:
+		return "left"
+	case 4:
+		return "failed"
+	default:
+		return "none"
+	}
+}
+
+// Members returns the LAN members seen by the agent.
+func (c *ConsulAlertClient) Members() ([]Member, error) {
+	agentMembers, err := c.api.Agent().Members(false)
+	if err != nil {
+		apiErrors.Inc("members")
+		return nil, err
+	}
+	members := make([]Member, 0, len(agentMembers))
+	for _, member := range agentMembers {
+		members = append(members, Member{
+			Name:   member.Name,
+			Addr:   member.Addr,
+			Status: memberStatus(member.Status),
+		})
+	}
+	return members, nil
+}
@@ -0,0 +1,30 @@
+package consul
+
+import (
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const notifierStatePrefix = "consul-alerts/notifier-state/"
+
+// NotifierState returns the value a notifier stored under key, nil if there
+// is none.
+func (c *ConsulAlertClient) NotifierState(notifier, key string) ([]byte, error) {
+	kvPair, _, err := c.api.KV().Get(notifierStatePrefix+notifier+"/"+key, nil)
+	if err != nil {
+		apiErrors.Inc("notifier_state")
+		return nil, err
+	}
+	if kvPair == nil {
+		return nil, nil
+	}
+	return kvPair.Value, nil
+}
+
+// SetNotifierState stores a notifier value under key.
+func (c *ConsulAlertClient) SetNotifierState(notifier, key string, value []byte) error {
+	if _, err := c.api.KV().Put(&consulapi.KVPair{Key: notifierStatePrefix + notifier + "/" + key, Value: value}, nil); err != nil {
+		apiErrors.Inc("notifier_state")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const overridePrefix = "consul-alerts/overrides/"
+
+// CheckOverride forces the status of a check, eg. to downgrade a known warning
+// to passing or to force a check critical during a manual incident. It's
+// stored under the same path as the check status and applied until ExpiresAt,
+// forever when empty.
+type CheckOverride struct {
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	By        string    `json:"by"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Active returns true if the override is in effect at the given time.
+func (o *CheckOverride) Active(now time.Time) bool {
+	return o.ExpiresAt.IsZero() || now.Before(o.ExpiresAt)
+}
+
+// Apply forces the status of the check and labels its output with the
+// override so the notifications tell it apart from the reported status.
+func (o *CheckOverride) Apply(check *Check) {
+	label := fmt.Sprintf("Status overridden from %s to %s", check.Status, o.Status)
+	if o.By != "" {
+		label += " by " + o.By
+	}
+	if o.Reason != "" {
+		label += ": " + o.Reason
+	}
+	check.OriginalStatus = check.Status
+	check.Status = o.Status
+	check.Output = label + ".\n\n" + check.Output
+}
+
+func (o *CheckOverride) validate() error {
+	switch o.Status {
+	case "passing", "warning", "critical":
+		return nil
+	}
+	return fmt.Errorf("expected passing, warning or critical status, got %q", o.Status)
+}
+
+// overrideKey is the key of the override of a check, under the same path as
+// its status.
+func (c *ConsulAlertClient) overrideKey(check *Check) string {
+	key := c.checkKey(check.Datacenter, check.Node, check.ServiceID, check.CheckID)
+	return overridePrefix + strings.TrimPrefix(key, "consul-alerts/checks/")
+}
+
+// overrides returns the active overrides by key. Invalid overrides are
+// logged and ignored.
+func (c *ConsulAlertClient) overrides(now time.Time) (map[string]CheckOverride, error) {
+	kvPairs, _, err := c.api.KV().List(overridePrefix, nil)
+	if err != nil {
+		apiErrors.Inc("list_overrides")
+		return nil, err
+	}
+	overrides := make(map[string]CheckOverride, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		if strings.HasSuffix(kvPair.Key, "/") {
+			continue
+		}
+		var override CheckOverride
+		if err := json.Unmarshal(kvPair.Value, &override); err != nil {
+			log.Warnf("Unable to read override %s: %s", kvPair.Key, err)
+			continue
+		}
+		if err := override.validate(); err != nil {
+			log.Warnf("Invalid override %s: %s", kvPair.Key, err)
+			continue
+		}
+		if override.Active(now) {
+			overrides[kvPair.Key] = override
+		}
+	}
+	return overrides, nil
+}
@@ -0,0 +1,63 @@
+package consul
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestCheckOverrideApply(t *testing.T) {
+	check := Check{Node: "db1", CheckID: "disk", Status: "warning", Output: "disk at 91%"}
+	override := CheckOverride{Status: "passing", Reason: "known issue", By: "ops"}
+	override.Apply(&check)
+
+	if check.Status != "passing" || check.OriginalStatus != "warning" {
+		t.Errorf("unexpected statuses %s %s", check.Status, check.OriginalStatus)
+	}
+	if check.Output != "Status overridden from warning to passing by ops: known issue.\n\ndisk at 91%" {
+		t.Errorf("unexpected output %q", check.Output)
+	}
+}
+
+func TestCheckOverrideActive(t *testing.T) {
+	now := time.Now()
+	if !(&CheckOverride{}).Active(now) {
+		t.Error("an override without expiry should be active")
+	}
+	if (&CheckOverride{ExpiresAt: now.Add(-time.Minute)}).Active(now) {
+		t.Error("an expired override should not be active")
+	}
+	if err := (&CheckOverride{Status: "unknown"}).validate(); err == nil {
+		t.Error("an unknown status should be rejected")
+	}
+}
+
+func TestOverridesUnavailableSkipsUpdate(t *testing.T) {
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/health/state/any":
+			w.Write([]byte(`[{"Node": "db1", "CheckID": "disk", "Name": "Disk", "Status": "warning"}]`))
+		case strings.HasPrefix(r.URL.Path, "/v1/kv/"+overridePrefix):
+			w.WriteHeader(500)
+		case r.Method == "PUT":
+			writes = append(writes, r.URL.Path)
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	if err := client.connect(ClientConfig{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	client.updateDatacenterCheckData("dc1")
+	if len(writes) > 0 {
+		t.Errorf("expected the checks left as they are without their overrides, got writes %v", writes)
+	}
+}
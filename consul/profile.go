@@ -0,0 +1,264 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"encoding/json"
+)
+
+const profilePrefix = "consul-alerts/config/profiles/"
+
+const profileSelectionPrefix = "consul-alerts/config/profile-selection/"
+
+const profilePatternsKey = profileSelectionPrefix + "patterns"
+
+// defaultProfile is the profile of the checks not bound to any profile.
+const defaultProfile = "default"
+
+// Profile changes how the alerts of the checks bound to it are sent.
+// ChangeThreshold is the number of seconds a status must hold before it is
+// notified, the checks change threshold when 0. ReminderInterval is the number
+// of minutes between the reminders of a check that is still failing, 0 sends
+// none. Notifiers limits the alerts to these notifiers, all of them when
+// empty. OutputChanges also notifies the failing checks whose output changed
+// while their status didn't, the parts of the outputs matching the
+// OutputIgnore regular expression and the whitespace left out.
+type Profile struct {
+	ChangeThreshold  int      `json:"change-threshold"`
+	ReminderInterval int      `json:"reminder-interval"`
+	Notifiers        []string `json:"notifiers"`
+	OutputChanges    bool     `json:"output-changes"`
+	OutputIgnore     string   `json:"output-ignore"`
+
+	outputIgnore *regexp.Regexp
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// OutputChanged returns true if the output changed materially, for the
+// profiles notifying the output changes.
+func (p *Profile) OutputChanged(previous, current string) bool {
+	if !p.OutputChanges {
+		return false
+	}
+	normalize := func(output string) string {
+		if p.outputIgnore != nil {
+			output = p.outputIgnore.ReplaceAllString(output, "")
+		}
+		return strings.TrimSpace(whitespace.ReplaceAllString(output, " "))
+	}
+	return normalize(previous) != normalize(current)
+}
+
+// ProfilesConfig holds the profiles by name, the profile bound to each check,
+// service and node name, and the profile patterns in precedence order.
+type ProfilesConfig struct {
+	Profiles map[string]Profile
+	Checks   map[string]string
+	Services map[string]string
+	Nodes    map[string]string
+	Patterns []ProfilePattern
+}
+
+// ProfilePattern binds the checks matching its node, service and check
+// patterns to a profile. Like the blacklist patterns, a pattern between
+// slashes like /^redis-.*/ is a regular expression, otherwise a glob.
+type ProfilePattern struct {
+	Node    string `json:"node"`
+	Service string `json:"service"`
+	Check   string `json:"check"`
+	Profile string `json:"profile"`
+
+	pattern BlacklistPattern
+}
+
+func (p *ProfilePattern) compile() error {
+	if p.Profile == "" {
+		return errors.New("profile is required")
+	}
+	p.pattern = BlacklistPattern{Node: p.Node, Service: p.Service, Check: p.Check}
+	return p.pattern.compile()
+}
+
+// Select returns the name of the profile of a check: the one bound to its
+// check id or name, else to its service id or name, else to its node, else
+// the first matching pattern, else the "default" profile. It returns "" when
+// none applies.
+func (p *ProfilesConfig) Select(check *Check) string {
+	bindings := []struct {
+		names map[string]string
+		keys  []string
+	}{
+		{p.Checks, []string{check.CheckID, check.Name}},
+		{p.Services, []string{check.ServiceID, check.ServiceName}},
+		{p.Nodes, []string{check.Node}},
+	}
+	for _, binding := range bindings {
+		for _, key := range binding.keys {
+			if name, found := binding.names[key]; found && key != "" {
+				return name
+			}
+		}
+	}
+	for _, pattern := range p.Patterns {
+		if pattern.pattern.Matches(check) {
+			return pattern.Profile
+		}
+	}
+	if _, found := p.Profiles[defaultProfile]; found {
+		return defaultProfile
+	}
+	return ""
+}
+
+// loadProfileValue loads a profile or a profile binding and returns true if
+// the key is one.
+func loadProfileValue(key string, data []byte, profiles *ProfilesConfig) (bool, error) {
+	switch {
+	case strings.HasPrefix(key, profilePrefix) && key != profilePrefix:
+		var profile Profile
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return true, fmt.Errorf(`expected a JSON object like {"change-threshold": 300, "reminder-interval": 60, "notifiers": ["email"]}, got %q`, data)
+		}
+		if profile.OutputIgnore != "" {
+			ignore, err := regexp.Compile(profile.OutputIgnore)
+			if err != nil {
+				return true, fmt.Errorf("output-ignore: %s", err)
+			}
+			profile.outputIgnore = ignore
+		}
+		profiles.Profiles[strings.TrimPrefix(key, profilePrefix)] = profile
+		return true, nil
+	case key == profilePatternsKey:
+		var patterns []ProfilePattern
+		if err := json.Unmarshal(data, &patterns); err != nil {
+			return true, fmt.Errorf(`expected a JSON array like [{"service": "/^redis-.*/", "profile": "cache-team"}], got %q`, data)
+		}
+		for i := range patterns {
+			if err := patterns[i].compile(); err != nil {
+				return true, fmt.Errorf("pattern %d: %s", i, err)
+			}
+		}
+		profiles.Patterns = patterns
+		return true, nil
+	case strings.HasPrefix(key, profileSelectionPrefix):
+		parts := strings.SplitN(strings.TrimPrefix(key, profileSelectionPrefix), "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return false, nil
+		}
+		bindings := map[string]map[string]string{
+			"checks":   profiles.Checks,
+			"services": profiles.Services,
+			"nodes":    profiles.Nodes,
+		}[parts[0]]
+		if bindings == nil {
+			return true, fmt.Errorf("unknown profile selection %s, expected checks, services or nodes", parts[0])
+		}
+		bindings[parts[1]] = strings.TrimSpace(string(data))
+		return true, nil
+	}
+	return false, nil
+}
+
+// profileProblems reports the bindings to profiles that don't exist.
+func profileProblems(profiles *ProfilesConfig) []error {
+	var problems []error
+	for kind, bindings := range map[string]map[string]string{"checks": profiles.Checks, "services": profiles.Services, "nodes": profiles.Nodes} {
+		for name, profile := range bindings {
+			if _, found := profiles.Profiles[profile]; !found {
+				problems = append(problems, fmt.Errorf("%s%s/%s: unknown profile %s", profileSelectionPrefix, kind, name, profile))
+			}
+		}
+	}
+	for i, pattern := range profiles.Patterns {
+		if _, found := profiles.Profiles[pattern.Profile]; !found {
+			problems = append(problems, fmt.Errorf("%s: pattern %d: unknown profile %s", profilePatternsKey, i, pattern.Profile))
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Error() < problems[j].Error() })
+	return problems
+}
+
+func (c *ConsulAlertClient) ProfilesConfig() *ProfilesConfig {
+	return c.currentConfig().Profiles
+}
+
+// CheckProfile returns the profile of a check, nil when it has none.
+func (c *ConsulAlertClient) CheckProfile(check *Check) *Profile {
+	name := c.currentConfig().Profiles.Select(check)
+	if profile, found := c.currentConfig().Profiles.Profiles[name]; found {
+		return &profile
+	}
+	return nil
+}
+
+// outputChanged returns true if the output of a check that is still failing
+// changed materially and its profile notifies the output changes.
+func (c *ConsulAlertClient) outputChanged(previous, current *Check) bool {
+	if previous == nil || previous.Status != current.Status || current.Status == "passing" {
+		return false
+	}
+	profile := c.CheckProfile(current)
+	return profile != nil && profile.OutputChanged(previous.Output, current.Output)
+}
+
+// changeThreshold returns the number of seconds the status of a check must
+// hold before it is notified.
+func (c *ConsulAlertClient) changeThreshold(check *Check) int {
+	if profile := c.CheckProfile(check); profile != nil && profile.ChangeThreshold > 0 {
+		return profile.ChangeThreshold
+	}
+	return c.currentConfig().Checks.ChangeThreshold
+}
+
+// DueReminders returns the failing checks whose profile reminder interval has
+// elapsed since they were last notified. Like the alerts, the blacklisted,
+// silenced, inhibited and in maintenance checks are left out, and the
+// reminders of the dependents of a failing service are rolled up into its
+// own.
+func (c *ConsulAlertClient) DueReminders(now time.Time) ([]Check, error) {
+	statuses, err := c.CheckStatuses("")
+	if err != nil {
+		return nil, err
+	}
+	silences, _ := c.Silences()
+	var checks []*Check
+	for _, status := range statuses {
+		checks = append(checks, status.HealthCheck)
+	}
+	var reminders []Check
+	for _, status := range statuses {
+		check := status.HealthCheck
+		if status.Current == "" || status.Current == "passing" || status.ForNotification || !status.AcknowledgedTimestamp.IsZero() || !c.ownsNode(check.Node) {
+			continue
+		}
+		profile := c.CheckProfile(check)
+		if profile == nil || profile.ReminderInterval <= 0 {
+			continue
+		}
+		last := status.NotifiedTimestamp
+		if last.Before(status.CurrentTimestamp) {
+			last = status.CurrentTimestamp
+		}
+		if now.Sub(last) < time.Duration(profile.ReminderInterval)*time.Minute {
+			continue
+		}
+		if c.IsBlacklisted(check) || !c.isWhitelisted(check) || c.inMaintenance(check) || isSilenced(silences, check, now) ||
+			inhibitedBy(c.currentConfig().Checks.InhibitRules, checks, check) != nil {
+			continue
+		}
+		reminder := *check
+		reminder.Status = status.Current
+		reminders = append(reminders, reminder)
+	}
+	if len(c.currentConfig().Checks.Dependencies) > 0 {
+		failing := failingServices(checks, c.currentConfig().Services.MinHealthy)
+		reminders, _ = rollupDependents(c.currentConfig().Checks.Dependencies, failing, reminders, nil)
+	}
+	return reminders, nil
+}
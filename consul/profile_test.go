@@ -0,0 +1,105 @@
+package consul
+
+import "testing"
+
+func TestProfileSelect(t *testing.T) {
+	profiles := &ProfilesConfig{
+		Profiles: map[string]Profile{"default": {}, "db": {}, "web": {}, "disk": {}},
+		Checks:   map[string]string{"disk": "disk"},
+		Services: map[string]string{"postgres": "db"},
+		Nodes:    map[string]string{"web-1": "web"},
+	}
+	tests := []struct {
+		check    Check
+		expected string
+	}{
+		{Check{Node: "web-1", ServiceName: "postgres", CheckID: "disk"}, "disk"},
+		{Check{Node: "web-1", ServiceName: "postgres", CheckID: "service:postgres"}, "db"},
+		{Check{Node: "web-1", CheckID: "serfHealth"}, "web"},
+		{Check{Node: "db-1", CheckID: "serfHealth"}, "default"},
+	}
+	for _, test := range tests {
+		if selected := profiles.Select(&test.check); selected != test.expected {
+			t.Errorf("expected profile %s for %+v, got %s", test.expected, test.check, selected)
+		}
+	}
+
+	var patterns ProfilesConfig
+	if _, err := loadProfileValue(profilePatternsKey, []byte(`[{"service": "/^redis-.*/", "profile": "cache"}, {"node": "cache-*", "profile": "web"}]`), &patterns); err != nil {
+		t.Fatal(err)
+	}
+	profiles.Patterns = patterns.Patterns
+	profiles.Profiles["cache"] = Profile{}
+	if selected := profiles.Select(&Check{Node: "cache-1", ServiceName: "redis-sessions"}); selected != "cache" {
+		t.Errorf("expected the first matching pattern, got %s", selected)
+	}
+	if selected := profiles.Select(&Check{Node: "cache-1", ServiceName: "memcached"}); selected != "web" {
+		t.Errorf("expected the node pattern, got %s", selected)
+	}
+	if selected := profiles.Select(&Check{Node: "cache-1", ServiceName: "postgres"}); selected != "db" {
+		t.Errorf("an exact binding should take precedence over the patterns, got %s", selected)
+	}
+
+	delete(profiles.Profiles, "default")
+	if selected := profiles.Select(&Check{Node: "db-1"}); selected != "" {
+		t.Errorf("expected no profile without a default one, got %s", selected)
+	}
+}
+
+func TestLoadProfileValue(t *testing.T) {
+	profiles := &ProfilesConfig{
+		Profiles: map[string]Profile{},
+		Checks:   map[string]string{},
+		Services: map[string]string{},
+		Nodes:    map[string]string{},
+	}
+	loaded, err := loadProfileValue(profilePrefix+"cache-team", []byte(`{"change-threshold": 300, "reminder-interval": 60, "notifiers": ["slack"]}`), profiles)
+	if !loaded || err != nil {
+		t.Fatalf("expected the profile to load, got %t: %v", loaded, err)
+	}
+	if profile := profiles.Profiles["cache-team"]; profile.ChangeThreshold != 300 || profile.ReminderInterval != 60 || len(profile.Notifiers) != 1 {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if loaded, err = loadProfileValue(profileSelectionPrefix+"services/redis", []byte("cache-team"), profiles); !loaded || err != nil || profiles.Services["redis"] != "cache-team" {
+		t.Errorf("expected the service binding to load, got %t: %v", loaded, err)
+	}
+	if _, err = loadProfileValue(profileSelectionPrefix+"tags/redis", []byte("cache-team"), profiles); err == nil {
+		t.Error("expected an error for an unknown selection")
+	}
+	if loaded, _ = loadProfileValue("consul-alerts/config/checks/enabled", []byte("true"), profiles); loaded {
+		t.Error("other keys should not be loaded")
+	}
+
+	if _, err = loadProfileValue(profilePatternsKey, []byte(`[{"service": "/(/", "profile": "cache-team"}]`), profiles); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+	if _, err = loadProfileValue(profilePatternsKey, []byte(`[{"service": "redis-*"}]`), profiles); err == nil {
+		t.Error("expected an error for a pattern without a profile")
+	}
+
+	profiles.Nodes["web-1"] = "missing"
+	profiles.Patterns = []ProfilePattern{{Node: "db-*", Profile: "other"}}
+	if problems := profileProblems(profiles); len(problems) != 2 {
+		t.Errorf("expected the binding to a missing profile to be reported, got %v", problems)
+	}
+}
+
+func TestProfileOutputChanged(t *testing.T) {
+	profiles := &ProfilesConfig{Profiles: map[string]Profile{}}
+	if _, err := loadProfileValue(profilePrefix+"deps", []byte(`{"output-changes": true, "output-ignore": "took [0-9.]+ms"}`), profiles); err != nil {
+		t.Fatal(err)
+	}
+	profile := profiles.Profiles["deps"]
+	if !profile.OutputChanged("redis unreachable, took 12ms", "postgres unreachable, took 12ms") {
+		t.Error("a different dependency should be a change")
+	}
+	if profile.OutputChanged("redis unreachable, took 12ms", "redis  unreachable, took 340ms\n") {
+		t.Error("the ignored parts and the whitespace shouldn't be a change")
+	}
+	if (&Profile{}).OutputChanged("redis", "postgres") {
+		t.Error("the output changes should only be notified when enabled")
+	}
+	if _, err := loadProfileValue(profilePrefix+"bad", []byte(`{"output-changes": true, "output-ignore": "("}`), profiles); err == nil {
+		t.Error("an invalid output-ignore should be rejected")
+	}
+}
@@ -0,0 +1,65 @@
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"encoding/json"
+)
+
+// quietHoursKey matches the quiet and working hours of a notifier.
+var quietHoursKey = regexp.MustCompile(`^consul-alerts/config/notifiers/([^/]+)/(quiet-hours|working-hours)$`)
+
+// QuietHoursConfig makes a notifier hold the alerts that aren't critical
+// during its QuietHours, or outside its WorkingHours, and send them in a
+// single summary when the window ends. The critical alerts, and the
+// recoveries of the critical alerts sent, are sent right away.
+type QuietHoursConfig struct {
+	QuietHours   *Schedule
+	WorkingHours *Schedule
+}
+
+// Quiet returns true if t is in the quiet hours or outside the working hours.
+func (q QuietHoursConfig) Quiet(t time.Time) bool {
+	return (q.QuietHours != nil && q.QuietHours.Active(t)) || (q.WorkingHours != nil && !q.WorkingHours.Active(t))
+}
+
+// Held returns true if the alerts with the status are held at t, notified
+// being the status of the last alert sent for the check.
+func (q QuietHoursConfig) Held(status, notified string, t time.Time) bool {
+	if status == "critical" || (status == "passing" && notified == "critical") {
+		return false
+	}
+	return q.Quiet(t)
+}
+
+// loadQuietHoursValue loads the quiet or working hours of a notifier and
+// returns true if the key is one of them.
+func loadQuietHoursValue(key string, data []byte, quietHours map[string]QuietHoursConfig) (bool, error) {
+	match := quietHoursKey.FindStringSubmatch(key)
+	if match == nil {
+		return false, nil
+	}
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return true, fmt.Errorf(`expected a JSON object like {"days": ["mon", "fri"], "hours": "22:00-07:00", "timezone": "Europe/Madrid"}, got %q`, data)
+	}
+	if err := schedule.compile(); err != nil {
+		return true, err
+	}
+	config := quietHours[match[1]]
+	if match[2] == "quiet-hours" {
+		config.QuietHours = &schedule
+	} else {
+		config.WorkingHours = &schedule
+	}
+	quietHours[match[1]] = config
+	return true, nil
+}
+
+// NotifierQuietHours returns the quiet hours of a notifier, "custom" for the
+// custom notifiers.
+func (c *ConsulAlertClient) NotifierQuietHours(name string) QuietHoursConfig {
+	return c.currentConfig().Notifiers.QuietHours[name]
+}
@@ -0,0 +1,38 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	quietHours := map[string]QuietHoursConfig{}
+	if found, err := loadQuietHoursValue("consul-alerts/config/notifiers/slack/quiet-hours", []byte(`{"hours": "22:00-07:00", "timezone": "UTC"}`), quietHours); !found || err != nil {
+		t.Fatal("unexpected result:", found, err)
+	}
+	if found, err := loadQuietHoursValue("consul-alerts/config/notifiers/email/working-hours", []byte(`{"days": ["mon", "tue", "wed", "thu", "fri"], "hours": "09:00-18:00", "timezone": "UTC"}`), quietHours); !found || err != nil {
+		t.Fatal("unexpected result:", found, err)
+	}
+	if _, err := loadQuietHoursValue("consul-alerts/config/notifiers/slack/quiet-hours", []byte(`{"hours": "22:00"}`), quietHours); err == nil {
+		t.Error("an invalid range should be rejected")
+	}
+	if found, _ := loadQuietHoursValue("consul-alerts/config/notifiers/slack/enabled", []byte("true"), quietHours); found {
+		t.Error("enabled isn't a quiet hours setting")
+	}
+
+	night := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	slack, email := quietHours["slack"], quietHours["email"]
+	if !slack.Held("warning", "passing", night) || slack.Held("critical", "passing", night) || slack.Held("warning", "passing", day) {
+		t.Error("the slack quiet hours should hold the non-critical alerts at night")
+	}
+	if slack.Held("passing", "critical", night) || !slack.Held("passing", "warning", night) {
+		t.Error("the recoveries of the critical alerts sent should be sent at night")
+	}
+	if !email.Held("passing", "warning", night) || email.Held("passing", "warning", day) || !email.Held("passing", "warning", day.AddDate(0, 0, 4)) {
+		t.Error("the email working hours should hold the non-critical alerts outside weekdays office hours")
+	}
+	if (QuietHoursConfig{}).Quiet(night) {
+		t.Error("a notifier without quiet hours is never quiet")
+	}
+}
@@ -0,0 +1,164 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const remediationPrefix = "consul-alerts/remediation/"
+
+// RemediationConfig configures the remediation handlers run when a check goes
+// critical. Cooldown is the minimum number of seconds between two runs of a
+// handler for the same check and MaxAttempts the number of runs before the
+// check passes again, for the handlers not setting their own. Timeout is the
+// number of seconds a handler can run. Notify sends the outcome of each run
+// through the notifiers.
+type RemediationConfig struct {
+	Handlers    []RemediationHandler
+	Cooldown    int
+	MaxAttempts int
+	Timeout     int
+	Notify      bool
+}
+
+// RemediationHandler is a command or webhook run when a check matching its
+// Node, Service and Check patterns goes critical. A handler without any
+// pattern runs for every check. Cooldown and MaxAttempts override the
+// configured ones when set. Name identifies the handler in the logs and the
+// notifications instead of its command, which may hold secrets.
+type RemediationHandler struct {
+	Name        string `json:"name"`
+	Node        string `json:"node"`
+	Service     string `json:"service"`
+	Check       string `json:"check"`
+	Command     string `json:"command"`
+	Cooldown    int    `json:"cooldown"`
+	MaxAttempts int    `json:"max-attempts"`
+
+	node, service, check func(string) bool
+}
+
+// RemediationAttempts are the runs of a handler for a check since the check
+// last passed.
+type RemediationAttempts struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// Matches returns true if the handler applies to the check. Like the routes,
+// the service pattern is matched against the service id and name, and the
+// check pattern against the check id and name.
+func (h *RemediationHandler) Matches(check *Check) bool {
+	if h.node != nil && !h.node(check.Node) {
+		return false
+	}
+	if h.service != nil && !h.service(check.ServiceID) && !h.service(check.ServiceName) {
+		return false
+	}
+	if h.check != nil && !h.check(check.CheckID) && !h.check(check.Name) {
+		return false
+	}
+	return true
+}
+
+// Id identifies the handler in the remediation attempts without storing its
+// command, which may hold secrets.
+func (h *RemediationHandler) Id() string {
+	hash := sha256.Sum256([]byte(h.Command))
+	return hex.EncodeToString(hash[:8])
+}
+
+// Label names the handler in the logs and the notifications, its Name or its
+// Id when it has none.
+func (h *RemediationHandler) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return "handler " + h.Id()
+}
+
+func (h *RemediationHandler) compile() error {
+	if h.Command == "" {
+		return errors.New("remediation handler without a command")
+	}
+	for _, name := range []struct {
+		pattern string
+		matcher *func(string) bool
+	}{{h.Node, &h.node}, {h.Service, &h.service}, {h.Check, &h.check}} {
+		if name.pattern == "" {
+			continue
+		}
+		matcher, err := patternMatcher(name.pattern)
+		if err != nil {
+			return err
+		}
+		*name.matcher = matcher
+	}
+	return nil
+}
+
+// loadRemediationHandlers loads a JSON array of remediation handlers.
+func loadRemediationHandlers(handlers *[]RemediationHandler, data []byte) error {
+	var val []RemediationHandler
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of {"service": ..., "check": ..., "command": ...} objects, got %q`, data)
+	}
+	for i := range val {
+		command, err := resolveSecrets(val[i].Command)
+		if err != nil {
+			return err
+		}
+		val[i].Command = command
+		if err := val[i].compile(); err != nil {
+			return fmt.Errorf("remediation handler %d: %s", i, err)
+		}
+	}
+	*handlers = val
+	return nil
+}
+
+// remediationKey is the key of the remediation attempts of a check, under
+// the same path as its status.
+func (c *ConsulAlertClient) remediationKey(check *Check) string {
+	key := c.checkKey(check.Datacenter, check.Node, check.ServiceID, check.CheckID)
+	return remediationPrefix + strings.TrimPrefix(key, "consul-alerts/checks/")
+}
+
+// RemediationAttempts returns the remediation attempts of a check by handler
+// id.
+func (c *ConsulAlertClient) RemediationAttempts(check *Check) (map[string]RemediationAttempts, error) {
+	kvPair, _, err := c.api.KV().Get(c.remediationKey(check), nil)
+	if err != nil {
+		apiErrors.Inc("remediation")
+		return nil, err
+	}
+	attempts := make(map[string]RemediationAttempts)
+	if kvPair != nil {
+		json.Unmarshal(kvPair.Value, &attempts)
+	}
+	return attempts, nil
+}
+
+// SetRemediationAttempts stores the remediation attempts of a check, they are
+// deleted when empty.
+func (c *ConsulAlertClient) SetRemediationAttempts(check *Check, attempts map[string]RemediationAttempts) error {
+	var err error
+	if len(attempts) == 0 {
+		_, err = c.api.KV().Delete(c.remediationKey(check), nil)
+	} else {
+		data, _ := json.Marshal(attempts)
+		_, err = c.api.KV().Put(&consulapi.KVPair{Key: c.remediationKey(check), Value: data}, nil)
+	}
+	if err != nil {
+		apiErrors.Inc("remediation")
+	}
+	return err
+}
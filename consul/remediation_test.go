@@ -0,0 +1,37 @@
+package consul
+
+import (
+	"testing"
+)
+
+func TestLoadRemediationHandlers(t *testing.T) {
+	var handlers []RemediationHandler
+	err := loadRemediationHandlers(&handlers, []byte(`[{"name": "restart web", "service": "web", "check": "/^http/", "command": "restart-web.sh", "max-attempts": 2}, {"command": "https://runbooks.example.com/remediate"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, all := handlers[0], handlers[1]
+	if web.MaxAttempts != 2 || web.Id() == all.Id() || web.Label() != "restart web" || all.Label() != "handler "+all.Id() {
+		t.Errorf("unexpected handlers %+v", handlers)
+	}
+	cases := []struct {
+		check   Check
+		matches bool
+	}{
+		{Check{Node: "web1", ServiceID: "web-1", ServiceName: "web", CheckID: "http-ping"}, true},
+		{Check{Node: "web1", ServiceID: "web-1", ServiceName: "web", CheckID: "disk"}, false},
+		{Check{Node: "db1", ServiceID: "db", ServiceName: "db", CheckID: "http"}, false},
+	}
+	for _, c := range cases {
+		if web.Matches(&c.check) != c.matches {
+			t.Errorf("expected %v for %+v", c.matches, c.check)
+		}
+		if !all.Matches(&c.check) {
+			t.Errorf("a handler without patterns should match %+v", c.check)
+		}
+	}
+
+	if err := loadRemediationHandlers(&handlers, []byte(`[{"service": "web"}]`)); err == nil {
+		t.Error("expected an error without a command")
+	}
+}
@@ -0,0 +1,132 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"encoding/json"
+)
+
+// Route sends the alerts matching it through its own notifiers and email
+// receivers instead of the default ones. A route matches the alerts of the
+// nodes, services and checks matching its Node, Service and Check patterns,
+// of the services having a tag matching one of its Tags patterns, and of the
+// nodes with metadata matching all its NodeMeta patterns, while its Schedule
+// is active. A route without any of them matches every alert. SlackChannel
+// replaces the channel of the slack notifier, ZulipStream and ZulipTopic the
+// stream and topic of the zulip notifier.
+type Route struct {
+	Node      string            `json:"node"`
+	Service   string            `json:"service"`
+	Check     string            `json:"check"`
+	Tags      []string          `json:"tags"`
+	NodeMeta  map[string]string `json:"node-meta"`
+	Schedule  *Schedule         `json:"schedule"`
+	Notifiers []string          `json:"notifiers"`
+	Receivers []string          `json:"receivers"`
+
+	SlackChannel string `json:"slack-channel"`
+	ZulipStream  string `json:"zulip-stream"`
+	ZulipTopic   string `json:"zulip-topic"`
+
+	node, service, check func(string) bool
+	tags                 []func(string) bool
+	nodeMeta             map[string]func(string) bool
+}
+
+// Matches returns true if the route applies to the check at the given time.
+// Like the blacklist patterns, the service pattern is matched against the
+// service id and name, and the check pattern against the check id and name.
+func (r *Route) Matches(check *Check, now time.Time) bool {
+	if r.Schedule != nil && !r.Schedule.Active(now) {
+		return false
+	}
+	if r.node != nil && !r.node(check.Node) {
+		return false
+	}
+	if r.service != nil && !r.service(check.ServiceID) && !r.service(check.ServiceName) {
+		return false
+	}
+	if r.check != nil && !r.check(check.CheckID) && !r.check(check.Name) {
+		return false
+	}
+	if len(r.tags) > 0 {
+		matched := false
+		for _, tag := range check.ServiceTags {
+			if matchesAny(r.tags, tag) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for key, matches := range r.nodeMeta {
+		value, found := check.NodeMeta[key]
+		if !found || !matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) compile() error {
+	if r.Schedule != nil {
+		if err := r.Schedule.compile(); err != nil {
+			return err
+		}
+	}
+	for _, name := range []struct {
+		pattern string
+		matcher *func(string) bool
+	}{{r.Node, &r.node}, {r.Service, &r.service}, {r.Check, &r.check}} {
+		if name.pattern == "" {
+			continue
+		}
+		matcher, err := patternMatcher(name.pattern)
+		if err != nil {
+			return err
+		}
+		*name.matcher = matcher
+	}
+	r.tags = make([]func(string) bool, len(r.Tags))
+	for i, tag := range r.Tags {
+		matcher, err := patternMatcher(tag)
+		if err != nil {
+			return err
+		}
+		r.tags[i] = matcher
+	}
+	r.nodeMeta = make(map[string]func(string) bool, len(r.NodeMeta))
+	for key, value := range r.NodeMeta {
+		matcher, err := patternMatcher(value)
+		if err != nil {
+			return err
+		}
+		r.nodeMeta[key] = matcher
+	}
+	return nil
+}
+
+// loadRoutes loads a JSON array of routes.
+func loadRoutes(routes *[]Route, data []byte) error {
+	var val []Route
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of {"service": "...", "tags": [...], "node-meta": {...}, "schedule": {...}, "notifiers": [...]} objects, got %q`, data)
+	}
+	for i := range val {
+		if err := val[i].compile(); err != nil {
+			return fmt.Errorf("route %d: %s", i, err)
+		}
+		for j, receiver := range val[i].Receivers {
+			receiver, err := resolveSecrets(receiver)
+			if err != nil {
+				return err
+			}
+			val[i].Receivers[j] = receiver
+		}
+	}
+	*routes = val
+	return nil
+}
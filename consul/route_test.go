@@ -0,0 +1,102 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadRoutes(t *testing.T) {
+	var routes []Route
+	err := loadRoutes(&routes, []byte(`[{"tags": ["team-db", "/^dba-/"], "notifiers": ["email"], "receivers": ["dba@example.com"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := routes[0]
+	if route.Notifiers[0] != "email" || route.Receivers[0] != "dba@example.com" {
+		t.Errorf("unexpected route %+v", route)
+	}
+	for _, tags := range [][]string{{"prod", "team-db"}, {"dba-primary"}} {
+		if !route.Matches(&Check{ServiceTags: tags}, time.Now()) {
+			t.Errorf("route should match %v", tags)
+		}
+	}
+	for _, tags := range [][]string{nil, {"prod", "team-web"}} {
+		if route.Matches(&Check{ServiceTags: tags}, time.Now()) {
+			t.Errorf("route shouldn't match %v", tags)
+		}
+	}
+}
+
+func TestRouteNodeMeta(t *testing.T) {
+	var routes []Route
+	err := loadRoutes(&routes, []byte(`[{"tags": ["prod"], "node-meta": {"team": "payments", "rack": "r*"}, "slack-channel": "#payments"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := routes[0]
+	if route.SlackChannel != "#payments" {
+		t.Errorf("unexpected slack channel %s", route.SlackChannel)
+	}
+	cases := []struct {
+		tags     []string
+		nodeMeta map[string]string
+		matches  bool
+	}{
+		{[]string{"prod"}, map[string]string{"team": "payments", "rack": "r12"}, true},
+		{[]string{"prod"}, map[string]string{"team": "payments"}, false},
+		{[]string{"prod"}, map[string]string{"team": "search", "rack": "r12"}, false},
+		{[]string{"dev"}, map[string]string{"team": "payments", "rack": "r12"}, false},
+	}
+	for _, c := range cases {
+		if route.Matches(&Check{ServiceTags: c.tags, NodeMeta: c.nodeMeta}, time.Now()) != c.matches {
+			t.Errorf("expected %v for %v and %v", c.matches, c.tags, c.nodeMeta)
+		}
+	}
+}
+
+func TestRouteSchedule(t *testing.T) {
+	var routes []Route
+	err := loadRoutes(&routes, []byte(`[{"schedule": {"days": ["sat", "sun"], "timezone": "UTC"}, "notifiers": ["pagerduty"]}, {"notifiers": ["email"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saturday := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if !routes[0].Matches(&Check{}, saturday) || routes[0].Matches(&Check{}, monday) {
+		t.Error("the weekend route should only match on weekends")
+	}
+	if !routes[1].Matches(&Check{}, monday) {
+		t.Error("a route without conditions should match every alert")
+	}
+}
+
+func TestRouteNames(t *testing.T) {
+	var routes []Route
+	err := loadRoutes(&routes, []byte(`[{"service": "/^redis-.*/", "check": "disk*", "notifiers": ["email"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		check   Check
+		matches bool
+	}{
+		{Check{Node: "cache-1", ServiceID: "redis-6379", ServiceName: "redis-cache", CheckID: "disk-usage"}, true},
+		{Check{Node: "cache-1", ServiceID: "redis-1", ServiceName: "cache", CheckID: "check-1", Name: "disk"}, true},
+		{Check{Node: "cache-1", ServiceName: "memcached", CheckID: "disk-usage"}, false},
+		{Check{Node: "cache-1", ServiceName: "redis-cache", CheckID: "memory"}, false},
+	}
+	for _, c := range cases {
+		if routes[0].Matches(&c.check, time.Now()) != c.matches {
+			t.Errorf("expected %v for %+v", c.matches, c.check)
+		}
+	}
+}
+
+func TestLoadRoutesInvalid(t *testing.T) {
+	var routes []Route
+	for _, data := range []string{`{}`, `[{"tags": ["/[/"]}]`, `[{"schedule": {"hours": "9-18"}}]`, `[{"node-meta": {"team": "[x"}}]`, `[{"service": "/(/"}]`} {
+		if err := loadRoutes(&routes, []byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is a weekly time window. Days are mon to sun, every day when empty.
+// Hours is a range like 09:00-18:00, the whole day when empty. A range ending
+// before it starts like 18:00-09:00 runs past midnight, the hours after
+// midnight belonging to the day the window started. Except lists the dates,
+// like 2026-12-25, when the schedule is not active. The schedule uses the
+// Timezone location, the local time when empty.
+type Schedule struct {
+	Days     []string `json:"days"`
+	Hours    string   `json:"hours"`
+	Timezone string   `json:"timezone"`
+	Except   []string `json:"except"`
+
+	days       map[time.Weekday]bool
+	start, end int
+	location   *time.Location
+}
+
+// Active returns true if t is in the schedule.
+func (s *Schedule) Active(t time.Time) bool {
+	t = t.In(s.location)
+	minute := t.Hour()*60 + t.Minute()
+	day := t
+	switch {
+	case s.start == s.end:
+	case s.start < s.end:
+		if minute < s.start || minute >= s.end {
+			return false
+		}
+	default:
+		if minute >= s.end && minute < s.start {
+			return false
+		}
+		if minute < s.end {
+			day = t.AddDate(0, 0, -1)
+		}
+	}
+	if len(s.days) > 0 && !s.days[day.Weekday()] {
+		return false
+	}
+	date := day.Format("2006-01-02")
+	for _, except := range s.Except {
+		if except == date {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Schedule) compile() error {
+	s.days = make(map[time.Weekday]bool, len(s.Days))
+	for _, day := range s.Days {
+		weekday, found := weekdays[strings.ToLower(day)]
+		if !found {
+			return fmt.Errorf("invalid day %s, expected mon, tue, wed, thu, fri, sat or sun", day)
+		}
+		s.days[weekday] = true
+	}
+	s.start, s.end = 0, 0
+	if s.Hours != "" {
+		var startHour, startMinute, endHour, endMinute int
+		_, err := fmt.Sscanf(s.Hours, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+		if err != nil || startHour > 24 || endHour > 24 || startMinute > 59 || endMinute > 59 {
+			return fmt.Errorf("invalid hours %s, expected a range like 09:00-18:00", s.Hours)
+		}
+		s.start = (startHour*60 + startMinute) % (24 * 60)
+		s.end = (endHour*60 + endMinute) % (24 * 60)
+	}
+	for _, except := range s.Except {
+		if _, err := time.Parse("2006-01-02", except); err != nil {
+			return fmt.Errorf("invalid date %s, expected a date like 2026-12-25", except)
+		}
+	}
+	s.location = time.Local
+	if s.Timezone != "" {
+		location, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %s: %s", s.Timezone, err)
+		}
+		s.location = location
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleActive(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("timezone database unavailable")
+	}
+	businessHours := &Schedule{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Hours: "09:00-18:00", Timezone: "Europe/Madrid", Except: []string{"2026-12-25"}}
+	nights := &Schedule{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Hours: "18:00-09:00", Timezone: "Europe/Madrid"}
+	for _, s := range []*Schedule{businessHours, nights} {
+		if err := s.compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		time          time.Time
+		business, off bool
+	}{
+		// wednesday
+		{time.Date(2026, 10, 14, 10, 0, 0, 0, madrid), true, false},
+		{time.Date(2026, 10, 14, 18, 0, 0, 0, madrid), false, true},
+		// 08:00 UTC is 10:00 in Madrid
+		{time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), true, false},
+		// saturday 02:00 belongs to the friday night
+		{time.Date(2026, 10, 17, 2, 0, 0, 0, madrid), false, true},
+		// monday 02:00 belongs to the sunday night
+		{time.Date(2026, 10, 19, 2, 0, 0, 0, madrid), false, false},
+		// christmas is a friday
+		{time.Date(2026, 12, 25, 10, 0, 0, 0, madrid), false, false},
+	}
+	for _, c := range cases {
+		if businessHours.Active(c.time) != c.business || nights.Active(c.time) != c.off {
+			t.Errorf("unexpected schedules at %s", c.time)
+		}
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	for _, s := range []*Schedule{
+		{Days: []string{"monday"}},
+		{Hours: "9-18"},
+		{Hours: "09:00-25:00"},
+		{Timezone: "Mars/Olympus"},
+		{Except: []string{"25/12/2026"}},
+	} {
+		if err := s.compile(); err == nil {
+			t.Errorf("expected an error for %+v", s)
+		}
+	}
+}
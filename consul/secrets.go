@@ -0,0 +1,44 @@
+package consul
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var secretReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// resolveSecrets replaces the ${ENV_VAR} references in value with the
+// environment variable and the ${file:/path} references with the content of
+// the file, without the trailing newline. A value of the form
+// vault:<path>#<key> is read from vault. A missing variable, file or secret is
+// an error so a secret is never silently replaced by an empty string.
+func resolveSecrets(value string) (string, error) {
+	if strings.HasPrefix(value, "vault:") {
+		if vault == nil {
+			return "", fmt.Errorf("vault is not configured, unable to resolve %s", value)
+		}
+		return vault.resolve(strings.TrimPrefix(value, "vault:"))
+	}
+
+	var err error
+	resolved := secretReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := secretReference.FindStringSubmatch(reference)[1]
+		if strings.HasPrefix(name, "file:") {
+			data, fileErr := ioutil.ReadFile(strings.TrimPrefix(name, "file:"))
+			if fileErr != nil {
+				err = fileErr
+				return ""
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		}
+		env, found := os.LookupEnv(name)
+		if !found {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return env
+	})
+	return resolved, err
+}
@@ -0,0 +1,35 @@
+package consul
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	os.Setenv("CONSUL_ALERTS_TEST_SECRET", "s3cret")
+	defer os.Unsetenv("CONSUL_ALERTS_TEST_SECRET")
+
+	file, err := ioutil.TempFile("", "consul-alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("from-file\n")
+	file.Close()
+
+	resolved, err := resolveSecrets("https://hooks.example.com/${CONSUL_ALERTS_TEST_SECRET}/${file:" + file.Name() + "}")
+	if err != nil || resolved != "https://hooks.example.com/s3cret/from-file" {
+		t.Errorf("unexpected result: %s, %v", resolved, err)
+	}
+
+	if resolved, _ := resolveSecrets("plain $value"); resolved != "plain $value" {
+		t.Errorf("values without references should not change: %s", resolved)
+	}
+	if _, err := resolveSecrets("${CONSUL_ALERTS_TEST_MISSING}"); err == nil {
+		t.Error("a missing variable should be an error")
+	}
+	if _, err := resolveSecrets("${file:/nonexistent/secret}"); err == nil {
+		t.Error("a missing file should be an error")
+	}
+}
@@ -0,0 +1,53 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// SelfServiceName is the consul service consul-alerts registers itself as.
+const SelfServiceName = "consul-alerts"
+
+// RegisterSelf registers this instance as a consul-alerts service on the local
+// agent with a TTL check and returns the check id. The check is marked passing
+// right away and added to the leader and shard sessions, so an instance that
+// stops updating it loses its leadership or its nodes.
+func (c *ConsulAlertClient) RegisterSelf(id string, port int, ttl time.Duration) (string, error) {
+	registration := &consulapi.AgentServiceRegistration{
+		ID:   id,
+		Name: SelfServiceName,
+		Port: port,
+		Check: &consulapi.AgentServiceCheck{
+			TTL: fmt.Sprintf("%ds", int(ttl.Seconds())),
+		},
+	}
+	if err := c.api.Agent().ServiceRegister(registration); err != nil {
+		return "", err
+	}
+	checkId := "service:" + id
+	if err := c.api.Agent().PassTTL(checkId, "Registered."); err != nil {
+		return "", err
+	}
+	c.sessionChecks = []string{checkId}
+	return checkId, nil
+}
+
+// UpdateSelfCheck sets the status of the self registration TTL check.
+func (c *ConsulAlertClient) UpdateSelfCheck(checkId, status, note string) error {
+	switch status {
+	case "passing":
+		return c.api.Agent().PassTTL(checkId, note)
+	case "warning":
+		return c.api.Agent().WarnTTL(checkId, note)
+	default:
+		return c.api.Agent().FailTTL(checkId, note)
+	}
+}
+
+// DeregisterSelf removes the consul-alerts service registered by RegisterSelf.
+func (c *ConsulAlertClient) DeregisterSelf(id string) error {
+	c.sessionChecks = nil
+	return c.api.Agent().ServiceDeregister(id)
+}
@@ -0,0 +1,63 @@
+package consul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"encoding/json"
+)
+
+const minHealthyPrefix = "consul-alerts/config/services/min-healthy/"
+
+// loadMinHealthy loads a JSON object of the minimum healthy instances of each
+// service.
+func loadMinHealthy(minHealthy *map[string]int, data []byte) error {
+	var val map[string]int
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON object of service names and instance counts like {"web": 2}, got %q`, data)
+	}
+	if val == nil {
+		val = map[string]int{}
+	}
+	*minHealthy = val
+	return nil
+}
+
+// loadServiceMinHealthy loads the minimum healthy instances of the service
+// named by the key. These override the min-healthy JSON object.
+func loadServiceMinHealthy(minHealthy map[string]int, key string, data []byte) error {
+	var minimum int
+	if err := loadCustomValue(&minimum, data, ConfigTypeInt); err != nil {
+		return err
+	}
+	minHealthy[strings.TrimPrefix(key, minHealthyPrefix)] = minimum
+	return nil
+}
+
+// Services returns the names of the services registered in the catalog,
+// sorted.
+func (c *ConsulAlertClient) Services() ([]string, error) {
+	services, _, err := c.api.Catalog().Services(nil)
+	if err != nil {
+		apiErrors.Inc("services")
+		return nil, err
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// HealthyInstances returns the number of instances of a service passing all
+// their checks.
+func (c *ConsulAlertClient) HealthyInstances(service string) (int, error) {
+	entries, _, err := c.api.Health().Service(service, "", true, nil)
+	if err != nil {
+		apiErrors.Inc("healthy_instances")
+		return 0, err
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,33 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+)
+
+// createSession creates a consul session that must be renewed within ttl. The
+// consul api package doesn't support session TTLs. The session is also tied to
+// the self registration check when there is one.
+func (c *ConsulAlertClient) createSession(name string, ttl, lockDelay time.Duration, behavior string) (string, error) {
+	newSession := map[string]interface{}{
+		"Name":      name,
+		"TTL":       fmt.Sprintf("%ds", int(ttl.Seconds())),
+		"LockDelay": fmt.Sprintf("%ds", int(lockDelay.Seconds())),
+		"Behavior":  behavior,
+	}
+	if len(c.sessionChecks) > 0 {
+		newSession["Checks"] = append([]string{"serfHealth"}, c.sessionChecks...)
+	}
+	var created struct{ ID string }
+	if _, err := c.request("PUT", "/v1/session/create", newSession, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// renewSession resets the TTL of a session. expired is true when the session
+// no longer exists.
+func (c *ConsulAlertClient) renewSession(session string) (expired bool, err error) {
+	code, err := c.request("PUT", "/v1/session/renew/"+session, nil, nil)
+	return code == 404, err
+}
@@ -0,0 +1,252 @@
+package consul
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hash/crc32"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const shardMembersPrefix = "consul-alerts/shards/"
+
+// ringReplicas is the number of points each member has on the hash ring.
+const ringReplicas = 64
+
+// hashRing assigns keys to members with consistent hashing so only a small
+// part of the keys move when a member joins or leaves.
+type hashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+func newHashRing(members []string) *hashRing {
+	ring := &hashRing{owners: make(map[uint32]string)}
+	for _, member := range members {
+		for i := 0; i < ringReplicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(member + "#" + strconv.Itoa(i)))
+			ring.points = append(ring.points, point)
+			ring.owners[point] = member
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// owner returns the member owning key, or an empty string if the ring is empty.
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Shard makes several consul-alerts instances split the nodes between them.
+// Each instance registers under consul-alerts/shards/ with a session and owns
+// the nodes assigned to it by the hash ring of the registered members.
+type Shard struct {
+	Id string
+
+	// OnChange is called when the members change.
+	OnChange func()
+
+	client  *ConsulAlertClient
+	lock    sync.RWMutex
+	session string
+	members []string
+	ring    *hashRing
+	stop    chan struct{}
+	left    bool
+}
+
+// EnableSharding makes the client only track the checks of the nodes owned by
+// this instance.
+func (c *ConsulAlertClient) EnableSharding(id string) *Shard {
+	c.shard = &Shard{
+		Id:     id,
+		client: c,
+		ring:   newHashRing(nil),
+		stop:   make(chan struct{}),
+	}
+	return c.shard
+}
+
+// Join registers the instance and keeps the membership up to date in the
+// background.
+func (s *Shard) Join() {
+	go s.run()
+}
+
+// Owns returns true if the checks of node are handled by this instance.
+func (s *Shard) Owns(node string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.ring.owner(node) == s.Id
+}
+
+// Members returns the ids of the registered instances.
+func (s *Shard) Members() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.members
+}
+
+func (s *Shard) run() {
+	config := s.client.currentConfig().Leader
+	ttl := time.Duration(config.SessionTTL) * time.Second
+	if ttl < minSessionTTL {
+		ttl = minSessionTTL
+	}
+	retry := time.Duration(config.RetryInterval) * time.Second
+	if retry < time.Second {
+		retry = time.Second
+	}
+
+	var waitIndex uint64
+	renewed := time.Now()
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		session := s.currentSession()
+		if session == "" {
+			s.register(ttl)
+		} else if time.Since(renewed) >= ttl/2 {
+			if expired, err := s.client.renewSession(session); expired {
+				log.Warnf("Shard session %s has expired.", session)
+				s.setSession(session, "")
+				continue
+			} else if err != nil {
+				apiErrors.Inc("shard")
+				log.Warnln("Unable to renew the shard session:", err)
+			}
+			renewed = time.Now()
+		}
+
+		options := &consulapi.QueryOptions{WaitIndex: waitIndex, WaitTime: ttl / 2}
+		kvPairs, meta, err := s.client.watchApi.KV().List(shardMembersPrefix, options)
+		if err != nil {
+			apiErrors.Inc("shard")
+			log.Warnln("Unable to retrieve the shard members:", err)
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(retry):
+			}
+			continue
+		}
+		waitIndex = meta.LastIndex
+		s.update(kvPairs)
+	}
+}
+
+func (s *Shard) currentSession() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.session
+}
+
+// setSession replaces the session if it is still old.
+func (s *Shard) setSession(old, session string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.session == old {
+		s.session = session
+	}
+}
+
+func (s *Shard) register(ttl time.Duration) {
+	session, err := s.client.createSession(shardMembersPrefix+s.Id, ttl, 0, "delete")
+	if err != nil {
+		apiErrors.Inc("shard")
+		log.Errorln("Unable to create the shard session:", err)
+		return
+	}
+	kvpair := &consulapi.KVPair{
+		Key:     shardMembersPrefix + s.Id,
+		Value:   []byte(s.Id),
+		Session: session,
+	}
+	if acquired, _, err := s.client.api.KV().Acquire(kvpair, nil); err != nil || !acquired {
+		apiErrors.Inc("shard")
+		log.Errorf("Unable to register shard member %s.", s.Id)
+		s.client.api.Session().Destroy(session, nil)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// the instance left while the session was created
+	if s.left {
+		s.client.api.Session().Destroy(session, nil)
+		return
+	}
+	log.Infof("%s joined the shard members.", s.Id)
+	s.session = session
+}
+
+// update rebuilds the hash ring from the member keys holding a session.
+func (s *Shard) update(kvPairs consulapi.KVPairs) {
+	members := make([]string, 0, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		if kvPair.Session != "" {
+			members = append(members, strings.TrimPrefix(kvPair.Key, shardMembersPrefix))
+		}
+	}
+	sort.Strings(members)
+
+	s.lock.Lock()
+	changed := strings.Join(members, ",") != strings.Join(s.members, ",")
+	if changed {
+		s.members = members
+		s.ring = newHashRing(members)
+	}
+	s.lock.Unlock()
+
+	if changed {
+		log.Infof("Shard members changed: %s", strings.Join(members, ", "))
+		if s.OnChange != nil {
+			go s.OnChange()
+		}
+	}
+}
+
+// Leave removes the instance from the members so its nodes are taken over
+// without waiting for the session TTL, and stops registering it again.
+func (s *Shard) Leave() {
+	s.lock.Lock()
+	if s.left {
+		s.lock.Unlock()
+		return
+	}
+	s.left = true
+	close(s.stop)
+	session := s.session
+	s.session = ""
+	s.lock.Unlock()
+
+	if session == "" {
+		return
+	}
+	if _, err := s.client.api.Session().Destroy(session, nil); err != nil {
+		apiErrors.Inc("shard")
+		log.Warnln("Unable to leave the shard members:", err)
+	}
+}
+
+// ownsNode returns true if the node is handled by this instance. Every node is
+// owned when sharding is disabled.
+func (c *ConsulAlertClient) ownsNode(node string) bool {
+	return c.shard == nil || c.shard.Owns(node)
+}
@@ -0,0 +1,70 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRingEmpty(t *testing.T) {
+	if owner := newHashRing(nil).owner("node1"); owner != "" {
+		t.Errorf("empty ring should have no owner, got %s", owner)
+	}
+}
+
+func TestHashRingSpreadsNodes(t *testing.T) {
+	ring := newHashRing([]string{"a", "b", "c"})
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		counts[ring.owner(fmt.Sprintf("node%d", i))]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		if counts[member] < 500 {
+			t.Errorf("%s owns too few nodes: %d", member, counts[member])
+		}
+	}
+}
+
+func TestHashRingMovesFewNodes(t *testing.T) {
+	before := newHashRing([]string{"a", "b", "c"})
+	after := newHashRing([]string{"a", "b", "c", "d"})
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		node := fmt.Sprintf("node%d", i)
+		if before.owner(node) != after.owner(node) {
+			if after.owner(node) != "d" {
+				t.Errorf("%s moved between existing members", node)
+			}
+			moved++
+		}
+	}
+	if moved > 1500 {
+		t.Errorf("too many nodes moved: %d", moved)
+	}
+}
+
+func TestOwnsNodeWithoutSharding(t *testing.T) {
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	if !client.ownsNode("node1") {
+		t.Error("every node should be owned without sharding")
+	}
+	shard := client.EnableSharding("a")
+	if client.ownsNode("node1") {
+		t.Error("no node should be owned before joining")
+	}
+	shard.ring = newHashRing([]string{"a"})
+	if !client.ownsNode("node1") {
+		t.Error("single member should own every node")
+	}
+}
+
+func TestShardLeaveStopsRegistering(t *testing.T) {
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}}
+	shard := client.EnableSharding("a")
+	shard.Leave()
+	shard.Leave()
+	// run would register the instance again with a nil consul api
+	shard.run()
+	if shard.currentSession() != "" {
+		t.Error("no session should be registered after leaving")
+	}
+}
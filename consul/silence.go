@@ -0,0 +1,139 @@
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"crypto/rand"
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+const silencePrefix = "consul-alerts/silences/"
+
+// Silence suppresses notifications for the checks matching its node, service
+// and check patterns between StartsAt and EndsAt. Patterns use path.Match
+// syntax and an empty pattern matches everything.
+type Silence struct {
+	ID        string    `json:"id"`
+	Node      string    `json:"node"`
+	Service   string    `json:"service"`
+	Check     string    `json:"check"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+// Active returns true if the silence is in effect at the given time.
+func (s *Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Matches returns true if the silence patterns match the check. The service
+// pattern is matched against the service id and name, and the check pattern
+// against the check id and name.
+func (s *Silence) Matches(check *Check) bool {
+	return matchPattern(s.Node, check.Node) &&
+		(matchPattern(s.Service, check.ServiceID) || matchPattern(s.Service, check.ServiceName)) &&
+		(matchPattern(s.Check, check.CheckID) || matchPattern(s.Check, check.Name))
+}
+
+func (s *Silence) Validate() error {
+	switch {
+	case s.Node == "" && s.Service == "" && s.Check == "":
+		return errors.New("at least one of node, service or check is required")
+	case !s.EndsAt.After(s.StartsAt):
+		return errors.New("endsAt must be after startsAt")
+	}
+	for _, pattern := range []string{s.Node, s.Service, s.Check} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+func newSilenceId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// Silences returns every stored silence, including expired ones.
+func (c *ConsulAlertClient) Silences() ([]Silence, error) {
+	kvPairs, _, err := c.api.KV().List(silencePrefix, nil)
+	if err != nil {
+		apiErrors.Inc("list_silences")
+		return nil, err
+	}
+	silences := make([]Silence, 0, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		if strings.HasSuffix(kvPair.Key, "/") {
+			continue
+		}
+		var silence Silence
+		if err := json.Unmarshal(kvPair.Value, &silence); err != nil {
+			log.Warnf("Unable to read silence %s: %s", kvPair.Key, err)
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	return silences, nil
+}
+
+// CreateSilence validates and stores the silence, assigning a new ID.
+func (c *ConsulAlertClient) CreateSilence(silence *Silence) error {
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+	silence.ID = newSilenceId()
+	data, _ := json.Marshal(silence)
+	if _, err := c.api.KV().Put(&consulapi.KVPair{Key: silencePrefix + silence.ID, Value: data}, nil); err != nil {
+		apiErrors.Inc("put_silence")
+		return err
+	}
+	return nil
+}
+
+func (c *ConsulAlertClient) DeleteSilence(id string) error {
+	if _, err := c.api.KV().Delete(silencePrefix+id, nil); err != nil {
+		apiErrors.Inc("delete_silence")
+		return err
+	}
+	return nil
+}
+
+// IsSilenced returns true if an active silence matches the check.
+func (c *ConsulAlertClient) IsSilenced(check *Check) bool {
+	silences, err := c.Silences()
+	if err != nil {
+		return false
+	}
+	return isSilenced(silences, check, time.Now())
+}
+
+func isSilenced(silences []Silence, check *Check, now time.Time) bool {
+	for _, silence := range silences {
+		if silence.Active(now) && silence.Matches(check) {
+			return true
+		}
+	}
+	return false
+}
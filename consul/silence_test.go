@@ -0,0 +1,39 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	silence := &Silence{Node: "web-*", Check: "disk"}
+	check := &Check{Node: "web-1", ServiceID: "nginx", CheckID: "disk"}
+	if !silence.Matches(check) {
+		t.Error("silence should match web-1 disk check")
+	}
+	check.Node = "db-1"
+	if silence.Matches(check) {
+		t.Error("silence should not match db-1")
+	}
+}
+
+func TestSilenceActive(t *testing.T) {
+	now := time.Now()
+	silence := &Silence{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}
+	if !silence.Active(now) {
+		t.Error("silence should be active")
+	}
+	if silence.Active(now.Add(2 * time.Hour)) {
+		t.Error("silence should have expired")
+	}
+}
+
+func TestSilenceValidate(t *testing.T) {
+	now := time.Now()
+	if err := (&Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}).Validate(); err == nil {
+		t.Error("silence without patterns should be invalid")
+	}
+	if err := (&Silence{Node: "[", StartsAt: now, EndsAt: now.Add(time.Hour)}).Validate(); err == nil {
+		t.Error("silence with bad pattern should be invalid")
+	}
+}
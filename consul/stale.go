@@ -0,0 +1,116 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// StaleChange is a check whose output and status stopped changing for longer
+// than the stale window of its type, or that changed again after being stale.
+type StaleChange struct {
+	Check
+	Stale bool
+	Since time.Time
+}
+
+// loadStaleAfter loads the stale windows in seconds by check type, like
+// {"script": 900}. There is no default window: the checks whose output
+// doesn't change on every run would be reported as stale while healthy.
+func loadStaleAfter(staleAfter *map[string]int, data []byte) error {
+	var val map[string]int
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON object of seconds by check type like {"script": 900}, got %q`, data)
+	}
+	for checkType, seconds := range val {
+		if checkType == "default" {
+			return fmt.Errorf("default: the stale detection is enabled by check type, list the types whose output changes on every run")
+		}
+		if seconds < 0 {
+			return fmt.Errorf("%s: expected a positive number of seconds, got %d", checkType, seconds)
+		}
+	}
+	*staleAfter = val
+	return nil
+}
+
+// staleWindow returns the stale window of a check, 0 when its type has none.
+func (c *ChecksConfig) staleWindow(check *Check) time.Duration {
+	if check.Type == "" {
+		return 0
+	}
+	return time.Duration(c.StaleAfter[check.Type]) * time.Second
+}
+
+// checkTypes returns the type of the checks of a datacenter, by node and
+// check id. The consul api package doesn't expose it.
+func (c *ConsulAlertClient) checkTypes(dc string) map[string]string {
+	var checks []struct {
+		Node    string
+		CheckID string
+		Type    string
+	}
+	if _, err := c.requestIn(dc, "GET", "/v1/health/state/any", nil, &checks); err != nil {
+		apiErrors.Inc("health_state")
+		log.Errorf("Unable to retrieve the check types of %s: %s", dc, err)
+	}
+	types := make(map[string]string, len(checks))
+	for _, check := range checks {
+		types[check.Node+"/"+check.CheckID] = check.Type
+	}
+	return types
+}
+
+// StaleChanges marks the checks whose output and status haven't changed for
+// longer than the stale window of their type as stale, and the stale checks
+// that changed again as fresh. It returns these changes. Like the alerts, the
+// blacklisted, silenced and in maintenance checks are left out.
+func (c *ConsulAlertClient) StaleChanges(now time.Time) ([]StaleChange, error) {
+	if len(c.currentConfig().Checks.StaleAfter) == 0 {
+		return nil, nil
+	}
+	kvPairs, _, err := c.api.KV().List("consul-alerts/checks/", nil)
+	if err != nil {
+		apiErrors.Inc("list_checks")
+		return nil, err
+	}
+	silences, _ := c.Silences()
+	var changes []StaleChange
+	for _, kvPair := range kvPairs {
+		var status Status
+		if err := json.Unmarshal(kvPair.Value, &status); err != nil || status.HealthCheck == nil || status.OutputTimestamp.IsZero() {
+			continue
+		}
+		check := status.HealthCheck
+		if !c.ownsNode(check.Node) {
+			continue
+		}
+		window := c.currentConfig().Checks.staleWindow(check)
+		stale := window > 0 && now.Sub(status.OutputTimestamp) > window
+		if stale == status.Stale {
+			continue
+		}
+		if stale && (c.IsBlacklisted(check) || !c.isWhitelisted(check) || c.inMaintenance(check) || isSilenced(silences, check, now)) {
+			continue
+		}
+
+		// the status is only updated if the check processing hasn't changed
+		// it meanwhile, it is looked at again on the next call otherwise
+		status.Stale = stale
+		data, _ := json.Marshal(status)
+		updated, _, err := c.api.KV().CAS(&consulapi.KVPair{Key: kvPair.Key, Value: data, ModifyIndex: kvPair.ModifyIndex}, nil)
+		if err != nil {
+			apiErrors.Inc("stale_checks")
+			return changes, err
+		}
+		if !updated {
+			continue
+		}
+		changes = append(changes, StaleChange{Check: *check, Stale: stale, Since: status.OutputTimestamp})
+	}
+	return changes, nil
+}
@@ -0,0 +1,39 @@
+package consul
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadStaleAfter(t *testing.T) {
+	var staleAfter map[string]int
+	if err := loadStaleAfter(&staleAfter, []byte(`{"script": 900, "http": 0}`)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if staleAfter["script"] != 900 || len(staleAfter) != 2 {
+		t.Errorf("unexpected stale windows %v", staleAfter)
+	}
+	if err := loadStaleAfter(&staleAfter, []byte(`{"default": 3600}`)); err == nil {
+		t.Error("a default window should be rejected")
+	}
+	if err := loadStaleAfter(&staleAfter, []byte(`{"script": -1}`)); err == nil {
+		t.Error("a negative window should be rejected")
+	}
+	if err := loadStaleAfter(&staleAfter, []byte(`900`)); err == nil {
+		t.Error("a number should be rejected")
+	}
+}
+
+func TestStaleWindow(t *testing.T) {
+	checks := &ChecksConfig{StaleAfter: map[string]int{"script": 900, "http": 0}}
+	for checkType, expected := range map[string]time.Duration{
+		"script": 15 * time.Minute,
+		"http":   0,
+		"ttl":    0,
+		"":       0,
+	} {
+		if window := checks.staleWindow(&Check{Type: checkType}); window != expected {
+			t.Errorf("expected %s for %q, got %s", expected, checkType, window)
+		}
+	}
+}
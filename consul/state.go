@@ -0,0 +1,106 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// StateVersion is the version of the state snapshot format.
+const StateVersion = 1
+
+// StateSnapshot is the tracked state of consul-alerts: the check states, with
+// their acknowledgements and the last notification times the reminders are
+// scheduled from, and the silences.
+type StateSnapshot struct {
+	Version    int       `json:"version"`
+	Exported   time.Time `json:"exported"`
+	Datacenter string    `json:"datacenter"`
+	Checks     []Status  `json:"checks"`
+	Silences   []Silence `json:"silences"`
+}
+
+// ExportState returns a snapshot of the tracked state.
+func (c *ConsulAlertClient) ExportState() (*StateSnapshot, error) {
+	statuses, err := c.CheckStatuses("")
+	if err != nil {
+		return nil, err
+	}
+	silences, err := c.Silences()
+	if err != nil {
+		return nil, err
+	}
+	return &StateSnapshot{
+		Version:    StateVersion,
+		Exported:   time.Now(),
+		Datacenter: c.datacenter,
+		Checks:     statuses,
+		Silences:   silences,
+	}, nil
+}
+
+// ImportState stores the check states and the silences of a snapshot,
+// replacing the ones with the same keys. The checks of the datacenter the
+// snapshot was exported from are imported as the checks of this datacenter.
+// The keys are replaced with a check-and-set against their index when the
+// import started, so the ones a running instance changed meanwhile are kept
+// and reported in the error.
+func (c *ConsulAlertClient) ImportState(snapshot *StateSnapshot) error {
+	if snapshot.Version != StateVersion {
+		return fmt.Errorf("unsupported state snapshot version %d, expected %d", snapshot.Version, StateVersion)
+	}
+	indexes := make(map[string]uint64)
+	for _, prefix := range []string{"consul-alerts/checks/", silencePrefix} {
+		kvPairs, _, err := c.api.KV().List(prefix, nil)
+		if err != nil {
+			apiErrors.Inc("import_state")
+			return err
+		}
+		for _, kvPair := range kvPairs {
+			indexes[kvPair.Key] = kvPair.ModifyIndex
+		}
+	}
+	var changed []string
+	put := func(key string, data []byte) error {
+		stored, _, err := c.api.KV().CAS(&consulapi.KVPair{Key: key, Value: data, ModifyIndex: indexes[key]}, nil)
+		if err != nil {
+			apiErrors.Inc("import_state")
+			return err
+		}
+		if !stored {
+			changed = append(changed, key)
+		}
+		return nil
+	}
+	for _, status := range snapshot.Checks {
+		if status.HealthCheck == nil {
+			continue
+		}
+		health := *status.HealthCheck
+		if health.Datacenter == snapshot.Datacenter {
+			health.Datacenter = ""
+		}
+		status.HealthCheck = &health
+		data, _ := json.Marshal(status)
+		if err := put(c.checkKey(health.Datacenter, health.Node, health.ServiceID, health.CheckID), data); err != nil {
+			return err
+		}
+	}
+	for _, silence := range snapshot.Silences {
+		if silence.ID == "" {
+			silence.ID = newSilenceId()
+		}
+		data, _ := json.Marshal(silence)
+		if err := put(silencePrefix+silence.ID, data); err != nil {
+			return err
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%d key(s) changed during the import were kept, stop the consul-alerts instances and import again: %s", len(changed), strings.Join(changed, ", "))
+	}
+	return nil
+}
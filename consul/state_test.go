@@ -0,0 +1,167 @@
+package consul
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestImportStateVersion(t *testing.T) {
+	client := &ConsulAlertClient{datacenter: "dc1"}
+	if err := client.ImportState(&StateSnapshot{Version: StateVersion + 1}); err == nil {
+		t.Error("expected an unsupported version error")
+	}
+}
+
+// memoryKV is an in-memory consul KV serving the List, Get, Put and CAS
+// requests.
+type memoryKV struct {
+	sync.Mutex
+	values  map[string][]byte
+	indexes map[string]uint64
+	index   uint64
+	// listed is called after each List
+	listed func(kv *memoryKV)
+}
+
+type memoryPair struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{values: make(map[string][]byte), indexes: make(map[string]uint64)}
+}
+
+func (kv *memoryKV) set(key string, value []byte) {
+	kv.index++
+	kv.values[key], kv.indexes[key] = value, kv.index
+}
+
+func (kv *memoryKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kv.Lock()
+	defer kv.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	_, recurse := r.URL.Query()["recurse"]
+	switch r.Method {
+	case "GET":
+		var pairs []memoryPair
+		for k, value := range kv.values {
+			if k == key || (recurse && strings.HasPrefix(k, key)) {
+				pairs = append(pairs, memoryPair{k, value, kv.indexes[k]})
+			}
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(404)
+			return
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+		json.NewEncoder(w).Encode(pairs)
+		if recurse && kv.listed != nil {
+			kv.listed(kv)
+		}
+	case "PUT":
+		if cas := r.URL.Query().Get("cas"); cas != "" {
+			if index, _ := strconv.ParseUint(cas, 10, 64); index != kv.indexes[key] {
+				w.Write([]byte("false"))
+				return
+			}
+		}
+		value, _ := ioutil.ReadAll(r.Body)
+		kv.set(key, value)
+		w.Write([]byte("true"))
+	}
+}
+
+func memoryClient(t *testing.T, kv *memoryKV, dc string) (*ConsulAlertClient, func()) {
+	server := httptest.NewServer(kv)
+	client := &ConsulAlertClient{state: &configState{config: DefaultAlertConfig()}, datacenter: dc}
+	if err := client.connect(ClientConfig{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	return client, server.Close
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	notified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	statuses := []Status{
+		{Current: "critical", CurrentTimestamp: notified, NotifiedTimestamp: notified, NotifiedStatus: "critical",
+			HealthCheck:    &Check{Node: "web1", ServiceID: "web", CheckID: "http", Status: "critical"},
+			AcknowledgedBy: "ops", AcknowledgedTimestamp: notified},
+		{Current: "warning", CurrentTimestamp: notified, HealthCheck: &Check{Datacenter: "dc3", Node: "db1", CheckID: "disk", Status: "warning"}},
+	}
+	source := newMemoryKV()
+	exporter, closeSource := memoryClient(t, source, "dc1")
+	defer closeSource()
+	for _, status := range statuses {
+		data, _ := json.Marshal(status)
+		check := status.HealthCheck
+		source.set(exporter.checkKey(check.Datacenter, check.Node, check.ServiceID, check.CheckID), data)
+	}
+	silence, _ := json.Marshal(Silence{ID: "s1", Node: "web1", EndsAt: notified.Add(time.Hour), CreatedBy: "ops"})
+	source.set(silencePrefix+"s1", silence)
+
+	snapshot, err := exporter.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(snapshot)
+	var imported StateSnapshot
+	json.Unmarshal(data, &imported)
+
+	target := newMemoryKV()
+	importer, closeTarget := memoryClient(t, target, "dc2")
+	defer closeTarget()
+	if err := importer.ImportState(&imported); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := target.values["consul-alerts/checks/web1/web/http"]; !found {
+		t.Errorf("expected the dc1 checks imported as the dc2 checks, got %v", target.indexes)
+	}
+	roundTrip, err := importer.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip.Checks, snapshot.Checks) || !reflect.DeepEqual(roundTrip.Silences, snapshot.Silences) {
+		t.Errorf("expected the imported state to export the same, got %+v and %+v", roundTrip, snapshot)
+	}
+}
+
+func TestImportStateKeepsChangedKeys(t *testing.T) {
+	target := newMemoryKV()
+	stored, _ := json.Marshal(Status{Current: "passing", HealthCheck: &Check{Node: "web1", CheckID: "disk", Status: "passing"}})
+	target.set("consul-alerts/checks/web1/_/disk", stored)
+	// a running instance updates the check while the state is imported
+	updated, _ := json.Marshal(Status{Current: "critical", HealthCheck: &Check{Node: "web1", CheckID: "disk", Status: "critical"}})
+	target.listed = func(kv *memoryKV) {
+		kv.listed = nil
+		kv.set("consul-alerts/checks/web1/_/disk", updated)
+	}
+	client, closeTarget := memoryClient(t, target, "dc1")
+	defer closeTarget()
+
+	snapshot := &StateSnapshot{Version: StateVersion, Datacenter: "dc1", Checks: []Status{
+		{Current: "warning", HealthCheck: &Check{Node: "web1", CheckID: "disk", Status: "warning"}},
+		{Current: "warning", HealthCheck: &Check{Node: "web2", CheckID: "disk", Status: "warning"}},
+	}}
+	err := client.ImportState(snapshot)
+	if err == nil || !strings.Contains(err.Error(), "consul-alerts/checks/web1/_/disk") {
+		t.Errorf("expected the changed key reported, got %v", err)
+	}
+	if string(target.values["consul-alerts/checks/web1/_/disk"]) != string(updated) {
+		t.Error("expected the changed check kept")
+	}
+	if _, found := target.values["consul-alerts/checks/web2/_/disk"]; !found {
+		t.Error("expected the other checks imported")
+	}
+}
@@ -0,0 +1,41 @@
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// timeoutKey matches the delivery timeout of a notifier.
+var timeoutKey = regexp.MustCompile(`^consul-alerts/config/notifiers/([^/]+)/timeout$`)
+
+// defaultNotifierTimeout is the delivery timeout, in seconds, of the notifiers
+// without one.
+const defaultNotifierTimeout = 60
+
+// loadTimeoutValue loads the delivery timeout of a notifier and returns true
+// if the key is one.
+func loadTimeoutValue(key string, data []byte, timeouts map[string]int) (bool, error) {
+	match := timeoutKey.FindStringSubmatch(key)
+	if match == nil {
+		return false, nil
+	}
+	var timeout int
+	if err := loadCustomValue(&timeout, data, ConfigTypeInt); err != nil {
+		return true, err
+	}
+	if timeout < 1 {
+		return true, fmt.Errorf("expected a timeout of at least 1 second, got %d", timeout)
+	}
+	timeouts[match[1]] = timeout
+	return true, nil
+}
+
+// NotifierTimeout returns how long a notifier, "custom" for each custom
+// notifier, has to deliver the alerts.
+func (c *ConsulAlertClient) NotifierTimeout(name string) time.Duration {
+	if timeout, found := c.currentConfig().Notifiers.Timeouts[name]; found {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultNotifierTimeout * time.Second
+}
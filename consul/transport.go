@@ -0,0 +1,112 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+)
+
+// TLSConfig configures HTTPS connections to the consul agent.
+type TLSConfig struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// tlsConfig builds the crypto/tls configuration from the CA and client
+// certificate files.
+func (t TLSConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// newTransport returns the address to give to the consul api and the
+// transport used to reach it. An https:// address enables TLS and a unix://
+// address connects to the agent through a unix socket.
+func newTransport(address string, tlsOptions TLSConfig) (string, *rewriteTransport, error) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		socket := strings.TrimPrefix(address, "unix://")
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// the host is only used to build the request url
+		address = "localhost"
+	case strings.HasPrefix(address, "https://"):
+		tlsOptions.Enabled = true
+		address = strings.TrimPrefix(address, "https://")
+	case strings.HasPrefix(address, "http://"):
+		address = strings.TrimPrefix(address, "http://")
+	}
+
+	if !tlsOptions.Enabled {
+		return address, &rewriteTransport{scheme: "http", transport: transport}, nil
+	}
+
+	config, err := tlsOptions.tlsConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	transport.TLSClientConfig = config
+	return address, &rewriteTransport{scheme: "https", transport: transport}, nil
+}
+
+// rewriteTransport sets the scheme and the extra query parameters of every
+// request. The consul api always builds http:// urls and doesn't know about
+// enterprise namespaces and partitions.
+type rewriteTransport struct {
+	scheme    string
+	params    url.Values
+	transport *http.Transport
+}
+
+func (r *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestUrl := *req.URL
+	requestUrl.Scheme = r.scheme
+	if len(r.params) > 0 {
+		query := requestUrl.Query()
+		for key, values := range r.params {
+			if query.Get(key) == "" {
+				query[key] = values
+			}
+		}
+		requestUrl.RawQuery = query.Encode()
+	}
+	request := *req
+	request.URL = &requestUrl
+	return r.transport.RoundTrip(&request)
+}
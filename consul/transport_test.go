@@ -0,0 +1,80 @@
+package consul
+
+import (
+	"testing"
+
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+func TestNewTransportForHttpAddress(t *testing.T) {
+	address, transport, err := newTransport("http://localhost:8500", TLSConfig{})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if address != "localhost:8500" {
+		t.Errorf("expected localhost:8500, got %s", address)
+	}
+	if transport.scheme != "http" {
+		t.Error("plain http should not use TLS")
+	}
+}
+
+func TestNewTransportForHttpsAddress(t *testing.T) {
+	address, transport, err := newTransport("https://consul.example.com:8501", TLSConfig{ServerName: "consul"})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if address != "consul.example.com:8501" {
+		t.Errorf("expected consul.example.com:8501, got %s", address)
+	}
+	if transport.scheme != "https" {
+		t.Fatal("https address should enable TLS")
+	}
+	if transport.transport.TLSClientConfig.ServerName != "consul" {
+		t.Error("server name should be set")
+	}
+}
+
+func TestNewTransportWithMissingCA(t *testing.T) {
+	if _, _, err := newTransport("localhost:8501", TLSConfig{Enabled: true, CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("missing CA file should fail")
+	}
+}
+
+func TestNewTransportForUnixSocket(t *testing.T) {
+	address, transport, err := newTransport("unix:///var/run/consul/http.sock", TLSConfig{})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if address != "localhost" {
+		t.Errorf("expected localhost, got %s", address)
+	}
+	if transport.transport.DialContext == nil {
+		t.Error("unix socket transport should dial the socket")
+	}
+}
+
+func TestRewriteTransportAddsParams(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	address, transport, _ := newTransport(server.URL, TLSConfig{})
+	transport.params = url.Values{"ns": {"team-a"}, "partition": {"default"}}
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get("http://" + address + "/v1/kv/key?ns=override")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	resp.Body.Close()
+	if query.Get("ns") != "override" {
+		t.Errorf("explicit ns should be kept, got %s", query.Get("ns"))
+	}
+	if query.Get("partition") != "default" {
+		t.Errorf("expected partition default, got %s", query.Get("partition"))
+	}
+}
@@ -0,0 +1,198 @@
+package consul
+
+import "errors"
+
+// The Validate methods report missing settings that would make an enabled
+// notifier fail on every notification. Disabled notifiers are always valid.
+
+func (c *EmailNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.Port <= 0:
+		return errors.New("port is required")
+	case c.SenderEmail == "":
+		return errors.New("sender-email is required")
+	case len(c.Receivers) == 0 && len(c.ServiceReceivers) == 0 && len(c.NodeReceivers) == 0 && len(c.DatacenterReceivers) == 0:
+		return errors.New("at least one receiver is required")
+	}
+	return nil
+}
+
+func (c *LogNotifierConfig) Validate() error {
+	if c.Enabled && c.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+func (c *InfluxdbNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Host == "":
+		return errors.New("host is required")
+	case c.Database == "":
+		return errors.New("database is required")
+	}
+	return nil
+}
+
+func (c *SlackNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Token != "" && c.Channel == "":
+		return errors.New("channel is required with a token")
+	case c.Token == "" && c.Url == "":
+		return errors.New("url is required")
+	}
+	return nil
+}
+
+func (c *PagerDutyNotifierConfig) Validate() error {
+	if c.Enabled && c.ServiceKey == "" {
+		return errors.New("service-key is required")
+	}
+	return nil
+}
+
+func (c *HipChatNotifierConfig) Validate() error {
+	if c.Enabled && c.RoomId == "" {
+		return errors.New("room-id is required")
+	}
+	return nil
+}
+
+func (c *VictorOpsNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.ApiKey == "":
+		return errors.New("api-key is required")
+	case c.RoutingKey == "":
+		return errors.New("routing-key is required")
+	}
+	return nil
+}
+
+func (c *ZulipNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.BotEmail == "" || c.ApiKey == "":
+		return errors.New("bot-email and api-key are required")
+	case c.Stream == "":
+		return errors.New("stream is required")
+	}
+	return nil
+}
+
+func (c *DiscordNotifierConfig) Validate() error {
+	if c.Enabled && c.Url == "" {
+		return errors.New("url is required")
+	}
+	return nil
+}
+
+func (c *NatsNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.Subject == "":
+		return errors.New("subject is required")
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	return nil
+}
+
+func (c *MqttNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.Qos < 0 || c.Qos > 2:
+		return errors.New("qos must be 0, 1 or 2")
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	return nil
+}
+
+func (c *SplunkNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.Token == "":
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (c *NrdpNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Url == "":
+		return errors.New("url is required")
+	case c.Token == "":
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (c *ZabbixNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Server == "":
+		return errors.New("server is required")
+	}
+	return nil
+}
+
+func (c *SentryNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Dsn == "":
+		return errors.New("dsn is required")
+	}
+	return nil
+}
+
+func (c *AwsNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Region == "":
+		return errors.New("region is required")
+	case c.QueueUrl == "" && c.EventBus == "":
+		return errors.New("queue-url or event-bus is required")
+	case c.AccessKeyId != "" && c.SecretAccessKey == "":
+		return errors.New("secret-access-key is required with access-key-id")
+	}
+	return nil
+}
+
+func (c *PubsubNotifierConfig) Validate() error {
+	switch {
+	case !c.Enabled:
+		return nil
+	case c.Project == "":
+		return errors.New("project is required")
+	case c.Topic == "":
+		return errors.New("topic is required")
+	}
+	return nil
+}
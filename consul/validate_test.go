@@ -0,0 +1,21 @@
+package consul
+
+import "testing"
+
+func TestDisabledNotifierIsValid(t *testing.T) {
+	config := DefaultAlertConfig()
+	if err := config.Notifiers.Email.Validate(); err != nil {
+		t.Errorf("disabled email notifier should be valid: %s", err)
+	}
+}
+
+func TestEnabledEmailNotifierRequiresReceivers(t *testing.T) {
+	email := &EmailNotifierConfig{Enabled: true, Url: "localhost", Port: 25, SenderEmail: "alerts@example.com"}
+	if err := email.Validate(); err == nil {
+		t.Error("email notifier without receivers should be invalid")
+	}
+	email.NodeReceivers = map[string][]string{"node1": []string{"ops@example.com"}}
+	if err := email.Validate(); err != nil {
+		t.Errorf("email notifier should be valid: %s", err)
+	}
+}
@@ -0,0 +1,163 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// vaultRefreshInterval is how long a secret without a lease is cached before
+// it's read again from vault.
+const vaultRefreshInterval = 5 * time.Minute
+
+// VaultConfig configures the vault server used to resolve the
+// vault:<path>#<key> config values.
+type VaultConfig struct {
+	Address string
+	Token   string
+	TLS     TLSConfig
+}
+
+// vault is the client used by resolveSecrets, nil if vault isn't configured.
+var vault *vaultClient
+
+type vaultSecret struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// vaultClient reads secrets from the vault HTTP API. Secrets are cached until
+// their lease expires so the config can be reloaded often without reading
+// vault every time, and rotated secrets are picked up on the next reload.
+type vaultClient struct {
+	address    string
+	token      string
+	httpClient *http.Client
+
+	lock    sync.Mutex
+	secrets map[string]vaultSecret
+}
+
+func newVaultClient(config VaultConfig) (*vaultClient, error) {
+	address, transport, err := newTransport(config.Address, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return &vaultClient{
+		address:    fmt.Sprintf("%s://%s", transport.scheme, address),
+		token:      config.Token,
+		httpClient: &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		secrets:    make(map[string]vaultSecret),
+	}, nil
+}
+
+// resolve returns the value of a vault:<path>#<key> reference. The last known
+// value is used if vault can't be reached once the secret has expired.
+func (v *vaultClient) resolve(reference string) (string, error) {
+	path, key := reference, ""
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		path, key = reference[:i], reference[i+1:]
+	}
+	if path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, expected vault:<path>#<key>", "vault:"+reference)
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	secret, cached := v.secrets[path]
+	if !cached || time.Now().After(secret.expires) {
+		fresh, err := v.read(path)
+		switch {
+		case err == nil:
+			secret = fresh
+			v.secrets[path] = secret
+		case cached:
+			log.Warnf("Unable to refresh vault secret %s, using the last known value: %s", path, err)
+		default:
+			return "", err
+		}
+	}
+
+	value, found := secret.values[key]
+	if !found {
+		return "", fmt.Errorf("key %s not found in vault secret %s", key, path)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// read fetches a secret. The values of the KV version 2 engine are nested
+// under data.data while version 1 values are directly under data.
+func (v *vaultClient) read(path string) (vaultSecret, error) {
+	var response struct {
+		LeaseDuration int                    `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := v.request("GET", "/v1/"+strings.TrimPrefix(path, "/"), &response); err != nil {
+		return vaultSecret{}, fmt.Errorf("unable to read vault secret %s: %s", path, err)
+	}
+
+	values := response.Data
+	if data, ok := response.Data["data"].(map[string]interface{}); ok && strings.Contains(path, "/data/") {
+		values = data
+	}
+	ttl := time.Duration(response.LeaseDuration) * time.Second
+	if ttl <= 0 || ttl > vaultRefreshInterval {
+		ttl = vaultRefreshInterval
+	}
+	return vaultSecret{values: values, expires: time.Now().Add(ttl)}, nil
+}
+
+// renewToken keeps a renewable token alive by renewing it when half of its
+// TTL has passed.
+func (v *vaultClient) renewToken() {
+	for {
+		var response struct {
+			Data struct {
+				TTL       int  `json:"ttl"`
+				Renewable bool `json:"renewable"`
+			} `json:"data"`
+		}
+		if err := v.request("GET", "/v1/auth/token/lookup-self", &response); err != nil {
+			log.Warnln("Unable to look up the vault token:", err)
+			time.Sleep(time.Minute)
+			continue
+		}
+		if !response.Data.Renewable || response.Data.TTL <= 0 {
+			log.Debugln("The vault token is not renewable.")
+			return
+		}
+
+		time.Sleep(time.Duration(response.Data.TTL) * time.Second / 2)
+		if err := v.request("POST", "/v1/auth/token/renew-self", nil); err != nil {
+			log.Warnln("Unable to renew the vault token:", err)
+		} else {
+			log.Debugln("Vault token renewed.")
+		}
+	}
+}
+
+func (v *vaultClient) request(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, v.address+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestVaultResolve(t *testing.T) {
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(403)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/consul-alerts":
+			reads++
+			fmt.Fprint(w, `{"lease_duration": 0, "data": {"data": {"smtp_password": "s3cret"}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/consul-alerts":
+			fmt.Fprint(w, `{"lease_duration": 3600, "data": {"token": "abc"}}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	client, err := newVaultClient(VaultConfig{Address: server.URL, Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if value, err := client.resolve("secret/data/consul-alerts#smtp_password"); err != nil || value != "s3cret" {
+			t.Errorf("unexpected kv v2 value: %s, %v", value, err)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("the secret should be cached, read %d times", reads)
+	}
+	if value, err := client.resolve("kv/consul-alerts#token"); err != nil || value != "abc" {
+		t.Errorf("unexpected kv v1 value: %s, %v", value, err)
+	}
+	if _, err := client.resolve("secret/data/consul-alerts#missing"); err == nil {
+		t.Error("a missing key should be an error")
+	}
+	if _, err := client.resolve("secret/data/other#key"); err == nil {
+		t.Error("a missing secret should be an error")
+	}
+	if _, err := client.resolve("secret/data/consul-alerts"); err == nil {
+		t.Error("a reference without a key should be an error")
+	}
+}
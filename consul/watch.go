@@ -0,0 +1,77 @@
+package consul
+
+import (
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/armon/consul-api"
+)
+
+// watchWaitTime is the maximum time a blocking query waits for changes.
+const watchWaitTime = 5 * time.Minute
+
+// WatchChecks runs a blocking query for the health checks of a datacenter. It
+// returns when the checks change after waitIndex or the wait time elapses,
+// together with the index to use for the next call. A zero waitIndex returns
+// immediately.
+func (c *ConsulAlertClient) WatchChecks(dc string, waitIndex uint64) ([]Check, uint64, error) {
+	options := &consulapi.QueryOptions{Datacenter: dc, WaitIndex: waitIndex, WaitTime: watchWaitTime}
+	healths, meta, err := c.watchApi.Health().State("any", options)
+	if err != nil {
+		apiErrors.Inc("watch_checks")
+		return nil, waitIndex, err
+	}
+	checks := make([]Check, len(healths))
+	for i, health := range healths {
+		checks[i] = toCheck(health, c.checkDatacenter(dc), c.clientConfig.Namespace)
+	}
+	return checks, meta.LastIndex, nil
+}
+
+// WatchEvents runs a blocking query for the user events. Only the events
+// received after the event identified by waitIndex are returned, mirroring
+// `consul watch -type event`.
+func (c *ConsulAlertClient) WatchEvents(waitIndex uint64) ([]Event, uint64, error) {
+	eventApi := c.watchApi.Event()
+	options := &consulapi.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime}
+	userEvents, meta, err := eventApi.List("", options)
+	if err != nil {
+		apiErrors.Inc("watch_events")
+		return nil, waitIndex, err
+	}
+
+	// prune to the events newer than the last one seen
+	for i := range userEvents {
+		if eventApi.IDToIndex(userEvents[i].ID) == waitIndex {
+			userEvents = userEvents[i+1:]
+			break
+		}
+	}
+
+	events := make([]Event, len(userEvents))
+	for i, userEvent := range userEvents {
+		events[i] = Event{
+			ID:            userEvent.ID,
+			Name:          userEvent.Name,
+			Payload:       userEvent.Payload,
+			NodeFilter:    userEvent.NodeFilter,
+			ServiceFilter: userEvent.ServiceFilter,
+			TagFilter:     userEvent.TagFilter,
+			Version:       uint(userEvent.Version),
+			LTime:         uint(userEvent.LTime),
+		}
+	}
+	return events, meta.LastIndex, nil
+}
+
+// WatchConfig runs a blocking query on the consul-alerts/config KV prefix. It
+// returns when a config value is added, changed or removed, or when the wait
+// time expires.
+func (c *ConsulAlertClient) WatchConfig(waitIndex uint64) (uint64, error) {
+	options := &consulapi.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime}
+	_, meta, err := c.watchApi.KV().List("consul-alerts/config", options)
+	if err != nil {
+		apiErrors.Inc("watch_config")
+		return waitIndex, err
+	}
+	return meta.LastIndex, nil
+}
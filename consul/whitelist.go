@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"fmt"
+
+	"encoding/json"
+)
+
+// WhitelistConfig restricts the notifications to the checks of the listed
+// services, the listed checks and the services with the listed tags when
+// enabled. The entries are patterns like the blacklist patterns.
+type WhitelistConfig struct {
+	Enabled  bool
+	Services []string
+	Checks   []string
+	Tags     []string
+
+	services, checks, tags []func(string) bool
+}
+
+// Matches returns true if the check can be notified.
+func (w *WhitelistConfig) Matches(check *Check) bool {
+	if !w.Enabled {
+		return true
+	}
+	if check.ServiceID != "" && (matchesAny(w.services, check.ServiceID) || matchesAny(w.services, check.ServiceName)) {
+		return true
+	}
+	if matchesAny(w.checks, check.CheckID) || matchesAny(w.checks, check.Name) {
+		return true
+	}
+	for _, tag := range check.ServiceTags {
+		if matchesAny(w.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(matchers []func(string) bool, value string) bool {
+	for _, matches := range matchers {
+		if matches(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWhitelistPatterns loads a JSON array of patterns.
+func loadWhitelistPatterns(patterns *[]string, matchers *[]func(string) bool, data []byte) error {
+	var val []string
+	if err := json.Unmarshal(data, &val); err != nil {
+		return fmt.Errorf(`expected a JSON array of patterns like ["web-*", "/^api-.*/"], got %q`, data)
+	}
+	compiled := make([]func(string) bool, len(val))
+	for i, pattern := range val {
+		matcher, err := patternMatcher(pattern)
+		if err != nil {
+			return err
+		}
+		compiled[i] = matcher
+	}
+	*patterns = val
+	*matchers = compiled
+	return nil
+}
+
+// isWhitelisted returns true if the check can be notified.
+func (c *ConsulAlertClient) isWhitelisted(check *Check) bool {
+	return c.currentConfig().Checks.Whitelist.Matches(check)
+}
@@ -0,0 +1,47 @@
+package consul
+
+import "testing"
+
+func TestWhitelistMatches(t *testing.T) {
+	whitelist := &WhitelistConfig{Enabled: true}
+	if err := loadWhitelistPatterns(&whitelist.Services, &whitelist.services, []byte(`["web-*"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadWhitelistPatterns(&whitelist.Checks, &whitelist.checks, []byte(`["serfHealth"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadWhitelistPatterns(&whitelist.Tags, &whitelist.tags, []byte(`["/^alert-.*/"]`)); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		check   Check
+		matches bool
+	}{
+		{Check{ServiceID: "web-1", ServiceName: "web", CheckID: "http"}, true},
+		{Check{ServiceID: "1", ServiceName: "web-frontend", CheckID: "http"}, true},
+		{Check{CheckID: "serfHealth"}, true},
+		{Check{ServiceID: "db", ServiceName: "db", CheckID: "tcp", ServiceTags: []string{"primary", "alert-dba"}}, true},
+		{Check{ServiceID: "db", ServiceName: "db", CheckID: "tcp", ServiceTags: []string{"primary"}}, false},
+		{Check{CheckID: "disk"}, false},
+	}
+	for _, c := range cases {
+		if whitelist.Matches(&c.check) != c.matches {
+			t.Errorf("expected %v for %+v", c.matches, c.check)
+		}
+	}
+
+	whitelist.Enabled = false
+	if !whitelist.Matches(&Check{CheckID: "disk"}) {
+		t.Error("a disabled whitelist should match every check")
+	}
+}
+
+func TestLoadWhitelistPatternsInvalid(t *testing.T) {
+	var patterns []string
+	var matchers []func(string) bool
+	for _, data := range []string{`web-*`, `["/[/"]`} {
+		if err := loadWhitelistPatterns(&patterns, &matchers, []byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"html/template"
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const maxRecentNotifications = 50
+
+// recent keeps the last notified messages in memory for the dashboard.
+var recent = struct {
+	sync.Mutex
+	messages notifier.Messages
+}{}
+
+func recordNotifications(messages notifier.Messages) {
+	recent.Lock()
+	defer recent.Unlock()
+	recent.messages = append(append(notifier.Messages{}, messages...), recent.messages...)
+	if len(recent.messages) > maxRecentNotifications {
+		recent.messages = recent.messages[:maxRecentNotifications]
+	}
+}
+
+func recentNotifications() notifier.Messages {
+	recent.Lock()
+	defer recent.Unlock()
+	return append(notifier.Messages{}, recent.messages...)
+}
+
+type dashboardData struct {
+	ClusterName   string
+	SystemStatus  string
+	FailCount     int
+	WarnCount     int
+	PassCount     int
+	Nodes         map[string]notifier.Messages
+	Services      map[string]notifier.Messages
+	Silences      []consul.Silence
+	Notifications notifier.Messages
+	Updated       time.Time
+}
+
+func (d dashboardData) IsCritical() bool {
+	return d.SystemStatus == notifier.SYSTEM_CRITICAL
+}
+
+func (d dashboardData) IsWarning() bool {
+	return d.SystemStatus == notifier.SYSTEM_UNSTABLE
+}
+
+func (d dashboardData) IsPassing() bool {
+	return d.SystemStatus == notifier.SYSTEM_HEALTHY
+}
+
+var dashboardTmpl = template.Must(template.New("dashboard").Parse(dashboardTemplate))
+
+// dashboardHandler renders the current cluster status as a web page.
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/ui" && r.URL.Path != "/ui/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	statuses, err := checkStatuses("")
+	if err != nil {
+		http.Error(w, "unable to retrieve check statuses: "+err.Error(), 503)
+		return
+	}
+
+	checks := make(notifier.Messages, 0, len(statuses))
+	for _, status := range statuses {
+		check := status.HealthCheck
+		checks = append(checks, notifier.Message{
+			Datacenter: check.Datacenter,
+			Namespace:  check.Namespace,
+			Node:       check.Node,
+			ServiceId:  check.ServiceID,
+			Service:    check.ServiceName,
+			CheckId:    check.CheckID,
+			Check:      check.Name,
+			Status:     status.Current,
+			Output:     check.Output,
+			Notes:      check.Notes,
+			Timestamp:  status.CurrentTimestamp,
+		})
+	}
+	systemStatus, pass, warn, fail := checks.Summary()
+
+	data := dashboardData{
+		ClusterName:   consulClient.EmailConfig().ClusterName,
+		SystemStatus:  systemStatus,
+		FailCount:     fail,
+		WarnCount:     warn,
+		PassCount:     pass,
+		Nodes:         make(map[string]notifier.Messages),
+		Services:      make(map[string]notifier.Messages),
+		Notifications: recentNotifications(),
+		Updated:       time.Now(),
+	}
+	for _, check := range checks {
+		if !check.IsCritical() && !check.IsWarning() {
+			continue
+		}
+		data.Nodes[check.Node] = append(data.Nodes[check.Node], check)
+		if check.Service != "" {
+			data.Services[check.Service] = append(data.Services[check.Service], check)
+		}
+	}
+
+	if silences, err := consulClient.Silences(); err == nil {
+		for _, silence := range silences {
+			if silence.Active(data.Updated) {
+				data.Silences = append(data.Silences, silence)
+			}
+		}
+		sort.Sort(silencesByEnd(data.Silences))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTmpl.Execute(w, data); err != nil {
+		log.Errorln("Unable to render dashboard:", err)
+	}
+}
+
+type silencesByEnd []consul.Silence
+
+func (s silencesByEnd) Len() int           { return len(s) }
+func (s silencesByEnd) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s silencesByEnd) Less(i, j int) bool { return s[i].EndsAt.Before(s[j].EndsAt) }
+
+const dashboardTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<title>{{ .ClusterName }}</title>
+		<meta http-equiv="refresh" content="30">
+		<style>
+			body { margin: 0; font-family: 'Helvetica', 'Arial', sans-serif; color: #000000; }
+			.section { margin-left: auto; margin-right: auto; width: 48em; padding-top: 10px; padding-bottom: 10px; }
+			.banner { padding: 10px; font-weight: bold; color: #ffffff; }
+			.check { margin-top: 10px; padding: 10px; }
+			.critical { background-color: #e13329; }
+			.warning { background-color: #eebb00; }
+			.passing { background-color: #24c75a; }
+			table { width: 100%; font-size: 0.85em; border-collapse: collapse; }
+			td, th { text-align: left; padding: 4px; border-bottom: 1px solid #dddddd; }
+			pre { white-space: pre-wrap; }
+		</style>
+	</head>
+
+	<body>
+		<div class="section">
+			<div class="banner {{ if .IsCritical }}critical{{ else if .IsWarning }}warning{{ else }}passing{{ end }}">
+				{{ .ClusterName }} is {{ .SystemStatus }}
+			</div>
+			<p>
+				<strong>Failed:</strong> {{ .FailCount }}
+				<strong>Warning:</strong> {{ .WarnCount }}
+				<strong>Passed:</strong> {{ .PassCount }}
+				<br/>
+				<span style="font-size: 0.8em;">Updated {{ .Updated.Format "2006-01-02 15:04:05 MST" }}</span>
+			</p>
+		</div>
+
+		<div class="section">
+			<h3>Checks by node</h3>
+			{{ range $name, $checks := .Nodes }}
+			<div style="font-size: 1.1em; margin-top: 15px;"><strong>Node: {{ $name }}</strong></div>
+			{{ range $check := $checks }}
+			<div class="check {{ if $check.IsCritical }}critical{{ else if $check.IsWarning }}warning{{ end }}">
+				<div style="font-weight: bold;">{{ with $check.Service }}{{ . }}: {{ end }}{{ $check.Check }}</div>
+				<div style="font-size: 0.85em;"><strong>Since: </strong>{{ $check.Timestamp }}</div>
+				{{ with $check.Notes }}<div><strong>Notes: </strong><pre>{{ . }}</pre></div>{{ end }}
+				<div><strong>Output:</strong><pre>{{ $check.Output }}</pre></div>
+			</div>
+			{{ end }}
+			{{ else }}
+			<p>All checks are passing.</p>
+			{{ end }}
+		</div>
+
+		<div class="section">
+			<h3>Checks by service</h3>
+			<table>
+				<tr><th>Service</th><th>Node</th><th>Check</th><th>Status</th></tr>
+				{{ range $name, $checks := .Services }}
+				{{ range $check := $checks }}
+				<tr><td>{{ $name }}</td><td>{{ $check.Node }}</td><td>{{ $check.Check }}</td><td>{{ $check.Status }}</td></tr>
+				{{ end }}
+				{{ end }}
+			</table>
+		</div>
+
+		<div class="section">
+			<h3>Active silences</h3>
+			<table>
+				<tr><th>Node</th><th>Service</th><th>Check</th><th>Ends</th><th>Created by</th><th>Comment</th></tr>
+				{{ range .Silences }}
+				<tr><td>{{ .Node }}</td><td>{{ .Service }}</td><td>{{ .Check }}</td><td>{{ .EndsAt.Format "2006-01-02 15:04 MST" }}</td><td>{{ .CreatedBy }}</td><td>{{ .Comment }}</td></tr>
+				{{ end }}
+			</table>
+		</div>
+
+		<div class="section">
+			<h3>Recent notifications</h3>
+			<table>
+				<tr><th>Time</th><th>Node</th><th>Service</th><th>Check</th><th>Status</th></tr>
+				{{ range .Notifications }}
+				<tr><td>{{ .Timestamp.Format "2006-01-02 15:04:05" }}</td><td>{{ .Node }}</td><td>{{ .Service }}</td><td>{{ .Check }}</td><td>{{ .Status }}</td></tr>
+				{{ end }}
+			</table>
+		</div>
+	</body>
+</html>
+`
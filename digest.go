@@ -0,0 +1,311 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// digestBatch holds the alerts of the digest of a notifier sent with the same
+// route receivers, slack channel and zulip stream and topic. The Quiet
+// batches hold the alerts of the quiet hours of the notifier.
+type digestBatch struct {
+	Receivers    []string          `json:"receivers,omitempty"`
+	SlackChannel string            `json:"slackChannel,omitempty"`
+	ZulipStream  string            `json:"zulipStream,omitempty"`
+	ZulipTopic   string            `json:"zulipTopic,omitempty"`
+	Quiet        bool              `json:"quiet,omitempty"`
+	Started      time.Time         `json:"started"`
+	Messages     notifier.Messages `json:"messages"`
+}
+
+// route returns the route the batch is sent with.
+func (b digestBatch) route() consul.Route {
+	return consul.Route{Receivers: b.Receivers, SlackChannel: b.SlackChannel, ZulipStream: b.ZulipStream, ZulipTopic: b.ZulipTopic}
+}
+
+// digests serializes the changes of the digests kept in consul.
+var digests sync.Mutex
+
+// digestKey is the notifier state key of the digests. The shard members keep
+// their own.
+func digestKey() string {
+	if shard != nil {
+		return "digest/" + shard.Id
+	}
+	return "digest"
+}
+
+// digest holds the messages of the statuses digested by a notifier, and the
+// ones held during its quiet hours, and returns the ones to send right away.
+// The messages are sent right away if the digest can't be saved.
+func digest(name string, route consul.Route, messages notifier.Messages) notifier.Messages {
+	config := consulClient.NotifierDigest(name)
+	quietHours := consulClient.NotifierQuietHours(name)
+	now := time.Now()
+	var sent, held, quiet notifier.Messages
+	for _, message := range messages {
+		switch {
+		case quietHours.Held(message.Status, message.NotifiedStatus, now):
+			quiet = append(quiet, message)
+		case config.Digested(message.Status):
+			held = append(held, message)
+		default:
+			sent = append(sent, message)
+		}
+	}
+	for _, batch := range []struct {
+		messages notifier.Messages
+		quiet    bool
+		name     string
+	}{{held, false, "digest"}, {quiet, true, "quiet hours"}} {
+		if len(batch.messages) == 0 {
+			continue
+		}
+		if err := holdForDigest(name, route, batch.messages, batch.quiet, now); err != nil {
+			log.Warnf("Unable to hold the %s %s, sending the alerts now: %s", name, batch.name, err)
+			sent = append(sent, batch.messages...)
+			continue
+		}
+		log.Debugf("%d alert(s) held for the %s %s.", len(batch.messages), name, batch.name)
+	}
+	// the alerts held before a check escalated are outdated
+	holding := config.Interval > 0 || quietHours.QuietHours != nil || quietHours.WorkingHours != nil
+	if holding && len(sent) > 0 {
+		if err := dropHeld(name, sent); err != nil {
+			log.Warnf("Unable to drop the alerts sent from the %s digest: %s", name, err)
+		}
+	}
+	return sent
+}
+
+// dropHeld removes the held alerts of the checks of the messages sent, like
+// the warnings of the checks turned critical since.
+func dropHeld(name string, messages notifier.Messages) error {
+	digests.Lock()
+	defer digests.Unlock()
+	batches, err := loadDigests(name)
+	if err != nil || len(batches) == 0 {
+		return err
+	}
+	kept, changed := withoutChecks(batches, messages)
+	if !changed {
+		return nil
+	}
+	return saveDigests(name, kept)
+}
+
+// withoutChecks returns the batches without the alerts of the checks of the
+// messages, the emptied batches removed, and whether any was removed.
+func withoutChecks(batches []digestBatch, messages notifier.Messages) ([]digestBatch, bool) {
+	changed := false
+	var kept []digestBatch
+	for _, batch := range batches {
+		var held notifier.Messages
+		for _, previous := range batch.Messages {
+			if containsCheck(messages, previous) {
+				changed = true
+			} else {
+				held = append(held, previous)
+			}
+		}
+		if batch.Messages = held; len(held) > 0 {
+			kept = append(kept, batch)
+		}
+	}
+	return kept, changed
+}
+
+func holdForDigest(name string, route consul.Route, messages notifier.Messages, quiet bool, now time.Time) error {
+	digests.Lock()
+	defer digests.Unlock()
+	batches, err := loadDigests(name)
+	if err != nil {
+		return err
+	}
+	batch := digestBatch{Receivers: route.Receivers, SlackChannel: route.SlackChannel, ZulipStream: route.ZulipStream, ZulipTopic: route.ZulipTopic, Quiet: quiet}
+	found := false
+	for i := range batches {
+		if batches[i].Quiet == quiet && reflect.DeepEqual(batches[i].route(), batch.route()) {
+			batches[i].Messages = mergeDigest(batches[i].Messages, messages)
+			found = true
+		}
+	}
+	if !found {
+		batch.Started, batch.Messages = now, mergeDigest(nil, messages)
+		batches = append(batches, batch)
+	}
+	return saveDigests(name, batches)
+}
+
+// mergeDigest adds the messages to the held ones, only the latest message of
+// each check is kept.
+func mergeDigest(held, messages notifier.Messages) notifier.Messages {
+	merged := append(notifier.Messages{}, held...)
+	for _, message := range messages {
+		replaced := false
+		for i, previous := range merged {
+			if sameCheck(previous, message) {
+				merged[i] = message
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, message)
+		}
+	}
+	return merged
+}
+
+func sameCheck(a, b notifier.Message) bool {
+	return a.Datacenter == b.Datacenter && a.Node == b.Node && a.ServiceId == b.ServiceId && a.CheckId == b.CheckId
+}
+
+func containsCheck(messages notifier.Messages, message notifier.Message) bool {
+	for _, m := range messages {
+		if sameCheck(m, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadDigests(name string) ([]digestBatch, error) {
+	data, err := consulClient.NotifierState(name, digestKey())
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var batches []digestBatch
+	if err := json.Unmarshal(data, &batches); err != nil {
+		log.Warnf("Ignoring the invalid %s digest: %s", name, err)
+		return nil, nil
+	}
+	return batches, nil
+}
+
+func saveDigests(name string, batches []digestBatch) error {
+	data, _ := json.Marshal(batches)
+	return consulClient.SetNotifierState(name, digestKey(), data)
+}
+
+// sendDueDigests sends the digests held for longer than the interval of their
+// notifier, or held by a notifier whose digest has been disabled since, and
+// the alerts held during the quiet hours that have ended. A batch is only
+// removed once sent, a failed one is sent again on the next run.
+func sendDueDigests(now time.Time) {
+	names := []string{"custom"}
+	for _, n := range builtinNotifiers() {
+		names = append(names, n.name)
+	}
+	for _, name := range names {
+		interval := time.Duration(consulClient.NotifierDigest(name).Interval) * time.Minute
+		for _, batch := range dueDigests(name, now, interval, consulClient.NotifierQuietHours(name)) {
+			if batch.Quiet {
+				log.Infof("Sending the %s quiet hours summary of %d alert(s).", name, len(batch.Messages))
+			} else {
+				log.Infof("Sending the %s digest of %d alert(s).", name, len(batch.Messages))
+			}
+			// the notifiers get a copy, the batch is matched to remove it
+			messages := append(notifier.Messages{}, batch.Messages...)
+			var deliveries []delivery
+			if name == "custom" {
+				deliveries = runCustomNotifiers(messages)
+			}
+			for _, n := range builtinNotifiers() {
+				if n.name == name {
+					deliveries = append(deliveries, runNotifier(name, routedNotifier(n.Notifier, batch.route()), messages))
+				}
+			}
+			if delivered(deliveries) {
+				removeDigest(name, batch)
+			}
+		}
+	}
+}
+
+// dueDigests returns the due digest batches of a notifier. The quiet hours
+// batches are due once the quiet hours are over.
+func dueDigests(name string, now time.Time, interval time.Duration, quietHours consul.QuietHoursConfig) []digestBatch {
+	digests.Lock()
+	defer digests.Unlock()
+	batches, err := loadDigests(name)
+	if err != nil {
+		log.Warnf("Unable to read the %s digest: %s", name, err)
+		return nil
+	}
+	var due []digestBatch
+	for _, batch := range batches {
+		if (batch.Quiet && !quietHours.Quiet(now)) || (!batch.Quiet && now.Sub(batch.Started) >= interval) {
+			due = append(due, batch)
+		}
+	}
+	return due
+}
+
+// delivered returns true if any of the notifiers sent the alerts, the batch
+// isn't sent again to the custom notifiers that did when another failed.
+func delivered(deliveries []delivery) bool {
+	for _, d := range deliveries {
+		if d.result == "sent" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDigest removes the sent alerts of a digest batch. The alerts merged
+// into the batch while it was sent are kept for the next one.
+func removeDigest(name string, sent digestBatch) {
+	digests.Lock()
+	defer digests.Unlock()
+	batches, err := loadDigests(name)
+	if err != nil {
+		log.Warnf("Unable to read the %s digest: %s", name, err)
+		return
+	}
+	if batches, removed := withoutSent(batches, sent); removed {
+		if err := saveDigests(name, batches); err != nil {
+			log.Warnf("Unable to save the %s digest: %s", name, err)
+		}
+	}
+}
+
+// withoutSent returns the batches without the sent alerts of a batch, the
+// emptied batch removed, and whether any was removed.
+func withoutSent(batches []digestBatch, sent digestBatch) ([]digestBatch, bool) {
+	changed := false
+	var kept []digestBatch
+	for _, batch := range batches {
+		if batch.Quiet == sent.Quiet && reflect.DeepEqual(batch.route(), sent.route()) {
+			var held notifier.Messages
+			for _, message := range batch.Messages {
+				if containsMessage(sent.Messages, message) {
+					changed = true
+				} else {
+					held = append(held, message)
+				}
+			}
+			if batch.Messages = held; len(held) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, batch)
+	}
+	return kept, changed
+}
+
+func containsMessage(messages notifier.Messages, message notifier.Message) bool {
+	for _, m := range messages {
+		if reflect.DeepEqual(m, message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+func TestMergeDigest(t *testing.T) {
+	held := notifier.Messages{
+		{Node: "web1", CheckId: "disk", Status: "warning"},
+		{Node: "web2", CheckId: "disk", Status: "warning"},
+	}
+	merged := mergeDigest(held, notifier.Messages{
+		{Node: "web1", CheckId: "disk", Status: "passing"},
+		{Node: "web1", CheckId: "load", Status: "warning"},
+	})
+
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 alerts, got %d", len(merged))
+	}
+	if merged[0].Status != "passing" {
+		t.Errorf("expected the latest status of web1/disk, got %s", merged[0].Status)
+	}
+	if held[0].Status != "warning" {
+		t.Error("the held alerts shouldn't be modified")
+	}
+}
+
+func TestWithoutChecks(t *testing.T) {
+	batches := []digestBatch{
+		{Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "warning"}, {Node: "web2", CheckId: "disk", Status: "warning"}}},
+		{Quiet: true, Messages: notifier.Messages{{Node: "web1", CheckId: "load", Status: "warning"}}},
+	}
+	kept, changed := withoutChecks(batches, notifier.Messages{{Node: "web1", CheckId: "load", Status: "critical"}, {Node: "web2", CheckId: "disk", Status: "critical"}})
+	if !changed || len(kept) != 1 || len(kept[0].Messages) != 1 || kept[0].Messages[0].Node != "web1" {
+		t.Errorf("expected only the web1 disk warning held, got %+v", kept)
+	}
+	if _, changed := withoutChecks(batches, notifier.Messages{{Node: "db1", CheckId: "disk"}}); changed {
+		t.Error("the batches without the check shouldn't change")
+	}
+}
+
+func TestWithoutSent(t *testing.T) {
+	sent := digestBatch{Receivers: []string{"ops@example.com"}, Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "warning"}}}
+	batches := []digestBatch{
+		{Receivers: []string{"ops@example.com"}, Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "warning"}, {Node: "web2", CheckId: "disk", Status: "warning"}}},
+		{Receivers: []string{"ops@example.com"}, Quiet: true, Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "warning"}}},
+		{Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "warning"}}},
+	}
+	kept, changed := withoutSent(batches, sent)
+	if !changed || len(kept) != 3 || len(kept[0].Messages) != 1 || kept[0].Messages[0].Node != "web2" {
+		t.Errorf("expected only the sent alert removed from its batch, got %+v", kept)
+	}
+
+	batches = []digestBatch{{Receivers: []string{"ops@example.com"}, Messages: notifier.Messages{{Node: "web1", CheckId: "disk", Status: "critical"}}}}
+	if _, changed := withoutSent(batches, sent); changed {
+		t.Error("the alerts merged into the batch while it was sent should be kept")
+	}
+	if kept, _ := withoutSent(batches, batches[0]); len(kept) != 0 {
+		t.Errorf("expected the emptied batch removed, got %+v", kept)
+	}
+}
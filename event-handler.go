@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"encoding/json"
 	"net/http"
-	"os/exec"
 
 	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
 
-	log "github.com/Sirupsen/logrus"
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
 var eventsChannel = make(chan []consul.Event)
@@ -17,64 +24,259 @@ var eventsChannel = make(chan []consul.Event)
 var firstEventRun bool = true
 
 func eventHandler(w http.ResponseWriter, r *http.Request) {
+	var events []consul.Event
+	toWatchObject(r.Body, &events)
+	handleEvents(events)
+	// set status to OK
+}
+
+// handleEvents queues the events for processing. This blocks until the event
+// processor is ready to take them.
+func handleEvents(events []consul.Event) {
+	if stopping() {
+		log.Debugln("Shutting down. Events ignored.")
+		return
+	}
 	consulClient.LoadConfig()
 	if firstEventRun {
-		log.Println("Now watching for events.")
+		log.Infoln("Now watching for events.")
 		firstEventRun = false
-		// set status to OK
 		return
 	}
 
 	if !consulClient.EventsEnabled() {
-		log.Println("Event handling disabled. Event ignored.")
-		// set to OK?
+		log.Debugln("Event handling disabled. Event ignored.")
 		return
 	}
 
-	var events []consul.Event
-	toWatchObject(r.Body, &events)
+	pipelineBegin()
 	eventsChannel <- events
-	// set status to OK
+}
+
+// eventQueue runs the events with at most len(slots) of them being processed
+// at the same time. The events are queued by name so the events with the same
+// name are processed one at a time, in the order they were received.
+type eventQueue struct {
+	sync.Mutex
+	pending map[string][]consul.Event
+	slots   chan struct{}
+	process func(consul.Event)
+}
+
+func newEventQueue(concurrency int, process func(consul.Event)) *eventQueue {
+	return &eventQueue{
+		pending: make(map[string][]consul.Event),
+		slots:   make(chan struct{}, concurrency),
+		process: process,
+	}
+}
+
+func (q *eventQueue) add(event consul.Event) {
+	q.Lock()
+	q.pending[event.Name] = append(q.pending[event.Name], event)
+	start := len(q.pending[event.Name]) == 1
+	q.Unlock()
+	if start {
+		go q.run(event.Name)
+	}
+}
+
+// run processes the queued events of name until there are none left. The
+// event being processed stays at the head of the queue so add doesn't start
+// another run for the same name.
+func (q *eventQueue) run(name string) {
+	for {
+		q.Lock()
+		event := q.pending[name][0]
+		q.Unlock()
+
+		q.slots <- struct{}{}
+		q.process(event)
+		<-q.slots
+
+		q.Lock()
+		remaining := q.pending[name][1:]
+		if len(remaining) == 0 {
+			delete(q.pending, name)
+			q.Unlock()
+			return
+		}
+		q.pending[name] = remaining
+		q.Unlock()
+	}
 }
 
 func processEvents() {
+	queue := newEventQueue(consulClient.EventConcurrency(), func(event consul.Event) {
+		processEvent(event)
+		pipelineEnd()
+	})
 	for {
-		events := <-eventsChannel
+		loopAlive("events processing")
+		var events []consul.Event
+		select {
+		case events = <-eventsChannel:
+		case <-time.After(loopIdleInterval):
+			continue
+		}
 		for _, event := range events {
-			processEvent(event)
+			pipelineBegin()
+			queue.add(event)
 		}
+		pipelineEnd()
 	}
 }
 
 func processEvent(event consul.Event) {
-	log.Println("----------------------------------------")
-	log.Printf("Processing event %s:\n", event.ID)
-	log.Println("----------------------------------------")
-	eventHandlers := consulClient.EventHandlers(event.Name)
-	for _, eventHandler := range eventHandlers {
-		executeEventHandler(event, eventHandler)
-	}
-	log.Printf("Event Processed.\n\n")
+	eventLog := log.WithFields(log.Fields{"event": event.ID, "name": event.Name})
+	if processed(event) {
+		eventLog.Infof("Event already processed (ltime %d), skipping.", event.LTime)
+		return
+	}
+	eventLog.Infoln("Processing event.")
+	var eventHandlers []string
+	for _, eventHandler := range consulClient.EventHandlers(event.Name) {
+		if eventHandler.Matches(event.Payload) {
+			eventHandlers = append(eventHandlers, eventHandler.Command)
+		} else {
+			eventLog.Debugf("Payload doesn't match the filter of %s.", eventHandler.Command)
+		}
+	}
+	handlerTimeout := consulClient.EventHandlerTimeout()
+	eventTimeout := consulClient.EventTimeout()
+	deadline := time.Now().Add(eventTimeout)
+	for i, eventHandler := range eventHandlers {
+		timeout := handlerTimeout
+		if eventTimeout > 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				eventHandlersExecuted.Add(float64(len(eventHandlers)-i), "skipped")
+				eventLog.Errorf("Event processing exceeded %s, %d handler(s) skipped.", eventTimeout, len(eventHandlers)-i)
+				break
+			}
+			if timeout <= 0 || remaining < timeout {
+				timeout = remaining
+			}
+		}
+		executeEventHandler(event, eventHandler, timeout)
+	}
+	if event.LTime > 0 {
+		if err := consulClient.SetLastEventLTime(event.Name, event.LTime); err != nil {
+			eventLog.Warnln("Unable to record the event as processed:", err)
+		}
+	}
+	eventLog.Debugln("Event processed.")
 }
 
-func executeEventHandler(event consul.Event, eventHandler string) {
+// processed returns true if an event with the same name and a later or equal
+// Lamport time was already processed. The events are processed if the last
+// Lamport time can't be read.
+func processed(event consul.Event) bool {
+	if event.LTime == 0 {
+		return false
+	}
+	last, err := consulClient.LastEventLTime(event.Name)
+	if err != nil {
+		log.WithField("event", event.ID).Warnln("Unable to read the last processed event:", err)
+		return false
+	}
+	return event.LTime <= last
+}
+
+func executeEventHandler(event consul.Event, eventHandler string, timeout time.Duration) {
 
 	data, err := json.Marshal(&event)
 	if err != nil {
-		log.Println("Unable to read event: ", event)
+		log.WithField("event", event.ID).Errorln("Unable to read event:", err)
 		// then what?
 	}
 
+	handlerLog := log.WithFields(log.Fields{"event": event.ID, "handler": eventHandler})
+	if isWebhook(eventHandler) {
+		err := postWebhook(webhookClient(), eventHandler, data, timeout, consulClient.EventWebhookRetries())
+		switch {
+		case err == nil:
+			eventHandlersExecuted.Inc("success")
+			handlerLog.Debugln("Event posted to the webhook.")
+		case errors.Is(err, context.DeadlineExceeded):
+			eventHandlersExecuted.Inc("timeout")
+			handlerLog.Errorf("Webhook timed out after %s: %s", timeout, err)
+			notifyHandlerFailure(event, eventHandler, fmt.Sprintf("timed out after %s", timeout), err.Error())
+		default:
+			eventHandlersExecuted.Inc("failed")
+			handlerLog.Errorln("Unable to post the event to the webhook:", err)
+			notifyHandlerFailure(event, eventHandler, "failed", err.Error())
+		}
+		return
+	}
+
+	cmd, err := newCommand(eventHandler)
+	if err != nil {
+		eventHandlersExecuted.Inc("failed")
+		handlerLog.Errorln("Unable to run handler:", err)
+		notifyHandlerFailure(event, eventHandler, "failed", err.Error())
+		return
+	}
+
 	input := bytes.NewReader(data)
 	output := new(bytes.Buffer)
-	cmd := exec.Command(eventHandler)
+	cmd.Env = append(os.Environ(), eventEnv(event)...)
 	cmd.Stdin = input
 	cmd.Stdout = output
 	cmd.Stderr = output
 
-	if err := cmd.Run(); err != nil {
-		log.Println("error running handler: ", err)
-	} else {
-		log.Printf(">>> \n%s -> %s:\n %s\n", event.ID, eventHandler, output)
+	switch err := runCommand(cmd, timeout); err.(type) {
+	case nil:
+		eventHandlersExecuted.Inc("success")
+		handlerLog.Debugf("Handler output:\n%s", output)
+	case errCommandTimeout:
+		eventHandlersExecuted.Inc("timeout")
+		handlerLog.Errorf("Handler %s. Output:\n%s", err, output)
+		notifyHandlerFailure(event, eventHandler, err.Error(), output.String())
+	default:
+		eventHandlersExecuted.Inc("failed")
+		handlerLog.Errorln("Error running handler:", err)
+		notifyHandlerFailure(event, eventHandler, "failed with "+err.Error(), output.String())
+	}
+}
+
+// maxHandlerOutput is the number of bytes of the handler output kept in the
+// failure alerts.
+const maxHandlerOutput = 4096
+
+// notifyHandlerFailure sends an alert through the notifiers when a handler
+// fails so broken automation doesn't go unnoticed.
+func notifyHandlerFailure(event consul.Event, eventHandler, reason, output string) {
+	if !consulClient.EventFailureAlerts() {
+		return
+	}
+	hostname, _ := os.Hostname()
+	sendMessages(notifier.Messages{handlerFailureMessage(hostname, event, eventHandler, reason, output)})
+}
+
+func handlerFailureMessage(hostname string, event consul.Event, eventHandler, reason, output string) notifier.Message {
+	if len(output) > maxHandlerOutput {
+		output = "...\n" + output[len(output)-maxHandlerOutput:]
+	}
+	return notifier.Message{
+		Node:      hostname,
+		ServiceId: "consul-alerts",
+		Service:   "consul-alerts",
+		CheckId:   "event-handler",
+		Check:     "Event handler " + event.Name,
+		Status:    "critical",
+		Output:    fmt.Sprintf("Handler %s for event %s (%s) %s.\n%s", eventHandler, event.Name, event.ID, reason, output),
+		Timestamp: time.Now(),
+	}
+}
+
+// eventEnv exposes the event to the handlers as environment variables so shell
+// handlers don't have to parse the JSON sent on stdin.
+func eventEnv(event consul.Event) []string {
+	return []string{
+		"CONSUL_EVENT_ID=" + event.ID,
+		"CONSUL_EVENT_NAME=" + event.Name,
+		"CONSUL_EVENT_LTIME=" + strconv.FormatUint(uint64(event.LTime), 10),
+		"CONSUL_EVENT_PAYLOAD=" + string(event.Payload),
 	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestEventEnv(t *testing.T) {
+	event := consul.Event{ID: "abc-123", Name: "deploy", Payload: []byte("v1.2.3"), LTime: 42}
+	cmd := exec.Command("sh", "-c", `echo "$CONSUL_EVENT_ID $CONSUL_EVENT_NAME $CONSUL_EVENT_LTIME $CONSUL_EVENT_PAYLOAD"`)
+	cmd.Env = append(os.Environ(), eventEnv(event)...)
+	output := new(bytes.Buffer)
+	cmd.Stdout = output
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "abc-123 deploy 42 v1.2.3\n" {
+		t.Errorf("unexpected environment: %s", output)
+	}
+}
+
+func TestEventQueue(t *testing.T) {
+	var lock sync.Mutex
+	var processed []string
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+
+	queue := newEventQueue(2, func(event consul.Event) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		processed = append(processed, event.Name+event.ID)
+		lock.Unlock()
+		wg.Done()
+	})
+
+	events := []consul.Event{
+		{Name: "deploy", ID: "1"}, {Name: "restart", ID: "1"}, {Name: "deploy", ID: "2"},
+		{Name: "backup", ID: "1"}, {Name: "deploy", ID: "3"}, {Name: "restart", ID: "2"},
+	}
+	wg.Add(len(events))
+	for _, event := range events {
+		queue.add(event)
+	}
+	wg.Wait()
+
+	if maxRunning != 2 {
+		t.Errorf("expected 2 events processed at the same time, got %d", maxRunning)
+	}
+	order := map[string]string{}
+	for _, p := range processed {
+		order[p[:len(p)-1]] += p[len(p)-1:]
+	}
+	if order["deploy"] != "123" || order["restart"] != "12" {
+		t.Errorf("events with the same name were not processed in order: %v", processed)
+	}
+}
+
+func TestHandlerFailureMessage(t *testing.T) {
+	event := consul.Event{ID: "abc-123", Name: "deploy"}
+	output := strings.Repeat("x", maxHandlerOutput) + "last line"
+	message := handlerFailureMessage("host1", event, "deploy.sh", "failed with exit status 2", output)
+	if message.Node != "host1" || message.Status != "critical" || message.Check != "Event handler deploy" {
+		t.Errorf("unexpected message: %+v", message)
+	}
+	if !strings.HasPrefix(message.Output, "Handler deploy.sh for event deploy (abc-123) failed with exit status 2.\n...\n") {
+		t.Errorf("unexpected output: %.80s", message.Output)
+	}
+	if !strings.HasSuffix(message.Output, "last line") || len(message.Output) > maxHandlerOutput+100 {
+		t.Error("the output should be truncated to its end")
+	}
+}
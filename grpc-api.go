@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/api"
+	"github.com/AcalephStorage/consul-alerts/consul"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/golang/protobuf/ptypes"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/codes"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/credentials"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/metadata"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/status"
+)
+
+var grpcServer *grpc.Server
+
+// serveGrpc serves the gRPC API on addr, over TLS when a certificate is given,
+// with the same authentication as the HTTP API.
+func serveGrpc(addr, tlsCert, tlsKey string, auth apiAuth) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if !auth.authorized(metadataRequest(ctx)) {
+				return nil, status.Error(codes.Unauthenticated, "invalid or missing credentials")
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !auth.authorized(metadataRequest(stream.Context())) {
+				return status.Error(codes.Unauthenticated, "invalid or missing credentials")
+			}
+			return handler(srv, stream)
+		}),
+	}
+	if tlsCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(tlsCert, tlsKey)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	grpcServer = grpc.NewServer(options...)
+	api.RegisterAlertsServer(grpcServer, alertsServer{})
+	go grpcServer.Serve(listener)
+	return nil
+}
+
+// metadataRequest copies the gRPC metadata of a call to the headers of a
+// request so the HTTP API authentication can check it.
+func metadataRequest(ctx context.Context) *http.Request {
+	r := &http.Request{Header: http.Header{}}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	return r
+}
+
+// alertsServer implements the gRPC API.
+type alertsServer struct{}
+
+func (alertsServer) ListAlerts(ctx context.Context, req *api.ListAlertsRequest) (*api.ListAlertsResponse, error) {
+	statuses, err := checkStatuses(req.Node)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp := &api.ListAlertsResponse{}
+	for _, s := range statuses {
+		if req.Status == "" || req.Status == s.Current {
+			resp.Alerts = append(resp.Alerts, toAlert(s))
+		}
+	}
+	return resp, nil
+}
+
+func (alertsServer) Acknowledge(ctx context.Context, req *api.AcknowledgeRequest) (*api.AcknowledgeResponse, error) {
+	if req.Node == "" || req.Check == "" {
+		return nil, status.Error(codes.InvalidArgument, "node and check are required")
+	}
+	by := req.By
+	if by == "" {
+		by = "grpc"
+		if username, _, ok := metadataRequest(ctx).BasicAuth(); ok {
+			by = username
+		}
+	}
+	checks, err := consulClient.Acknowledge(req.Node, req.Check, by, req.Comment, time.Now())
+	if err != nil && len(checks) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	resp := &api.AcknowledgeResponse{}
+	for _, check := range checks {
+		resp.Checks = append(resp.Checks, &api.Check{Node: check.Node, ServiceId: check.ServiceID, CheckId: check.CheckID, Status: check.Status})
+	}
+	if err != nil {
+		log.Warnln("Unable to acknowledge every check:", err)
+	}
+	return resp, nil
+}
+
+func (alertsServer) ListSilences(ctx context.Context, req *api.ListSilencesRequest) (*api.ListSilencesResponse, error) {
+	silences, err := consulClient.Silences()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp := &api.ListSilencesResponse{}
+	for _, silence := range silences {
+		resp.Silences = append(resp.Silences, toApiSilence(silence))
+	}
+	return resp, nil
+}
+
+func (alertsServer) CreateSilence(ctx context.Context, req *api.CreateSilenceRequest) (*api.Silence, error) {
+	var silence consul.Silence
+	if s := req.Silence; s != nil {
+		silence = consul.Silence{
+			Node:      s.Node,
+			Service:   s.Service,
+			Check:     s.Check,
+			StartsAt:  fromTimestamp(s.StartsAt),
+			EndsAt:    fromTimestamp(s.EndsAt),
+			CreatedBy: s.CreatedBy,
+			Comment:   s.Comment,
+		}
+	}
+	if req.Duration != nil {
+		duration, err := ptypes.Duration(req.Duration)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if silence.StartsAt.IsZero() {
+			silence.StartsAt = time.Now()
+		}
+		silence.EndsAt = silence.StartsAt.Add(duration)
+	}
+	if err := consulClient.CreateSilence(&silence); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return toApiSilence(silence), nil
+}
+
+func (alertsServer) DeleteSilence(ctx context.Context, req *api.DeleteSilenceRequest) (*api.DeleteSilenceResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if err := consulClient.DeleteSilence(req.Id); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &api.DeleteSilenceResponse{}, nil
+}
+
+func (alertsServer) WatchTransitions(req *api.WatchTransitionsRequest, stream api.Alerts_WatchTransitionsServer) error {
+	query := consul.HistoryQuery{Node: req.Node, Service: req.Service, Check: req.Check}
+	ch := transitions.subscribe()
+	defer transitions.unsubscribe(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case entry := <-ch:
+			if !query.Matches(entry) {
+				continue
+			}
+			if err := stream.Send(toTransition(entry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// transitionFeed passes the check status changes to the WatchTransitions
+// streams. A stream too slow to keep up misses the changes.
+type transitionFeed struct {
+	sync.Mutex
+	subscribers map[chan consul.HistoryEntry]bool
+}
+
+var transitions = &transitionFeed{subscribers: make(map[chan consul.HistoryEntry]bool)}
+
+func (f *transitionFeed) subscribe() chan consul.HistoryEntry {
+	f.Lock()
+	defer f.Unlock()
+	ch := make(chan consul.HistoryEntry, 64)
+	f.subscribers[ch] = true
+	return ch
+}
+
+func (f *transitionFeed) unsubscribe(ch chan consul.HistoryEntry) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.subscribers, ch)
+}
+
+func (f *transitionFeed) publish(entry consul.HistoryEntry) {
+	f.Lock()
+	defer f.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- entry:
+		default:
+			log.Warnf("Dropping the %s:%s:%s transition for a slow gRPC stream.", entry.Node, entry.ServiceId, entry.CheckId)
+		}
+	}
+}
+
+func toAlert(s consul.Status) *api.Alert {
+	check := s.HealthCheck
+	return &api.Alert{
+		Datacenter:          check.Datacenter,
+		Namespace:           check.Namespace,
+		Node:                check.Node,
+		ServiceId:           check.ServiceID,
+		Service:             check.ServiceName,
+		CheckId:             check.CheckID,
+		Check:               check.Name,
+		Status:              s.Current,
+		StatusSince:         toTimestamp(s.CurrentTimestamp),
+		PendingStatus:       s.Pending,
+		PendingSince:        toTimestamp(s.PendingTimestamp),
+		LastNotified:        toTimestamp(s.NotifiedTimestamp),
+		ForNotification:     s.ForNotification,
+		Acknowledged:        toTimestamp(s.AcknowledgedTimestamp),
+		AcknowledgedBy:      s.AcknowledgedBy,
+		AcknowledgedComment: s.AcknowledgedComment,
+		Output:              check.Output,
+		Notes:               check.Notes,
+	}
+}
+
+func toApiSilence(silence consul.Silence) *api.Silence {
+	return &api.Silence{
+		Id:        silence.ID,
+		Node:      silence.Node,
+		Service:   silence.Service,
+		Check:     silence.Check,
+		StartsAt:  toTimestamp(silence.StartsAt),
+		EndsAt:    toTimestamp(silence.EndsAt),
+		CreatedBy: silence.CreatedBy,
+		Comment:   silence.Comment,
+	}
+}
+
+func toTransition(entry consul.HistoryEntry) *api.Transition {
+	return &api.Transition{
+		Time:       toTimestamp(entry.Time),
+		Datacenter: entry.Datacenter,
+		Node:       entry.Node,
+		ServiceId:  entry.ServiceId,
+		Service:    entry.Service,
+		CheckId:    entry.CheckId,
+		Check:      entry.Check,
+		From:       entry.From,
+		Status:     entry.Status,
+		Output:     entry.Output,
+	}
+}
+
+// toTimestamp converts a time, the zero time is left unset.
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, _ := ptypes.TimestampProto(t)
+	return ts
+}
+
+func fromTimestamp(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, _ := ptypes.Timestamp(ts)
+	return t
+}
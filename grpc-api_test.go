@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/google.golang.org/grpc/metadata"
+)
+
+func TestGrpcAuthorization(t *testing.T) {
+	auth := apiAuth{token: "secret", username: "ops", password: "pass"}
+	tests := []struct {
+		md       metadata.MD
+		expected bool
+	}{
+		{metadata.Pairs("authorization", "Bearer secret"), true},
+		{metadata.Pairs("x-consul-alerts-token", "secret"), true},
+		{metadata.Pairs("authorization", "Basic b3BzOnBhc3M="), true},
+		{metadata.Pairs("authorization", "Bearer wrong"), false},
+		{metadata.MD{}, false},
+	}
+	for _, test := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), test.md)
+		if authorized := auth.authorized(metadataRequest(ctx)); authorized != test.expected {
+			t.Errorf("expected %v for %v, got %v", test.expected, test.md, authorized)
+		}
+	}
+}
+
+func TestTransitionFeed(t *testing.T) {
+	feed := &transitionFeed{subscribers: make(map[chan consul.HistoryEntry]bool)}
+	ch := feed.subscribe()
+	feed.publish(consul.HistoryEntry{Node: "web-1", CheckId: "disk", From: "passing", Status: "critical"})
+	select {
+	case entry := <-ch:
+		transition := toTransition(entry)
+		if transition.Node != "web-1" || transition.From != "passing" || transition.Status != "critical" || transition.Time != nil {
+			t.Errorf("unexpected transition %v", transition)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a transition")
+	}
+
+	feed.unsubscribe(ch)
+	feed.publish(consul.HistoryEntry{Node: "web-2"})
+	select {
+	case entry := <-ch:
+		t.Errorf("unexpected transition after unsubscribing %v", entry)
+	default:
+	}
+}
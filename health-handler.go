@@ -5,7 +5,7 @@ import (
 
 	"net/http"
 
-	log "github.com/Sirupsen/logrus"
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -13,10 +13,11 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	node := r.URL.Query().Get("node")
 	service := r.URL.Query().Get("service")
 	check := r.URL.Query().Get("check")
+	dc := r.URL.Query().Get("dc")
 
-	log.Println(node, service, check)
+	log.WithFields(log.Fields{"node": node, "service": service, "check": check}).Debugln("Health status requested.")
 
-	status, output := consulClient.CheckStatus(node, service, check)
+	status, output := consulClient.CheckStatus(dc, node, service, check)
 
 	var code int
 	switch status {
@@ -29,7 +30,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		code = 404
 	}
 
-	log.Printf("health status check result for node=%s,service=%s,check=%s: %d", node, service, check, code)
+	log.WithFields(log.Fields{"node": node, "service": service, "check": check}).Debugf("Health status check result: %d", code)
 
 	var result string
 	if output == "" {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+var heartbeatClient = &http.Client{Timeout: 10 * time.Second}
+
+// runHeartbeat sends the heartbeat requests while consul-alerts is healthy. A
+// dead man's switch service like healthchecks.io or an OpsGenie heartbeat then
+// alerts when consul-alerts dies, loses consul, or its pipeline breaks.
+func runHeartbeat() {
+	for {
+		config := consulClient.HeartbeatConfig()
+		interval := time.Duration(config.Interval) * time.Second
+		if interval <= 0 {
+			interval = 60 * time.Second
+		}
+		if config.URL != "" {
+			sendHeartbeat(config)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// sendHeartbeat sends a heartbeat from the leader, or from every shard member
+// as each one watches its own nodes, when the self health is ok.
+func sendHeartbeat(config *consul.HeartbeatConfig) {
+	health := currentSelfHealth()
+	if shard == nil && !health.Leader {
+		return
+	}
+	if health.Status != "ok" {
+		heartbeats.Inc("skipped")
+		log.Warnf("Unhealthy, heartbeat not sent: %s", selfHealthProblems(health))
+		return
+	}
+	if err := postHeartbeat(config); err != nil {
+		heartbeats.Inc("failed")
+		log.Errorln("Unable to send the heartbeat:", err)
+		return
+	}
+	heartbeats.Inc("sent")
+}
+
+func postHeartbeat(config *consul.HeartbeatConfig) error {
+	req, err := http.NewRequest("GET", config.URL, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range config.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := heartbeatClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// selfHealthProblems describes why the self health is not ok.
+func selfHealthProblems(health selfHealth) string {
+	var problems []string
+	if health.Consul != "ok" {
+		problems = append(problems, "consul: "+health.Consul)
+	}
+	for watcher, running := range health.Watchers {
+		if !running {
+			problems = append(problems, watcher+" watcher stopped")
+		}
+	}
+	for name, status := range health.Notifiers {
+		if status != "ok" {
+			problems = append(problems, name+": "+status)
+		}
+	}
+	return strings.Join(problems, ", ")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestPostHeartbeat(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	config := &consul.HeartbeatConfig{URL: server.URL + "/ping", Headers: map[string]string{"Authorization": "GenieKey secret"}}
+	if err := postHeartbeat(config); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "GenieKey secret" {
+		t.Errorf("expected the configured header, got %q", authorization)
+	}
+	config.URL = server.URL + "/missing"
+	if err := postHeartbeat(config); err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
+
+func TestSelfHealthProblems(t *testing.T) {
+	health := selfHealth{
+		Consul:    "cluster has no leader",
+		Watchers:  map[string]bool{"checks": false},
+		Notifiers: map[string]string{"slack": "url is required"},
+	}
+	expected := "consul: cluster has no leader, checks watcher stopped, slack: url is required"
+	if problems := selfHealthProblems(health); problems != expected {
+		t.Errorf("unexpected problems %q", problems)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/history"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// historyPruneInterval is how often the entries past the retention are
+// deleted from the SQL history.
+const historyPruneInterval = time.Hour
+
+// historyBackend is the SQL history opened for the current configuration.
+var historyBackend struct {
+	sync.Mutex
+	backend string
+	dsn     string
+	store   *history.Store
+}
+
+// useHistoryBackend opens the SQL history backend when its configuration
+// changed. The history stays in consul KV when it can't be opened.
+func useHistoryBackend() {
+	config := consulClient.HistoryConfig()
+	historyBackend.Lock()
+	defer historyBackend.Unlock()
+	if config.Backend == historyBackend.backend && config.DSN == historyBackend.dsn {
+		return
+	}
+	if historyBackend.store != nil {
+		consulClient.SetHistoryStore(nil)
+		historyBackend.store.Close()
+		historyBackend.store = nil
+	}
+	historyBackend.backend, historyBackend.dsn = "", ""
+	if config.Backend == "consul" {
+		historyBackend.backend = config.Backend
+		return
+	}
+	store, err := history.Open(config.Backend, config.DSN)
+	if err != nil {
+		log.Errorf("Unable to open the %s history, keeping the history in consul: %s", config.Backend, err)
+		return
+	}
+	log.Infof("Using the %s history.", config.Backend)
+	historyBackend.backend, historyBackend.dsn, historyBackend.store = config.Backend, config.DSN, store
+	consulClient.SetHistoryStore(store)
+}
+
+// runHistoryRetention deletes the SQL history entries older than the
+// retention.
+func runHistoryRetention() {
+	for {
+		time.Sleep(historyPruneInterval)
+		if stopping() {
+			return
+		}
+		pruneHistory(time.Now())
+	}
+}
+
+func pruneHistory(now time.Time) {
+	days := consulClient.HistoryConfig().Retention
+	historyBackend.Lock()
+	defer historyBackend.Unlock()
+	if historyBackend.store == nil || days <= 0 {
+		return
+	}
+	deleted, err := historyBackend.store.Prune(now.AddDate(0, 0, -days))
+	if err != nil {
+		log.Warnln("Unable to delete the expired history entries:", err)
+		return
+	}
+	if deleted > 0 {
+		log.Infof("Deleted %d history entries older than %d days.", deleted, days)
+	}
+}
+
+// historyProblems reports a SQL history backend without a DSN, and the
+// sqlite backend in a binary built without cgo.
+func historyProblems(client consul.Consul) []string {
+	config := client.HistoryConfig()
+	if config.Backend != "consul" && config.DSN == "" {
+		return []string{"consul-alerts/config/history/dsn: required by the " + config.Backend + " history backend"}
+	}
+	if config.Backend == "sqlite" && !history.SqliteBuilt {
+		return []string{"consul-alerts/config/history/backend: sqlite needs a consul-alerts built with cgo"}
+	}
+	return nil
+}
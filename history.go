@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// recordDeliveries adds the messages sent by a notifier to the history.
+func recordDeliveries(name string, messages notifier.Messages, success bool) {
+	entries := make([]consul.HistoryEntry, len(messages))
+	for i, message := range messages {
+		entries[i] = consul.HistoryEntry{
+			Type:       "notification",
+			Datacenter: message.Datacenter,
+			Node:       message.Node,
+			ServiceId:  message.ServiceId,
+			Service:    message.Service,
+			CheckId:    message.CheckId,
+			Check:      message.Check,
+			Status:     message.Status,
+			Output:     message.Output,
+			Notifier:   name,
+			Result:     resultLabel(success),
+		}
+	}
+	if err := consulClient.RecordHistory(entries); err != nil {
+		log.Warnf("Unable to record the %s notifications in the history: %s", name, err)
+	}
+}
+
+// historyQuery builds a history query from the filter values. since and until
+// are RFC3339 times or durations before now, like 24h.
+func historyQuery(kind, node, service, check, since, until, limit string, now time.Time) (consul.HistoryQuery, error) {
+	query := consul.HistoryQuery{Type: kind, Node: node, Service: service, Check: check}
+	var err error
+	if query.Since, err = parseHistoryTime(since, now); err != nil {
+		return query, fmt.Errorf("invalid since: %s", err)
+	}
+	if query.Until, err = parseHistoryTime(until, now); err != nil {
+		return query, fmt.Errorf("invalid until: %s", err)
+	}
+	if limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return query, fmt.Errorf("invalid limit: expected a number, got %q", limit)
+		}
+	}
+	return query, nil
+}
+
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// historyHandler serves the alert history at /v1/history, filtered by the
+// type, node, service, check, since, until and limit query parameters.
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(405)
+		return
+	}
+	params := r.URL.Query()
+	query, err := historyQuery(params.Get("type"), params.Get("node"), params.Get("service"), params.Get("check"),
+		params.Get("since"), params.Get("until"), params.Get("limit"), time.Now())
+	if err != nil {
+		writeJson(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+	entries, err := consulClient.History(query)
+	if err != nil {
+		writeJson(w, 503, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJson(w, 200, entries)
+}
+
+// historyMode prints the alert history stored in consul.
+func historyMode(arguments map[string]interface{}) {
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+	useHistoryBackend()
+
+	query, err := historyQuery(stringOption(arguments, "--type", ""), stringOption(arguments, "--node", ""),
+		stringOption(arguments, "--service", ""), stringOption(arguments, "--check", ""),
+		stringOption(arguments, "--since", ""), stringOption(arguments, "--until", ""),
+		stringOption(arguments, "--limit", ""), time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	entries, err := consulClient.History(query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to read the history:", err)
+		os.Exit(3)
+	}
+
+	if boolOption(arguments, "--json", "") {
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTYPE\tNODE\tSERVICE\tCHECK\tSTATUS\tDETAILS")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.Type,
+			entry.Node, entry.Service, entry.Check, entry.Status, historyDetails(entry))
+	}
+	w.Flush()
+}
+
+// historyDetails describes the previous status of a transition or the
+// notifier and result of a notification.
+func historyDetails(entry consul.HistoryEntry) string {
+	if entry.Type == "transition" {
+		return "from " + entry.From
+	}
+	return strings.TrimSpace(entry.Notifier + " " + entry.Result)
+}
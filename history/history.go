@@ -0,0 +1,170 @@
+// Package history stores the alert history in a SQL database, SQLite or
+// PostgreSQL, instead of the consul KV ring buffer, for long-term reporting.
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"database/sql"
+
+	_ "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/lib/pq"
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+// Backends are the supported SQL backends and their database/sql driver.
+var Backends = map[string]string{
+	"sqlite":   "sqlite3",
+	"postgres": "postgres",
+}
+
+var schemas = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS history (
+		sequence INTEGER PRIMARY KEY AUTOINCREMENT,
+		time TIMESTAMP NOT NULL,
+		%s)`,
+	"postgres": `CREATE TABLE IF NOT EXISTS history (
+		sequence BIGSERIAL PRIMARY KEY,
+		time TIMESTAMPTZ NOT NULL,
+		%s)`,
+}
+
+// columns are the history table columns after the sequence and the time.
+var columns = []string{"type", "datacenter", "node", "service_id", "service", "check_id", "check_name", "from_status", "status", "output", "notifier", "result"}
+
+// Store is a SQL database holding the history entries.
+type Store struct {
+	db      *sql.DB
+	backend string
+}
+
+// Open opens the database of a backend and creates the history table if
+// needed. dsn is the database file of sqlite and the connection string of
+// postgres.
+func Open(backend, dsn string) (*Store, error) {
+	driver, found := Backends[backend]
+	if !found {
+		return nil, fmt.Errorf("unknown history backend %q", backend)
+	}
+	if backend == "sqlite" && !SqliteBuilt {
+		return nil, fmt.Errorf("the sqlite history backend needs a consul-alerts built with cgo")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if backend == "sqlite" {
+		// a single connection avoids the sqlite lock errors of concurrent writes
+		db.SetMaxOpenConns(1)
+	}
+	definitions := make([]string, len(columns))
+	for i, column := range columns {
+		definitions[i] = column + " TEXT NOT NULL DEFAULT ''"
+	}
+	schema := fmt.Sprintf(schemas[backend], strings.Join(definitions, ",\n\t\t"))
+	for _, statement := range []string{schema, "CREATE INDEX IF NOT EXISTS history_time ON history (time)"} {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Store{db: db, backend: backend}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// placeholder returns the nth (from 1) query parameter placeholder.
+func (s *Store) placeholder(n int) string {
+	if s.backend == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Record inserts the entries, their sequence is set by the database.
+func (s *Store) Record(entries []consul.HistoryEntry) error {
+	placeholders := make([]string, len(columns)+1)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	insert := fmt.Sprintf("INSERT INTO history (time, %s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		_, err := tx.Exec(insert, entry.Time.UTC(), entry.Type, entry.Datacenter, entry.Node, entry.ServiceId, entry.Service,
+			entry.CheckId, entry.Check, entry.From, entry.Status, entry.Output, entry.Notifier, entry.Result)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Query returns the entries selected by the query, oldest first. The type and
+// the time range are filtered by the database, the patterns while reading the
+// entries.
+func (s *Store) Query(query consul.HistoryQuery) ([]consul.HistoryEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Type != "" {
+		args = append(args, query.Type)
+		conditions = append(conditions, "type = "+s.placeholder(len(args)))
+	}
+	if !query.Since.IsZero() {
+		args = append(args, query.Since.UTC())
+		conditions = append(conditions, "time >= "+s.placeholder(len(args)))
+	}
+	if !query.Until.IsZero() {
+		args = append(args, query.Until.UTC())
+		conditions = append(conditions, "time < "+s.placeholder(len(args)))
+	}
+	statement := "SELECT sequence, time, " + strings.Join(columns, ", ") + " FROM history"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// newest first so the limit stops the scan
+	rows, err := s.db.Query(statement+" ORDER BY sequence DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []consul.HistoryEntry
+	for rows.Next() {
+		var entry consul.HistoryEntry
+		err := rows.Scan(&entry.Sequence, &entry.Time, &entry.Type, &entry.Datacenter, &entry.Node, &entry.ServiceId, &entry.Service,
+			&entry.CheckId, &entry.Check, &entry.From, &entry.Status, &entry.Output, &entry.Notifier, &entry.Result)
+		if err != nil {
+			return nil, err
+		}
+		if !query.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if query.Limit > 0 && len(entries) == query.Limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Prune deletes the entries older than before and returns their number.
+func (s *Store) Prune(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM history WHERE time < "+s.placeholder(1), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestSqliteStore(t *testing.T) {
+	if !SqliteBuilt {
+		t.Skip("built without cgo")
+	}
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := Open("sqlite", filepath.Join(dir, "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []consul.HistoryEntry{
+		{Time: start, Type: "transition", Node: "web-1", CheckId: "disk", Check: "Disk", From: "passing", Status: "critical"},
+		{Time: start.Add(time.Minute), Type: "notification", Node: "web-1", CheckId: "disk", Check: "Disk", Status: "critical", Notifier: "email", Result: "sent"},
+		{Time: start.Add(time.Hour), Type: "transition", Node: "db-1", ServiceId: "redis", Service: "redis", CheckId: "ping", Check: "Ping", From: "passing", Status: "warning"},
+		{Time: start.Add(2 * time.Hour), Type: "transition", Node: "web-2", CheckId: "disk", Check: "Disk", From: "passing", Status: "critical"},
+	}
+	if err := store.Record(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    consul.HistoryQuery
+		expected []string
+	}{
+		{consul.HistoryQuery{}, []string{"web-1", "web-1", "db-1", "web-2"}},
+		{consul.HistoryQuery{Type: "transition", Node: "web-*"}, []string{"web-1", "web-2"}},
+		{consul.HistoryQuery{Service: "redis"}, []string{"db-1"}},
+		{consul.HistoryQuery{Since: start.Add(time.Minute), Until: start.Add(2 * time.Hour)}, []string{"web-1", "db-1"}},
+		{consul.HistoryQuery{Type: "transition", Limit: 2}, []string{"db-1", "web-2"}},
+	}
+	for _, test := range tests {
+		found, err := store.Query(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var nodes []string
+		for _, entry := range found {
+			nodes = append(nodes, entry.Node)
+		}
+		if len(nodes) != len(test.expected) {
+			t.Errorf("expected %v for %+v, got %v", test.expected, test.query, nodes)
+			continue
+		}
+		for i := range nodes {
+			if nodes[i] != test.expected[i] {
+				t.Errorf("expected %v for %+v, got %v", test.expected, test.query, nodes)
+				break
+			}
+		}
+	}
+
+	found, _ := store.Query(consul.HistoryQuery{Type: "notification"})
+	if len(found) != 1 || found[0].Notifier != "email" || found[0].Result != "sent" || !found[0].Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("unexpected notification entry %+v", found)
+	}
+
+	deleted, err := store.Prune(start.Add(time.Hour))
+	if err != nil || deleted != 2 {
+		t.Errorf("expected 2 pruned entries, got %d (%v)", deleted, err)
+	}
+	if found, _ := store.Query(consul.HistoryQuery{}); len(found) != 2 {
+		t.Errorf("expected 2 entries after pruning, got %d", len(found))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !cgo
+// +build !cgo
+
+package history
+
+// SqliteBuilt is whether the sqlite backend is built in, it needs cgo.
+const SqliteBuilt = false
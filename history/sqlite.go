@@ -0,0 +1,11 @@
+//go:build cgo
+// +build cgo
+
+package history
+
+import (
+	_ "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/mattn/go-sqlite3"
+)
+
+// SqliteBuilt is whether the sqlite backend is built in, it needs cgo.
+const SqliteBuilt = true
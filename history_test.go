@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryQuery(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	query, err := historyQuery("transition", "web-*", "", "", "24h", "2024-03-01T11:00:00Z", "10", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !query.Since.Equal(now.Add(-24*time.Hour)) || !query.Until.Equal(now.Add(-time.Hour)) || query.Limit != 10 {
+		t.Errorf("unexpected query: %+v", query)
+	}
+	if _, err := historyQuery("", "", "", "", "yesterday", "", "", now); err == nil {
+		t.Error("expected an error for an invalid since")
+	}
+	if _, err := historyQuery("", "", "", "", "", "", "ten", now); err == nil {
+		t.Error("expected an error for an invalid limit")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// keyWatcherRefresh is how often the configured prefixes are checked to start
+// or stop their watchers.
+const keyWatcherRefresh = 10 * time.Second
+
+// KeyChange is a KV value change sent to the key handlers. OldValue is null
+// for an added key and NewValue is null for a deleted key.
+type KeyChange struct {
+	Key      string
+	OldValue *string
+	NewValue *string
+}
+
+// runKeyWatchers runs a watcher for each prefix of the key handlers, starting
+// and stopping them as the config changes.
+func runKeyWatchers() {
+	setWatcherRunning("keys", true)
+	defer setWatcherRunning("keys", false)
+
+	log.Infoln("Starting keys watcher.")
+	running := make(map[string]chan struct{})
+	for {
+		prefixes := make(map[string]bool)
+		for _, handler := range consulClient.KeyHandlers() {
+			prefixes[handler.Prefix] = true
+		}
+		for prefix := range prefixes {
+			if _, found := running[prefix]; !found {
+				stop := make(chan struct{})
+				running[prefix] = stop
+				go watchKeyPrefix(prefix, stop)
+			}
+		}
+		for prefix, stop := range running {
+			if !prefixes[prefix] {
+				close(stop)
+				delete(running, prefix)
+			}
+		}
+		time.Sleep(keyWatcherRefresh)
+	}
+}
+
+// watchKeyPrefix runs the handlers of prefix with the changed values each time
+// a value under it changes. The values found on the first query are not
+// handled.
+func watchKeyPrefix(prefix string, stop chan struct{}) {
+	log.Infof("Watching keys under %s.", prefix)
+	var index uint64
+	var values map[string]string
+	for {
+		select {
+		case <-stop:
+			log.Infof("Stopped watching keys under %s.", prefix)
+			return
+		default:
+		}
+
+		current, lastIndex, err := consulClient.WatchKeys(prefix, index)
+		if err != nil {
+			log.Warnf("Unable to watch keys under %s, retrying in %s: %s", prefix, watchRetryInterval, err)
+			time.Sleep(watchRetryInterval)
+			continue
+		}
+		if index != 0 && lastIndex != index {
+			if changes := keyChanges(values, current); len(changes) > 0 {
+				handleKeyChanges(prefix, changes)
+			}
+		}
+		values = current
+		index = lastIndex
+	}
+}
+
+// keyChanges returns the keys added, changed or deleted between the previous
+// and current values, sorted by key.
+func keyChanges(previous, current map[string]string) []KeyChange {
+	var changes []KeyChange
+	for key, value := range current {
+		value := value
+		if oldValue, found := previous[key]; !found {
+			changes = append(changes, KeyChange{Key: key, NewValue: &value})
+		} else if oldValue != value {
+			changes = append(changes, KeyChange{Key: key, OldValue: &oldValue, NewValue: &value})
+		}
+	}
+	for key, value := range previous {
+		value := value
+		if _, found := current[key]; !found {
+			changes = append(changes, KeyChange{Key: key, OldValue: &value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// handleKeyChanges sends the changes as JSON to the handlers of prefix, on
+// stdin for commands or in the body for webhooks. The KV is shared by the
+// cluster so only the leader runs the handlers.
+func handleKeyChanges(prefix string, changes []KeyChange) {
+	if leaderCandidate != nil && !leaderCandidate.IsLeader() {
+		log.Debugf("Currently not the leader. Ignoring changes under %s.", prefix)
+		return
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		log.Errorln("Unable to encode the key changes:", err)
+		return
+	}
+	timeout := consulClient.EventHandlerTimeout()
+	for _, handler := range consulClient.KeyHandlers() {
+		if handler.Prefix != prefix {
+			continue
+		}
+		handlerLog := log.WithFields(log.Fields{"prefix": prefix, "handler": handler.Command})
+		handlerLog.Infof("Running handler for %d key change(s).", len(changes))
+
+		if isWebhook(handler.Command) {
+			err = postWebhook(webhookClient(), handler.Command, data, timeout, consulClient.EventWebhookRetries())
+		} else if cmd, cmdErr := newCommand(handler.Command); cmdErr != nil {
+			err = cmdErr
+		} else {
+			output := new(bytes.Buffer)
+			cmd.Stdin = bytes.NewReader(data)
+			cmd.Stdout = output
+			cmd.Stderr = output
+			err = runCommand(cmd, timeout)
+			handlerLog.Debugf("Handler output:\n%s", strings.TrimSpace(output.String()))
+		}
+
+		if err != nil {
+			keyHandlersExecuted.Inc("failed")
+			handlerLog.Errorln("Error running key handler:", err)
+		} else {
+			keyHandlersExecuted.Inc("success")
+		}
+	}
+}
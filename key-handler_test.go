@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"encoding/json"
+)
+
+func TestKeyChanges(t *testing.T) {
+	previous := map[string]string{"app/a": "1", "app/b": "2", "app/c": "3"}
+	current := map[string]string{"app/a": "1", "app/b": "20", "app/d": "4"}
+	data, _ := json.Marshal(keyChanges(previous, current))
+	expected := `[{"Key":"app/b","OldValue":"2","NewValue":"20"},{"Key":"app/c","OldValue":"3","NewValue":null},{"Key":"app/d","OldValue":null,"NewValue":"4"}]`
+	if string(data) != expected {
+		t.Errorf("unexpected changes: %s", data)
+	}
+	if changes := keyChanges(previous, previous); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileRotatesOnSize(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "consul-alerts-log")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consul-alerts.log")
+
+	file, err := openRotatingFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for i := 0; i < 5; i++ {
+		file.Write([]byte("12345678\n"))
+		// rotated files are named after the time of the rotation
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Errorf("expected 2 rotated files, got %d", len(backups))
+	}
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != "12345678\n" {
+		t.Errorf("unexpected log file content: %q", data)
+	}
+}
+
+func TestRotatingFileRotatesOnAge(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "consul-alerts-log")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consul-alerts.log")
+
+	file, err := openRotatingFile(path, 0, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	file.Write([]byte("first\n"))
+	file.created = time.Now().Add(-2 * time.Hour)
+	file.Write([]byte("second\n"))
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 1 {
+		t.Errorf("expected 1 rotated file, got %d", len(backups))
+	}
+}
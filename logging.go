@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// configureLogging sets the log level (debug, info, warn or error) and the log
+// format (text or json).
+func configureLogging(level, format string) error {
+	if level == "" {
+		level = "info"
+	}
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format: %s", format)
+	}
+	log.SetLevel(logLevel)
+	return nil
+}
+
+// configureLogFile writes the logs to path instead of stderr, rotating the file
+// when it reaches maxSize megabytes or is older than maxAge hours, and keeping
+// maxBackups rotated files.
+func configureLogFile(path string, maxSize, maxAge, maxBackups int) error {
+	file, err := openRotatingFile(path, int64(maxSize)*1024*1024, time.Duration(maxAge)*time.Hour, maxBackups)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(file)
+	return nil
+}
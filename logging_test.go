@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestConfigureLogging(t *testing.T) {
+	defer configureLogging("info", "text")
+
+	if err := configureLogging("debug", "json"); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := configureLogging("", ""); err != nil {
+		t.Error("empty settings should use the defaults:", err)
+	}
+	if err := configureLogging("verbose", ""); err == nil {
+		t.Error("unknown level should fail")
+	}
+	if err := configureLogging("info", "xml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
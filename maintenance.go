@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+// notifyMaintenance sends a notice for each node or service entering or
+// exiting maintenance when maintenance notices are enabled.
+func notifyMaintenance() {
+	changes := consulClient.MaintenanceChanges()
+	if len(changes) > 0 && consulClient.MaintenanceNotices() {
+		routeMessages(maintenanceMessages(changes), nil)
+	}
+}
+
+func maintenanceMessages(changes []consul.MaintenanceChange) notifier.Messages {
+	messages := make(notifier.Messages, len(changes))
+	for i, change := range changes {
+		message := notifier.Message{
+			Datacenter: change.Datacenter,
+			Node:       change.Node,
+			CheckId:    "_node_maintenance",
+			Check:      "Node maintenance",
+			Timestamp:  time.Now(),
+		}
+		subject := "Node " + change.Node
+		if change.ServiceID != "" {
+			message.ServiceId = change.ServiceID
+			message.Service = change.ServiceName
+			message.CheckId = "_service_maintenance:" + change.ServiceID
+			message.Check = "Service maintenance"
+			subject = fmt.Sprintf("Service %s on %s", change.ServiceID, change.Node)
+		}
+		if change.Entered {
+			message.Status = "warning"
+			message.Output = subject + " entered maintenance mode."
+			if change.Reason != "" {
+				message.Output += " Reason: " + change.Reason
+			}
+		} else {
+			message.Status = "passing"
+			message.Output = subject + " exited maintenance mode."
+		}
+		messages[i] = message
+	}
+	return messages
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestMaintenanceMessages(t *testing.T) {
+	changes := []consul.MaintenanceChange{
+		{Maintenance: consul.Maintenance{Node: "web-01", Reason: "kernel upgrade"}, Entered: true},
+		{Maintenance: consul.Maintenance{Node: "db-01", ServiceID: "redis-1", ServiceName: "redis"}, Entered: false},
+	}
+	messages := maintenanceMessages(changes)
+	expected := []struct{ checkId, status, output string }{
+		{"_node_maintenance", "warning", "Node web-01 entered maintenance mode. Reason: kernel upgrade"},
+		{"_service_maintenance:redis-1", "passing", "Service redis-1 on db-01 exited maintenance mode."},
+	}
+	for i, e := range expected {
+		m := messages[i]
+		if m.CheckId != e.checkId || m.Status != e.status || m.Output != e.output {
+			t.Errorf("unexpected message %d: %+v", i, m)
+		}
+	}
+	if messages[1].Service != "redis" {
+		t.Errorf("expected the service name, got %s", messages[1].Service)
+	}
+}
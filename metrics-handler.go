@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/AcalephStorage/consul-alerts/metrics"
+)
+
+var (
+	alertsProcessed = metrics.NewCounterVec(
+		"consul_alerts_alerts_processed_total",
+		"Number of alerts processed for notification, by check status.",
+		"status",
+	)
+	notificationsSent = metrics.NewCounterVec(
+		"consul_alerts_notifications_total",
+		"Number of notifications sent by each notifier, by result.",
+		"notifier", "result",
+	)
+	notificationDuration = metrics.NewHistogramVec(
+		"consul_alerts_notification_duration_seconds",
+		"Time taken by each notifier to send a notification.",
+		metrics.DefaultBuckets,
+		"notifier",
+	)
+	checksProcessingDuration = metrics.NewHistogramVec(
+		"consul_alerts_checks_processing_duration_seconds",
+		"Time taken to process the check changes and notify the new alerts, after the change threshold.",
+		metrics.DefaultBuckets,
+	)
+	checkUpdatesCoalesced = metrics.NewCounterVec(
+		"consul_alerts_check_updates_coalesced_total",
+		"Number of check updates merged into the processing of an earlier one.",
+	)
+	eventHandlersExecuted = metrics.NewCounterVec(
+		"consul_alerts_event_handlers_executed_total",
+		"Number of event handlers executed, by result.",
+		"result",
+	)
+	keyHandlersExecuted = metrics.NewCounterVec(
+		"consul_alerts_key_handlers_executed_total",
+		"Number of key handlers executed, by result.",
+		"result",
+	)
+	remediationsExecuted = metrics.NewCounterVec(
+		"consul_alerts_remediations_executed_total",
+		"Number of remediation handlers executed, by result.",
+		"result",
+	)
+	nodeChanges = metrics.NewCounterVec(
+		"consul_alerts_node_changes_total",
+		"Number of node joins, leaves and failures notified, by alert status.",
+		"status",
+	)
+	heartbeats = metrics.NewCounterVec(
+		"consul_alerts_heartbeats_total",
+		"Number of heartbeats, by result.",
+		"result",
+	)
+	configReloads = metrics.NewCounterVec(
+		"consul_alerts_config_reloads_total",
+		"Number of config reloads triggered by a KV change, by result.",
+		"result",
+	)
+)
+
+func resultLabel(success bool) string {
+	if success {
+		return "sent"
+	}
+	return "failed"
+}
@@ -0,0 +1,199 @@
+// Package metrics is a minimal registry of counters and histograms exposed in
+// the Prometheus text format, and optionally sent to StatsD.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"net/http"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type metric interface {
+	write(buf *bytes.Buffer)
+}
+
+var registry = struct {
+	sync.Mutex
+	metrics []metric
+}{}
+
+func register(m metric) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.metrics = append(registry.metrics, m)
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+	register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := labelString(c.labels, labelValues)
+	c.Lock()
+	c.values[key] += v
+	c.Unlock()
+	emitStatsd(c.name, "c", v, c.labels, labelValues)
+}
+
+// Value returns the current value for the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := labelString(c.labels, labelValues)
+	c.Lock()
+	defer c.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(buf *bytes.Buffer) {
+	c.Lock()
+	defer c.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(buf, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+	register(h)
+	return h
+}
+
+// Observe adds a single observation for the given label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelString(h.labels, labelValues)
+	h.Lock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+	h.Unlock()
+
+	// the durations in seconds are sent as StatsD timings in milliseconds
+	if strings.HasSuffix(h.name, "_seconds") {
+		emitStatsd(h.name, "ms", v*1000, h.labels, labelValues)
+	} else {
+		emitStatsd(h.name, "h", v, h.labels, labelValues)
+	}
+}
+
+func (h *HistogramVec) write(buf *bytes.Buffer) {
+	h.Lock()
+	defer h.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		labels := append(append([]string{}, h.labels...), "le")
+		for i, bound := range h.buckets {
+			values := append(append([]string{}, s.labelValues...), formatFloat(bound))
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", h.name, labelString(labels, values), s.counts[i])
+		}
+		values := append(append([]string{}, s.labelValues...), "+Inf")
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", h.name, labelString(labels, values), s.count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", h.name, key, formatFloat(s.sum))
+		fmt.Fprintf(buf, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+// Handler writes every registered metric in the Prometheus text format.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	registry.Lock()
+	for _, m := range registry.metrics {
+		m.write(&buf)
+	}
+	registry.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+func labelString(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%s", label, strconv.Quote(value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
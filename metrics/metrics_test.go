@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"net/http/httptest"
+)
+
+func TestCounterVec(t *testing.T) {
+	counter := NewCounterVec("test_counter_total", "A test counter.", "status")
+	counter.Inc("passing")
+	counter.Inc("passing")
+	counter.Inc("critical")
+	if counter.Value("passing") != 2 || counter.Value("critical") != 1 {
+		t.Errorf("unexpected counter values: %v", counter.values)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	histogram := NewHistogramVec("test_duration_seconds", "A test histogram.", []float64{1, 5}, "notifier")
+	histogram.Observe(2, "email")
+
+	w := httptest.NewRecorder()
+	Handler(w, nil)
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{notifier="email",le="1"} 0`,
+		`test_duration_seconds_bucket{notifier="email",le="5"} 1`,
+		`test_duration_seconds_bucket{notifier="email",le="+Inf"} 1`,
+		`test_duration_seconds_sum{notifier="email"} 2`,
+		`test_duration_seconds_count{notifier="email"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestStatsdLine(t *testing.T) {
+	labels := []string{"notifier", "result"}
+	values := []string{"email", "sent"}
+	if line := statsdLine("consul_alerts.notifications", "c", 1, labels, values, false); line != "consul_alerts.notifications.email.sent:1|c" {
+		t.Errorf("unexpected statsd line %q", line)
+	}
+	if line := statsdLine("notifications", "ms", 250, labels, values, true); line != "notifications:250|ms|#notifier:email,result:sent" {
+		t.Errorf("unexpected dogstatsd line %q", line)
+	}
+}
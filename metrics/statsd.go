@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"net"
+)
+
+// statsd sends the counter increments and histogram observations to a StatsD
+// or DogStatsD agent as they are recorded. The connection is nil unless
+// EnableStatsd is called.
+var statsd = struct {
+	sync.Mutex
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+}{}
+
+// EnableStatsd sends the metrics over UDP to the StatsD agent at address, with
+// prefix prepended to their names. DogStatsD gets the labels as tags, plain
+// StatsD gets the label values appended to the name.
+func EnableStatsd(address, prefix string, dogstatsd bool) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	statsd.Lock()
+	defer statsd.Unlock()
+	statsd.conn = conn
+	statsd.prefix = prefix
+	statsd.dogstatsd = dogstatsd
+	return nil
+}
+
+// emitStatsd sends a value of the given StatsD type: c for counters, ms for
+// timings and h for histograms. Write errors are ignored, like UDP losses.
+func emitStatsd(name, kind string, value float64, labels, labelValues []string) {
+	statsd.Lock()
+	defer statsd.Unlock()
+	if statsd.conn == nil {
+		return
+	}
+	statsd.conn.Write([]byte(statsdLine(statsd.prefix+name, kind, value, labels, labelValues, statsd.dogstatsd)))
+}
+
+var statsdUnsafe = regexp.MustCompile(`[^A-Za-z0-9_\-]`)
+
+func statsdLine(name, kind string, value float64, labels, labelValues []string, dogstatsd bool) string {
+	var tags []string
+	for i, label := range labels {
+		labelValue := ""
+		if i < len(labelValues) {
+			labelValue = labelValues[i]
+		}
+		if dogstatsd {
+			tags = append(tags, label+":"+strings.Replace(labelValue, ",", "_", -1))
+		} else if labelValue != "" {
+			name += "." + statsdUnsafe.ReplaceAllString(labelValue, "_")
+		}
+	}
+	line := fmt.Sprintf("%s:%s|%s", name, formatFloat(value), kind)
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// runNodeWatcher polls the gossip members and alerts when a node joins,
+// leaves or is marked as failed by serf. Consul has no blocking query for the
+// member statuses so they are polled every nodes/interval seconds.
+func runNodeWatcher() {
+	setWatcherRunning("nodes", true)
+	defer setWatcherRunning("nodes", false)
+
+	log.Infoln("Starting nodes watcher.")
+	var members map[string]consul.Member
+	for {
+		config := consulClient.NodesConfig()
+		interval := time.Duration(config.Interval) * time.Second
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		if !config.Enabled {
+			// the alerts start from the statuses found once enabled again
+			members = nil
+			time.Sleep(interval)
+			continue
+		}
+
+		current, err := consulClient.Members()
+		if err != nil {
+			log.Warnf("Unable to list the nodes, retrying in %s: %s", interval, err)
+			time.Sleep(interval)
+			continue
+		}
+		currentMembers := make(map[string]consul.Member, len(current))
+		for _, member := range current {
+			currentMembers[member.Name] = member
+		}
+		if members != nil {
+			if messages := nodeMessages(members, currentMembers); len(messages) > 0 {
+				notifyNodeChanges(messages, config)
+			}
+		}
+		members = currentMembers
+		time.Sleep(interval)
+	}
+}
+
+// nodeMessages returns an alert for each member that joined, left or failed
+// between the previous and current polls, sorted by node. A node leaving
+// gracefully goes through leaving then left, only the first is reported.
+func nodeMessages(previous, current map[string]consul.Member) notifier.Messages {
+	var messages notifier.Messages
+	for name, member := range current {
+		previousStatus := previous[name].Status
+		if member.Status == previousStatus {
+			continue
+		}
+		switch member.Status {
+		case "alive":
+			messages = append(messages, nodeMessage(member, "passing", "joined the cluster"))
+		case "leaving", "left":
+			if previousStatus != "leaving" && previousStatus != "left" {
+				messages = append(messages, nodeMessage(member, "warning", "left the cluster"))
+			}
+		case "failed":
+			messages = append(messages, nodeMessage(member, "critical", "failed, it stopped answering the gossip probes"))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Node < messages[j].Node })
+	return messages
+}
+
+func nodeMessage(member consul.Member, status, change string) notifier.Message {
+	return notifier.Message{
+		Node:      member.Name,
+		CheckId:   "node-membership",
+		Check:     "Node membership",
+		Status:    status,
+		Output:    fmt.Sprintf("Node %s (%s) %s.", member.Name, member.Addr, change),
+		Timestamp: time.Now(),
+	}
+}
+
+// notifyNodeChanges sends the node alerts through the notifiers configured
+// for them. The members are the same on every agent so only the leader
+// notifies.
+func notifyNodeChanges(messages notifier.Messages, config *consul.NodesConfig) {
+	if leaderCandidate != nil && !leaderCandidate.IsLeader() {
+		log.Debugf("Currently not the leader. Ignoring %d node change(s).", len(messages))
+		return
+	}
+	for _, message := range messages {
+		log.WithField("node", message.Node).Infoln(message.Output)
+		nodeChanges.Inc(message.Status)
+	}
+	sendMessagesTo(messages, consul.Route{Notifiers: config.Notifiers, Receivers: config.Receivers})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestNodeMessages(t *testing.T) {
+	members := func(statuses ...string) map[string]consul.Member {
+		m := make(map[string]consul.Member)
+		for i := 0; i < len(statuses); i += 2 {
+			m[statuses[i]] = consul.Member{Name: statuses[i], Addr: "10.0.0.1", Status: statuses[i+1]}
+		}
+		return m
+	}
+	previous := members("a", "alive", "b", "alive", "c", "alive", "d", "leaving", "e", "failed")
+	current := members("a", "alive", "b", "leaving", "c", "failed", "d", "left", "e", "alive", "f", "alive")
+
+	messages := nodeMessages(previous, current)
+	expected := []struct{ node, status, output string }{
+		{"b", "warning", "Node b (10.0.0.1) left the cluster."},
+		{"c", "critical", "Node c (10.0.0.1) failed, it stopped answering the gossip probes."},
+		{"e", "passing", "Node e (10.0.0.1) joined the cluster."},
+		{"f", "passing", "Node f (10.0.0.1) joined the cluster."},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %v", len(expected), messages)
+	}
+	for i, e := range expected {
+		m := messages[i]
+		if m.Node != e.node || m.Status != e.status || m.Output != e.output {
+			t.Errorf("unexpected message %d: %+v", i, m)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+// httpClients keeps a client for each http setting, by their JSON, so the
+// notifiers built for each batch of alerts reuse their connections.
+var httpClients = struct {
+	sync.Mutex
+	clients map[string]*http.Client
+}{clients: make(map[string]*http.Client)}
+
+// notifierHttpClient returns the http client of a notifier.
+func notifierHttpClient(name string) *http.Client {
+	return cachedHttpClient(notifierHttpConfig(consulClient, name))
+}
+
+// webhookClient returns the http client of the event and key handler
+// webhooks.
+func webhookClient() *http.Client {
+	return cachedHttpClient(notifier.HttpConfig{Headers: consulClient.EventWebhookHeaders()})
+}
+
+// cachedHttpClient returns the http client of the settings. The requests of
+// invalid settings fail rather than bypassing the proxy or leaving out the
+// headers.
+func cachedHttpClient(config notifier.HttpConfig) *http.Client {
+	key, _ := json.Marshal(config)
+	httpClients.Lock()
+	defer httpClients.Unlock()
+	if client, found := httpClients.clients[string(key)]; found {
+		return client
+	}
+	client, err := config.Client()
+	if err != nil {
+		client = &http.Client{Transport: failingTransport{err}}
+	}
+	httpClients.clients[string(key)] = client
+	return client
+}
+
+func notifierHttpConfig(client consul.Consul, name string) notifier.HttpConfig {
+	config := client.NotifierHttp(name)
+	return notifier.HttpConfig{Proxy: config.Proxy, CACert: config.CACert, Headers: config.Headers}
+}
+
+// httpProblems reports the invalid http settings of the HTTP notifiers and
+// the webhooks.
+func httpProblems(client consul.Consul) []string {
+	var problems []string
+	names := []string{"email", "influxdb", "slack", "pagerduty", "hipchat", "victorops", "zulip", "discord", "splunk", "nrdp", "sentry", "aws", "pubsub"}
+	for name := range client.NotifierInstances() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := notifierHttpConfig(client, name).Client(); err != nil {
+			problems = append(problems, "consul-alerts/config/notifiers/"+name+"/http: "+err.Error())
+		}
+	}
+	if _, err := (notifier.HttpConfig{Headers: client.EventWebhookHeaders()}).Client(); err != nil {
+		problems = append(problems, "consul-alerts/config/events/webhook-headers: "+err.Error())
+	}
+	return problems
+}
+
+// failingTransport fails all the requests with the error of the invalid http
+// settings.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
@@ -0,0 +1,195 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// awsBatchSize is the most messages an SQS batch or EventBridge PutEvents
+// call takes.
+const awsBatchSize = 10
+
+// AwsNotifier sends each alert as JSON to an SQS queue, as the event detail
+// to an EventBridge bus, or to both. The requests are signed with the
+// static credentials when set, else with the credentials of the environment
+// or of the instance or task role. EventsEndpoint overrides the EventBridge
+// endpoint of the region, eg. for a VPC endpoint.
+type AwsNotifier struct {
+	failures
+
+	Region         string
+	Credentials    AwsCredentials
+	QueueUrl       string
+	EventBus       string
+	EventsEndpoint string
+	Source         string
+	DetailType     string
+	DryRun         bool
+	Http           *http.Client
+}
+
+func (aws *AwsNotifier) Notify(messages Messages) bool {
+	if aws.DryRun {
+		for _, message := range messages {
+			data, _ := json.Marshal(message)
+			logDryRun("aws", aws.QueueUrl+" "+aws.EventBus+"\n"+string(data))
+		}
+		return true
+	}
+	credentials, err := awsCredentials(aws.Credentials)
+	if err != nil {
+		aws.fail("Unable to send the alerts to aws:", err)
+		return false
+	}
+
+	result := true
+	for start := 0; start < len(messages); start += awsBatchSize {
+		end := start + awsBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		batch := messages[start:end]
+		if aws.QueueUrl != "" {
+			if err := aws.sendToQueue(credentials, batch); err != nil {
+				aws.fail("Unable to send the alerts to sqs:", err)
+				result = false
+			}
+		}
+		if aws.EventBus != "" {
+			if err := aws.putEvents(credentials, batch); err != nil {
+				aws.fail("Unable to put the alert events to eventbridge:", err)
+				result = false
+			}
+		}
+	}
+	if result {
+		log.Infof("Sent %d alert(s) to aws.", len(messages))
+	}
+	return result
+}
+
+// sendToQueue sends the messages with an SQS SendMessageBatch call. The
+// messages of a FIFO queue are grouped by check and deduplicated by their
+// content.
+func (aws *AwsNotifier) sendToQueue(credentials AwsCredentials, messages Messages) error {
+	fifo := strings.HasSuffix(aws.QueueUrl, ".fifo")
+	entries := make([]map[string]string, len(messages))
+	for i, message := range messages {
+		data, _ := json.Marshal(message)
+		entries[i] = map[string]string{"Id": strconv.Itoa(i), "MessageBody": string(data)}
+		if fifo {
+			hash := sha256.Sum256(data)
+			entries[i]["MessageGroupId"] = message.datacenterPrefix() + message.Node + "/" + message.ServiceId + "/" + message.CheckId
+			entries[i]["MessageDeduplicationId"] = hex.EncodeToString(hash[:])
+		}
+	}
+	queue, err := url.Parse(aws.QueueUrl)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Failed []struct {
+			Id      string
+			Code    string
+			Message string
+		}
+	}
+	endpoint := queue.Scheme + "://" + queue.Host + "/"
+	request := map[string]interface{}{"QueueUrl": aws.QueueUrl, "Entries": entries}
+	if err := aws.call(credentials, endpoint, "sqs", "application/x-amz-json-1.0", "AmazonSQS.SendMessageBatch", request, &response); err != nil {
+		return err
+	}
+	if len(response.Failed) > 0 {
+		failed := response.Failed[0]
+		return fmt.Errorf("%d message(s) failed, eg. %s: %s", len(response.Failed), failed.Code, failed.Message)
+	}
+	return nil
+}
+
+// putEvents puts the messages as the detail of EventBridge events.
+func (aws *AwsNotifier) putEvents(credentials AwsCredentials, messages Messages) error {
+	source, detailType := aws.Source, aws.DetailType
+	if source == "" {
+		source = "consul-alerts"
+	}
+	if detailType == "" {
+		detailType = "Consul Alert"
+	}
+	entries := make([]map[string]interface{}, len(messages))
+	for i, message := range messages {
+		data, _ := json.Marshal(message)
+		entries[i] = map[string]interface{}{
+			"EventBusName": aws.EventBus,
+			"Source":       source,
+			"DetailType":   detailType,
+			"Detail":       string(data),
+		}
+		if !message.Timestamp.IsZero() {
+			entries[i]["Time"] = message.Timestamp.Unix()
+		}
+	}
+	endpoint := aws.EventsEndpoint
+	if endpoint == "" {
+		endpoint = "https://events." + aws.Region + ".amazonaws.com/"
+	}
+	var response struct {
+		FailedEntryCount int
+		Entries          []struct {
+			ErrorCode    string
+			ErrorMessage string
+		}
+	}
+	request := map[string]interface{}{"Entries": entries}
+	if err := aws.call(credentials, endpoint, "events", "application/x-amz-json-1.1", "AWSEvents.PutEvents", request, &response); err != nil {
+		return err
+	}
+	if response.FailedEntryCount > 0 {
+		for _, entry := range response.Entries {
+			if entry.ErrorCode != "" {
+				return fmt.Errorf("%d event(s) failed, eg. %s: %s", response.FailedEntryCount, entry.ErrorCode, entry.ErrorMessage)
+			}
+		}
+		return fmt.Errorf("%d event(s) failed", response.FailedEntryCount)
+	}
+	return nil
+}
+
+// call makes a signed call to an AWS JSON protocol API.
+func (aws *AwsNotifier) call(credentials AwsCredentials, endpoint, service, contentType, target string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", target)
+	signAwsRequest(req, body, credentials, aws.Region, service, time.Now())
+	res, err := httpClient(aws.Http).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != 200 {
+		return fmt.Errorf("%s returned %d: %s", target, res.StatusCode, data)
+	}
+	return json.Unmarshal(data, response)
+}
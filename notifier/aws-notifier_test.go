@@ -0,0 +1,216 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestSignAwsRequest(t *testing.T) {
+	// the example of the AWS Signature Version 4 documentation
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	credentials := AwsCredentials{AccessKeyId: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAwsRequest(req, nil, credentials, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if authorization := req.Header.Get("Authorization"); authorization != expected {
+		t.Errorf("unexpected authorization %s", authorization)
+	}
+}
+
+func TestAwsNotify(t *testing.T) {
+	calls := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=key/") {
+			t.Errorf("unsigned request %v", r.Header)
+		}
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		calls[r.Header.Get("X-Amz-Target")] = request
+		if r.Header.Get("X-Amz-Target") == "AWSEvents.PutEvents" {
+			w.Write([]byte(`{"FailedEntryCount": 0, "Entries": [{"EventId": "1"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Successful": [{"Id": "0"}]}`))
+	}))
+	defer server.Close()
+
+	aws := &AwsNotifier{
+		Region:         "eu-west-1",
+		Credentials:    AwsCredentials{AccessKeyId: "key", SecretAccessKey: "secret"},
+		QueueUrl:       server.URL + "/123456789012/alerts.fifo",
+		EventBus:       "ops",
+		EventsEndpoint: server.URL,
+	}
+	if !aws.Notify(Messages{{Node: "web1", ServiceId: "web", CheckId: "http", Status: "critical"}}) {
+		t.Fatal("expected the alerts to be sent")
+	}
+	entry := calls["AmazonSQS.SendMessageBatch"]["Entries"].([]interface{})[0].(map[string]interface{})
+	if entry["MessageGroupId"] != "web1/web/http" || !strings.Contains(entry["MessageBody"].(string), `"Status":"critical"`) {
+		t.Errorf("unexpected sqs entry %v", entry)
+	}
+	event := calls["AWSEvents.PutEvents"]["Entries"].([]interface{})[0].(map[string]interface{})
+	if event["EventBusName"] != "ops" || event["Source"] != "consul-alerts" || event["DetailType"] != "Consul Alert" {
+		t.Errorf("unexpected event %v", event)
+	}
+}
+
+func TestAwsNotifyFailedEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"FailedEntryCount": 1, "Entries": [{"ErrorCode": "AccessDenied", "ErrorMessage": "denied"}]}`))
+	}))
+	defer server.Close()
+
+	aws := &AwsNotifier{Region: "eu-west-1", Credentials: AwsCredentials{AccessKeyId: "key"}, EventBus: "ops", EventsEndpoint: server.URL}
+	if aws.Notify(Messages{{Node: "web1", CheckId: "http", Status: "critical"}}) {
+		t.Error("expected the failed event to fail the notification")
+	}
+}
+
+func TestInstanceRoleCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			w.Write([]byte("session"))
+			return
+		}
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "session" {
+			w.WriteHeader(401)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("consul-alerts\n"))
+		case "/latest/meta-data/iam/security-credentials/consul-alerts":
+			w.Write([]byte(`{"AccessKeyId": "role", "SecretAccessKey": "secret", "Token": "token", "Expiration": "2100-01-01T00:00:00Z"}`))
+		}
+	}))
+	defer server.Close()
+
+	defer func(metadataUrl string) { awsMetadataUrl = metadataUrl }(awsMetadataUrl)
+	awsMetadataUrl = server.URL
+	defer awsEnv(map[string]string{"AWS_SHARED_CREDENTIALS_FILE": "/nonexistent", "AWS_CONFIG_FILE": "/nonexistent"})()
+
+	credentials, err := awsCredentials(AwsCredentials{})
+	if err != nil || credentials.AccessKeyId != "role" || credentials.SessionToken != "token" {
+		t.Errorf("unexpected credentials %+v %v", credentials, err)
+	}
+}
+
+// awsEnv sets the AWS credentials variables, the others unset, and returns
+// a function restoring them.
+func awsEnv(vars map[string]string) func() {
+	names := []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ROLE_SESSION_NAME", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"}
+	previous := make(map[string]string)
+	for _, name := range names {
+		if value, found := os.LookupEnv(name); found {
+			previous[name] = value
+		}
+		os.Unsetenv(name)
+	}
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
+	awsRoleCredentials.credentials = nil
+	return func() {
+		for _, name := range names {
+			os.Unsetenv(name)
+		}
+		for name, value := range previous {
+			os.Setenv(name, value)
+		}
+		awsRoleCredentials.credentials = nil
+	}
+}
+
+func TestProfileCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	credentialsFile := filepath.Join(dir, "credentials")
+	ioutil.WriteFile(credentialsFile, []byte("[default]\naws_access_key_id = default\naws_secret_access_key = secret\n"), 0600)
+	configFile := filepath.Join(dir, "config")
+	ioutil.WriteFile(configFile, []byte("[profile alerts]\nregion = eu-west-1\naws_access_key_id = alerts\naws_secret_access_key = secret\naws_session_token = token\n"), 0600)
+
+	defer awsEnv(map[string]string{"AWS_SHARED_CREDENTIALS_FILE": credentialsFile, "AWS_CONFIG_FILE": configFile})()
+	if credentials, err := awsCredentials(AwsCredentials{}); err != nil || credentials.AccessKeyId != "default" {
+		t.Errorf("expected the default profile, got %+v %v", credentials, err)
+	}
+	os.Setenv("AWS_PROFILE", "alerts")
+	if credentials, err := awsCredentials(AwsCredentials{}); err != nil || credentials.AccessKeyId != "alerts" || credentials.SessionToken != "token" {
+		t.Errorf("expected the alerts profile of the config file, got %+v %v", credentials, err)
+	}
+}
+
+func TestWebIdentityCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Action") != "AssumeRoleWithWebIdentity" || r.FormValue("WebIdentityToken") != "jwt" ||
+			r.FormValue("RoleArn") != "arn:aws:iam::123456789012:role/alerts" {
+			w.WriteHeader(403)
+			return
+		}
+		w.Write([]byte(`<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>irsa</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("jwt\n"), 0600)
+
+	defer func(stsUrl string) { awsStsUrl = stsUrl }(awsStsUrl)
+	awsStsUrl = server.URL
+	defer awsEnv(map[string]string{"AWS_SHARED_CREDENTIALS_FILE": "/nonexistent", "AWS_CONFIG_FILE": "/nonexistent",
+		"AWS_WEB_IDENTITY_TOKEN_FILE": tokenFile, "AWS_ROLE_ARN": "arn:aws:iam::123456789012:role/alerts"})()
+	credentials, err := awsCredentials(AwsCredentials{})
+	if err != nil || credentials.AccessKeyId != "irsa" || credentials.SessionToken != "token" || credentials.Expiration.Year() != 2100 {
+		t.Errorf("unexpected credentials %+v %v", credentials, err)
+	}
+}
+
+func TestContainerFullUriCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "pod-token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"AccessKeyId": "pod", "SecretAccessKey": "secret", "Token": "token", "Expiration": "2100-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("pod-token"), 0600)
+
+	defer awsEnv(map[string]string{"AWS_SHARED_CREDENTIALS_FILE": "/nonexistent", "AWS_CONFIG_FILE": "/nonexistent",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI": server.URL + "/v1/credentials", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE": tokenFile})()
+	credentials, err := awsCredentials(AwsCredentials{})
+	if err != nil || credentials.AccessKeyId != "pod" || credentials.SessionToken != "token" {
+		t.Errorf("unexpected credentials %+v %v", credentials, err)
+	}
+}
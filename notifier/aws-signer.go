@@ -0,0 +1,332 @@
+package notifier
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+// awsMetadataUrl is the EC2 instance metadata service, awsContainerUrl the
+// ECS task credentials endpoint and awsStsUrl the STS endpoint, the regional
+// one of AWS_REGION when empty.
+var (
+	awsMetadataUrl  = "http://169.254.169.254"
+	awsContainerUrl = "http://169.254.170.2"
+	awsStsUrl       = ""
+)
+
+// AwsCredentials are the access keys the AWS requests are signed with.
+type AwsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string `json:"Token"`
+	Expiration      time.Time
+}
+
+var awsRoleCredentials = struct {
+	sync.Mutex
+	credentials *AwsCredentials
+}{}
+
+// awsCredentials returns the static credentials if set, else the ones of the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY variables, else the ones of the
+// AWS_PROFILE shared profile, else the role of the web identity token (EKS
+// IRSA), of the container (ECS task or EKS Pod Identity) or of the EC2
+// instance. The role credentials are cached until shortly before they expire.
+func awsCredentials(static AwsCredentials) (AwsCredentials, error) {
+	if static.AccessKeyId != "" {
+		return static, nil
+	}
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return AwsCredentials{
+			AccessKeyId:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if credentials, found := profileCredentials(); found {
+		return credentials, nil
+	}
+
+	awsRoleCredentials.Lock()
+	defer awsRoleCredentials.Unlock()
+	if cached := awsRoleCredentials.credentials; cached != nil && time.Now().Add(5*time.Minute).Before(cached.Expiration) {
+		return *cached, nil
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	var credentials AwsCredentials
+	var err error
+	switch {
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "":
+		credentials, err = webIdentityCredentials(client)
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		err = awsGetJson(client, awsContainerUrl+os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"), nil, &credentials)
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "":
+		credentials, err = containerCredentials(client, os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"))
+	default:
+		credentials, err = instanceRoleCredentials(client)
+	}
+	if err != nil {
+		return AwsCredentials{}, fmt.Errorf("no aws credentials: %s", err)
+	}
+	awsRoleCredentials.credentials = &credentials
+	return credentials, nil
+}
+
+// profileCredentials reads the access keys of the AWS_PROFILE profile, or
+// the default one, from the shared credentials file, then from the shared
+// config file.
+func profileCredentials() (AwsCredentials, bool) {
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	home := os.Getenv("HOME")
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		credentialsFile = filepath.Join(home, ".aws", "credentials")
+	}
+	if credentials, found := iniCredentials(credentialsFile, profile); found {
+		return credentials, true
+	}
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = filepath.Join(home, ".aws", "config")
+	}
+	// the config file sections are "profile <name>", but for the default one
+	section := "profile " + profile
+	if profile == "default" {
+		section = profile
+	}
+	return iniCredentials(configFile, section)
+}
+
+// iniCredentials reads the access keys of a section of an AWS shared file.
+func iniCredentials(path, section string) (AwsCredentials, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return AwsCredentials{}, false
+	}
+	defer file.Close()
+	var credentials AwsCredentials
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if current != section || len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "aws_access_key_id":
+			credentials.AccessKeyId = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+	return credentials, credentials.AccessKeyId != ""
+}
+
+type stsWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      time.Time
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// webIdentityCredentials assumes the AWS_ROLE_ARN role with the token of
+// AWS_WEB_IDENTITY_TOKEN_FILE, as set up by EKS IAM roles for service
+// accounts. The token file is read each time, it's rotated.
+func webIdentityCredentials(client *http.Client) (AwsCredentials, error) {
+	token, err := ioutil.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return AwsCredentials{}, err
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = fmt.Sprintf("consul-alerts-%d", time.Now().Unix())
+	}
+	endpoint := awsStsUrl
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com"
+		if region := os.Getenv("AWS_REGION"); region != "" {
+			endpoint = "https://sts." + region + ".amazonaws.com"
+		}
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {os.Getenv("AWS_ROLE_ARN")},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, _ := http.NewRequest("POST", endpoint+"/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data, err := awsRead(client, req)
+	if err != nil {
+		return AwsCredentials{}, err
+	}
+	var response stsWebIdentityResponse
+	if err := xml.Unmarshal(data, &response); err != nil {
+		return AwsCredentials{}, err
+	}
+	sts := response.Credentials
+	return AwsCredentials{sts.AccessKeyId, sts.SecretAccessKey, sts.SessionToken, sts.Expiration}, nil
+}
+
+// containerCredentials reads the credentials of the container endpoint of
+// AWS_CONTAINER_CREDENTIALS_FULL_URI, as set up by EKS Pod Identity, with the
+// authorization token of AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE or
+// AWS_CONTAINER_AUTHORIZATION_TOKEN.
+func containerCredentials(client *http.Client, uri string) (AwsCredentials, error) {
+	authorization := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		token, err := ioutil.ReadFile(file)
+		if err != nil {
+			return AwsCredentials{}, err
+		}
+		authorization = strings.TrimSpace(string(token))
+	}
+	var header http.Header
+	if authorization != "" {
+		header = http.Header{"Authorization": {authorization}}
+	}
+	var credentials AwsCredentials
+	err := awsGetJson(client, uri, header, &credentials)
+	return credentials, err
+}
+
+// instanceRoleCredentials reads the credentials of the EC2 instance role with
+// an IMDSv2 session token.
+func instanceRoleCredentials(client *http.Client) (AwsCredentials, error) {
+	req, _ := http.NewRequest("PUT", awsMetadataUrl+"/latest/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := awsRead(client, req)
+	if err != nil {
+		return AwsCredentials{}, err
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+	req, _ = http.NewRequest("GET", awsMetadataUrl+"/latest/meta-data/iam/security-credentials/", nil)
+	req.Header = header
+	roles, err := awsRead(client, req)
+	if err != nil {
+		return AwsCredentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return AwsCredentials{}, errors.New("no instance role")
+	}
+	var credentials AwsCredentials
+	err = awsGetJson(client, awsMetadataUrl+"/latest/meta-data/iam/security-credentials/"+role, header, &credentials)
+	return credentials, err
+}
+
+func awsGetJson(client *http.Client, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	data, err := awsRead(client, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func awsRead(client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned %d: %s", req.URL.Path, res.StatusCode, data)
+	}
+	return data, nil
+}
+
+// signAwsRequest signs a request with AWS Signature Version 4. The host and
+// the headers already set on the request are signed.
+func signAwsRequest(req *http.Request, body []byte, credentials AwsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders string
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{req.Method, path, query, canonicalHeaders, signedHeaders, hex.EncodeToString(payloadHash[:])}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSha256Sum(key, part)
+	}
+	signature := hex.EncodeToString(hmacSha256Sum(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyId, scope, signedHeaders, signature))
+}
+
+func hmacSha256Sum(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"net/http"
+)
+
+// SetDeadline makes a notifier stop sending at deadline: the requests of the
+// HTTP notifiers are canceled, and the connections of the email, zabbix, NATS
+// and MQTT notifiers time out. The notifier is changed, it must be built for
+// a single batch of alerts.
+func SetDeadline(n Notifier, deadline time.Time) {
+	switch n := n.(type) {
+	case *EmailNotifier:
+		n.Deadline = deadline
+		n.OAuth2.Http = withDeadline(n.OAuth2.Http, deadline)
+	case *ZabbixNotifier:
+		n.Deadline = deadline
+	case *NatsNotifier:
+		n.Deadline = deadline
+	case *MqttNotifier:
+		n.Deadline = deadline
+	case *AwsNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *DiscordNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *HipChatNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *InfluxdbNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *NrdpNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *PagerDutyNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *PubsubNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *SentryNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *SlackNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *SplunkNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *VictorOpsNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	case *ZulipNotifier:
+		n.Http = withDeadline(n.Http, deadline)
+	}
+}
+
+// withDeadline returns a copy of client, the default client when nil, whose
+// requests are canceled at deadline.
+func withDeadline(client *http.Client, deadline time.Time) *http.Client {
+	copied := *httpClient(client)
+	transport := copied.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	copied.Transport = deadlineTransport{deadline, transport}
+	return &copied
+}
+
+// deadlineTransport cancels the requests, and the reading of their response,
+// at the deadline.
+type deadlineTransport struct {
+	deadline  time.Time
+	transport http.RoundTripper
+}
+
+func (t deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithDeadline(req.Context(), t.deadline)
+	res, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = cancelBody{res.Body, cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// timeoutBefore returns timeout, or less to end at deadline when set.
+func timeoutBefore(deadline time.Time, timeout time.Duration) time.Duration {
+	if deadline.IsZero() {
+		return timeout
+	}
+	if remaining := time.Until(deadline); remaining < timeout {
+		return remaining
+	}
+	return timeout
+}
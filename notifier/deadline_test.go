@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestSetDeadlineCancelsRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	zulip := &ZulipNotifier{Url: server.URL + "/", BotEmail: "alerts-bot@example.com", ApiKey: "key", Stream: "ops"}
+	SetDeadline(zulip, time.Now().Add(100*time.Millisecond))
+	start := time.Now()
+	if zulip.Notify(Messages{{Node: "web1", Check: "http", Status: "critical"}}) {
+		t.Fatal("expected the send to fail at the deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the send to stop at the deadline, it took %s", elapsed)
+	}
+}
+
+func TestSetDeadlineTimesOutConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	zabbix := &ZabbixNotifier{Host: "consul", Server: listener.Addr().String()}
+	SetDeadline(zabbix, time.Now().Add(100*time.Millisecond))
+	start := time.Now()
+	if zabbix.Notify(Messages{{Node: "web1", Check: "http", Status: "critical"}}) {
+		t.Fatal("expected the send to fail at the deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the send to stop at the deadline, it took %s", elapsed)
+	}
+}
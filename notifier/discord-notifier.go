@@ -0,0 +1,159 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const (
+	// discordMaxEmbeds is the number of embeds a webhook message can hold.
+	discordMaxEmbeds = 10
+	// discordMaxEmbedsSize is the number of characters of the embeds of a
+	// webhook message.
+	discordMaxEmbedsSize = 6000
+	// discordMaxFieldValue is the length of an embed field value.
+	discordMaxFieldValue = 1024
+)
+
+// discordColors are the embed colors by check status.
+var discordColors = map[string]int{
+	"passing":  0x2eb67d,
+	"warning":  0xecb22e,
+	"critical": 0xe01e5a,
+}
+
+// DiscordNotifier posts the alerts to a Discord webhook, an embed per check
+// colored by its status.
+type DiscordNotifier struct {
+	failures
+
+	ClusterName string
+	Url         string
+	Username    string
+	AvatarUrl   string
+	DryRun      bool
+	Http        *http.Client
+}
+
+type discordMessage struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarUrl string         `json:"avatar_url,omitempty"`
+	Content   string         `json:"content"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title  string         `json:"title"`
+	Color  int            `json:"color"`
+	Fields []discordField `json:"fields"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func (discord *DiscordNotifier) Notify(messages Messages) bool {
+	overallStatus, pass, warn, fail := messages.Summary()
+	content := fmt.Sprintf("**%s** is **%s**. Fail: %d, Warn: %d, Pass: %d", discord.ClusterName, overallStatus, fail, warn, pass)
+
+	embeds := make([]discordEmbed, len(messages))
+	for i, message := range messages {
+		embeds[i] = discordEmbedOf(message)
+	}
+	result := true
+	for _, batch := range discordBatches(embeds) {
+		payload := discordMessage{Username: discord.Username, AvatarUrl: discord.AvatarUrl, Content: content, Embeds: batch}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			discord.fail("Unable to marshal discord payload:", err)
+			return false
+		}
+		if discord.DryRun {
+			logDryRun("discord", string(data))
+			continue
+		}
+		if err := discord.send(data); err != nil {
+			discord.fail("Unable to notify discord:", err)
+			result = false
+		}
+	}
+	if result {
+		log.Infoln("Discord notification sent.")
+	}
+	return result
+}
+
+// discordBatches splits the embeds in the messages they fit in, by count and
+// by total size.
+func discordBatches(embeds []discordEmbed) [][]discordEmbed {
+	var batches [][]discordEmbed
+	var batch []discordEmbed
+	size := 0
+	for _, embed := range embeds {
+		embedSize := embed.size()
+		if len(batch) == discordMaxEmbeds || (len(batch) > 0 && size+embedSize > discordMaxEmbedsSize) {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, embed)
+		size += embedSize
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// size is the number of characters of the embed counted by Discord.
+func (e discordEmbed) size() int {
+	size := utf8.RuneCountInString(e.Title)
+	for _, field := range e.Fields {
+		size += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return size
+}
+
+func discordEmbedOf(message Message) discordEmbed {
+	service := message.Service
+	if service == "" {
+		service = "-"
+	}
+	fields := []discordField{
+		{Name: "Node", Value: message.datacenterPrefix() + message.Node, Inline: true},
+		{Name: "Service", Value: service, Inline: true},
+		{Name: "Check", Value: message.Check, Inline: true},
+	}
+	if message.Output != "" {
+		// the end of the output fits in the field with the code block and
+		// the truncation mark
+		output := OutputLimits{MaxBytes: discordMaxFieldValue - 11}.apply(message.Output)
+		fields = append(fields, discordField{Name: "Output", Value: "```\n" + output + "```"})
+	}
+	return discordEmbed{
+		Title:  fmt.Sprintf("%s%s:%s:%s is %s", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status),
+		Color:  discordColors[message.Status],
+		Fields: fields,
+	}
+}
+
+func (discord *DiscordNotifier) send(data []byte) error {
+	res, err := httpClient(discord.Http).Post(discord.Url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected response %d: %s", res.StatusCode, body)
+	}
+	return nil
+}
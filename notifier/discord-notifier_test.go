@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestDiscordNotify(t *testing.T) {
+	var payloads []discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload discordMessage
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	messages := Messages{{Node: "web1", Service: "web", Check: "http", Status: "critical", Output: strings.Repeat("x", 2000)}}
+	for i := 0; i < 10; i++ {
+		messages = append(messages, Message{Node: fmt.Sprintf("db%d", i), Check: "disk", Status: "passing"})
+	}
+	discord := &DiscordNotifier{ClusterName: "cluster", Url: server.URL, Username: "consul-alerts"}
+	if !discord.Notify(messages) {
+		t.Fatal("expected the alerts to be posted")
+	}
+	if len(payloads) != 2 || len(payloads[0].Embeds) != 10 || len(payloads[1].Embeds) != 1 {
+		t.Fatalf("expected the embeds in messages of 10, got %+v", payloads)
+	}
+	embed := payloads[0].Embeds[0]
+	if embed.Color != discordColors["critical"] || embed.Fields[0].Value != "web1" || embed.Fields[1].Value != "web" {
+		t.Errorf("unexpected embed %+v", embed)
+	}
+	if output := embed.Fields[3].Value; len(output) > discordMaxFieldValue {
+		t.Errorf("expected the output to fit in a field, got %d characters", len(output))
+	}
+	if payloads[1].Embeds[0].Color != discordColors["passing"] || payloads[0].Username != "consul-alerts" {
+		t.Errorf("unexpected message %+v", payloads[1])
+	}
+}
+
+func TestDiscordBatchesSize(t *testing.T) {
+	var embeds []discordEmbed
+	for i := 0; i < 8; i++ {
+		embeds = append(embeds, discordEmbedOf(Message{Node: fmt.Sprintf("web%d", i), Check: "http", Status: "critical", Output: strings.Repeat("x", 2000)}))
+	}
+	batches := discordBatches(embeds)
+	if len(batches) != 2 || len(batches[0]) != 5 || len(batches[1]) != 3 {
+		t.Fatalf("expected the embeds split by size, got %d batches", len(batches))
+	}
+	for _, batch := range batches {
+		size := 0
+		for _, embed := range batch {
+			size += embed.size()
+		}
+		if size > discordMaxEmbedsSize {
+			t.Errorf("expected the embeds of a message under %d characters, got %d", discordMaxEmbedsSize, size)
+		}
+	}
+}
+
+func TestDiscordFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+	}))
+	defer server.Close()
+
+	discord := &DiscordNotifier{Url: server.URL}
+	if discord.Notify(Messages{{Node: "web1", Check: "disk", Status: "critical"}}) {
+		t.Error("expected the rejected message to fail")
+	}
+	if err := Failure(discord); err == nil || !strings.Contains(err.Error(), "Unknown Webhook") {
+		t.Errorf("expected the rejection as the failure, got %v", err)
+	}
+}
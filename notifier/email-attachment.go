@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"encoding/base64"
+	"mime/multipart"
+	"net/textproto"
+)
+
+type emailAttachment struct {
+	Filename string
+	Content  []byte
+}
+
+// truncateOutputs shortens check outputs longer than MaxOutputSize bytes and
+// returns the full outputs as attachments. The given alerts are not modified.
+func (emailNotifier *EmailNotifier) truncateOutputs(alerts Messages) (Messages, []emailAttachment) {
+	maxSize := emailNotifier.MaxOutputSize
+	if maxSize <= 0 {
+		return alerts, nil
+	}
+
+	truncated := make(Messages, len(alerts))
+	var attachments []emailAttachment
+	for i, alert := range alerts {
+		if len(alert.Output) > maxSize {
+			filename := attachmentName(alert)
+			attachments = append(attachments, emailAttachment{filename, []byte(alert.Output)})
+
+			cut := maxSize
+			for cut > 0 && !utf8.RuneStart(alert.Output[cut]) {
+				cut--
+			}
+			alert.Output = fmt.Sprintf("%s\n... output truncated, see attachment %s", alert.Output[:cut], filename)
+		}
+		truncated[i] = alert
+	}
+	return truncated, attachments
+}
+
+func attachmentName(alert Message) string {
+	parts := []string{alert.Node}
+	if alert.Datacenter != "" {
+		parts = []string{alert.Datacenter, alert.Node}
+	}
+	if alert.ServiceId != "" {
+		parts = append(parts, alert.ServiceId)
+	}
+	parts = append(parts, alert.CheckId)
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, strings.Join(parts, "-"))
+	return name + ".txt"
+}
+
+// multipartBody wraps the html body and the attachments in a multipart/mixed
+// body and returns the headers describing it.
+func multipartBody(html string, attachments []emailAttachment) (string, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	htmlHeader := textproto.MIMEHeader{}
+	htmlHeader.Set("Content-Type", `text/html; charset="UTF-8"`)
+	part, err := writer.CreatePart(htmlHeader)
+	if err != nil {
+		return "", "", err
+	}
+	part.Write([]byte(html))
+
+	for _, attachment := range attachments {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf(`text/plain; charset="UTF-8"; name="%s"`, attachment.Filename))
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, attachment.Filename))
+		header.Set("Content-Transfer-Encoding", "base64")
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", err
+	}
+
+	headers := "MIME-version: 1.0;\n"
+	headers += fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\";\n\n", writer.Boundary())
+	return headers, body.String(), nil
+}
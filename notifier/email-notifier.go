@@ -2,17 +2,35 @@ package notifier
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"crypto/tls"
+	"crypto/x509"
 	"html/template"
+	"io/ioutil"
+	"mime"
+	"net/mail"
 	"net/smtp"
+	texttemplate "text/template"
 
 	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
 type EmailNotifier struct {
+	failures
+
 	ClusterName string
 	Template    string
+
+	// SubjectTemplate is a text/template executed with EmailData.
+	SubjectTemplate string
+
 	Url         string
 	Port        int
 	Username    string
@@ -20,8 +38,58 @@ type EmailNotifier struct {
 	SenderAlias string
 	SenderEmail string
 	Receivers   []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+
+	// Additional receivers keyed by service name and node name. These only
+	// receive the alerts for their own services or nodes.
+	ServiceReceivers    map[string][]string
+	NodeReceivers       map[string][]string
+	DatacenterReceivers map[string][]string
+
+	// Datacenter is the datacenter of the alerts without one, the agent's.
+	Datacenter string
+
+	// Outputs longer than MaxOutputSize bytes are truncated and attached as
+	// text files. Zero disables truncation.
+	MaxOutputSize int
+
+	// DeliveryMode can be "" (a single digest email), "node" (one email per
+	// node), or "service" (one email per service).
+	DeliveryMode string
+
+	// TLS settings. TLSMode can be "" (use STARTTLS when offered), "starttls"
+	// (require STARTTLS), "tls" (implicit TLS, usually port 465), or "none".
+	TLSMode            string
+	CACert             string
+	InsecureSkipVerify bool
+
+	// AuthMode can be "" (PLAIN when credentials are set), "none", "plain",
+	// "login", "cram-md5", or "xoauth2".
+	AuthMode string
+	OAuth2   OAuth2Config
+
+	// DryRun logs the rendered email instead of sending it.
+	DryRun bool
+
+	// Deadline is when the SMTP connections time out, see SetDeadline.
+	Deadline time.Time
 }
 
+const (
+	EmailDeliveryDigest  = ""
+	EmailDeliveryNode    = "node"
+	EmailDeliveryService = "service"
+)
+
+const (
+	EmailTLSDefault  = ""
+	EmailTLSNone     = "none"
+	EmailTLSStartTLS = "starttls"
+	EmailTLS         = "tls"
+)
+
 type EmailData struct {
 	ClusterName  string
 	SystemStatus string
@@ -29,6 +97,23 @@ type EmailData struct {
 	WarnCount    int
 	PassCount    int
 	Nodes        map[string]Messages
+	Scope        string
+}
+
+// Services returns the sorted names of the services affected by the alerts.
+func (e EmailData) Services() []string {
+	seen := make(map[string]bool)
+	services := []string{}
+	for _, messages := range e.Nodes {
+		for _, message := range messages {
+			if message.Service != "" && !seen[message.Service] {
+				seen[message.Service] = true
+				services = append(services, message.Service)
+			}
+		}
+	}
+	sort.Strings(services)
+	return services
 }
 
 func (e EmailData) IsCritical() bool {
@@ -44,6 +129,39 @@ func (e EmailData) IsPassing() bool {
 }
 
 func (emailNotifier *EmailNotifier) Notify(alerts Messages) bool {
+	result := true
+	thread := emailIncidents.open(alerts, messageIdDomain(emailNotifier.SenderEmail))
+	for _, group := range emailNotifier.receiverGroups(alerts) {
+		switch emailNotifier.DeliveryMode {
+		case EmailDeliveryNode:
+			byNode := mapByNodes(group.Messages)
+			for _, node := range sortedKeys(byNode) {
+				if !emailNotifier.notifyReceivers(thread, group.Receivers, byNode[node], node) {
+					result = false
+				}
+			}
+		case EmailDeliveryService:
+			byService := mapByServices(group.Messages)
+			for _, service := range sortedKeys(byService) {
+				if !emailNotifier.notifyReceivers(thread, group.Receivers, byService[service], service) {
+					result = false
+				}
+			}
+		default:
+			if !emailNotifier.notifyReceivers(thread, group.Receivers, group.Messages, "") {
+				result = false
+			}
+		}
+	}
+	return result
+}
+
+// notifyReceivers sends a single email for the alerts. The subject is scoped to
+// the given node or service when sending one email per node or service.
+func (emailNotifier *EmailNotifier) notifyReceivers(thread *emailThread, receivers []string, alerts Messages, scope string) bool {
+
+	threadHeaders := thread.headers(alerts)
+	alerts, attachments := emailNotifier.truncateOutputs(alerts)
 
 	overAllStatus, pass, warn, fail := alerts.Summary()
 	nodeMap := mapByNodes(alerts)
@@ -55,43 +173,334 @@ func (emailNotifier *EmailNotifier) Notify(alerts Messages) bool {
 		WarnCount:    warn,
 		PassCount:    pass,
 		Nodes:        nodeMap,
+		Scope:        scope,
 	}
 
-	var tmpl *template.Template
-	var err error
-	if emailNotifier.Template == "" {
-		tmpl, err = template.New("base").Parse(defaultTemplate)
-	} else {
-		tmpl, err = template.ParseFiles(emailNotifier.Template)
-	}
-
+	tmpl, err := emailNotifier.bodyTemplate()
 	if err != nil {
-		log.Println("Template error, unable to send email notification: ", err)
+		emailNotifier.fail("Template error, unable to send email notification:", err)
 		return false
 	}
 
 	var body bytes.Buffer
 	if err := tmpl.Execute(&body, e); err != nil {
-		log.Println("Template error, unable to send email notification: ", err)
+		emailNotifier.fail("Template error, unable to send email notification:", err)
 		return false
 	}
 
-	msg := ""
-	msg += fmt.Sprintf("From: \"%s\" <%s>\n", emailNotifier.SenderAlias, emailNotifier.SenderEmail)
-	msg += fmt.Sprintf("Subject: %s is %s\n", emailNotifier.ClusterName, overAllStatus)
-	msg += "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	msg += body.String()
+	subject, err := emailNotifier.subject(e)
+	if err != nil {
+		emailNotifier.fail("Subject template error, unable to send email notification:", err)
+		return false
+	}
 
-	addr := fmt.Sprintf("%s:%d", emailNotifier.Url, emailNotifier.Port)
-	auth := smtp.PlainAuth("", emailNotifier.Username, emailNotifier.Password, emailNotifier.Url)
-	if err := smtp.SendMail(addr, auth, emailNotifier.SenderEmail, emailNotifier.Receivers, []byte(msg)); err != nil {
-		log.Println("Unable to send notification:", err)
+	msg := emailNotifier.headers(receivers, subject)
+	msg += threadHeaders
+	if len(attachments) == 0 {
+		msg += "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+		msg += body.String()
+	} else {
+		mimeHeaders, mimeBody, err := multipartBody(body.String(), attachments)
+		if err != nil {
+			emailNotifier.fail("Unable to attach check output, unable to send email notification:", err)
+			return false
+		}
+		msg += mimeHeaders
+		msg += mimeBody
+	}
+
+	envelope := make([]string, 0, len(receivers)+len(emailNotifier.Cc)+len(emailNotifier.Bcc))
+	envelope = append(envelope, receivers...)
+	envelope = append(envelope, emailNotifier.Cc...)
+	envelope = append(envelope, emailNotifier.Bcc...)
+	if emailNotifier.DryRun {
+		return logDryRun("email", fmt.Sprintf("Envelope: %s\n%s", strings.Join(envelope, ", "), msg))
+	}
+	if err := emailNotifier.sendMail(envelope, []byte(msg)); err != nil {
+		emailNotifier.fail("Unable to send notification:", err)
 		return false
 	}
-	log.Println("Email notification sent.")
+	log.Infoln("Email notification sent.")
 	return true
 }
 
+// headers builds the address and subject headers. Bcc receivers are only
+// added to the envelope and never appear in the headers.
+func (emailNotifier *EmailNotifier) headers(receivers []string, subject string) string {
+	from := &mail.Address{Name: emailNotifier.SenderAlias, Address: emailNotifier.SenderEmail}
+	headers := fmt.Sprintf("From: %s\n", from)
+	headers += fmt.Sprintf("To: %s\n", strings.Join(receivers, ", "))
+	if len(emailNotifier.Cc) > 0 {
+		headers += fmt.Sprintf("Cc: %s\n", strings.Join(emailNotifier.Cc, ", "))
+	}
+	if emailNotifier.ReplyTo != "" {
+		headers += fmt.Sprintf("Reply-To: %s\n", emailNotifier.ReplyTo)
+	}
+	headers += fmt.Sprintf("Subject: %s\n", mime.QEncoding.Encode("UTF-8", subject))
+	return headers
+}
+
+// bodyTemplate parses the Template file, or the default template if none is
+// set.
+func (emailNotifier *EmailNotifier) bodyTemplate() (*template.Template, error) {
+	if emailNotifier.Template == "" {
+		return template.New("base").Parse(defaultTemplate)
+	}
+	return template.ParseFiles(emailNotifier.Template)
+}
+
+// ValidateTemplates reports a missing Template file or a syntax error in the
+// body or subject templates.
+func (emailNotifier *EmailNotifier) ValidateTemplates() error {
+	if _, err := emailNotifier.bodyTemplate(); err != nil {
+		return fmt.Errorf("template: %s", err)
+	}
+	if _, err := texttemplate.New("subject").Parse(emailNotifier.SubjectTemplate); err != nil {
+		return fmt.Errorf("subject-template: %s", err)
+	}
+	return nil
+}
+
+// subject renders the SubjectTemplate, or the default subject if none is set.
+// Line breaks are removed so the result is always a single header line.
+func (emailNotifier *EmailNotifier) subject(e EmailData) (string, error) {
+	subjectTemplate := emailNotifier.SubjectTemplate
+	if subjectTemplate == "" {
+		subjectTemplate = defaultSubjectTemplate
+	}
+	tmpl, err := texttemplate.New("subject").Parse(subjectTemplate)
+	if err != nil {
+		return "", err
+	}
+	var subject bytes.Buffer
+	if err := tmpl.Execute(&subject, e); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(subject.String()), " "), nil
+}
+
+// Recipients returns every address the alerts are sent to, including the Cc
+// and Bcc ones, sorted.
+func (emailNotifier *EmailNotifier) Recipients(alerts Messages) []string {
+	seen := make(map[string]bool)
+	var recipients []string
+	add := func(receivers []string) {
+		for _, receiver := range receivers {
+			if !seen[receiver] {
+				seen[receiver] = true
+				recipients = append(recipients, receiver)
+			}
+		}
+	}
+	for _, group := range emailNotifier.receiverGroups(alerts) {
+		add(group.Receivers)
+	}
+	add(emailNotifier.Cc)
+	add(emailNotifier.Bcc)
+	sort.Strings(recipients)
+	return recipients
+}
+
+type receiverGroup struct {
+	Receivers []string
+	Messages  Messages
+}
+
+// receiverGroups splits the alerts by recipient. The global receivers get all
+// alerts while service and node receivers only get the alerts they own.
+// Receivers that end up with the same set of alerts share a single email.
+func (emailNotifier *EmailNotifier) receiverGroups(alerts Messages) []receiverGroup {
+	global := make(map[string]bool)
+	for _, receiver := range emailNotifier.Receivers {
+		global[receiver] = true
+	}
+
+	owned := make(map[string][]int)
+	var order []string
+	addOwner := func(receivers []string, index int) {
+		for _, receiver := range receivers {
+			if global[receiver] {
+				continue
+			}
+			indices := owned[receiver]
+			if len(indices) > 0 && indices[len(indices)-1] == index {
+				continue
+			}
+			if indices == nil {
+				order = append(order, receiver)
+			}
+			owned[receiver] = append(indices, index)
+		}
+	}
+	for i, alert := range alerts {
+		addOwner(emailNotifier.ServiceReceivers[alert.Service], i)
+		addOwner(emailNotifier.NodeReceivers[alert.Node], i)
+		dc := alert.Datacenter
+		if dc == "" {
+			dc = emailNotifier.Datacenter
+		}
+		addOwner(emailNotifier.DatacenterReceivers[dc], i)
+	}
+
+	groups := []receiverGroup{}
+	if len(emailNotifier.Receivers) > 0 {
+		groups = append(groups, receiverGroup{emailNotifier.Receivers, alerts})
+	}
+	groupIndex := make(map[string]int)
+	for _, receiver := range order {
+		indices := owned[receiver]
+		key := fmt.Sprint(indices)
+		if i, ok := groupIndex[key]; ok {
+			groups[i].Receivers = append(groups[i].Receivers, receiver)
+			continue
+		}
+		messages := make(Messages, len(indices))
+		for j, index := range indices {
+			messages[j] = alerts[index]
+		}
+		groupIndex[key] = len(groups)
+		groups = append(groups, receiverGroup{[]string{receiver}, messages})
+	}
+	return groups
+}
+
+func (emailNotifier *EmailNotifier) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         emailNotifier.Url,
+		InsecureSkipVerify: emailNotifier.InsecureSkipVerify,
+	}
+	if emailNotifier.CACert != "" {
+		pem, err := ioutil.ReadFile(emailNotifier.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", emailNotifier.CACert)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// dial connects to the SMTP server and negotiates TLS according to TLSMode.
+func (emailNotifier *EmailNotifier) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(emailNotifier.Url, strconv.Itoa(emailNotifier.Port))
+
+	mode := emailNotifier.TLSMode
+	switch mode {
+	case EmailTLSDefault, EmailTLSNone, EmailTLSStartTLS, EmailTLS:
+	default:
+		return nil, fmt.Errorf("unknown tls mode: %s", mode)
+	}
+
+	var tlsConfig *tls.Config
+	if mode != EmailTLSNone {
+		var err error
+		if tlsConfig, err = emailNotifier.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Deadline: emailNotifier.Deadline}
+	if mode == EmailTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !emailNotifier.Deadline.IsZero() {
+		conn.SetDeadline(emailNotifier.Deadline)
+	}
+
+	client, err := smtp.NewClient(conn, emailNotifier.Url)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if mode == EmailTLSDefault || mode == EmailTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if mode == EmailTLSStartTLS {
+			client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+	}
+	return client, nil
+}
+
+func (emailNotifier *EmailNotifier) sendMail(receivers []string, msg []byte) error {
+	client, err := emailNotifier.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	auth, err := emailNotifier.smtpAuth()
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(emailNotifier.SenderEmail); err != nil {
+		return err
+	}
+	for _, receiver := range receivers {
+		if err := client.Rcpt(receiver); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// mapByServices groups the alerts by service. Node checks with no service are
+// grouped by node instead.
+func mapByServices(alerts Messages) map[string]Messages {
+	serviceMap := make(map[string]Messages)
+	for _, alert := range alerts {
+		key := alert.Service
+		if key == "" {
+			key = alert.Node
+		}
+		serviceMap[key] = append(serviceMap[key], alert)
+	}
+	return serviceMap
+}
+
+func sortedKeys(m map[string]Messages) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func mapByNodes(alerts Messages) map[string]Messages {
 	nodeMap := make(map[string]Messages)
 	for _, alert := range alerts {
@@ -106,6 +515,8 @@ func mapByNodes(alerts Messages) map[string]Messages {
 	return nodeMap
 }
 
+const defaultSubjectTemplate = `{{ .ClusterName }}{{ with .Scope }}: {{ . }}{{ end }} is {{ .SystemStatus }}`
+
 var defaultTemplate string = `
 <!DOCTYPE html>
 <html lang="en">
@@ -149,6 +560,7 @@ var defaultTemplate string = `
 			<div style="font-size: 1.1em;">
 				<strong>Node: </strong>
 				<strong>{{ $name }}</strong>
+				{{ with (index $checks 0).NodeAddress }}({{ . }}){{ end }}
 			</div>
 
 			{{ range $check := $checks }}
@@ -163,6 +575,18 @@ var defaultTemplate string = `
 					<strong>Since: </strong>
 					<span>{{ $check.Timestamp }}</span>
 				</div>
+				{{ with $check.Datacenter }}
+				<div style="font-size: 0.85em;">
+					<strong>Datacenter: </strong>
+					<span>{{ $check.Datacenter }}</span>
+				</div>
+				{{ end }}
+				{{ with $check.Namespace }}
+				<div style="font-size: 0.85em;">
+					<strong>Namespace: </strong>
+					<span>{{ $check.Namespace }}</span>
+				</div>
+				{{ end }}
 				{{ with $check.Notes }}
 				<div style="padding-top: 15px;">
 					<strong>Notes: </strong>
@@ -173,6 +597,11 @@ var defaultTemplate string = `
 					<strong>Output:</strong>
 					<pre>{{ $check.Output }}</pre>
 				</div>
+				{{ with $check.Links }}
+				<div style="font-size: 0.85em;">
+					{{ range $i, $link := . }}{{ if $i }} | {{ end }}<a href="{{ $link.Url }}">{{ $link.Name }}</a>{{ end }}
+				</div>
+				{{ end }}
 			</div>
 			{{ end }}
 
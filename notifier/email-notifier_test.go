@@ -0,0 +1,119 @@
+package notifier
+
+import "testing"
+
+func TestReceiverGroups(t *testing.T) {
+	email := &EmailNotifier{
+		Receivers: []string{"all@example.com"},
+		ServiceReceivers: map[string][]string{
+			"redis": []string{"db@example.com", "all@example.com"},
+			"web":   []string{"web@example.com"},
+		},
+		NodeReceivers: map[string][]string{
+			"node1": []string{"db@example.com", "node1@example.com"},
+		},
+	}
+	alerts := Messages{
+		Message{Node: "node1", Service: "redis"},
+		Message{Node: "node2", Service: "web"},
+	}
+	groups := email.receiverGroups(alerts)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d: %v", len(groups), groups)
+	}
+	if len(groups[0].Receivers) != 1 || len(groups[0].Messages) != 2 {
+		t.Errorf("global receivers should get every alert: %v", groups[0])
+	}
+	if len(groups[1].Receivers) != 2 || len(groups[1].Messages) != 1 || groups[1].Messages[0].Service != "redis" {
+		t.Errorf("db and node1 receivers should share the redis alert: %v", groups[1])
+	}
+	if groups[2].Receivers[0] != "web@example.com" || groups[2].Messages[0].Service != "web" {
+		t.Errorf("web receiver should only get the web alert: %v", groups[2])
+	}
+}
+
+func TestMapByServices(t *testing.T) {
+	alerts := Messages{
+		Message{Node: "node1", Service: "redis"},
+		Message{Node: "node2", Service: "redis"},
+		Message{Node: "node2"},
+	}
+	services := mapByServices(alerts)
+	if len(services["redis"]) != 2 || len(services["node2"]) != 1 {
+		t.Errorf("unexpected service grouping: %v", services)
+	}
+	if keys := sortedKeys(services); keys[0] != "node2" || keys[1] != "redis" {
+		t.Errorf("keys should be sorted: %v", keys)
+	}
+}
+
+func TestDefaultSubject(t *testing.T) {
+	email := &EmailNotifier{}
+	e := EmailData{ClusterName: "Consul-Alerts", SystemStatus: SYSTEM_CRITICAL, Scope: "node1"}
+	if subject, _ := email.subject(e); subject != "Consul-Alerts: node1 is CRITICAL" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+}
+
+func TestSubjectTemplate(t *testing.T) {
+	email := &EmailNotifier{SubjectTemplate: "[{{ .FailCount }}]\n{{ range .Services }}{{ . }} {{ end }}"}
+	e := EmailData{
+		FailCount: 2,
+		Nodes: map[string]Messages{
+			"node1": Messages{Message{Service: "web"}, Message{Service: "redis"}},
+			"node2": Messages{Message{Service: "web"}},
+		},
+	}
+	if subject, _ := email.subject(e); subject != "[2] redis web" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	email := &EmailNotifier{
+		SenderAlias: "Consul Alerts",
+		SenderEmail: "alerts@example.com",
+		Cc:          []string{"cc@example.com"},
+		Bcc:         []string{"audit@example.com"},
+		ReplyTo:     "tickets@example.com",
+	}
+	headers := email.headers([]string{"ops@example.com", "dev@example.com"}, "cluster is HEALTHY")
+	expected := "From: \"Consul Alerts\" <alerts@example.com>\n" +
+		"To: ops@example.com, dev@example.com\n" +
+		"Cc: cc@example.com\n" +
+		"Reply-To: tickets@example.com\n" +
+		"Subject: cluster is HEALTHY\n"
+	if headers != expected {
+		t.Errorf("unexpected headers:\n%s", headers)
+	}
+}
+
+func TestTruncateOutputs(t *testing.T) {
+	email := &EmailNotifier{MaxOutputSize: 5}
+	alerts := Messages{
+		Message{Node: "node1", CheckId: "serf", Output: "ok"},
+		Message{Node: "node1", ServiceId: "redis", CheckId: "service:redis", Output: "0123456789"},
+	}
+	truncated, attachments := email.truncateOutputs(alerts)
+	if len(attachments) != 1 || attachments[0].Filename != "node1-redis-service_redis.txt" {
+		t.Fatalf("unexpected attachments: %v", attachments)
+	}
+	if string(attachments[0].Content) != "0123456789" || alerts[1].Output != "0123456789" {
+		t.Error("attachment should contain the full output")
+	}
+	if truncated[0].Output != "ok" || truncated[1].Output[:6] != "01234\n" {
+		t.Errorf("unexpected truncated outputs: %v", truncated)
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	if err := (&EmailNotifier{}).ValidateTemplates(); err != nil {
+		t.Error("the default templates should be valid:", err)
+	}
+	if err := (&EmailNotifier{SubjectTemplate: "{{ .ClusterName "}).ValidateTemplates(); err == nil {
+		t.Error("an unclosed action in the subject template should be reported")
+	}
+	if err := (&EmailNotifier{Template: "/nonexistent/template.html"}).ValidateTemplates(); err == nil {
+		t.Error("a missing template file should be reported")
+	}
+}
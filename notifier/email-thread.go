@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// emailIncidents tracks the root Message-ID of every open incident so
+// follow-up and recovery emails can reference it. The state is kept in memory
+// and a restart starts new threads.
+var emailIncidents = &incidentTracker{roots: make(map[string]string)}
+
+type incidentTracker struct {
+	sync.Mutex
+	roots map[string]string
+}
+
+// emailThread holds the Message-ID roots of the checks in a single Notify call.
+type emailThread struct {
+	tracker *incidentTracker
+	domain  string
+	roots   map[string]string
+	opened  map[string]bool
+	sent    int
+}
+
+// open looks up the incident of every alert. Incidents are opened by a
+// non-passing alert and closed by a passing one, the root of a new incident is
+// the first email sent about it.
+func (t *incidentTracker) open(alerts Messages, domain string) *emailThread {
+	t.Lock()
+	defer t.Unlock()
+
+	thread := &emailThread{
+		tracker: t,
+		domain:  domain,
+		roots:   make(map[string]string),
+		opened:  make(map[string]bool),
+	}
+	for _, alert := range alerts {
+		key := incidentKey(alert)
+		root, exists := t.roots[key]
+		switch {
+		case exists && alert.IsPassing():
+			delete(t.roots, key)
+		case !exists && !alert.IsPassing():
+			thread.opened[key] = true
+		}
+		if root != "" {
+			thread.roots[key] = root
+		}
+	}
+	return thread
+}
+
+func (t *incidentTracker) setRoot(key, root string) {
+	t.Lock()
+	defer t.Unlock()
+	t.roots[key] = root
+}
+
+// headers returns the Message-ID, In-Reply-To and References headers for an
+// email containing the given alerts. The email becomes the root of the thread
+// of every incident it opens.
+func (thread *emailThread) headers(alerts Messages) string {
+	keys := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		keys = append(keys, incidentKey(alert))
+	}
+	thread.sent++
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%d", strings.Join(keys, ","), thread.sent, time.Now().UnixNano())))
+	messageId := fmt.Sprintf("<%x@%s>", hash, thread.domain)
+
+	refs := []string{}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if thread.opened[key] {
+			thread.opened[key] = false
+			thread.roots[key] = messageId
+			thread.tracker.setRoot(key, messageId)
+			continue
+		}
+		root := thread.roots[key]
+		if root == "" || root == messageId || seen[root] {
+			continue
+		}
+		seen[root] = true
+		refs = append(refs, root)
+	}
+
+	headers := fmt.Sprintf("Message-ID: %s\n", messageId)
+	if len(refs) > 0 {
+		headers += fmt.Sprintf("In-Reply-To: %s\n", refs[0])
+		headers += fmt.Sprintf("References: %s\n", strings.Join(refs, " "))
+	}
+	return headers
+}
+
+func incidentKey(alert Message) string {
+	return alert.Datacenter + "/" + alert.Namespace + "/" + alert.Node + "/" + alert.ServiceId + "/" + alert.CheckId
+}
+
+// messageIdDomain returns the domain part used in generated Message-IDs.
+func messageIdDomain(senderEmail string) string {
+	if at := strings.LastIndex(senderEmail, "@"); at >= 0 && at < len(senderEmail)-1 {
+		return senderEmail[at+1:]
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "consul-alerts"
+}
@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmailThreading(t *testing.T) {
+	tracker := &incidentTracker{roots: make(map[string]string)}
+	critical := Messages{Message{Node: "node1", CheckId: "mem", Status: "critical"}}
+	passing := Messages{Message{Node: "node1", CheckId: "mem", Status: "passing"}}
+
+	first := tracker.open(critical, "example.com").headers(critical)
+	root := tracker.roots["//node1//mem"]
+	if root == "" || !strings.HasPrefix(first, "Message-ID: "+root+"\n") {
+		t.Fatalf("first email should be the thread root, root=%s headers=%s", root, first)
+	}
+
+	recovery := tracker.open(passing, "example.com").headers(passing)
+	if strings.Contains(recovery, "Message-ID: "+root) || !strings.Contains(recovery, "In-Reply-To: "+root+"\n") {
+		t.Errorf("recovery email should reply to the root, headers=%s", recovery)
+	}
+	if len(tracker.roots) != 0 {
+		t.Errorf("incident should be closed after recovery: %v", tracker.roots)
+	}
+}
+
+func TestEmailThreadingSeveralIncidents(t *testing.T) {
+	tracker := &incidentTracker{roots: make(map[string]string)}
+	critical := Messages{
+		Message{Node: "node1", CheckId: "mem", Status: "critical"},
+		Message{Node: "node1", CheckId: "disk", Status: "warning"},
+	}
+	passing := Messages{Message{Node: "node1", CheckId: "mem", Status: "passing"}}
+
+	first := tracker.open(critical, "example.com").headers(critical)
+	root := tracker.roots["//node1//mem"]
+	if root == "" || tracker.roots["//node1//disk"] != root || !strings.HasPrefix(first, "Message-ID: "+root+"\n") {
+		t.Fatalf("the email should be the root of both incidents, roots=%v headers=%s", tracker.roots, first)
+	}
+	if strings.Contains(first, "In-Reply-To") {
+		t.Errorf("the first email should not reply to anything, headers=%s", first)
+	}
+
+	recovery := tracker.open(passing, "example.com").headers(passing)
+	if !strings.Contains(recovery, "In-Reply-To: "+root+"\n") || !strings.Contains(recovery, "References: "+root+"\n") {
+		t.Errorf("recovery email should reply to the sent email, headers=%s", recovery)
+	}
+	if len(tracker.roots) != 1 {
+		t.Errorf("only the recovered incident should be closed: %v", tracker.roots)
+	}
+}
+
+func TestMessageIdDomain(t *testing.T) {
+	if domain := messageIdDomain("alerts@example.com"); domain != "example.com" {
+		t.Errorf("unexpected domain: %s", domain)
+	}
+}
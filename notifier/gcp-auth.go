@@ -0,0 +1,129 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// gcpMetadataUrl is the metadata server of the GCE instances and of the GKE
+// pods with workload identity.
+var gcpMetadataUrl = "http://metadata.google.internal"
+
+type gcpServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyId string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenUri     string `json:"token_uri"`
+}
+
+// gcpToken returns an access token of the scope for the service account of
+// the credentials file, or of GOOGLE_APPLICATION_CREDENTIALS when it's empty,
+// or else for the service account of the metadata server. The tokens are
+// cached with the OAuth2 tokens until shortly before they expire.
+func gcpToken(credentialsFile, scope string, client *http.Client) (string, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	cacheKey := "gcp:" + credentialsFile + ":" + scope
+	oauth2Tokens.Lock()
+	defer oauth2Tokens.Unlock()
+	if cached, ok := oauth2Tokens.tokens[cacheKey]; ok && time.Now().Before(cached.expiry) {
+		return cached.accessToken, nil
+	}
+
+	var req *http.Request
+	if credentialsFile != "" {
+		data, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return "", err
+		}
+		var account gcpServiceAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return "", fmt.Errorf("invalid credentials file %s: %s", credentialsFile, err)
+		}
+		if account.TokenUri == "" {
+			account.TokenUri = defaultOAuth2TokenUrl
+		}
+		assertion, err := account.assertion(scope, time.Now())
+		if err != nil {
+			return "", err
+		}
+		form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+		req, _ = http.NewRequest("POST", account.TokenUri, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		client = &http.Client{Timeout: 5 * time.Second}
+		req, _ = http.NewRequest("GET", gcpMetadataUrl+"/computeMetadata/v1/instance/service-accounts/default/token?scopes="+url.QueryEscape(scope), nil)
+		req.Header.Set("Metadata-Flavor", "Google")
+	}
+	res, err := httpClient(client).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("token request failed: %s", body)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token request returned no access token")
+	}
+	expiry := time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	oauth2Tokens.tokens[cacheKey] = cachedToken{token.AccessToken, expiry}
+	return token.AccessToken, nil
+}
+
+// assertion returns the JWT exchanged for an access token of the service
+// account, signed with its private key.
+func (account gcpServiceAccount) assertion(scope string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", errors.New("no private key in the credentials file")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("expected an rsa private key in the credentials file")
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": account.PrivateKeyId})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": scope,
+		"aud":   account.TokenUri,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,115 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const defaultHipChatUrl = "https://api.hipchat.com/v2/room/{room}/notification"
+
+// HipChatNotifier posts room notifications to HipChat or any chat server with
+// a HipChat-compatible room message API. Url is a pattern where {room} is
+// replaced with the escaped RoomId.
+type HipChatNotifier struct {
+	failures
+
+	ClusterName  string
+	Url          string
+	RoomId       string
+	AuthToken    string
+	From         string
+	PassingColor string
+	WarningColor string
+	FailColor    string
+	DryRun       bool
+	Http         *http.Client
+}
+
+type hipChatMessage struct {
+	Message       string `json:"message"`
+	MessageFormat string `json:"message_format"`
+	Color         string `json:"color"`
+	Notify        bool   `json:"notify"`
+	From          string `json:"from,omitempty"`
+}
+
+func (hipchat *HipChatNotifier) Notify(messages Messages) bool {
+
+	overallStatus, pass, warn, fail := messages.Summary()
+
+	text := fmt.Sprintf(header, hipchat.ClusterName, overallStatus, fail, warn, pass)
+	for _, message := range messages {
+		text += fmt.Sprintf("\n%s%s:%s:%s is %s.", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status)
+		text += fmt.Sprintf("\n%s", message.Output)
+	}
+
+	payload := hipChatMessage{
+		Message:       text,
+		MessageFormat: "text",
+		Color:         hipchat.color(overallStatus),
+		Notify:        overallStatus != SYSTEM_HEALTHY,
+		From:          hipchat.From,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		hipchat.fail("Unable to marshal hipchat payload:", err)
+		return false
+	}
+
+	pattern := hipchat.Url
+	if pattern == "" {
+		pattern = defaultHipChatUrl
+	}
+	roomUrl := strings.Replace(pattern, "{room}", url.QueryEscape(hipchat.RoomId), -1)
+	if hipchat.DryRun {
+		return logDryRun("hipchat", roomUrl+"\n"+string(data))
+	}
+
+	req, err := http.NewRequest("POST", roomUrl, bytes.NewBuffer(data))
+	if err != nil {
+		hipchat.fail("Unable to create hipchat request:", err)
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hipchat.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+hipchat.AuthToken)
+	}
+
+	res, err := httpClient(hipchat.Http).Do(req)
+	if err != nil {
+		hipchat.fail("Unable to send data to hipchat:", err)
+		return false
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(res.Body)
+		hipchat.fail("Unable to notify hipchat:", string(body))
+		return false
+	}
+	log.Infoln("HipChat notification sent.")
+	return true
+}
+
+func (hipchat *HipChatNotifier) color(overallStatus string) string {
+	var color, fallback string
+	switch overallStatus {
+	case SYSTEM_CRITICAL:
+		color, fallback = hipchat.FailColor, "red"
+	case SYSTEM_UNSTABLE:
+		color, fallback = hipchat.WarningColor, "yellow"
+	default:
+		color, fallback = hipchat.PassingColor, "green"
+	}
+	if color == "" {
+		return fallback
+	}
+	return color
+}
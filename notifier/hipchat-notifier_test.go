@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"testing"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestHipChatNotify(t *testing.T) {
+	var path, auth string
+	var payload hipChatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	hipchat := &HipChatNotifier{
+		ClusterName: "cluster",
+		Url:         server.URL + "/v2/room/{room}/notification",
+		RoomId:      "ops",
+		AuthToken:   "token",
+		FailColor:   "purple",
+	}
+	if !hipchat.Notify(Messages{Message{Status: "critical"}}) {
+		t.Fatal("notification should succeed")
+	}
+	if path != "/v2/room/ops/notification" || auth != "Bearer token" {
+		t.Errorf("unexpected request path=%s auth=%s", path, auth)
+	}
+	if payload.Color != "purple" || !payload.Notify {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+}
+
+func TestHipChatDryRun(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	hipchat := &HipChatNotifier{
+		Url:    server.URL + "/v2/room/{room}/notification",
+		RoomId: "ops",
+		DryRun: true,
+	}
+	if !hipchat.Notify(Messages{Message{Status: "critical"}}) {
+		t.Error("dry run should succeed")
+	}
+	if called {
+		t.Error("dry run should not send the notification")
+	}
+}
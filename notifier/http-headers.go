@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+)
+
+// headerFuncs are the functions of the header templates, eg. to sign the
+// body: {{ hmacSha256 "secret" .Body }}.
+var headerFuncs = template.FuncMap{
+	"hmacSha256": func(key, message string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(message))
+		return hex.EncodeToString(mac.Sum(nil))
+	},
+	"sha256": func(message string) string {
+		sum := sha256.Sum256([]byte(message))
+		return hex.EncodeToString(sum[:])
+	},
+	"base64": func(message string) string {
+		return base64.StdEncoding.EncodeToString([]byte(message))
+	},
+}
+
+// headerData is what the header templates are executed with. Timestamp is
+// in unix seconds.
+type headerData struct {
+	Method    string
+	Url       string
+	Host      string
+	Path      string
+	Body      string
+	Timestamp int64
+}
+
+func parseHeaders(headers map[string]string) (map[string]*template.Template, error) {
+	parsed := make(map[string]*template.Template, len(headers))
+	for name, value := range headers {
+		if name == "" {
+			return nil, fmt.Errorf("empty header name")
+		}
+		tmpl, err := template.New(name).Funcs(headerFuncs).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %s", name, err)
+		}
+		parsed[name] = tmpl
+	}
+	return parsed, nil
+}
+
+// headerTransport adds the headers to the requests.
+type headerTransport struct {
+	headers   map[string]*template.Template
+	transport http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data := headerData{
+		Method:    req.Method,
+		Url:       req.URL.String(),
+		Host:      req.URL.Host,
+		Path:      req.URL.Path,
+		Timestamp: time.Now().Unix(),
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		data.Body = string(content)
+	}
+
+	// the request can't be modified by a transport
+	req = req.Clone(req.Context())
+	for name, tmpl := range t.headers {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("header %s: %s", name, err)
+		}
+		req.Header.Set(name, value.String())
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"bytes"
+	"testing"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestHttpConfigHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer server.Close()
+
+	client, err := HttpConfig{Headers: map[string]string{
+		"X-Api-Key":   "secret",
+		"X-Signature": `sha256={{ hmacSha256 "key" .Body }}`,
+		"X-Route":     "{{ .Method }} {{ .Path }}",
+	}}.Client()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	req, _ := http.NewRequest("POST", server.URL+"/alerts", bytes.NewReader([]byte(`{"status":"critical"}`)))
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	res.Body.Close()
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(`{"status":"critical"}`))
+	if signature := received.Get("X-Signature"); signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("unexpected signature %q", signature)
+	}
+	if received.Get("X-Api-Key") != "secret" || received.Get("X-Route") != "POST /alerts" {
+		t.Errorf("unexpected headers %v", received)
+	}
+	if len(req.Header) != 0 {
+		t.Error("the request shouldn't be modified")
+	}
+}
+
+func TestHttpConfigInvalidHeaders(t *testing.T) {
+	if _, err := (HttpConfig{Headers: map[string]string{"X-Signature": "{{ hmacSha256 .Body"}}).Client(); err == nil {
+		t.Error("an invalid template should be rejected")
+	}
+}
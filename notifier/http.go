@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// HttpConfig sets how the HTTP notifiers reach their servers. Proxy is an
+// http://, https:// or socks5:// URL, the HTTP_PROXY and HTTPS_PROXY
+// environment variables are used when it's empty. CACert is a PEM CA bundle
+// trusted in addition to the system CAs. Headers are added to each request,
+// their values are templates of the request (see headerData).
+type HttpConfig struct {
+	Proxy   string
+	CACert  string
+	Headers map[string]string
+}
+
+// Client returns an http client using the proxy, the CA bundle and the
+// headers.
+func (config HttpConfig) Client() (*http.Client, error) {
+	headers, err := parseHeaders(config.Headers)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if config.Proxy != "" {
+		proxyUrl, err := parseProxy(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	if config.CACert != "" {
+		pem, err := ioutil.ReadFile(config.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if len(headers) == 0 {
+		return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+	}
+	return &http.Client{Transport: &headerTransport{headers, transport}, Timeout: 30 * time.Second}, nil
+}
+
+// parseProxy parses an http://, https:// or socks5:// proxy URL.
+func parseProxy(proxy string) (*url.URL, error) {
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("expected an http://, https:// or socks5:// proxy URL, got %q", proxy)
+	}
+	if proxyUrl.Host == "" {
+		return nil, fmt.Errorf("missing the proxy host in %q", proxy)
+	}
+	return proxyUrl, nil
+}
+
+// httpClient returns client, or the default client if it's nil.
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
@@ -1,30 +1,42 @@
 package notifier
 
 import (
+	"encoding/json"
+	"net/http"
+
 	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/influxdb/influxdb/client"
 )
 
 type InfluxdbNotifier struct {
+	failures
+
 	Host       string
 	Username   string
 	Password   string
 	Database   string
 	SeriesName string
+	DryRun     bool
+	Http       *http.Client
 }
 
 func (influxdb *InfluxdbNotifier) Notify(messages Messages) bool {
+	if influxdb.DryRun {
+		data, _ := json.Marshal(influxdb.toSeries(messages))
+		return logDryRun("influxdb", string(data))
+	}
 
 	config := &client.ClientConfig{
-		Host:     influxdb.Host,
-		Username: influxdb.Username,
-		Password: influxdb.Password,
-		Database: influxdb.Database,
+		Host:       influxdb.Host,
+		Username:   influxdb.Username,
+		Password:   influxdb.Password,
+		Database:   influxdb.Database,
+		HttpClient: httpClient(influxdb.Http),
 	}
 
 	influxdbClient, err := client.New(config)
 	if err != nil {
-		log.Println("unable to access influxdb. can't send notification. ", err)
+		influxdb.fail("unable to access influxdb. can't send notification.", err)
 		return false
 	}
 
@@ -32,11 +44,11 @@ func (influxdb *InfluxdbNotifier) Notify(messages Messages) bool {
 	err = influxdbClient.WriteSeries(seriesList)
 
 	if err != nil {
-		log.Println("unable to send notifications: ", err)
+		influxdb.fail("unable to send notifications:", err)
 		return false
 	}
 
-	log.Println("influxdb notification sent.")
+	log.Infoln("influxdb notification sent.")
 	return true
 }
 
@@ -50,6 +62,7 @@ func (influxdb *InfluxdbNotifier) toSeries(messages Messages) []*client.Series {
 		"notes",
 		"output",
 		"status",
+		"datacenter",
 	}
 
 	seriesList := make([]*client.Series, len(messages))
@@ -62,6 +75,7 @@ func (influxdb *InfluxdbNotifier) toSeries(messages Messages) []*client.Series {
 			message.Notes,
 			message.Output,
 			message.Status,
+			message.Datacenter,
 		}
 
 		series := &client.Series{
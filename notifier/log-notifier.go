@@ -1,6 +1,7 @@
 package notifier
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path"
@@ -14,25 +15,32 @@ type LogNotifier struct {
 
 func (logNotifier *LogNotifier) Notify(alerts Messages) bool {
 
-	logrus.Println("logging messages...")
+	logrus.Debugln("logging messages...")
 
 	logDir := path.Dir(logNotifier.LogFile)
 	err := os.MkdirAll(logDir, os.ModePerm)
 	if err != nil {
-		logrus.Printf("unable to create directory for logfile: %v\n", err)
+		logrus.Errorf("unable to create directory for logfile: %v", err)
 		return false
 	}
 
 	file, err := os.OpenFile(logNotifier.LogFile, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
 	if err != nil {
-		logrus.Printf("unable to write to logfile: %v\n", err)
+		logrus.Errorf("unable to write to logfile: %v", err)
 		return false
 	}
 
 	logger := log.New(file, "[consul-notifier] ", log.LstdFlags)
 	for _, alert := range alerts {
-		logger.Printf("Node=%s, Service=%s, Check=%s, Status=%s\n", alert.Node, alert.Service, alert.Check, alert.Status)
+		scope := ""
+		if alert.Datacenter != "" {
+			scope += fmt.Sprintf("Datacenter=%s, ", alert.Datacenter)
+		}
+		if alert.Namespace != "" {
+			scope += fmt.Sprintf("Namespace=%s, ", alert.Namespace)
+		}
+		logger.Printf("%sNode=%s, Service=%s, Check=%s, Status=%s\n", scope, alert.Node, alert.Service, alert.Check, alert.Status)
 	}
-	logrus.Println("Notifications logged.")
+	logrus.Infoln("Notifications logged.")
 	return true
 }
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	defaultMqttTopic = "consul-alerts/{datacenter}/{node}/{check}"
+	mqttTimeout      = 10 * time.Second
+)
+
+// mqttTopicLevel replaces the characters a topic level can't hold.
+var mqttTopicLevel = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// MqttNotifier publishes the state of each check as JSON to its own MQTT
+// topic, retained by default so a subscriber gets the current state of every
+// check right away. Topic is a pattern where {datacenter}, {node}, {service}
+// and {check} are replaced with the datacenter, node, service id and check id
+// of the check. Url is a tcp://, ssl:// or ws:// broker URL.
+type MqttNotifier struct {
+	failures
+
+	Url      string
+	ClientId string
+	Topic    string
+	Qos      int
+	Retain   bool
+	Username string
+	Password string
+	CACert   string
+	TLSCert  string
+	TLSKey   string
+	DryRun   bool
+
+	// Deadline is when the sends stop, see SetDeadline.
+	Deadline time.Time
+}
+
+func (m *MqttNotifier) Notify(messages Messages) bool {
+	if m.DryRun {
+		for _, message := range messages {
+			data, _ := json.Marshal(message)
+			logDryRun("mqtt", m.topic(message)+"\n"+string(data))
+		}
+		return true
+	}
+
+	options, err := m.options()
+	if err != nil {
+		m.fail("Unable to configure the mqtt client:", err)
+		return false
+	}
+	client := mqtt.NewClient(options)
+	if err := m.wait(client.Connect()); err != nil {
+		m.fail("Unable to connect to the mqtt broker:", err)
+		return false
+	}
+	defer client.Disconnect(250)
+
+	result := true
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			m.fail("Unable to marshal mqtt payload:", err)
+			result = false
+			continue
+		}
+		topic := m.topic(message)
+		if err := m.wait(client.Publish(topic, byte(m.Qos), m.Retain, data)); err != nil {
+			m.failf("Unable to publish to mqtt topic %s: %s", topic, err)
+			result = false
+		}
+	}
+	if result {
+		log.Infof("Published %d alert(s) to mqtt.", len(messages))
+	}
+	return result
+}
+
+func (m *MqttNotifier) topic(message Message) string {
+	pattern := m.Topic
+	if pattern == "" {
+		pattern = defaultMqttTopic
+	}
+	level := func(value string) string {
+		if value == "" {
+			return "_"
+		}
+		return mqttTopicLevel.Replace(value)
+	}
+	replacer := strings.NewReplacer(
+		"{datacenter}", level(message.Datacenter),
+		"{node}", level(message.Node),
+		"{service}", level(message.ServiceId),
+		"{check}", level(message.CheckId),
+	)
+	return replacer.Replace(pattern)
+}
+
+func (m *MqttNotifier) options() (*mqtt.ClientOptions, error) {
+	clientId := m.ClientId
+	if clientId == "" {
+		clientId = "consul-alerts"
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(m.Url).
+		SetClientID(clientId).
+		SetUsername(m.Username).
+		SetPassword(m.Password).
+		SetConnectTimeout(timeoutBefore(m.Deadline, mqttTimeout)).
+		SetAutoReconnect(false)
+	if m.CACert == "" && m.TLSCert == "" {
+		return options, nil
+	}
+	config := &tls.Config{}
+	if m.CACert != "" {
+		pem, err := ioutil.ReadFile(m.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", m.CACert)
+		}
+		config.RootCAs = pool
+	}
+	if m.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(m.TLSCert, m.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return options.SetTLSConfig(config), nil
+}
+
+// wait waits for an mqtt operation and returns its error.
+func (m *MqttNotifier) wait(token mqtt.Token) error {
+	timeout := timeoutBefore(m.Deadline, mqttTimeout)
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return token.Error()
+}
@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+func TestMqttNotify(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var lock sync.Mutex
+	var connect *packets.ConnectPacket
+	published := []*packets.PublishPacket{}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			packet, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			lock.Lock()
+			switch p := packet.(type) {
+			case *packets.ConnectPacket:
+				connect = p
+				packets.NewControlPacket(packets.Connack).Write(conn)
+			case *packets.PublishPacket:
+				published = append(published, p)
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				ack.Write(conn)
+			}
+			lock.Unlock()
+		}
+	}()
+
+	m := &MqttNotifier{Url: "tcp://" + listener.Addr().String(), Qos: 1, Retain: true, Username: "alerts"}
+	messages := Messages{
+		{Datacenter: "dc1", Node: "web1", CheckId: "service:web", Status: "critical"},
+		{Node: "db1", CheckId: "disk", Status: "passing"},
+	}
+	if !m.Notify(messages) {
+		t.Fatal("expected the alerts to be published")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if connect == nil || connect.Username != "alerts" {
+		t.Errorf("unexpected connect %v", connect)
+	}
+	if len(published) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(published))
+	}
+	if published[0].TopicName != "consul-alerts/dc1/web1/service:web" || published[1].TopicName != "consul-alerts/_/db1/disk" {
+		t.Errorf("unexpected topics %s, %s", published[0].TopicName, published[1].TopicName)
+	}
+	var message Message
+	json.Unmarshal(published[0].Payload, &message)
+	if !published[0].Retain || published[0].Qos != 1 || message.Status != "critical" {
+		t.Errorf("unexpected message %v %+v", published[0], message)
+	}
+}
+
+func TestMqttTopic(t *testing.T) {
+	m := &MqttNotifier{Topic: "alerts/{node}/{service}/{check}"}
+	if topic := m.topic(Message{Node: "web1", ServiceId: "web/api", CheckId: "http#1"}); topic != "alerts/web1/web_api/http_1" {
+		t.Errorf("unexpected topic %q", topic)
+	}
+}
@@ -0,0 +1,110 @@
+package notifier
+
+import (
+	"time"
+
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/nats-io/nats.go"
+)
+
+const defaultNatsSubject = "consul-alerts.alerts"
+
+// NatsNotifier publishes each alert as JSON to a NATS subject. With
+// JetStream, each alert is acknowledged by the stream bound to the subject.
+// Url is a comma separated list of servers. The connection is authenticated
+// with a credentials file, a token, or a username and password, and uses
+// TLS with the CA and client certificate files when set.
+type NatsNotifier struct {
+	failures
+
+	Url         string
+	Subject     string
+	JetStream   bool
+	Credentials string
+	Token       string
+	Username    string
+	Password    string
+	CACert      string
+	TLSCert     string
+	TLSKey      string
+	DryRun      bool
+
+	// Deadline is when the sends stop, see SetDeadline.
+	Deadline time.Time
+}
+
+func (n *NatsNotifier) Notify(messages Messages) bool {
+	subject := n.Subject
+	if subject == "" {
+		subject = defaultNatsSubject
+	}
+	if n.DryRun {
+		for _, message := range messages {
+			data, _ := json.Marshal(message)
+			logDryRun("nats", subject+"\n"+string(data))
+		}
+		return true
+	}
+
+	conn, err := nats.Connect(n.Url, n.options()...)
+	if err != nil {
+		n.fail("Unable to connect to nats:", err)
+		return false
+	}
+	defer conn.Close()
+	publish := conn.Publish
+	if n.JetStream {
+		js, err := conn.JetStream()
+		if err != nil {
+			n.fail("Unable to use nats jetstream:", err)
+			return false
+		}
+		publish = func(subject string, data []byte) error {
+			_, err := js.Publish(subject, data)
+			return err
+		}
+	}
+
+	result := true
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			n.fail("Unable to marshal nats payload:", err)
+			result = false
+			continue
+		}
+		if err := publish(subject, data); err != nil {
+			n.failf("Unable to publish %s%s:%s:%s to nats: %s", message.datacenterPrefix(), message.Node, message.ServiceId, message.CheckId, err)
+			result = false
+		}
+	}
+	if err := conn.FlushTimeout(timeoutBefore(n.Deadline, 10*time.Second)); err != nil {
+		n.fail("Unable to flush the nats messages:", err)
+		return false
+	}
+	if result {
+		log.Infof("Published %d alert(s) to nats.", len(messages))
+	}
+	return result
+}
+
+func (n *NatsNotifier) options() []nats.Option {
+	options := []nats.Option{nats.Name("consul-alerts"), nats.Timeout(timeoutBefore(n.Deadline, 10*time.Second))}
+	switch {
+	case n.Credentials != "":
+		options = append(options, nats.UserCredentials(n.Credentials))
+	case n.Token != "":
+		options = append(options, nats.Token(n.Token))
+	case n.Username != "":
+		options = append(options, nats.UserInfo(n.Username, n.Password))
+	}
+	if n.CACert != "" {
+		options = append(options, nats.RootCAs(n.CACert))
+	}
+	if n.TLSCert != "" {
+		options = append(options, nats.ClientCert(n.TLSCert, n.TLSKey))
+	}
+	return options
+}
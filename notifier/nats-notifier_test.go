@@ -0,0 +1,119 @@
+package notifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"encoding/json"
+)
+
+// fakeNats is a NATS server speaking enough of the protocol for a client to
+// publish, acknowledging the JetStream publishes.
+type fakeNats struct {
+	sync.Mutex
+	listener  net.Listener
+	connects  []string
+	published map[string][]string
+}
+
+func newFakeNats(t *testing.T) *fakeNats {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeNats{listener: listener, published: make(map[string][]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeNats) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeNats) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.2.0\",\"headers\":true,\"max_payload\":1048576}\r\n")
+	reader := bufio.NewReader(conn)
+	sids := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT":
+			s.Lock()
+			s.connects = append(s.connects, strings.TrimSpace(line[len("CONNECT "):]))
+			s.Unlock()
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			sids[strings.TrimSuffix(fields[1], "*")] = fields[len(fields)-1]
+		case "PUB":
+			var size int
+			fmt.Sscan(fields[len(fields)-1], &size)
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.Lock()
+			s.published[fields[1]] = append(s.published[fields[1]], string(payload[:size]))
+			s.Unlock()
+			if len(fields) == 4 {
+				// the reply inbox of a jetstream publish
+				for prefix, sid := range sids {
+					if strings.HasPrefix(fields[2], prefix) {
+						ack := `{"stream":"ALERTS","seq":1}`
+						fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[2], sid, len(ack), ack)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNatsNotify(t *testing.T) {
+	server := newFakeNats(t)
+	defer server.listener.Close()
+
+	messages := Messages{{Node: "web1", CheckId: "http", Status: "critical"}, {Node: "web2", CheckId: "http", Status: "passing"}}
+	for _, jetStream := range []bool{false, true} {
+		n := &NatsNotifier{Url: server.url(), Subject: fmt.Sprint("alerts.", jetStream), JetStream: jetStream, Token: "secret"}
+		if !n.Notify(messages) {
+			t.Fatalf("expected the alerts to be published, jetstream %v", jetStream)
+		}
+	}
+
+	server.Lock()
+	defer server.Unlock()
+	for _, subject := range []string{"alerts.false", "alerts.true"} {
+		if len(server.published[subject]) != 2 {
+			t.Fatalf("expected 2 alerts on %s, got %v", subject, server.published)
+		}
+		var message Message
+		json.Unmarshal([]byte(server.published[subject][0]), &message)
+		if message.Node != "web1" || message.Status != "critical" {
+			t.Errorf("unexpected alert %+v", message)
+		}
+	}
+	if !strings.Contains(server.connects[0], `"auth_token":"secret"`) {
+		t.Errorf("expected the token in %s", server.connects[0])
+	}
+}
@@ -1,6 +1,14 @@
 package notifier
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
 
 const (
 	SYSTEM_HEALTHY  string = "HEALTHY"
@@ -8,16 +16,36 @@ const (
 	SYSTEM_CRITICAL string = "CRITICAL"
 )
 
+// Message is an alert of a check. NodeAddress, ServiceAddress and ServicePort
+// come from the catalog, ServiceAddress being the node address when the
+// service doesn't set its own. Links are the configured dashboard or runbook
+// links of the alert.
 type Message struct {
-	Node      string
-	ServiceId string
-	Service   string
-	CheckId   string
-	Check     string
-	Status    string
-	Output    string
-	Notes     string
-	Timestamp time.Time
+	Datacenter     string
+	Namespace      string
+	Node           string
+	NodeAddress    string
+	NodeMeta       map[string]string
+	ServiceId      string
+	Service        string
+	ServiceAddress string
+	ServicePort    int
+	Tags           []string
+	CheckId        string
+	Check          string
+	Status         string
+	// NotifiedStatus is the status of the last alert sent for the check.
+	NotifiedStatus string
+	Output         string
+	Notes          string
+	Links          []Link
+	Timestamp      time.Time
+}
+
+// Link is a named url, like a dashboard or a runbook page.
+type Link struct {
+	Name string
+	Url  string
 }
 
 type Messages []Message
@@ -26,6 +54,63 @@ type Notifier interface {
 	Notify(alerts Messages) bool
 }
 
+// Failure returns the errors of the failed sends of n, nil when none failed
+// or n doesn't report them.
+func Failure(n Notifier) error {
+	if f, ok := n.(interface {
+		Err() error
+	}); ok {
+		return f.Err()
+	}
+	return nil
+}
+
+// failures logs and keeps the errors of the sends of a notifier.
+type failures struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (f *failures) fail(args ...interface{}) {
+	log.Errorln(args...)
+	f.add(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (f *failures) failf(format string, args ...interface{}) {
+	log.Errorf(format, args...)
+	f.add(fmt.Sprintf(format, args...))
+}
+
+func (f *failures) add(message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, message)
+}
+
+// Err returns the errors of the sends, nil when none failed.
+func (f *failures) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errors) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(f.errors, "; "))
+}
+
+// logDryRun logs what a notifier would have sent when running in dry-run mode.
+func logDryRun(notifier, payload string) bool {
+	log.WithField("notifier", notifier).Infof("Dry run, notification not sent:\n%s", payload)
+	return true
+}
+
+// datacenterPrefix returns "dc:" for messages tagged with a datacenter.
+func (m Message) datacenterPrefix() string {
+	if m.Datacenter == "" {
+		return ""
+	}
+	return m.Datacenter + ":"
+}
+
 func (m Message) IsCritical() bool {
 	return m.Status == "critical"
 }
@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultNrdpHost    = "{node}"
+	defaultNrdpService = "{check}"
+)
+
+// nrdpStates are the Nagios states by check status, the other statuses are
+// UNKNOWN.
+var nrdpStates = map[string]int{
+	"passing":  0,
+	"warning":  1,
+	"critical": 2,
+}
+
+// NrdpNotifier submits the alerts as passive check results to a Nagios or
+// Icinga NRDP endpoint. Host and Service are patterns where {datacenter},
+// {node}, {service} and {check} are replaced with the datacenter, node,
+// service name and check name, so the results match the existing Nagios
+// hosts and services. The node health checks (serfHealth) are submitted as
+// host results.
+type NrdpNotifier struct {
+	failures
+
+	Url     string
+	Token   string
+	Host    string
+	Service string
+	DryRun  bool
+	Http    *http.Client
+}
+
+type nrdpCheckResults struct {
+	XMLName xml.Name          `xml:"checkresults"`
+	Results []nrdpCheckResult `xml:"checkresult"`
+}
+
+type nrdpCheckResult struct {
+	Type        string `xml:"type,attr"`
+	CheckType   int    `xml:"checktype,attr"`
+	Hostname    string `xml:"hostname"`
+	Servicename string `xml:"servicename,omitempty"`
+	State       int    `xml:"state"`
+	Output      string `xml:"output"`
+}
+
+type nrdpResponse struct {
+	Status  int    `xml:"status"`
+	Message string `xml:"message"`
+}
+
+func (nrdp *NrdpNotifier) Notify(messages Messages) bool {
+	results := nrdpCheckResults{}
+	for _, message := range messages {
+		results.Results = append(results.Results, nrdp.result(message))
+	}
+	data, err := xml.Marshal(results)
+	if err != nil {
+		nrdp.fail("Unable to marshal nrdp check results:", err)
+		return false
+	}
+	if nrdp.DryRun {
+		return logDryRun("nrdp", string(data))
+	}
+
+	form := url.Values{
+		"token":   {nrdp.Token},
+		"cmd":     {"submitcheck"},
+		"XMLDATA": {xml.Header + string(data)},
+	}
+	res, err := httpClient(nrdp.Http).PostForm(nrdp.Url, form)
+	if err != nil {
+		nrdp.fail("Unable to submit the check results to nrdp:", err)
+		return false
+	}
+	defer res.Body.Close()
+	var response nrdpResponse
+	if err := xml.NewDecoder(res.Body).Decode(&response); err != nil || res.StatusCode != 200 {
+		nrdp.failf("Unable to submit the check results to nrdp: unexpected response code %d", res.StatusCode)
+		return false
+	}
+	if response.Status != 0 {
+		nrdp.failf("Unable to submit the check results to nrdp: %s", response.Message)
+		return false
+	}
+	log.Infof("Submitted %d check result(s) to nrdp.", len(messages))
+	return true
+}
+
+func (nrdp *NrdpNotifier) result(message Message) nrdpCheckResult {
+	result := nrdpCheckResult{
+		Type:      "service",
+		CheckType: 1,
+		Hostname:  checkPattern(nrdp.Host, defaultNrdpHost, message),
+	}
+	if message.CheckId == "serfHealth" {
+		// the host states are UP and DOWN
+		result.Type = "host"
+		if message.Status != "passing" {
+			result.State = 1
+		}
+		result.Output = nrdpOutput(nrdpHostStatus(message.Status), message)
+		return result
+	}
+	state, found := nrdpStates[message.Status]
+	if !found {
+		state = 3
+	}
+	result.Servicename = checkPattern(nrdp.Service, defaultNrdpService, message)
+	result.State = state
+	result.Output = nrdpOutput(nrdpStatus(message.Status), message)
+	return result
+}
+
+// nrdpOutput returns the output of a check result: a status line starting
+// with its Nagios state, then the check output as the long output.
+func nrdpOutput(state string, message Message) string {
+	output := fmt.Sprintf("%s - %s is %s", strings.ToUpper(state), message.Check, message.Status)
+	if check := strings.TrimSpace(message.Output); check != "" {
+		output += "\n" + check
+	}
+	return output
+}
+
+func nrdpStatus(status string) string {
+	switch status {
+	case "passing":
+		return "ok"
+	case "warning", "critical":
+		return status
+	}
+	return "unknown"
+}
+
+func nrdpHostStatus(status string) string {
+	if status == "passing" {
+		return "up"
+	}
+	return "down"
+}
+
+// checkPattern replaces {datacenter}, {node}, {service}, {service-id},
+// {check} and {check-id} in pattern, or in fallback when pattern is empty,
+// with the datacenter, node, service name and id and check name and id of a
+// message.
+func checkPattern(pattern, fallback string, message Message) string {
+	if pattern == "" {
+		pattern = fallback
+	}
+	replacer := strings.NewReplacer(
+		"{datacenter}", message.Datacenter,
+		"{node}", message.Node,
+		"{service}", message.Service,
+		"{service-id}", message.ServiceId,
+		"{check}", message.Check,
+		"{check-id}", message.CheckId,
+	)
+	return replacer.Replace(pattern)
+}
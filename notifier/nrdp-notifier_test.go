@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestNrdpNotify(t *testing.T) {
+	var token, cmd string
+	var results nrdpCheckResults
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, cmd = r.FormValue("token"), r.FormValue("cmd")
+		xml.Unmarshal([]byte(r.FormValue("XMLDATA")), &results)
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><result><status>0</status><message>OK</message></result>`))
+	}))
+	defer server.Close()
+
+	nrdp := &NrdpNotifier{Url: server.URL, Token: "secret", Service: "{service} {check}"}
+	messages := Messages{
+		{Node: "web1", Service: "web", CheckId: "service:web", Check: "HTTP", Status: "critical", Output: "connection refused"},
+		{Node: "web1", CheckId: "serfHealth", Check: "Serf Health Status", Status: "passing"},
+		{Node: "db1", Service: "db", CheckId: "disk", Check: "Disk", Status: "warning"},
+		{Node: "db2", CheckId: "serfHealth", Check: "Serf Health Status", Status: "critical"},
+	}
+	if !nrdp.Notify(messages) {
+		t.Fatal("expected the check results to be submitted")
+	}
+	if token != "secret" || cmd != "submitcheck" || len(results.Results) != 4 {
+		t.Fatalf("unexpected submission %s %s %+v", token, cmd, results)
+	}
+	critical := results.Results[0]
+	if critical.Type != "service" || critical.Hostname != "web1" || critical.Servicename != "web HTTP" || critical.State != 2 ||
+		!strings.HasPrefix(critical.Output, "CRITICAL - HTTP is critical\nconnection refused") {
+		t.Errorf("unexpected service result %+v", critical)
+	}
+	if host := results.Results[1]; host.Type != "host" || host.Servicename != "" || host.State != 0 || !strings.HasPrefix(host.Output, "UP - ") {
+		t.Errorf("unexpected host result %+v", host)
+	}
+	if results.Results[2].State != 1 {
+		t.Errorf("expected a warning state, got %+v", results.Results[2])
+	}
+	if host := results.Results[3]; host.Type != "host" || host.State != 1 || !strings.HasPrefix(host.Output, "DOWN - ") {
+		t.Errorf("expected a down host, got %+v", host)
+	}
+}
+
+func TestNrdpFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<result><status>-1</status><message>BAD TOKEN</message></result>`))
+	}))
+	defer server.Close()
+
+	nrdp := &NrdpNotifier{Url: server.URL, Token: "wrong"}
+	if nrdp.Notify(Messages{{Node: "web1", Check: "Disk", Status: "critical"}}) {
+		t.Error("expected the rejected results to fail")
+	}
+}
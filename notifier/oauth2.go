@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os/exec"
+)
+
+const defaultOAuth2TokenUrl = "https://oauth2.googleapis.com/token"
+
+// OAuth2Config holds the settings used to obtain an access token, either by
+// running TokenCommand or by exchanging RefreshToken at TokenUrl.
+type OAuth2Config struct {
+	ClientId     string
+	ClientSecret string
+	RefreshToken string
+	TokenUrl     string
+	TokenCommand string
+	Http         *http.Client
+}
+
+type cachedToken struct {
+	accessToken string
+	expiry      time.Time
+}
+
+var oauth2Tokens = struct {
+	sync.Mutex
+	tokens map[string]cachedToken
+}{tokens: make(map[string]cachedToken)}
+
+// Token returns an access token. Refreshed tokens are cached until shortly
+// before they expire; the token command is run on every call.
+func (config OAuth2Config) Token() (string, error) {
+	if config.TokenCommand != "" {
+		output, err := exec.Command("sh", "-c", config.TokenCommand).Output()
+		if err != nil {
+			return "", fmt.Errorf("token command failed: %s", err)
+		}
+		token := strings.TrimSpace(string(output))
+		if token == "" {
+			return "", errors.New("token command returned an empty token")
+		}
+		return token, nil
+	}
+
+	if config.ClientId == "" || config.RefreshToken == "" {
+		return "", errors.New("oauth2 client id and refresh token are required")
+	}
+
+	cacheKey := config.ClientId + ":" + config.RefreshToken
+	oauth2Tokens.Lock()
+	defer oauth2Tokens.Unlock()
+	if cached, ok := oauth2Tokens.tokens[cacheKey]; ok && time.Now().Before(cached.expiry) {
+		return cached.accessToken, nil
+	}
+
+	tokenUrl := config.TokenUrl
+	if tokenUrl == "" {
+		tokenUrl = defaultOAuth2TokenUrl
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {config.ClientId},
+		"client_secret": {config.ClientSecret},
+		"refresh_token": {config.RefreshToken},
+	}
+	res, err := httpClient(config.Http).PostForm(tokenUrl, form)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var body bytes.Buffer
+	body.ReadFrom(res.Body)
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("token refresh failed: %s", body.String())
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body.Bytes(), &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token refresh returned no access token")
+	}
+
+	expiry := time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	oauth2Tokens.tokens[cacheKey] = cachedToken{token.AccessToken, expiry}
+	return token.AccessToken, nil
+}
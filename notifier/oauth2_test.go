@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"fmt"
+	"testing"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestOAuth2TokenRefresh(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.FormValue("refresh_token") != "refresh" {
+			w.WriteHeader(400)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"access","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	config := OAuth2Config{ClientId: "client", RefreshToken: "refresh", TokenUrl: server.URL}
+	for i := 0; i < 2; i++ {
+		if token, err := config.Token(); err != nil || token != "access" {
+			t.Fatalf("unexpected token=%s, err=%v", token, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("token should be cached, got %d requests", requests)
+	}
+}
+
+func TestOAuth2TokenCommand(t *testing.T) {
+	config := OAuth2Config{TokenCommand: "echo token"}
+	if token, err := config.Token(); err != nil || token != "token" {
+		t.Errorf("unexpected token=%s, err=%v", token, err)
+	}
+}
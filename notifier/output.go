@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ansiEscape matches the ANSI CSI sequences (colors, cursor moves), OSC
+// sequences (titles, hyperlinks) and the other two-byte escapes.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// OutputLimits shortens and cleans the check outputs before a notifier sends
+// them. The end of the output is kept since that's usually where the error
+// is. Zero values disable the limits.
+type OutputLimits struct {
+	MaxBytes int
+	MaxLines int
+	Sanitize bool
+}
+
+// Apply returns a copy of the messages with the limits applied to their
+// outputs.
+func (l OutputLimits) Apply(messages Messages) Messages {
+	if l.MaxBytes <= 0 && l.MaxLines <= 0 && !l.Sanitize {
+		return messages
+	}
+	limited := make(Messages, len(messages))
+	for i, message := range messages {
+		message.Output = l.apply(message.Output)
+		limited[i] = message
+	}
+	return limited
+}
+
+func (l OutputLimits) apply(output string) string {
+	if l.Sanitize {
+		output = sanitizeOutput(output)
+	}
+	truncated := false
+	if l.MaxLines > 0 {
+		trimmed := strings.TrimSuffix(output, "\n")
+		lines := strings.SplitAfter(trimmed, "\n")
+		if len(lines) > l.MaxLines {
+			output = strings.Join(lines[len(lines)-l.MaxLines:], "") + output[len(trimmed):]
+			truncated = true
+		}
+	}
+	if l.MaxBytes > 0 && len(output) > l.MaxBytes {
+		cut := len(output) - l.MaxBytes
+		for cut < len(output) && !utf8.RuneStart(output[cut]) {
+			cut++
+		}
+		output = output[cut:]
+		truncated = true
+	}
+	if truncated {
+		output = "...\n" + output
+	}
+	return output
+}
+
+// sanitizeOutput removes the ANSI escape sequences, the control characters
+// other than newlines and tabs, and the invalid UTF-8.
+func sanitizeOutput(output string) string {
+	output = ansiEscape.ReplaceAllString(output, "")
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	output = strings.ToValidUTF8(output, "")
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, output)
+}
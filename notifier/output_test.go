@@ -0,0 +1,40 @@
+package notifier
+
+import "testing"
+
+func TestOutputLimitsSanitize(t *testing.T) {
+	limits := OutputLimits{Sanitize: true}
+	output := "\x1b[31mFAIL\x1b[0m disk\r\n\x1b]0;title\x07usage\x00 95%\tof /\xff\n"
+	if got := limits.apply(output); got != "FAIL disk\nusage 95%\tof /\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestOutputLimitsMaxLines(t *testing.T) {
+	limits := OutputLimits{MaxLines: 2}
+	if got := limits.apply("a\nb\nc\nd\n"); got != "...\nc\nd\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+	if got := limits.apply("a\nb\n"); got != "a\nb\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestOutputLimitsMaxBytes(t *testing.T) {
+	limits := OutputLimits{MaxBytes: 4}
+	if got := limits.apply("abcdef"); got != "...\ncdef" {
+		t.Errorf("unexpected output %q", got)
+	}
+	// a multibyte character is not split
+	if got := limits.apply("abcdé"); got != "...\ncdé" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestOutputLimitsApplyCopies(t *testing.T) {
+	messages := Messages{{Output: "abcdef"}}
+	limited := OutputLimits{MaxBytes: 2}.Apply(messages)
+	if messages[0].Output != "abcdef" || limited[0].Output != "...\nef" {
+		t.Errorf("unexpected outputs %q and %q", messages[0].Output, limited[0].Output)
+	}
+}
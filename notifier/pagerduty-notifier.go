@@ -1,50 +1,108 @@
 package notifier
 
 import (
-	"github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/darkcrux/gopherduty"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"encoding/json"
+	"net/http"
 
 	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
 type PagerDutyNotifier struct {
+	failures
+
 	ServiceKey string
 	ClientName string
 	ClientUrl  string
+	DryRun     bool
+	Http       *http.Client
 }
 
-func (pd *PagerDutyNotifier) Notify(messages Messages) bool {
+// pagerDutyEventsUrl is the PagerDuty generic events API endpoint.
+var pagerDutyEventsUrl = "https://events.pagerduty.com/generic/2010-04-15/create_event.json"
 
-	client := gopherduty.NewClient(pd.ServiceKey)
+type pagerDutyEvent struct {
+	ServiceKey  string      `json:"service_key"`
+	EventType   string      `json:"event_type"`
+	IncidentKey string      `json:"incident_key,omitempty"`
+	Description string      `json:"description"`
+	Client      string      `json:"client,omitempty"`
+	ClientUrl   string      `json:"client_url,omitempty"`
+	Details     interface{} `json:"details"`
+}
+
+type pagerDutyResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors"`
+}
+
+func (pd *PagerDutyNotifier) Notify(messages Messages) bool {
 
 	result := true
 
 	for _, message := range messages {
-		incidentKey := message.Node
+		incidentKey := message.datacenterPrefix() + message.Node
 		if message.ServiceId != "" {
 			incidentKey += ":" + message.ServiceId
 		}
 		incidentKey += ":" + message.CheckId
-		var response *gopherduty.PagerDutyResponse
+		if pd.DryRun {
+			action := "trigger"
+			if message.IsPassing() {
+				action = "resolve"
+			}
+			logDryRun("pagerduty", fmt.Sprintf("%s %s: %s", action, incidentKey, message.Status))
+			continue
+		}
+
+		event := pagerDutyEvent{ServiceKey: pd.ServiceKey, IncidentKey: incidentKey, Details: message}
 		switch {
 		case message.IsPassing():
-			description := incidentKey + " is now HEALTHY"
-			response = client.Resolve(incidentKey, description, message)
+			event.EventType = "resolve"
+			event.Description = incidentKey + " is now HEALTHY"
 		case message.IsWarning():
-			description := incidentKey + " is UNSTABLE"
-			response = client.Trigger(incidentKey, description, pd.ClientName, pd.ClientUrl, message)
+			event.EventType = "trigger"
+			event.Description = incidentKey + " is UNSTABLE"
+			event.Client, event.ClientUrl = pd.ClientName, pd.ClientUrl
 		case message.IsCritical():
-			description := incidentKey + " is CRITICAL"
-			response = client.Trigger(incidentKey, description, pd.ClientName, pd.ClientUrl, message)
+			event.EventType = "trigger"
+			event.Description = incidentKey + " is CRITICAL"
+			event.Client, event.ClientUrl = pd.ClientName, pd.ClientUrl
+		default:
+			continue
 		}
 
-		if response.HasErrors() {
-			for _, err := range response.Errors {
-				log.Printf("Error sending %s notification to pagerduty: %s\n", incidentKey, err)
-			}
+		if err := pd.send(event); err != nil {
+			pd.failf("Error sending %s notification to pagerduty: %s", incidentKey, err)
 			result = false
 		}
 	}
 
-	log.Println("PagerDuty notification complete")
+	log.Infoln("PagerDuty notification complete")
 	return result
 }
+
+// send posts an event to the PagerDuty events API.
+func (pd *PagerDutyNotifier) send(event pagerDutyEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	res, err := httpClient(pd.Http).Post(pagerDutyEventsUrl, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var response pagerDutyResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("unexpected response code: %d", res.StatusCode)
+	}
+	if res.StatusCode != 200 || response.Status != "success" {
+		return fmt.Errorf("%s %s", response.Message, strings.Join(response.Errors, ", "))
+	}
+	return nil
+}
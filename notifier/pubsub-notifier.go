@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultPubsubEndpoint = "https://pubsub.googleapis.com"
+	pubsubScope           = "https://www.googleapis.com/auth/pubsub"
+)
+
+// PubsubNotifier publishes each alert as JSON to a Google Cloud Pub/Sub
+// topic, with the status, node, service and check as attributes for the
+// subscription filters. It's authenticated with the service account key of
+// CredentialsFile, or of GOOGLE_APPLICATION_CREDENTIALS, or else with the
+// service account of the metadata server, eg. with GKE workload identity.
+type PubsubNotifier struct {
+	failures
+
+	Project         string
+	Topic           string
+	CredentialsFile string
+	Endpoint        string
+	DryRun          bool
+	Http            *http.Client
+}
+
+type pubsubMessage struct {
+	Data       []byte            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func (pubsub *PubsubNotifier) Notify(messages Messages) bool {
+	request := struct {
+		Messages []pubsubMessage `json:"messages"`
+	}{}
+	for _, message := range messages {
+		data, _ := json.Marshal(message)
+		attributes := map[string]string{}
+		for attribute, value := range map[string]string{
+			"datacenter": message.Datacenter,
+			"node":       message.Node,
+			"service":    message.Service,
+			"check":      message.CheckId,
+			"status":     message.Status,
+		} {
+			if value != "" {
+				attributes[attribute] = value
+			}
+		}
+		request.Messages = append(request.Messages, pubsubMessage{data, attributes})
+	}
+	if pubsub.DryRun {
+		for _, message := range request.Messages {
+			logDryRun("pubsub", fmt.Sprintf("%s %v\n%s", pubsub.Topic, message.Attributes, message.Data))
+		}
+		return true
+	}
+	if err := pubsub.publish(request); err != nil {
+		pubsub.fail("Unable to publish the alerts to pubsub:", err)
+		return false
+	}
+	log.Infof("Published %d alert(s) to pubsub.", len(messages))
+	return true
+}
+
+func (pubsub *PubsubNotifier) publish(request interface{}) error {
+	token, err := gcpToken(pubsub.CredentialsFile, pubsubScope, pubsub.Http)
+	if err != nil {
+		return err
+	}
+	endpoint := pubsub.Endpoint
+	if endpoint == "" {
+		endpoint = defaultPubsubEndpoint
+	}
+	body, _ := json.Marshal(request)
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish", endpoint, pubsub.Project, pubsub.Topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := httpClient(pubsub.Http).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		data, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected response %d: %s", res.StatusCode, data)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestPubsubNotify(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var published struct {
+		Messages []pubsubMessage
+	}
+	var path, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			parts := strings.Split(r.FormValue("assertion"), ".")
+			signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+				t.Errorf("invalid assertion signature: %s", err)
+			}
+			w.Write([]byte(`{"access_token": "access", "expires_in": 3600}`))
+			return
+		}
+		path, authorization = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&published)
+		w.Write([]byte(`{"messageIds": ["1"]}`))
+	}))
+	defer server.Close()
+
+	credentials, _ := json.Marshal(gcpServiceAccount{
+		ClientEmail: "alerts@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenUri:    server.URL + "/token",
+	})
+	file, _ := ioutil.TempFile("", "credentials")
+	file.Write(credentials)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	pubsub := &PubsubNotifier{Project: "ops", Topic: "alerts", CredentialsFile: file.Name(), Endpoint: server.URL}
+	if !pubsub.Notify(Messages{{Node: "web1", Service: "web", CheckId: "http", Status: "critical"}}) {
+		t.Fatal("expected the alerts to be published")
+	}
+	if path != "/v1/projects/ops/topics/alerts:publish" || authorization != "Bearer access" {
+		t.Errorf("unexpected request %s %s", path, authorization)
+	}
+	message := published.Messages[0]
+	if message.Attributes["status"] != "critical" || message.Attributes["node"] != "web1" || !strings.Contains(string(message.Data), `"CheckId":"http"`) {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
+
+func TestGcpMetadataToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("scopes") != pubsubScope {
+			w.WriteHeader(403)
+			return
+		}
+		w.Write([]byte(`{"access_token": "workload", "expires_in": 3600}`))
+	}))
+	defer server.Close()
+
+	defer func(metadataUrl, credentials string) {
+		gcpMetadataUrl = metadataUrl
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
+		delete(oauth2Tokens.tokens, "gcp::"+pubsubScope)
+	}(gcpMetadataUrl, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	gcpMetadataUrl = server.URL
+	os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	if token, err := gcpToken("", pubsubScope, nil); err != nil || token != "workload" {
+		t.Errorf("unexpected token %s %v", token, err)
+	}
+}
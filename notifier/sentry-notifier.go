@@ -0,0 +1,156 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// SentryNotifier records the critical checks as Sentry events, grouped by
+// node and check so the repeated failures of a check are a single issue.
+// The other statuses are not sent. Dsn is the client key DSN of the project,
+// eg. https://<key>@sentry.example.com/<project>.
+type SentryNotifier struct {
+	failures
+
+	Dsn         string
+	Environment string
+	Level       string
+	DryRun      bool
+	Http        *http.Client
+}
+
+type sentryEvent struct {
+	EventId     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Platform    string            `json:"platform"`
+	ServerName  string            `json:"server_name"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Fingerprint []string          `json:"fingerprint"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra"`
+}
+
+func (sentry *SentryNotifier) Notify(messages Messages) bool {
+	var critical Messages
+	for _, message := range messages {
+		if message.IsCritical() {
+			critical = append(critical, message)
+		}
+	}
+	if len(critical) == 0 {
+		return true
+	}
+	storeUrl, auth, err := parseSentryDsn(sentry.Dsn)
+	if err != nil {
+		sentry.fail("Invalid sentry dsn:", err)
+		return false
+	}
+
+	result := true
+	for _, message := range critical {
+		data, err := json.Marshal(sentry.event(message))
+		if err != nil {
+			sentry.fail("Unable to marshal sentry event:", err)
+			result = false
+			continue
+		}
+		if sentry.DryRun {
+			logDryRun("sentry", string(data))
+			continue
+		}
+		if err := sentry.send(storeUrl, auth, data); err != nil {
+			sentry.failf("Unable to send the %s:%s event to sentry: %s", message.Node, message.CheckId, err)
+			result = false
+		}
+	}
+	if result {
+		log.Infof("Sent %d event(s) to sentry.", len(critical))
+	}
+	return result
+}
+
+func (sentry *SentryNotifier) event(message Message) sentryEvent {
+	level := sentry.Level
+	if level == "" {
+		level = "error"
+	}
+	timestamp := message.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	tags := map[string]string{"node": message.Node, "check": message.CheckId, "status": message.Status}
+	for tag, value := range map[string]string{"datacenter": message.Datacenter, "service": message.Service} {
+		if value != "" {
+			tags[tag] = value
+		}
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return sentryEvent{
+		EventId:     hex.EncodeToString(id),
+		Timestamp:   timestamp.UTC().Format("2006-01-02T15:04:05"),
+		Level:       level,
+		Logger:      "consul-alerts",
+		Platform:    "other",
+		ServerName:  message.Node,
+		Environment: sentry.Environment,
+		Message:     fmt.Sprintf("%s%s:%s:%s is %s", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status),
+		Fingerprint: []string{message.datacenterPrefix() + message.Node, message.ServiceId, message.CheckId},
+		Tags:        tags,
+		Extra:       map[string]string{"output": message.Output, "notes": message.Notes},
+	}
+}
+
+func (sentry *SentryNotifier) send(storeUrl, auth string, data []byte) error {
+	req, err := http.NewRequest("POST", storeUrl, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", auth)
+	res, err := httpClient(sentry.Http).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected response %d: %s", res.StatusCode, body)
+	}
+	return nil
+}
+
+// parseSentryDsn returns the store endpoint and the auth header of a DSN.
+func parseSentryDsn(dsn string) (storeUrl, auth string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return "", "", fmt.Errorf("expected a dsn like https://<key>@<host>/<project>, got %q", dsn)
+	}
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	auth = "Sentry sentry_version=7, sentry_client=consul-alerts/1.0, sentry_key=" + u.User.Username()
+	if secret, found := u.User.Password(); found {
+		auth += ", sentry_secret=" + secret
+	}
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project), auth, nil
+}
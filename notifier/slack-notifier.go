@@ -3,6 +3,7 @@ package notifier
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	"io/ioutil"
 
@@ -18,6 +19,8 @@ Fail: %d, Warn: %d, Pass: %d
 `
 
 type SlackNotifier struct {
+	failures
+
 	ClusterName string `json:"-"`
 	Url         string `json:"-"`
 	Channel     string `json:"channel"`
@@ -25,40 +28,60 @@ type SlackNotifier struct {
 	IconUrl     string `json:"icon_url"`
 	IconEmoji   string `json:"icon_emoji"`
 	Text        string `json:"text"`
+	DryRun      bool   `json:"-"`
+
+	// With a bot Token, the alerts are posted with the Web API at ApiUrl
+	// and each check keeps a single thread, stored in Threads. An alert
+	// within ThreadWindow of the recovery continues the thread.
+	Token        string        `json:"-"`
+	ApiUrl       string        `json:"-"`
+	Threads      StateStore    `json:"-"`
+	ThreadWindow time.Duration `json:"-"`
+
+	// Http is the client used to reach slack, the default one when nil.
+	Http *http.Client `json:"-"`
 }
 
 func (slack *SlackNotifier) Notify(messages Messages) bool {
+	if slack.Token != "" && slack.Threads != nil {
+		return slack.notifyThreads(messages)
+	}
 
 	overallStatus, pass, warn, fail := messages.Summary()
 
 	text := fmt.Sprintf(header, slack.ClusterName, overallStatus, fail, warn, pass)
 
 	for _, message := range messages {
-		text += fmt.Sprintf("\n%s:%s:%s is %s.", message.Node, message.Service, message.Check, message.Status)
+		text += fmt.Sprintf("\n%s%s:%s:%s is %s.", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status)
 		text += fmt.Sprintf("\n%s", message.Output)
+		text += slackLinks(message)
 	}
 
 	slack.Text = text
 
 	data, err := json.Marshal(slack)
 	if err != nil {
-		log.Println("Unable to marshal slack payload:", err)
+		slack.fail("Unable to marshal slack payload:", err)
 		return false
 	}
 
+	if slack.DryRun {
+		return logDryRun("slack", string(data))
+	}
+
 	b := bytes.NewBuffer(data)
-	if res, err := http.Post(slack.Url, "application/json", b); err != nil {
-		log.Println("Unable to send data to slack:", err)
+	if res, err := httpClient(slack.Http).Post(slack.Url, "application/json", b); err != nil {
+		slack.fail("Unable to send data to slack:", err)
 		return false
 	} else {
 		defer res.Body.Close()
 		statusCode := res.StatusCode
 		if statusCode != 200 {
 			body, _ := ioutil.ReadAll(res.Body)
-			log.Println("Unable to notify slack:", string(body))
+			slack.fail("Unable to notify slack:", string(body))
 			return false
 		} else {
-			log.Println("Slack notification sent.")
+			log.Infoln("Slack notification sent.")
 			return true
 		}
 	}
@@ -0,0 +1,211 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encoding/json"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const slackApiUrl = "https://slack.com/api"
+
+// StateStore keeps the state a notifier needs across notifications, like the
+// slack message of an incident. It is shared by the consul-alerts instances.
+type StateStore interface {
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+}
+
+// slackIncident is the slack message started for a check. Resolved is when
+// the check went back to passing, the next alert of the check within the
+// thread window continues the same thread.
+type slackIncident struct {
+	Channel  string    `json:"channel"`
+	Ts       string    `json:"ts"`
+	Resolved time.Time `json:"resolved,omitempty"`
+}
+
+// slackApiResponse is the common part of the slack Web API responses.
+type slackApiResponse struct {
+	Ok      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+}
+
+// notifyThreads posts each alert to the thread of its check through the slack
+// Web API. The first alert of an incident starts a message which is updated
+// with the latest status, and every status change is replied in its thread.
+func (slack *SlackNotifier) notifyThreads(messages Messages) bool {
+	success := true
+	for _, message := range messages {
+		if err := slack.notifyThread(message); err != nil {
+			slack.failf("Unable to notify slack of %s:%s:%s: %s", message.Node, message.Service, message.Check, err)
+			success = false
+		}
+	}
+	if success {
+		log.Infoln("Slack notification sent.")
+	}
+	return success
+}
+
+func (slack *SlackNotifier) notifyThread(message Message) error {
+	key := slackIncidentKey(message)
+	incident, err := slack.incident(key)
+	if err != nil {
+		return err
+	}
+	if incident != nil && !incident.Resolved.IsZero() && time.Since(incident.Resolved) > slack.ThreadWindow {
+		incident = nil
+	}
+	text := slackMessageText(message)
+
+	// a thread that can't be replied to, like when its message or channel was
+	// deleted, is replaced with a new one
+	replaced := false
+	if incident != nil {
+		err := slack.continueThread(incident, text)
+		if err == nil {
+			incident.Resolved = time.Time{}
+			if message.IsPassing() {
+				incident.Resolved = time.Now()
+			}
+			return slack.saveIncident(key, incident)
+		}
+		log.Warnf("Unable to continue the slack thread of %s, starting a new one: %s", key, err)
+		replaced = true
+	}
+
+	response, err := slack.callApi("chat.postMessage", map[string]string{"channel": slack.Channel, "text": text})
+	if err != nil || (message.IsPassing() && !replaced) {
+		return err
+	}
+	incident = &slackIncident{Channel: response.Channel, Ts: response.Ts}
+	if message.IsPassing() {
+		incident.Resolved = time.Now()
+	}
+	return slack.saveIncident(key, incident)
+}
+
+// continueThread replies the text in the thread of the incident and updates
+// its first message with it. Only a failed reply is returned, the reply was
+// already posted when the update fails.
+func (slack *SlackNotifier) continueThread(incident *slackIncident, text string) error {
+	reply := map[string]string{"channel": incident.Channel, "thread_ts": incident.Ts, "text": text}
+	if _, err := slack.callApi("chat.postMessage", reply); err != nil {
+		return err
+	}
+	update := map[string]string{"channel": incident.Channel, "ts": incident.Ts, "text": text}
+	if _, err := slack.callApi("chat.update", update); err != nil {
+		log.Warnf("Unable to update the first slack message of the thread %s: %s", incident.Ts, err)
+	}
+	return nil
+}
+
+func (slack *SlackNotifier) incident(key string) (*slackIncident, error) {
+	data, err := slack.Threads.Get(key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var incident slackIncident
+	if err := json.Unmarshal(data, &incident); err != nil {
+		log.Warnf("Ignoring the invalid slack thread of %s: %s", key, err)
+		return nil, nil
+	}
+	return &incident, nil
+}
+
+// saveIncident stores the incident, except in dry-run mode where no message
+// was posted.
+func (slack *SlackNotifier) saveIncident(key string, incident *slackIncident) error {
+	if slack.DryRun {
+		return nil
+	}
+	data, _ := json.Marshal(incident)
+	return slack.Threads.Put(key, data)
+}
+
+// callApi calls a slack Web API method with the bot token. The username and
+// icon are added to the messages.
+func (slack *SlackNotifier) callApi(method string, params map[string]string) (*slackApiResponse, error) {
+	if method == "chat.postMessage" {
+		for name, value := range map[string]string{"username": slack.Username, "icon_url": slack.IconUrl, "icon_emoji": slack.IconEmoji} {
+			if value != "" {
+				params[name] = value
+			}
+		}
+	}
+	data, _ := json.Marshal(params)
+	if slack.DryRun {
+		logDryRun("slack", method+" "+string(data))
+		return &slackApiResponse{Ok: true, Channel: params["channel"], Ts: "dry-run"}, nil
+	}
+
+	apiUrl := slack.ApiUrl
+	if apiUrl == "" {
+		apiUrl = slackApiUrl
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(apiUrl, "/")+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+slack.Token)
+	res, err := httpClient(slack.Http).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected response code: %d", res.StatusCode)
+	}
+	var response slackApiResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if !response.Ok {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// slackMessageText describes the current status of a single check.
+func slackMessageText(message Message) string {
+	text := fmt.Sprintf("%s%s:%s:%s is %s.", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status)
+	if message.Output != "" {
+		text += "\n" + message.Output
+	}
+	return text + slackLinks(message)
+}
+
+// slackLinks formats the links of a message as slack links on a line of
+// their own.
+func slackLinks(message Message) string {
+	if len(message.Links) == 0 {
+		return ""
+	}
+	links := make([]string, len(message.Links))
+	for i, link := range message.Links {
+		links[i] = fmt.Sprintf("<%s|%s>", link.Url, link.Name)
+	}
+	return "\n" + strings.Join(links, " | ")
+}
+
+// slackIncidentKey identifies the thread of a check, the empty parts are
+// replaced with "_" to keep the key usable in the KV.
+func slackIncidentKey(message Message) string {
+	parts := []string{message.Datacenter, message.Namespace, message.Node, message.ServiceId, message.CheckId}
+	for i, part := range parts {
+		if part == "" {
+			parts[i] = "_"
+		}
+	}
+	return strings.Join(parts, "/")
+}
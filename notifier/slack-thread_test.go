@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Get(key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m memoryStore) Put(key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func TestSlackThreads(t *testing.T) {
+	var calls []string
+	var replies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-token" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		var params map[string]string
+		json.NewDecoder(r.Body).Decode(&params)
+		calls = append(calls, r.URL.Path)
+		if params["thread_ts"] != "" {
+			replies = append(replies, params["thread_ts"])
+		}
+		json.NewEncoder(w).Encode(slackApiResponse{Ok: true, Channel: "C1", Ts: "100.1"})
+	}))
+	defer server.Close()
+
+	store := memoryStore{}
+	slack := &SlackNotifier{Channel: "#ops", Token: "xoxb-token", ApiUrl: server.URL, Threads: store, ThreadWindow: time.Hour}
+	critical := Message{Node: "web-1", CheckId: "disk", Check: "disk", Status: "critical"}
+	passing := critical
+	passing.Status = "passing"
+
+	for _, message := range []Message{critical, passing, critical} {
+		if !slack.Notify(Messages{message}) {
+			t.Fatal("notification failed")
+		}
+	}
+	expected := []string{"/chat.postMessage", "/chat.postMessage", "/chat.update", "/chat.postMessage", "/chat.update"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("expected calls %v, got %v", expected, calls)
+			break
+		}
+	}
+	if len(replies) != 2 || replies[0] != "100.1" || replies[1] != "100.1" {
+		t.Errorf("the changes should be replied in the first message thread, got %v", replies)
+	}
+
+	// a recovery older than the thread window closes the thread
+	var incident slackIncident
+	json.Unmarshal(store["_/_/web-1/_/disk"], &incident)
+	incident.Resolved = time.Now().Add(-2 * time.Hour)
+	data, _ := json.Marshal(incident)
+	store["_/_/web-1/_/disk"] = data
+	calls, replies = nil, nil
+	slack.Notify(Messages{critical})
+	if len(calls) != 1 || len(replies) != 0 {
+		t.Errorf("expected a new message, got calls %v and replies %v", calls, replies)
+	}
+}
+
+func TestSlackThreadUpdateFailed(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path == "/chat.update" {
+			json.NewEncoder(w).Encode(slackApiResponse{Error: "message_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(slackApiResponse{Ok: true, Channel: "C1", Ts: "200.1"})
+	}))
+	defer server.Close()
+
+	store := memoryStore{"_/_/web-1/_/disk": []byte(`{"channel": "C1", "ts": "100.1"}`)}
+	slack := &SlackNotifier{Channel: "#ops", Token: "xoxb-token", ApiUrl: server.URL, Threads: store, ThreadWindow: time.Hour}
+	if !slack.Notify(Messages{{Node: "web-1", CheckId: "disk", Check: "disk", Status: "passing"}}) {
+		t.Fatal("notification failed")
+	}
+	if len(calls) != 2 || calls[0] != "/chat.postMessage" || calls[1] != "/chat.update" {
+		t.Errorf("a failed update should not post a new message, got %v", calls)
+	}
+	var incident slackIncident
+	json.Unmarshal(store["_/_/web-1/_/disk"], &incident)
+	if incident.Ts != "100.1" || incident.Resolved.IsZero() {
+		t.Errorf("expected the thread kept and resolved, got %+v", incident)
+	}
+}
+
+func TestSlackThreadReplaced(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		json.NewDecoder(r.Body).Decode(&params)
+		calls = append(calls, r.URL.Path)
+		if params["thread_ts"] != "" {
+			json.NewEncoder(w).Encode(slackApiResponse{Error: "thread_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(slackApiResponse{Ok: true, Channel: "C1", Ts: "200.1"})
+	}))
+	defer server.Close()
+
+	store := memoryStore{"_/_/web-1/_/disk": []byte(`{"channel": "C1", "ts": "100.1"}`)}
+	slack := &SlackNotifier{Channel: "#ops", Token: "xoxb-token", ApiUrl: server.URL, Threads: store, ThreadWindow: time.Hour}
+	if !slack.Notify(Messages{{Node: "web-1", CheckId: "disk", Check: "disk", Status: "passing"}}) {
+		t.Fatal("notification failed")
+	}
+	if len(calls) != 2 || calls[1] != "/chat.postMessage" {
+		t.Errorf("expected a new message after the failed reply, got %v", calls)
+	}
+	var incident slackIncident
+	json.Unmarshal(store["_/_/web-1/_/disk"], &incident)
+	if incident.Ts != "200.1" || incident.Resolved.IsZero() {
+		t.Errorf("expected the incident replaced, got %+v", incident)
+	}
+
+	dryRun := &SlackNotifier{Channel: "#ops", Token: "xoxb-token", DryRun: true, Threads: memoryStore{}, ThreadWindow: time.Hour}
+	dryRun.Notify(Messages{{Node: "web-1", CheckId: "disk", Check: "disk", Status: "critical"}})
+	if len(dryRun.Threads.(memoryStore)) != 0 {
+		t.Error("no incident should be saved in dry-run mode")
+	}
+}
+
+func TestSlackLinks(t *testing.T) {
+	message := Message{Node: "web1", Check: "http", Status: "critical", Links: []Link{
+		{Name: "Grafana", Url: "https://grafana.example.com/d/node?var-node=web1"},
+		{Name: "Runbook", Url: "https://wiki.example.com/runbooks/http"},
+	}}
+	if text := slackMessageText(message); !strings.HasSuffix(text, "\n<https://grafana.example.com/d/node?var-node=web1|Grafana> | <https://wiki.example.com/runbooks/http|Runbook>") {
+		t.Errorf("unexpected text %q", text)
+	}
+}
@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+
+	"net/smtp"
+)
+
+const (
+	EmailAuthDefault = ""
+	EmailAuthNone    = "none"
+	EmailAuthPlain   = "plain"
+	EmailAuthLogin   = "login"
+	EmailAuthCramMD5 = "cram-md5"
+	EmailAuthXOAuth2 = "xoauth2"
+)
+
+// smtpAuth returns the smtp.Auth for the configured AuthMode. A nil Auth means
+// the AUTH command should be skipped entirely.
+func (emailNotifier *EmailNotifier) smtpAuth() (smtp.Auth, error) {
+	username := emailNotifier.Username
+	password := emailNotifier.Password
+	host := emailNotifier.Url
+
+	switch emailNotifier.AuthMode {
+	case EmailAuthDefault:
+		if username == "" && password == "" {
+			return nil, nil
+		}
+		return smtp.PlainAuth("", username, password, host), nil
+	case EmailAuthNone:
+		return nil, nil
+	case EmailAuthPlain:
+		return smtp.PlainAuth("", username, password, host), nil
+	case EmailAuthLogin:
+		return &loginAuth{username: username, password: password, host: host}, nil
+	case EmailAuthCramMD5:
+		return smtp.CRAMMD5Auth(username, password), nil
+	case EmailAuthXOAuth2:
+		token, err := emailNotifier.OAuth2.Token()
+		if err != nil {
+			return nil, err
+		}
+		return &xoauth2Auth{username: username, token: token}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode: %s", emailNotifier.AuthMode)
+	}
+}
+
+// loginAuth implements the non-standard but widely deployed LOGIN mechanism.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:", "User Name\x00":
+		return []byte(a.username), nil
+	case "Password:", "Password\x00":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism used by Gmail and Office 365.
+type xoauth2Auth struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	resp := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", a.username, a.token)
+	return "XOAUTH2", []byte(resp), nil
+}
+
+// Next answers the error challenge with an empty response so the server
+// completes the exchange and reports the failure.
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,33 @@
+package notifier
+
+import "testing"
+
+func TestSmtpAuthSkippedWithoutCredentials(t *testing.T) {
+	email := &EmailNotifier{Url: "smtp.example.com"}
+	auth, err := email.smtpAuth()
+	if auth != nil || err != nil {
+		t.Errorf("auth should be skipped without credentials, auth=%v, err=%v", auth, err)
+	}
+}
+
+func TestSmtpAuthLogin(t *testing.T) {
+	email := &EmailNotifier{Url: "smtp.example.com", Username: "user", Password: "pass", AuthMode: EmailAuthLogin}
+	auth, err := email.smtpAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := auth.(*loginAuth)
+	if resp, _ := login.Next([]byte("Username:"), true); string(resp) != "user" {
+		t.Errorf("expected username, got %s", resp)
+	}
+	if resp, _ := login.Next([]byte("Password:"), true); string(resp) != "pass" {
+		t.Errorf("expected password, got %s", resp)
+	}
+}
+
+func TestSmtpAuthUnknownMode(t *testing.T) {
+	email := &EmailNotifier{AuthMode: "digest-md5"}
+	if _, err := email.smtpAuth(); err == nil {
+		t.Error("unknown auth mode should fail")
+	}
+}
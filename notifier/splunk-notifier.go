@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"encoding/json"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const splunkEventPath = "/services/collector/event"
+
+// SplunkNotifier sends each alert to a Splunk HTTP Event Collector as an
+// event holding the full check output, with the check and its status as
+// indexed fields. Url is the collector, eg. https://splunk:8088.
+type SplunkNotifier struct {
+	failures
+
+	Url        string
+	Token      string
+	Index      string
+	Source     string
+	SourceType string
+	DryRun     bool
+	Http       *http.Client
+}
+
+type splunkEvent struct {
+	Time       float64           `json:"time,omitempty"`
+	Host       string            `json:"host"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      string            `json:"event"`
+	Fields     map[string]string `json:"fields"`
+}
+
+type splunkResponse struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+func (splunk *SplunkNotifier) Notify(messages Messages) bool {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, message := range messages {
+		if err := encoder.Encode(splunk.event(message)); err != nil {
+			splunk.fail("Unable to marshal splunk event:", err)
+			return false
+		}
+	}
+	if splunk.DryRun {
+		return logDryRun("splunk", body.String())
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(splunk.Url, "/")+splunkEventPath, &body)
+	if err != nil {
+		splunk.fail("Unable to create splunk request:", err)
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+splunk.Token)
+	res, err := httpClient(splunk.Http).Do(req)
+	if err != nil {
+		splunk.fail("Unable to send the events to splunk:", err)
+		return false
+	}
+	defer res.Body.Close()
+	var response splunkResponse
+	json.NewDecoder(res.Body).Decode(&response)
+	if res.StatusCode != 200 || response.Code != 0 {
+		splunk.failf("Unable to notify splunk: %d %s", res.StatusCode, response.Text)
+		return false
+	}
+	log.Infof("Sent %d event(s) to splunk.", len(messages))
+	return true
+}
+
+// event returns the event of a message. The collector rejects blank events,
+// so a check without output is described by its status.
+func (splunk *SplunkNotifier) event(message Message) splunkEvent {
+	output := message.Output
+	if strings.TrimSpace(output) == "" {
+		output = fmt.Sprintf("%s%s:%s:%s is %s.", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status)
+	}
+	fields := map[string]string{
+		"node":     message.Node,
+		"check_id": message.CheckId,
+		"check":    message.Check,
+		"status":   message.Status,
+	}
+	for field, value := range map[string]string{"datacenter": message.Datacenter, "service_id": message.ServiceId, "service": message.Service} {
+		if value != "" {
+			fields[field] = value
+		}
+	}
+	event := splunkEvent{
+		Host:       message.Node,
+		Source:     splunk.Source,
+		SourceType: splunk.SourceType,
+		Index:      splunk.Index,
+		Event:      output,
+		Fields:     fields,
+	}
+	// the collector uses the receive time without one
+	if !message.Timestamp.IsZero() {
+		event.Time = float64(message.Timestamp.UnixNano()) / 1e9
+	}
+	return event
+}
@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestSplunkNotify(t *testing.T) {
+	var path, auth string
+	var events []splunkEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		decoder := json.NewDecoder(r.Body)
+		for decoder.More() {
+			var event splunkEvent
+			decoder.Decode(&event)
+			events = append(events, event)
+		}
+		w.Write([]byte(`{"text": "Success", "code": 0}`))
+	}))
+	defer server.Close()
+
+	splunk := &SplunkNotifier{Url: server.URL + "/", Token: "token", Index: "alerts", SourceType: "consul:alert"}
+	now := time.Unix(1700000000, 0)
+	messages := Messages{
+		{Node: "web1", ServiceId: "web", Service: "web", CheckId: "http", Check: "HTTP", Status: "critical", Output: "line 1\nline 2", Timestamp: now},
+		{Node: "db1", CheckId: "disk", Check: "Disk", Status: "passing", Timestamp: now},
+	}
+	if !splunk.Notify(messages) {
+		t.Fatal("expected the events to be sent")
+	}
+	if path != "/services/collector/event" || auth != "Splunk token" {
+		t.Errorf("unexpected request %s %s", path, auth)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Event != "line 1\nline 2" || events[0].Index != "alerts" || events[0].SourceType != "consul:alert" ||
+		events[0].Time != 1700000000 || events[0].Fields["status"] != "critical" || events[0].Fields["service"] != "web" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+	if events[1].Event != "db1::Disk is passing." {
+		t.Errorf("expected the status as the event without output, got %q", events[1].Event)
+	}
+}
+
+func TestSplunkFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		w.Write([]byte(`{"text": "Invalid token", "code": 4}`))
+	}))
+	defer server.Close()
+
+	splunk := &SplunkNotifier{Url: server.URL, Token: "wrong"}
+	if splunk.Notify(Messages{{Node: "web1", CheckId: "disk", Status: "critical"}}) {
+		t.Error("expected the rejected events to fail")
+	}
+}
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const defaultVictorOpsUrl = "https://alert.victorops.com/integrations/generic/20131114/alert"
+
+// VictorOpsNotifier sends the alerts to the VictorOps (Splunk On-Call) REST
+// endpoint. Each check is an incident, triggered while it is warning or
+// critical and recovered when it passes again. Url is the REST endpoint
+// without the api key and routing key.
+type VictorOpsNotifier struct {
+	failures
+
+	Url        string
+	ApiKey     string
+	RoutingKey string
+	DryRun     bool
+	Http       *http.Client
+}
+
+type victorOpsAlert struct {
+	MessageType       string `json:"message_type"`
+	EntityId          string `json:"entity_id"`
+	EntityDisplayName string `json:"entity_display_name"`
+	StateMessage      string `json:"state_message"`
+	MonitoringTool    string `json:"monitoring_tool"`
+	Datacenter        string `json:"datacenter,omitempty"`
+	Node              string `json:"node"`
+	Service           string `json:"service,omitempty"`
+	Check             string `json:"check"`
+	Status            string `json:"status"`
+}
+
+type victorOpsResponse struct {
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+func (vo *VictorOpsNotifier) Notify(messages Messages) bool {
+
+	result := true
+
+	for _, message := range messages {
+		entityId := message.datacenterPrefix() + message.Node
+		if message.ServiceId != "" {
+			entityId += ":" + message.ServiceId
+		}
+		entityId += ":" + message.CheckId
+
+		alert := victorOpsAlert{
+			EntityId:       entityId,
+			StateMessage:   message.Output,
+			MonitoringTool: "consul-alerts",
+			Datacenter:     message.Datacenter,
+			Node:           message.Node,
+			Service:        message.Service,
+			Check:          message.Check,
+			Status:         message.Status,
+		}
+		switch {
+		case message.IsPassing():
+			alert.MessageType = "RECOVERY"
+			alert.EntityDisplayName = entityId + " is now HEALTHY"
+		case message.IsWarning():
+			alert.MessageType = "WARNING"
+			alert.EntityDisplayName = entityId + " is UNSTABLE"
+		case message.IsCritical():
+			alert.MessageType = "CRITICAL"
+			alert.EntityDisplayName = entityId + " is CRITICAL"
+		default:
+			continue
+		}
+		if vo.DryRun {
+			logDryRun("victorops", fmt.Sprintf("%s %s: %s", alert.MessageType, entityId, message.Status))
+			continue
+		}
+
+		if err := vo.send(alert); err != nil {
+			vo.failf("Error sending %s notification to victorops: %s", entityId, err)
+			result = false
+		}
+	}
+
+	log.Infoln("VictorOps notification complete")
+	return result
+}
+
+// send posts an alert to the routing key of the REST endpoint.
+func (vo *VictorOpsNotifier) send(alert victorOpsAlert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	endpoint := vo.Url
+	if endpoint == "" {
+		endpoint = defaultVictorOpsUrl
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(vo.ApiKey) + "/" + url.PathEscape(vo.RoutingKey)
+	res, err := httpClient(vo.Http).Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var response victorOpsResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("unexpected response code: %d", res.StatusCode)
+	}
+	if res.StatusCode != 200 || response.Result != "success" {
+		return fmt.Errorf("unexpected response %d: %s", res.StatusCode, response.Message)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"testing"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestVictorOpsNotify(t *testing.T) {
+	var paths []string
+	var alerts []victorOpsAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var alert victorOpsAlert
+		json.NewDecoder(r.Body).Decode(&alert)
+		alerts = append(alerts, alert)
+		w.Write([]byte(`{"result": "success", "entity_id": "` + alert.EntityId + `"}`))
+	}))
+	defer server.Close()
+
+	vo := &VictorOpsNotifier{Url: server.URL + "/alert/", ApiKey: "key", RoutingKey: "ops"}
+	messages := Messages{
+		{Node: "web1", ServiceId: "web", CheckId: "http", Status: "critical", Output: "timeout"},
+		{Node: "web1", CheckId: "disk", Status: "warning"},
+		{Node: "web1", ServiceId: "web", CheckId: "http", Status: "passing"},
+	}
+	if !vo.Notify(messages) {
+		t.Fatal("expected the alerts to be sent")
+	}
+	if len(paths) != 3 || paths[0] != "/alert/key/ops" {
+		t.Fatalf("unexpected requests %v", paths)
+	}
+	for i, expected := range []string{"CRITICAL", "WARNING", "RECOVERY"} {
+		if alerts[i].MessageType != expected {
+			t.Errorf("expected a %s alert, got %+v", expected, alerts[i])
+		}
+	}
+	if alerts[0].EntityId != "web1:web:http" || alerts[2].EntityId != alerts[0].EntityId || alerts[0].StateMessage != "timeout" {
+		t.Errorf("expected the recovery to resolve the incident, got %+v", alerts)
+	}
+}
+
+func TestVictorOpsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"result": "failure", "message": "Missing fields"}`))
+	}))
+	defer server.Close()
+
+	vo := &VictorOpsNotifier{Url: server.URL, ApiKey: "key", RoutingKey: "ops"}
+	if vo.Notify(Messages{{Node: "web1", CheckId: "disk", Status: "critical"}}) {
+		t.Error("expected the rejected alert to fail")
+	}
+}
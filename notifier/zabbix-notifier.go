@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"encoding/binary"
+	"encoding/json"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultZabbixHost = "{node}"
+	defaultZabbixKey  = "consul.check[{check-id}]"
+	zabbixTimeout     = 10 * time.Second
+)
+
+// zabbixHeader starts the Zabbix protocol packets, before the little endian
+// data length.
+var zabbixHeader = []byte("ZBXD\x01")
+
+// ZabbixNotifier sends the check statuses as trapper item values to a Zabbix
+// server with the sender protocol, like zabbix_sender. Host and Key are the
+// patterns of the Zabbix host and item key of a check, see checkPattern. The
+// value is the status, or 0, 1, 2 and 3 for passing, warning, critical and
+// the other statuses when Numeric is set.
+type ZabbixNotifier struct {
+	failures
+
+	Server  string
+	Host    string
+	Key     string
+	Numeric bool
+	DryRun  bool
+
+	// Deadline is when the sends stop, see SetDeadline.
+	Deadline time.Time
+}
+
+type zabbixRequest struct {
+	Request string       `json:"request"`
+	Data    []zabbixItem `json:"data"`
+	Clock   int64        `json:"clock"`
+}
+
+type zabbixItem struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock,omitempty"`
+}
+
+type zabbixResponse struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+func (zabbix *ZabbixNotifier) Notify(messages Messages) bool {
+	request := zabbixRequest{Request: "sender data", Clock: time.Now().Unix()}
+	for _, message := range messages {
+		item := zabbixItem{
+			Host:  checkPattern(zabbix.Host, defaultZabbixHost, message),
+			Key:   checkPattern(zabbix.Key, defaultZabbixKey, message),
+			Value: zabbix.value(message.Status),
+		}
+		if !message.Timestamp.IsZero() {
+			item.Clock = message.Timestamp.Unix()
+		}
+		request.Data = append(request.Data, item)
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		zabbix.fail("Unable to marshal zabbix sender data:", err)
+		return false
+	}
+	if zabbix.DryRun {
+		return logDryRun("zabbix", string(data))
+	}
+
+	response, err := zabbix.send(data)
+	if err != nil {
+		zabbix.fail("Unable to send the values to zabbix:", err)
+		return false
+	}
+	// info is like "processed: 1; failed: 0; total: 1; seconds spent: 0.000055"
+	var processed, failed, total int
+	fmt.Sscanf(response.Info, "processed: %d; failed: %d; total: %d", &processed, &failed, &total)
+	if response.Response != "success" || failed > 0 {
+		zabbix.failf("Zabbix didn't process every value, check the hosts and the trapper items: %s", response.Info)
+		return false
+	}
+	log.Infof("Sent %d value(s) to zabbix.", len(messages))
+	return true
+}
+
+func (zabbix *ZabbixNotifier) value(status string) string {
+	if !zabbix.Numeric {
+		return status
+	}
+	if state, found := nrdpStates[status]; found {
+		return fmt.Sprint(state)
+	}
+	return "3"
+}
+
+// send sends a sender protocol packet and reads the response.
+func (zabbix *ZabbixNotifier) send(data []byte) (*zabbixResponse, error) {
+	server := zabbix.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "10051")
+	}
+	timeout := timeoutBefore(zabbix.Deadline, zabbixTimeout)
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	packet := bytes.NewBuffer(append([]byte{}, zabbixHeader...))
+	binary.Write(packet, binary.LittleEndian, uint64(len(data)))
+	packet.Write(data)
+	if _, err := conn.Write(packet.Bytes()); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(zabbixHeader)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(zabbixHeader)], zabbixHeader) {
+		return nil, fmt.Errorf("unexpected response header %q", strings.TrimSpace(string(header)))
+	}
+	length := binary.LittleEndian.Uint64(header[len(zabbixHeader):])
+	var response zabbixResponse
+	if err := json.NewDecoder(io.LimitReader(conn, int64(length))).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,83 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"encoding/binary"
+	"encoding/json"
+)
+
+// fakeZabbix answers each sender request with the response of failed, after
+// passing the request to requests.
+func fakeZabbix(t *testing.T, failed int, requests chan zabbixRequest) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			header := make([]byte, 13)
+			io.ReadFull(conn, header)
+			data := make([]byte, binary.LittleEndian.Uint64(header[5:]))
+			io.ReadFull(conn, data)
+			var request zabbixRequest
+			json.Unmarshal(data, &request)
+			requests <- request
+
+			response, _ := json.Marshal(zabbixResponse{"success", fmt.Sprintf("processed: %d; failed: %d; total: %d; seconds spent: 0.000055", len(request.Data)-failed, failed, len(request.Data))})
+			packet := bytes.NewBufferString("ZBXD\x01")
+			binary.Write(packet, binary.LittleEndian, uint64(len(response)))
+			packet.Write(response)
+			conn.Write(packet.Bytes())
+			conn.Close()
+		}
+	}()
+	return listener
+}
+
+func TestZabbixNotify(t *testing.T) {
+	requests := make(chan zabbixRequest, 1)
+	listener := fakeZabbix(t, 0, requests)
+	defer listener.Close()
+
+	zabbix := &ZabbixNotifier{Server: listener.Addr().String(), Key: "consul.status[{service-id},{check-id}]", Numeric: true}
+	messages := Messages{
+		{Node: "web1", ServiceId: "web", CheckId: "http", Status: "critical"},
+		{Node: "db1", CheckId: "disk", Status: "passing"},
+	}
+	if !zabbix.Notify(messages) {
+		t.Fatal("expected the values to be sent")
+	}
+	request := <-requests
+	if request.Request != "sender data" || len(request.Data) != 2 {
+		t.Fatalf("unexpected request %+v", request)
+	}
+	if item := request.Data[0]; item.Host != "web1" || item.Key != "consul.status[web,http]" || item.Value != "2" {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if item := request.Data[1]; item.Value != "0" {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
+
+func TestZabbixFailed(t *testing.T) {
+	requests := make(chan zabbixRequest, 1)
+	listener := fakeZabbix(t, 1, requests)
+	defer listener.Close()
+
+	zabbix := &ZabbixNotifier{Server: listener.Addr().String()}
+	if zabbix.Notify(Messages{{Node: "web1", CheckId: "disk", Status: "warning"}}) {
+		t.Error("expected the unprocessed value to fail")
+	}
+	if item := (<-requests).Data[0]; item.Key != "consul.check[disk]" || item.Value != "warning" {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
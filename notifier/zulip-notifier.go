@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+const defaultZulipTopic = "{service}"
+
+// ZulipNotifier posts Markdown messages to a Zulip stream as a bot. Url is
+// the Zulip server, eg. https://example.zulipchat.com. Topic is a pattern
+// where {service} is replaced with the service of the check, or its node for
+// the node checks, {node} with the node and {cluster} with ClusterName, so
+// the alerts are threaded by service by default.
+type ZulipNotifier struct {
+	failures
+
+	ClusterName string
+	Url         string
+	BotEmail    string
+	ApiKey      string
+	Stream      string
+	Topic       string
+	DryRun      bool
+	Http        *http.Client
+}
+
+type zulipResponse struct {
+	Result string `json:"result"`
+	Msg    string `json:"msg"`
+}
+
+func (zulip *ZulipNotifier) Notify(messages Messages) bool {
+	var topics []string
+	byTopic := make(map[string]Messages)
+	for _, message := range messages {
+		topic := zulip.topic(message)
+		if _, found := byTopic[topic]; !found {
+			topics = append(topics, topic)
+		}
+		byTopic[topic] = append(byTopic[topic], message)
+	}
+
+	result := true
+	for _, topic := range topics {
+		content := zulip.content(byTopic[topic])
+		if zulip.DryRun {
+			logDryRun("zulip", fmt.Sprintf("%s > %s\n%s", zulip.Stream, topic, content))
+			continue
+		}
+		if err := zulip.send(topic, content); err != nil {
+			zulip.failf("Unable to notify zulip topic %s: %s", topic, err)
+			result = false
+		}
+	}
+	if result {
+		log.Infoln("Zulip notification sent.")
+	}
+	return result
+}
+
+func (zulip *ZulipNotifier) topic(message Message) string {
+	pattern := zulip.Topic
+	if pattern == "" {
+		pattern = defaultZulipTopic
+	}
+	service := message.Service
+	if service == "" {
+		service = message.Node
+	}
+	replacer := strings.NewReplacer("{service}", service, "{node}", message.Node, "{cluster}", zulip.ClusterName)
+	return replacer.Replace(pattern)
+}
+
+// content renders the messages in Zulip Markdown, the outputs in code blocks.
+func (zulip *ZulipNotifier) content(messages Messages) string {
+	overallStatus, pass, warn, fail := messages.Summary()
+	text := fmt.Sprintf("**%s** is **%s**.\nFail: %d, Warn: %d, Pass: %d\n", zulip.ClusterName, overallStatus, fail, warn, pass)
+	for _, message := range messages {
+		text += fmt.Sprintf("\n* **%s%s:%s:%s** is **%s**.", message.datacenterPrefix(), message.Node, message.Service, message.Check, message.Status)
+		if output := strings.TrimSpace(message.Output); output != "" {
+			text += fmt.Sprintf("\n```text\n%s\n```", output)
+		}
+	}
+	return text
+}
+
+// send posts a stream message with the bot credentials.
+func (zulip *ZulipNotifier) send(topic, content string) error {
+	form := url.Values{
+		"type":    {"stream"},
+		"to":      {zulip.Stream},
+		"topic":   {topic},
+		"content": {content},
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(zulip.Url, "/")+"/api/v1/messages", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(zulip.BotEmail, zulip.ApiKey)
+	res, err := httpClient(zulip.Http).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var response zulipResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("unexpected response code: %d", res.StatusCode)
+	}
+	if res.StatusCode != 200 || response.Result != "success" {
+		return fmt.Errorf("unexpected response %d: %s", res.StatusCode, response.Msg)
+	}
+	return nil
+}
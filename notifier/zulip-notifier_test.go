@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestZulipNotify(t *testing.T) {
+	type post struct{ user, stream, topic, content string }
+	var posts []post
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		user, _, _ := r.BasicAuth()
+		posts = append(posts, post{user, r.FormValue("to"), r.FormValue("topic"), r.FormValue("content")})
+		w.Write([]byte(`{"result": "success", "msg": "", "id": 42}`))
+	}))
+	defer server.Close()
+
+	zulip := &ZulipNotifier{ClusterName: "cluster", Url: server.URL + "/", BotEmail: "alerts-bot@example.com", ApiKey: "key", Stream: "ops"}
+	messages := Messages{
+		{Node: "web1", Service: "web", Check: "http", Status: "critical", Output: "connection refused"},
+		{Node: "db1", Check: "disk", Status: "warning"},
+		{Node: "web2", Service: "web", Check: "http", Status: "passing"},
+	}
+	if !zulip.Notify(messages) {
+		t.Fatal("expected the messages to be posted")
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected a message per topic, got %+v", posts)
+	}
+	if posts[0].user != "alerts-bot@example.com" || posts[0].stream != "ops" || posts[0].topic != "web" || posts[1].topic != "db1" {
+		t.Errorf("unexpected posts %+v", posts)
+	}
+	if !strings.Contains(posts[0].content, "**web1:web:http** is **critical**.\n```text\nconnection refused\n```") ||
+		!strings.Contains(posts[0].content, "web2:web:http") {
+		t.Errorf("unexpected content %q", posts[0].content)
+	}
+}
+
+func TestZulipTopic(t *testing.T) {
+	zulip := &ZulipNotifier{ClusterName: "prod", Topic: "{cluster} alerts"}
+	if topic := zulip.topic(Message{Node: "web1", Service: "web"}); topic != "prod alerts" {
+		t.Errorf("unexpected topic %q", topic)
+	}
+}
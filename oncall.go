@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// icalPrefix marks an on-call receiver read from an iCal calendar, eg.
+// ical+https://example.com/oncall.ics.
+const icalPrefix = "ical+"
+
+// onCallCacheTime is how long the on-call contacts are kept before fetching
+// them again.
+const onCallCacheTime = time.Minute
+
+var onCallClient = &http.Client{Timeout: 10 * time.Second}
+
+var onCall = struct {
+	sync.Mutex
+	contacts map[string]onCallContacts
+}{contacts: make(map[string]onCallContacts)}
+
+type onCallContacts struct {
+	contacts []string
+	fetched  time.Time
+	// known are the last contacts found, used when nobody is on call
+	known []string
+}
+
+var emailAddress = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+func isOnCallSource(receiver string) bool {
+	return isWebhook(receiver) || strings.HasPrefix(receiver, icalPrefix)
+}
+
+// resolveReceivers replaces the on-call sources in receivers with the current
+// on-call contacts.
+func resolveReceivers(receivers []string) []string {
+	var resolved []string
+	for _, receiver := range receivers {
+		if isOnCallSource(receiver) {
+			resolved = append(resolved, currentOnCall(receiver)...)
+		} else {
+			resolved = append(resolved, receiver)
+		}
+	}
+	return resolved
+}
+
+// currentOnCall returns the contacts of an on-call source. The last contacts
+// found are used when the source can't be reached or nobody is on call. The
+// source is fetched without the lock, a slow one doesn't hold up the others.
+func currentOnCall(source string) []string {
+	onCall.Lock()
+	cached := onCall.contacts[source]
+	onCall.Unlock()
+	if time.Since(cached.fetched) < onCallCacheTime {
+		return cached.contacts
+	}
+	contacts, err := fetchOnCall(source, time.Now())
+	if err != nil {
+		log.WithField("source", source).Errorf("Unable to get the on-call contacts, using the %d last known: %s", len(cached.known), err)
+		return cached.known
+	}
+	known := contacts
+	if len(contacts) == 0 {
+		log.WithField("source", source).Warnf("Nobody is on call, using the %d last known contacts.", len(cached.known))
+		contacts, known = cached.known, cached.known
+	}
+	onCall.Lock()
+	onCall.contacts[source] = onCallContacts{contacts, time.Now(), known}
+	onCall.Unlock()
+	return contacts
+}
+
+func fetchOnCall(source string, now time.Time) ([]string, error) {
+	resp, err := onCallClient.Get(strings.TrimPrefix(source, icalPrefix))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(source, icalPrefix) {
+		return icalContacts(data, now)
+	}
+	return listContacts(data), nil
+}
+
+// listContacts reads a JSON array of contacts, or one contact per line.
+func listContacts(data []byte) []string {
+	var contacts []string
+	if err := json.Unmarshal(data, &contacts); err == nil {
+		return contacts
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			contacts = append(contacts, line)
+		}
+	}
+	return contacts
+}
+
+// icalEvent is a VEVENT of an on-call calendar.
+type icalEvent struct {
+	uid          string
+	recurrenceId time.Time
+	start, end   time.Time
+	allDay       bool
+	rule         string
+	exdates      []time.Time
+	attendees    []string
+	summary      []string
+}
+
+// icalContacts returns the email addresses of the events happening at now,
+// from their attendees or, without attendees, from their summary. The
+// recurring events are expanded, a calendar with a recurrence rule that
+// isn't supported is rejected.
+func icalContacts(data []byte, now time.Time) ([]string, error) {
+	if !bytes.Contains(data, []byte("BEGIN:VCALENDAR")) {
+		return nil, fmt.Errorf("not an iCal calendar")
+	}
+	var events []icalEvent
+	var event *icalEvent
+	for _, line := range unfoldICal(data) {
+		name, params, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icalEvent{}
+		case name == "END" && value == "VEVENT" && event != nil:
+			events = append(events, *event)
+			event = nil
+		case event == nil:
+		case name == "UID":
+			event.uid = value
+		case name == "RECURRENCE-ID":
+			event.recurrenceId = parseICalTime(value, params)
+		case name == "DTSTART":
+			event.start, event.allDay = parseICalTime(value, params), len(value) == len("20060102")
+		case name == "DTEND":
+			event.end = parseICalTime(value, params)
+		case name == "RRULE":
+			event.rule = value
+		case name == "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				event.exdates = append(event.exdates, parseICalTime(date, params))
+			}
+		case name == "ATTENDEE":
+			if strings.HasPrefix(strings.ToLower(value), "mailto:") {
+				event.attendees = append(event.attendees, value[len("mailto:"):])
+			}
+		case name == "SUMMARY":
+			event.summary = emailAddress.FindAllString(value, -1)
+		}
+	}
+
+	// the moved or changed occurrences replace the ones of the recurring event
+	replaced := make(map[string][]time.Time)
+	for _, event := range events {
+		if !event.recurrenceId.IsZero() {
+			replaced[event.uid] = append(replaced[event.uid], event.recurrenceId)
+		}
+	}
+	var contacts []string
+	for _, event := range events {
+		active, err := event.active(now, replaced[event.uid])
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			continue
+		}
+		if len(event.attendees) > 0 {
+			contacts = append(contacts, event.attendees...)
+		} else {
+			contacts = append(contacts, event.summary...)
+		}
+	}
+	return contacts, nil
+}
+
+// active returns true if the event, or one of its occurrences but the
+// replaced ones, is happening at now.
+func (e icalEvent) active(now time.Time, replaced []time.Time) (bool, error) {
+	if e.start.IsZero() {
+		return false, nil
+	}
+	if e.rule == "" || !e.recurrenceId.IsZero() {
+		return !now.Before(e.start) && (e.end.IsZero() || now.Before(e.end)), nil
+	}
+	duration := e.end.Sub(e.start)
+	if e.end.IsZero() {
+		if !e.allDay {
+			return false, fmt.Errorf("recurring event %s without DTEND", e.uid)
+		}
+		duration = 24 * time.Hour
+	}
+	rule, err := parseICalRule(e.rule, map[string]string{"TZID": e.start.Location().String()})
+	if err != nil {
+		return false, err
+	}
+	excluded := append(append([]time.Time{}, e.exdates...), replaced...)
+	return rule.active(e.start, duration, now, excluded), nil
+}
+
+// icalRule is a recurrence rule: the FREQ, INTERVAL, COUNT, UNTIL parts and
+// the BYDAY days of the weekly rules.
+type icalRule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	days     []time.Weekday
+}
+
+// icalMaxPeriods bounds the periods of a rule looked at before giving up on
+// it.
+const icalMaxPeriods = 100000
+
+var icalWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func parseICalRule(value string, params map[string]string) (icalRule, error) {
+	rule := icalRule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			return rule, fmt.Errorf("invalid RRULE %s", value)
+		}
+		name, value := strings.ToUpper(part[:eq]), part[eq+1:]
+		var err error
+		switch name {
+		case "FREQ":
+			rule.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+		case "UNTIL":
+			if rule.until = parseICalTime(value, params); rule.until.IsZero() {
+				err = fmt.Errorf("invalid time")
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, found := icalWeekdays[strings.ToUpper(day)]
+				if !found {
+					return rule, fmt.Errorf("unsupported RRULE day %s in %s", day, value)
+				}
+				rule.days = append(rule.days, weekday)
+			}
+		case "WKST":
+			// the weeks start on monday
+		default:
+			return rule, fmt.Errorf("unsupported RRULE part %s", part)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("invalid RRULE %s: %s", part, err)
+		}
+	}
+	switch {
+	case rule.freq != "DAILY" && rule.freq != "WEEKLY" && rule.freq != "MONTHLY" && rule.freq != "YEARLY":
+		return rule, fmt.Errorf("unsupported RRULE frequency %q", rule.freq)
+	case len(rule.days) > 0 && rule.freq != "WEEKLY":
+		return rule, fmt.Errorf("unsupported RRULE BYDAY for a %s frequency", strings.ToLower(rule.freq))
+	case rule.interval < 1:
+		return rule, fmt.Errorf("invalid RRULE interval %d", rule.interval)
+	}
+	sort.Slice(rule.days, func(i, j int) bool { return weekdayIndex(rule.days[i]) < weekdayIndex(rule.days[j]) })
+	return rule, nil
+}
+
+// active returns true if an occurrence starting at start, but the excluded
+// ones, lasts at now.
+func (r icalRule) active(start time.Time, duration time.Duration, now time.Time, excluded []time.Time) bool {
+	occurrences := 0
+	for period := 0; period < icalMaxPeriods; period++ {
+		for _, occurrence := range r.period(start, period*r.interval) {
+			if occurrence.After(now) || (!r.until.IsZero() && occurrence.After(r.until)) {
+				return false
+			}
+			if occurrences++; r.count > 0 && occurrences > r.count {
+				return false
+			}
+			if now.Before(occurrence.Add(duration)) && !containsTime(excluded, occurrence) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// period returns the occurrences of the nth period of the rule, in order.
+func (r icalRule) period(start time.Time, n int) []time.Time {
+	switch r.freq {
+	case "DAILY":
+		return []time.Time{start.AddDate(0, 0, n)}
+	case "WEEKLY":
+		week := start.AddDate(0, 0, 7*n)
+		if len(r.days) == 0 {
+			return []time.Time{week}
+		}
+		monday := week.AddDate(0, 0, -weekdayIndex(week.Weekday()))
+		var occurrences []time.Time
+		for _, day := range r.days {
+			if occurrence := monday.AddDate(0, 0, weekdayIndex(day)); !occurrence.Before(start) {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+		return occurrences
+	case "MONTHLY":
+		// the months without the day of the start are skipped
+		if occurrence := start.AddDate(0, n, 0); occurrence.Day() == start.Day() {
+			return []time.Time{occurrence}
+		}
+	case "YEARLY":
+		if occurrence := start.AddDate(n, 0, 0); occurrence.Day() == start.Day() {
+			return []time.Time{occurrence}
+		}
+	}
+	return nil
+}
+
+// weekdayIndex is the index of a day in a week starting on monday.
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, other := range times {
+		if other.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// unfoldICal joins the continuation lines, starting with a space or a tab,
+// to the line they continue.
+func unfoldICal(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitICalLine splits a content line like DTSTART;TZID=Europe/Madrid:20261016T090000.
+func splitICalLine(line string) (name string, params map[string]string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return line, nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params = make(map[string]string)
+	for _, param := range parts[1:] {
+		if eq := strings.Index(param, "="); eq > 0 {
+			params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+func parseICalTime(value string, params map[string]string) time.Time {
+	location := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			location = tz
+		}
+	}
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if strings.HasSuffix(layout, "Z") {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
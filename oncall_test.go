@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+const onCallCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+DTSTART:20261016T080000Z
+DTEND:20261016T200000Z
+SUMMARY:On call - Primary
+ATTENDEE;CN=Alex:mailto:alex@example.com
+ATTENDEE;CN=Sam;ROLE=REQ-PARTI
+ CIPANT:mailto:sam@example.com
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;TZID=Europe/Madrid:20261016T220000
+DTEND;TZID=Europe/Madrid:20261017T080000
+SUMMARY:On call night shift: kim@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestICalContacts(t *testing.T) {
+	cases := []struct {
+		now      time.Time
+		contacts []string
+	}{
+		{time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), []string{"alex@example.com", "sam@example.com"}},
+		{time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC), []string{"kim@example.com"}},
+		{time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC), nil},
+	}
+	for _, c := range cases {
+		contacts, err := icalContacts([]byte(onCallCalendar), c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(contacts, c.contacts) {
+			t.Errorf("unexpected contacts at %s: %v", c.now, contacts)
+		}
+	}
+	if _, err := icalContacts([]byte("<html></html>"), time.Now()); err == nil {
+		t.Error("expected an error for a non iCal response")
+	}
+}
+
+func TestListContacts(t *testing.T) {
+	expected := []string{"alex@example.com", "sam@example.com"}
+	for _, data := range []string{`["alex@example.com", "sam@example.com"]`, "alex@example.com\n\nsam@example.com\n"} {
+		if contacts := listContacts([]byte(data)); !reflect.DeepEqual(contacts, expected) {
+			t.Errorf("unexpected contacts for %q: %v", data, contacts)
+		}
+	}
+}
+
+func TestResolveReceivers(t *testing.T) {
+	available, nobody := true, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(500)
+			return
+		}
+		if nobody {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `["oncall@example.com"]`)
+	}))
+	defer server.Close()
+
+	receivers := []string{"ops@example.com", server.URL}
+	expected := []string{"ops@example.com", "oncall@example.com"}
+	if resolved := resolveReceivers(receivers); !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("unexpected receivers %v", resolved)
+	}
+
+	// the last known contacts are used when the source fails
+	available = false
+	onCall.Lock()
+	cached := onCall.contacts[server.URL]
+	cached.fetched = time.Time{}
+	onCall.contacts[server.URL] = cached
+	onCall.Unlock()
+	if resolved := resolveReceivers(receivers); !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("unexpected receivers %v", resolved)
+	}
+
+	// and when nobody is on call
+	available, nobody = true, true
+	onCall.Lock()
+	cached = onCall.contacts[server.URL]
+	cached.fetched = time.Time{}
+	onCall.contacts[server.URL] = cached
+	onCall.Unlock()
+	if resolved := resolveReceivers(receivers); !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("unexpected receivers %v", resolved)
+	}
+}
+
+const recurringCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:weekdays
+DTSTART;TZID=Europe/Madrid:20260105T090000
+DTEND;TZID=Europe/Madrid:20260105T180000
+RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20261231T235959Z
+EXDATE;TZID=Europe/Madrid:20261012T090000
+ATTENDEE:mailto:alex@example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:weekdays
+RECURRENCE-ID;TZID=Europe/Madrid:20261015T090000
+DTSTART;TZID=Europe/Madrid:20261015T090000
+DTEND;TZID=Europe/Madrid:20261015T180000
+ATTENDEE:mailto:sam@example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:weekends
+DTSTART;VALUE=DATE:20261003
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU;COUNT=4
+SUMMARY:Weekend on call kim@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestICalRecurringContacts(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	cases := []struct {
+		now      time.Time
+		contacts []string
+	}{
+		{time.Date(2026, 10, 14, 12, 0, 0, 0, madrid), []string{"alex@example.com"}},
+		{time.Date(2026, 10, 14, 19, 0, 0, 0, madrid), nil},
+		// excluded, and replaced by another attendee
+		{time.Date(2026, 10, 12, 12, 0, 0, 0, madrid), nil},
+		{time.Date(2026, 10, 15, 12, 0, 0, 0, madrid), []string{"sam@example.com"}},
+		// past the UNTIL
+		{time.Date(2027, 1, 4, 12, 0, 0, 0, madrid), nil},
+		// every other weekend, 4 days
+		{time.Date(2026, 10, 4, 12, 0, 0, 0, time.Local), []string{"kim@example.com"}},
+		{time.Date(2026, 10, 11, 12, 0, 0, 0, time.Local), nil},
+		{time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local), []string{"kim@example.com"}},
+		{time.Date(2026, 11, 1, 12, 0, 0, 0, time.Local), nil},
+	}
+	for _, c := range cases {
+		contacts, err := icalContacts([]byte(recurringCalendar), c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(contacts, c.contacts) {
+			t.Errorf("unexpected contacts at %s: %v", c.now, contacts)
+		}
+	}
+
+	unsupported := strings.Replace(recurringCalendar, "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU;COUNT=4", "FREQ=MONTHLY;BYDAY=1SA", 1)
+	if _, err := icalContacts([]byte(unsupported), time.Now()); err == nil {
+		t.Error("expected an unsupported recurrence rule to be rejected")
+	}
+}
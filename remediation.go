@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// remediationLock keeps the runs of the transitions and of the timer from
+// running the handlers of a check twice.
+var remediationLock sync.Mutex
+
+// remediate runs the remediation handlers of the alerts gone critical and
+// resets the attempts of the checks passing again. The handlers run in the
+// background so they don't delay the check processing.
+func remediate(alerts []consul.Check) {
+	config := consulClient.RemediationConfig()
+	if len(config.Handlers) == 0 {
+		return
+	}
+	pipelineBegin()
+	go func() {
+		defer pipelineEnd()
+		remediationLock.Lock()
+		defer remediationLock.Unlock()
+		var messages notifier.Messages
+		for i := range alerts {
+			messages = append(messages, remediateCheck(config, &alerts[i], time.Now())...)
+		}
+		if len(messages) > 0 && config.Notify {
+			routeMessages(messages, nil)
+		}
+	}()
+}
+
+// remediateFailing runs the remediation handlers again for the checks still
+// critical, so the handlers make their next attempts once they have cooled
+// down without waiting for the check to change status. Only the checks whose
+// critical alert was sent are remediated, like on a transition.
+func remediateFailing() {
+	if len(consulClient.RemediationConfig().Handlers) == 0 {
+		return
+	}
+	statuses, err := consulClient.CheckStatuses("")
+	if err != nil {
+		log.Warnln("Unable to look up the critical checks to remediate:", err)
+		return
+	}
+	var failing []consul.Check
+	for _, status := range statuses {
+		if status.HealthCheck == nil || status.Current != "critical" || status.ForNotification || status.NotifiedStatus != "critical" {
+			continue
+		}
+		check := *status.HealthCheck
+		check.Status = status.Current
+		if !consulClient.IsSilenced(&check) {
+			failing = append(failing, check)
+		}
+	}
+	if len(failing) > 0 {
+		remediate(failing)
+	}
+}
+
+// remediateCheck runs the handlers of a critical check that are not cooling
+// down and haven't used all their attempts, and returns their outcome. The
+// attempts are recorded before the handlers run so a new leader doesn't run
+// them again.
+func remediateCheck(config *consul.RemediationConfig, check *consul.Check, now time.Time) notifier.Messages {
+	var handlers []consul.RemediationHandler
+	for _, handler := range config.Handlers {
+		if handler.Matches(check) {
+			handlers = append(handlers, handler)
+		}
+	}
+	if len(handlers) == 0 || (check.Status != "critical" && check.Status != "passing") {
+		return nil
+	}
+	checkLog := log.WithField("check", fmt.Sprintf("%s:%s:%s", check.Node, check.ServiceID, check.CheckID))
+	attempts, err := consulClient.RemediationAttempts(check)
+	if err != nil {
+		checkLog.Warnln("Unable to read the remediation attempts, skipping the remediation:", err)
+		return nil
+	}
+	if check.Status == "passing" {
+		if reset, changed := resetRemediationAttempts(config, handlers, attempts, now); changed {
+			if err := consulClient.SetRemediationAttempts(check, reset); err != nil {
+				checkLog.Warnln("Unable to reset the remediation attempts:", err)
+			}
+		}
+		return nil
+	}
+
+	var messages notifier.Messages
+	for _, handler := range handlers {
+		handlerLog := checkLog.WithField("handler", handler.Label())
+		previous := attempts[handler.Id()]
+		if reason := remediationSkipped(config, handler, previous, now); reason != "" {
+			remediationsExecuted.Inc("skipped")
+			handlerLog.Infof("Remediation skipped, %s.", reason)
+			continue
+		}
+		attempt := consul.RemediationAttempts{Count: previous.Count + 1, Last: now}
+		attempts[handler.Id()] = attempt
+		if err := consulClient.SetRemediationAttempts(check, attempts); err != nil {
+			handlerLog.Warnln("Unable to record the remediation attempt, skipping the remediation:", err)
+			return messages
+		}
+
+		handlerLog.Infof("Running remediation, attempt %d.", attempt.Count)
+		output, err := runRemediation(handler.Command, check, attempt.Count, time.Duration(config.Timeout)*time.Second)
+		// the errors of the webhooks hold their url
+		var failure error
+		if err != nil {
+			failure = errors.New(strings.Replace(err.Error(), handler.Command, handler.Label(), -1))
+		}
+		switch {
+		case err == nil:
+			remediationsExecuted.Inc("success")
+			handlerLog.Debugf("Remediation output:\n%s", output)
+		case errors.Is(err, context.DeadlineExceeded) || errors.As(err, new(errCommandTimeout)):
+			remediationsExecuted.Inc("timeout")
+			handlerLog.Errorf("Remediation timed out: %s. Output:\n%s", failure, output)
+		default:
+			remediationsExecuted.Inc("failed")
+			handlerLog.Errorf("Remediation failed: %s. Output:\n%s", failure, output)
+		}
+		messages = append(messages, remediationMessage(check, handler.Label(), attempt.Count, maxRemediationAttempts(config, handler), failure, output))
+	}
+	return messages
+}
+
+func maxRemediationAttempts(config *consul.RemediationConfig, handler consul.RemediationHandler) int {
+	if handler.MaxAttempts > 0 {
+		return handler.MaxAttempts
+	}
+	return config.MaxAttempts
+}
+
+func remediationCooldown(config *consul.RemediationConfig, handler consul.RemediationHandler) time.Duration {
+	if handler.Cooldown > 0 {
+		return time.Duration(handler.Cooldown) * time.Second
+	}
+	return time.Duration(config.Cooldown) * time.Second
+}
+
+// resetRemediationAttempts resets the attempts of a check passing again. The
+// last run is kept until the handler cooled down, so a check restarting in a
+// loop, passing and failing again, is still remediated once per cooldown.
+func resetRemediationAttempts(config *consul.RemediationConfig, handlers []consul.RemediationHandler, attempts map[string]consul.RemediationAttempts, now time.Time) (map[string]consul.RemediationAttempts, bool) {
+	reset := make(map[string]consul.RemediationAttempts)
+	for _, handler := range handlers {
+		previous, found := attempts[handler.Id()]
+		if found && now.Before(previous.Last.Add(remediationCooldown(config, handler))) {
+			reset[handler.Id()] = consul.RemediationAttempts{Last: previous.Last}
+		}
+	}
+	return reset, !reflect.DeepEqual(reset, attempts) && len(attempts) > 0
+}
+
+// remediationSkipped returns why a handler can't run again for a check, or an
+// empty string if it can.
+func remediationSkipped(config *consul.RemediationConfig, handler consul.RemediationHandler, previous consul.RemediationAttempts, now time.Time) string {
+	if max := maxRemediationAttempts(config, handler); max > 0 && previous.Count >= max {
+		return fmt.Sprintf("%d of %d attempts made", previous.Count, max)
+	}
+	if next := previous.Last.Add(remediationCooldown(config, handler)); now.Before(next) {
+		return "cooling down until " + next.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// runRemediation sends the check as JSON to a handler, on stdin for commands
+// or in the body for webhooks, and returns the command output.
+func runRemediation(handler string, check *consul.Check, attempt int, timeout time.Duration) (string, error) {
+	data, _ := json.Marshal(check)
+	if isWebhook(handler) {
+		return "", postWebhook(webhookClient(), handler, data, timeout, consulClient.EventWebhookRetries())
+	}
+	cmd, err := newCommand(handler)
+	if err != nil {
+		return "", err
+	}
+	output := new(bytes.Buffer)
+	cmd.Env = append(os.Environ(), remediationEnv(check, attempt)...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err = runCommand(cmd, timeout)
+	return output.String(), err
+}
+
+// remediationEnv exposes the check to the commands as environment variables
+// so shell handlers don't have to parse the JSON sent on stdin.
+func remediationEnv(check *consul.Check, attempt int) []string {
+	return []string{
+		"CONSUL_DATACENTER=" + check.Datacenter,
+		"CONSUL_NODE=" + check.Node,
+		"CONSUL_SERVICE_ID=" + check.ServiceID,
+		"CONSUL_SERVICE=" + check.ServiceName,
+		"CONSUL_CHECK_ID=" + check.CheckID,
+		"CONSUL_CHECK=" + check.Name,
+		"CONSUL_CHECK_OUTPUT=" + check.Output,
+		"CONSUL_REMEDIATION_ATTEMPT=" + strconv.Itoa(attempt),
+	}
+}
+
+// remediationMessage reports the outcome of a remediation run, passing when
+// the handler succeeded and warning when it failed. Like the maintenance
+// notices, it's an alert of its own next to the check.
+func remediationMessage(check *consul.Check, handler string, attempt, maxAttempts int, err error, output string) notifier.Message {
+	if len(output) > maxHandlerOutput {
+		output = "...\n" + output[len(output)-maxHandlerOutput:]
+	}
+	attempts := strconv.Itoa(attempt)
+	if maxAttempts > 0 {
+		attempts += " of " + strconv.Itoa(maxAttempts)
+	}
+	message := notifier.Message{
+		Datacenter: check.Datacenter,
+		Node:       check.Node,
+		NodeMeta:   check.NodeMeta,
+		ServiceId:  check.ServiceID,
+		Service:    check.ServiceName,
+		Tags:       check.ServiceTags,
+		CheckId:    "_remediation:" + check.CheckID,
+		Check:      "Remediation of " + check.Name,
+		Status:     "passing",
+		Output:     fmt.Sprintf("Remediation %s succeeded, attempt %s.\n%s", handler, attempts, output),
+		Timestamp:  time.Now(),
+	}
+	if err != nil {
+		message.Status = "warning"
+		message.Output = fmt.Sprintf("Remediation %s failed, attempt %s: %s.\n%s", handler, attempts, err, output)
+	}
+	return message
+}
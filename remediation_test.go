@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestRemediationSkipped(t *testing.T) {
+	config := &consul.RemediationConfig{Cooldown: 300, MaxAttempts: 3}
+	now := time.Now()
+	cases := []struct {
+		handler  consul.RemediationHandler
+		previous consul.RemediationAttempts
+		skipped  string
+	}{
+		{consul.RemediationHandler{}, consul.RemediationAttempts{}, ""},
+		{consul.RemediationHandler{}, consul.RemediationAttempts{Count: 1, Last: now.Add(-time.Minute)}, "cooling down"},
+		{consul.RemediationHandler{Cooldown: 30}, consul.RemediationAttempts{Count: 1, Last: now.Add(-time.Minute)}, ""},
+		{consul.RemediationHandler{}, consul.RemediationAttempts{Count: 3, Last: now.Add(-time.Hour)}, "3 of 3 attempts made"},
+		{consul.RemediationHandler{MaxAttempts: 5}, consul.RemediationAttempts{Count: 3, Last: now.Add(-time.Hour)}, ""},
+	}
+	for _, c := range cases {
+		skipped := remediationSkipped(config, c.handler, c.previous, now)
+		if (c.skipped == "") != (skipped == "") || !strings.HasPrefix(skipped, c.skipped) {
+			t.Errorf("expected %q for %+v %+v, got %q", c.skipped, c.handler, c.previous, skipped)
+		}
+	}
+}
+
+func TestResetRemediationAttempts(t *testing.T) {
+	config := &consul.RemediationConfig{Cooldown: 300}
+	recent, old := consul.RemediationHandler{Command: "restart-web.sh"}, consul.RemediationHandler{Command: "restart-db.sh"}
+	now := time.Now()
+	attempts := map[string]consul.RemediationAttempts{
+		recent.Id(): {Count: 2, Last: now.Add(-time.Minute)},
+		old.Id():    {Count: 3, Last: now.Add(-time.Hour)},
+	}
+	reset, changed := resetRemediationAttempts(config, []consul.RemediationHandler{recent, old}, attempts, now)
+	if !changed || len(reset) != 1 || reset[recent.Id()].Count != 0 || !reset[recent.Id()].Last.Equal(attempts[recent.Id()].Last) {
+		t.Errorf("expected the recent run to be kept for the cooldown, got %v", reset)
+	}
+	if _, changed := resetRemediationAttempts(config, []consul.RemediationHandler{recent}, reset, now); changed {
+		t.Error("reset attempts should not be saved again")
+	}
+}
+
+func TestRemediationMessage(t *testing.T) {
+	check := &consul.Check{Node: "web1", ServiceID: "web", ServiceName: "web", CheckID: "http", Name: "HTTP"}
+	message := remediationMessage(check, "restart-web.sh", 1, 3, nil, "restarted")
+	if message.Status != "passing" || message.CheckId != "_remediation:http" || !strings.Contains(message.Output, "attempt 1 of 3") {
+		t.Errorf("unexpected message %+v", message)
+	}
+	message = remediationMessage(check, "restart-web.sh", 2, 3, errors.New("exit status 1"), "no such unit")
+	if message.Status != "warning" || !strings.Contains(message.Output, "failed, attempt 2 of 3: exit status 1") {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
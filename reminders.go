@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"time"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// reminderInterval is how often the reminders, the changes held by a profile
+// change threshold, the stale checks and the digests are checked.
+const reminderInterval = time.Minute
+
+// scheduleRand is seeded per instance, the global source isn't seeded so
+// every instance would draw the same delays.
+var scheduleRand = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())<<32))
+
+// scheduleShare is the share of the jitter this instance sends its reminders
+// and digests after they are due, so the instances don't all send them at
+// the same poll.
+var scheduleShare = scheduleRand.Float64()
+
+// runReminders sends the reminders of the checks still failing after their
+// profile reminder interval. It also resumes the check processing for the
+// pending changes of the profiles with a longer change threshold than the
+// checks one, which no check change would otherwise pick up, notifies the
+// stale checks, sends the due digests and remediates the checks still
+// critical. Each run is delayed by a random jitter, and the reminders and
+// digests are sent after a per instance offset, so the instances of several
+// datacenters don't all send at once.
+func runReminders() {
+	for {
+		time.Sleep(reminderInterval + randomDelay(scheduleJitter()))
+		if !consulClient.ChecksEnabled() {
+			continue
+		}
+		if shard == nil && !leaderCandidate.IsLeader() {
+			continue
+		}
+
+		if stopping() {
+			return
+		}
+
+		if heldChanges(time.Now()) && len(checksChannel) == 0 {
+			queueChecks(nil)
+		}
+		sendReminders()
+		notifyStale(time.Now())
+		sendDigests()
+		remediateFailing()
+	}
+}
+
+// randomDelay returns a random delay under max, bounded to the reminder
+// interval so the runs don't overlap.
+func randomDelay(max time.Duration) time.Duration {
+	if max > reminderInterval {
+		max = reminderInterval
+	}
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(scheduleRand.Int63n(int64(max)))
+}
+
+// scheduleTime is the time the reminders and digests are due at for this
+// instance, now less its share of the jitter.
+func scheduleTime(now time.Time, jitter time.Duration) time.Time {
+	if jitter > reminderInterval {
+		jitter = reminderInterval
+	}
+	if jitter <= 0 {
+		return now
+	}
+	return now.Add(-time.Duration(scheduleShare * float64(jitter)))
+}
+
+// scheduleJitter is the configured schedule jitter.
+func scheduleJitter() time.Duration {
+	return time.Duration(consulClient.ScheduleJitter()) * time.Second
+}
+
+func sendDigests() {
+	pipelineBegin()
+	defer pipelineEnd()
+	sendDueDigests(scheduleTime(time.Now(), scheduleJitter()))
+}
+
+func sendReminders() {
+	pipelineBegin()
+	defer pipelineEnd()
+	reminders, err := consulClient.DueReminders(scheduleTime(time.Now(), scheduleJitter()))
+	if err != nil {
+		log.Warnln("Unable to look up the due reminders:", err)
+		return
+	}
+	if len(reminders) > 0 {
+		log.Infof("Sending %d reminder(s).", len(reminders))
+		deliver(reminders)
+	}
+}
+
+// heldChanges returns true if a status change has been pending for longer
+// than the checks change threshold.
+func heldChanges(now time.Time) bool {
+	statuses, err := consulClient.CheckStatuses("")
+	if err != nil {
+		return false
+	}
+	threshold := time.Duration(consulClient.CheckChangeThreshold()) * time.Second
+	for _, status := range statuses {
+		if status.Pending != "" && now.Sub(status.PendingTimestamp) > threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomDelay(t *testing.T) {
+	if d := randomDelay(0); d != 0 {
+		t.Errorf("expected no delay without a jitter, got %s", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := randomDelay(10 * time.Second); d < 0 || d >= 10*time.Second {
+			t.Fatalf("expected a delay under 10s, got %s", d)
+		}
+		if d := randomDelay(time.Hour); d >= reminderInterval {
+			t.Fatalf("expected a delay under the reminder interval, got %s", d)
+		}
+	}
+}
+
+func TestScheduleTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if at := scheduleTime(now, 0); !at.Equal(now) {
+		t.Errorf("expected no offset without a jitter, got %s", at)
+	}
+	if at := scheduleTime(now, 30*time.Second); at.After(now) || !at.After(now.Add(-30*time.Second)) {
+		t.Errorf("expected an offset under 30s, got %s", now.Sub(at))
+	}
+	if at := scheduleTime(now, time.Hour); !at.After(now.Add(-reminderInterval)) {
+		t.Errorf("expected an offset under the reminder interval, got %s", now.Sub(at))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// routeMessages sends the messages matching a route through its notifiers and
+// receivers, and the others through the default notifiers. When notifiers is
+// not empty, only these notifiers are used.
+func routeMessages(messages notifier.Messages, notifiers []string) {
+	routes := consulClient.Routes()
+	unrouted, routed := groupByRoute(routes, messages, time.Now())
+	if len(unrouted) > 0 {
+		if route, ok := limitNotifiers(consul.Route{}, notifiers); ok {
+			sendMessagesTo(unrouted, route)
+		}
+	}
+	for i, group := range routed {
+		if len(group) == 0 {
+			continue
+		}
+		if route, ok := limitNotifiers(routes[i], notifiers); ok {
+			sendMessagesTo(group, route)
+		} else {
+			log.Infof("No notifier of route %d is allowed by the profile, %d alert(s) not sent.", i, len(group))
+		}
+	}
+}
+
+// limitNotifiers restricts the notifiers of a route to notifiers. ok is false
+// when none of the route notifiers is left.
+func limitNotifiers(route consul.Route, notifiers []string) (limited consul.Route, ok bool) {
+	if len(notifiers) == 0 {
+		return route, true
+	}
+	if len(route.Notifiers) == 0 {
+		route.Notifiers = notifiers
+		return route, true
+	}
+	var allowed []string
+	for _, name := range route.Notifiers {
+		for _, n := range notifiers {
+			if n == name {
+				allowed = append(allowed, name)
+				break
+			}
+		}
+	}
+	route.Notifiers = allowed
+	return route, len(allowed) > 0
+}
+
+type notifierGroup struct {
+	notifiers []string
+	messages  notifier.Messages
+}
+
+// groupByNotifiers groups the messages having the same notifiers, in the
+// order they first appear.
+func groupByNotifiers(messages notifier.Messages, notifiers [][]string) []notifierGroup {
+	var groups []notifierGroup
+	index := make(map[string]int)
+	for i, message := range messages {
+		key := strings.Join(notifiers[i], ",")
+		if j, found := index[key]; found {
+			groups[j].messages = append(groups[j].messages, message)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, notifierGroup{notifiers[i], notifier.Messages{message}})
+	}
+	return groups
+}
+
+// groupByRoute groups the messages by the first route matching them at now.
+// routed has a group for each route.
+func groupByRoute(routes []consul.Route, messages notifier.Messages, now time.Time) (unrouted notifier.Messages, routed []notifier.Messages) {
+	routed = make([]notifier.Messages, len(routes))
+	for _, message := range messages {
+		check := &consul.Check{
+			Node:        message.Node,
+			NodeMeta:    message.NodeMeta,
+			ServiceID:   message.ServiceId,
+			ServiceName: message.Service,
+			ServiceTags: message.Tags,
+			CheckID:     message.CheckId,
+			Name:        message.Check,
+		}
+		matched := false
+		for i := range routes {
+			if routes[i].Matches(check, now) {
+				routed[i] = append(routed[i], message)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unrouted = append(unrouted, message)
+		}
+	}
+	return unrouted, routed
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+func TestLimitNotifiers(t *testing.T) {
+	if route, ok := limitNotifiers(consul.Route{}, []string{"email"}); !ok || len(route.Notifiers) != 1 {
+		t.Errorf("a route with every notifier should use the profile ones, got %v", route.Notifiers)
+	}
+	route, ok := limitNotifiers(consul.Route{Notifiers: []string{"slack", "email"}}, []string{"email", "log"})
+	if !ok || len(route.Notifiers) != 1 || route.Notifiers[0] != "email" {
+		t.Errorf("expected only email, got %v", route.Notifiers)
+	}
+	if _, ok := limitNotifiers(consul.Route{Notifiers: []string{"slack"}}, []string{"email"}); ok {
+		t.Error("expected no notifier to be left")
+	}
+}
+
+func TestGroupByNotifiers(t *testing.T) {
+	messages := notifier.Messages{{Node: "a"}, {Node: "b"}, {Node: "c"}}
+	groups := groupByNotifiers(messages, [][]string{{"email"}, nil, {"email"}})
+	if len(groups) != 2 || len(groups[0].messages) != 2 || groups[0].messages[1].Node != "c" || len(groups[1].notifiers) != 0 {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+}
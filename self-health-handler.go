@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+type selfHealth struct {
+	Status    string            `json:"status"`
+	Leader    bool              `json:"leader"`
+	LeaderId  string            `json:"leaderNode"`
+	Consul    string            `json:"consul"`
+	Watchers  map[string]bool   `json:"watchers"`
+	Notifiers map[string]string `json:"notifiers"`
+	Shards    []string          `json:"shardMembers,omitempty"`
+}
+
+// selfHealthHandler reports the health of consul-alerts itself. It returns 503
+// when consul is unreachable, a watcher has stopped, or an enabled notifier is
+// misconfigured.
+func selfHealthHandler(w http.ResponseWriter, r *http.Request) {
+	health := currentSelfHealth()
+	code := 200
+	if health.Status != "ok" {
+		code = 503
+	}
+	writeJson(w, code, health)
+}
+
+func currentSelfHealth() selfHealth {
+	health := selfHealth{
+		Status:    "ok",
+		Consul:    "ok",
+		Watchers:  watcherStatus(),
+		Notifiers: notifierStatus(consulClient),
+	}
+
+	if err := consulClient.Ping(); err != nil {
+		health.Consul = err.Error()
+		health.Status = "unhealthy"
+	} else {
+		health.LeaderId = leaderCandidate.Leader()
+		health.Leader = leaderCandidate.IsLeader()
+	}
+	if shard != nil {
+		health.Shards = shard.Members()
+	}
+	for _, running := range health.Watchers {
+		if !running {
+			health.Status = "unhealthy"
+		}
+	}
+	for _, status := range health.Notifiers {
+		if status != "ok" {
+			health.Status = "unhealthy"
+		}
+	}
+	return health
+}
+
+// readyHandler reports whether consul-alerts can process checks, that is it
+// isn't shutting down, consul is reachable and a consul-alerts leader has been
+// elected.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	if stopping() {
+		writeJson(w, 503, map[string]string{"status": "not ready", "reason": "shutting down"})
+		return
+	}
+	if err := consulClient.Ping(); err != nil {
+		writeJson(w, 503, map[string]string{"status": "not ready", "reason": err.Error()})
+		return
+	}
+	if leaderCandidate.Leader() == "" {
+		writeJson(w, 503, map[string]string{"status": "not ready", "reason": "no consul-alerts leader"})
+		return
+	}
+	writeJson(w, 200, map[string]string{"status": "ready"})
+}
+
+// livezHandler reports whether the main loops are making progress. It doesn't
+// depend on consul so a consul outage doesn't restart every instance.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	if stalled := stalledLoops(time.Now()); len(stalled) > 0 {
+		writeJson(w, 503, map[string]interface{}{"status": "stalled", "loops": stalled})
+		return
+	}
+	writeJson(w, 200, map[string]string{"status": "alive"})
+}
+
+type validator interface {
+	Validate() error
+}
+
+// notifierStatus validates the builtin notifiers and the notifier instances
+// by name.
+func notifierStatus(client consul.Consul) map[string]string {
+	validators := map[string]validator{
+		"email":     client.EmailConfig(),
+		"log":       client.LogConfig(),
+		"influxdb":  client.InfluxdbConfig(),
+		"slack":     client.SlackConfig(),
+		"pagerduty": client.PagerDutyConfig(),
+		"hipchat":   client.HipChatConfig(),
+		"victorops": client.VictorOpsConfig(),
+		"zulip":     client.ZulipConfig(),
+		"discord":   client.DiscordConfig(),
+		"nats":      client.NatsConfig(),
+		"mqtt":      client.MqttConfig(),
+		"splunk":    client.SplunkConfig(),
+		"nrdp":      client.NrdpConfig(),
+		"zabbix":    client.ZabbixConfig(),
+		"sentry":    client.SentryConfig(),
+		"aws":       client.AwsConfig(),
+		"pubsub":    client.PubsubConfig(),
+	}
+	for name, instance := range client.NotifierInstances() {
+		validators[name] = typeValidator(instance.Notifiers, instance.Type)
+	}
+	status := make(map[string]string, len(validators))
+	for name, validator := range validators {
+		if err := validator.Validate(); err != nil {
+			status[name] = err.Error()
+		} else {
+			status[name] = "ok"
+		}
+	}
+	return status
+}
+
+// typeValidator returns the config of a notifier type.
+func typeValidator(config *consul.NotifiersConfig, notifierType string) validator {
+	switch notifierType {
+	case "email":
+		return config.Email
+	case "log":
+		return config.Log
+	case "influxdb":
+		return config.Influxdb
+	case "slack":
+		return config.Slack
+	case "pagerduty":
+		return config.PagerDuty
+	case "victorops":
+		return config.VictorOps
+	case "zulip":
+		return config.Zulip
+	case "discord":
+		return config.Discord
+	case "nats":
+		return config.Nats
+	case "mqtt":
+		return config.Mqtt
+	case "splunk":
+		return config.Splunk
+	case "nrdp":
+		return config.Nrdp
+	case "zabbix":
+		return config.Zabbix
+	case "sentry":
+		return config.Sentry
+	case "aws":
+		return config.Aws
+	case "pubsub":
+		return config.Pubsub
+	default:
+		return config.HipChat
+	}
+}
+
+func writeJson(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
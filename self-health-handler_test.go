@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"net/http/httptest"
+)
+
+func TestLivezHandler(t *testing.T) {
+	loops.Lock()
+	loops.alive = map[string]time.Time{"checks processing": time.Now()}
+	loops.Unlock()
+	defer func() {
+		loops.Lock()
+		loops.alive = make(map[string]time.Time)
+		loops.Unlock()
+	}()
+
+	w := httptest.NewRecorder()
+	livezHandler(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != 200 {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	loops.Lock()
+	loops.alive["events processing"] = time.Now().Add(-loopStallTimeout - time.Minute)
+	loops.Unlock()
+	w = httptest.NewRecorder()
+	livezHandler(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != 503 || w.Body.String() != `{"loops":["events processing"],"status":"stalled"}` {
+		t.Errorf("expected the stalled events processing, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestReadyHandlerStopping(t *testing.T) {
+	pipeline.stopping = true
+	defer func() { pipeline.stopping = false }()
+
+	w := httptest.NewRecorder()
+	readyHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 503 {
+		t.Errorf("expected 503 while shutting down, got %d", w.Code)
+	}
+}
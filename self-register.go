@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// selfCheckTTL is the TTL of the self registration check, it is updated three
+// times within it.
+const selfCheckTTL = 30 * time.Second
+
+// loopStallTimeout is how long a main loop can go without reporting before
+// the self registration check turns critical. It covers the longest blocking
+// query and a slow notification.
+const loopStallTimeout = 10 * time.Minute
+
+// loopIdleInterval is how often an idle loop reports that it is alive.
+const loopIdleInterval = 30 * time.Second
+
+// selfServiceId is the id of the consul-alerts service registered by this
+// instance, empty when it isn't registered.
+var selfServiceId string
+
+// loops tracks when each main loop last reported it was alive.
+var loops = struct {
+	sync.Mutex
+	alive map[string]time.Time
+}{alive: make(map[string]time.Time)}
+
+// loopAlive records that the named loop is still making progress.
+func loopAlive(name string) {
+	loops.Lock()
+	defer loops.Unlock()
+	loops.alive[name] = time.Now()
+}
+
+// stalledLoops returns the loops that haven't reported within
+// loopStallTimeout of now, sorted by name.
+func stalledLoops(now time.Time) []string {
+	loops.Lock()
+	defer loops.Unlock()
+	var stalled []string
+	for name, alive := range loops.alive {
+		if now.Sub(alive) > loopStallTimeout {
+			stalled = append(stalled, name)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
+
+// selfCheckStatus returns the status and note of the self registration check.
+func selfCheckStatus(now time.Time) (string, string) {
+	if stalled := stalledLoops(now); len(stalled) > 0 {
+		return "critical", "Stalled: " + strings.Join(stalled, ", ")
+	}
+	return "passing", "All loops are running."
+}
+
+// registerSelf registers this instance as a consul-alerts service with the
+// port of the api address, then keeps its TTL check updated.
+func registerSelf(id, addr string) error {
+	_, portValue, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid api address %s: %s", addr, err)
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return fmt.Errorf("invalid api address %s: %s", addr, err)
+	}
+	checkId, err := consulClient.RegisterSelf(id, port, selfCheckTTL)
+	if err != nil {
+		return err
+	}
+	selfServiceId = id
+	log.Infoln("Registered as consul service", id)
+	go runSelfCheck(checkId)
+	return nil
+}
+
+// runSelfCheck updates the self registration check until consul-alerts shuts
+// down.
+func runSelfCheck(checkId string) {
+	for {
+		time.Sleep(selfCheckTTL / 3)
+		status, note := selfCheckStatus(time.Now())
+		if status != "passing" {
+			log.Errorln("Self registration check is failing.", note)
+		}
+		if err := consulClient.UpdateSelfCheck(checkId, status, note); err != nil {
+			log.Warnln("Unable to update the self registration check:", err)
+		}
+	}
+}
+
+// deregisterSelf removes the service registered by registerSelf.
+func deregisterSelf() {
+	if selfServiceId == "" {
+		return
+	}
+	if err := consulClient.DeregisterSelf(selfServiceId); err != nil {
+		log.Warnln("Unable to deregister the consul-alerts service:", err)
+	}
+}
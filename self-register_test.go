@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelfCheckStatus(t *testing.T) {
+	now := time.Now()
+	loops.Lock()
+	loops.alive = map[string]time.Time{
+		"checks watcher":    now.Add(-time.Minute),
+		"checks processing": now.Add(-time.Minute),
+	}
+	loops.Unlock()
+	defer func() {
+		loops.Lock()
+		loops.alive = make(map[string]time.Time)
+		loops.Unlock()
+	}()
+
+	if status, note := selfCheckStatus(now); status != "passing" {
+		t.Errorf("expected passing, got %s: %s", status, note)
+	}
+
+	later := now.Add(loopStallTimeout + 30*time.Second)
+	loops.Lock()
+	loops.alive["checks watcher"] = later
+	loops.Unlock()
+	status, note := selfCheckStatus(later)
+	if status != "critical" || note != "Stalled: checks processing" {
+		t.Errorf("expected the checks processing to be stalled, got %s: %s", status, note)
+	}
+}
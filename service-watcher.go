@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// runServiceWatcher polls the catalog and alerts when a monitored service is
+// registered or deregistered, or has fewer healthy instances than its
+// minimum. A deregistered service has no checks left, so the checks watcher
+// can't report it. The minimums are enforced even when the registration
+// alerts are disabled, and regardless of the checks change threshold.
+func runServiceWatcher() {
+	setWatcherRunning("services", true)
+	defer setWatcherRunning("services", false)
+
+	log.Infoln("Starting services watcher.")
+	var instances map[string]int
+	below := make(map[string]bool)
+	for {
+		config := consulClient.ServicesConfig()
+		interval := time.Duration(config.Interval) * time.Second
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		if !config.Enabled {
+			// the alerts start from the services found once enabled again
+			instances = nil
+		}
+		if !config.Enabled && len(config.MinHealthy) == 0 {
+			time.Sleep(interval)
+			continue
+		}
+
+		current, err := serviceInstances(config)
+		if err != nil {
+			log.Warnf("Unable to list the services, retrying in %s: %s", interval, err)
+			time.Sleep(interval)
+			continue
+		}
+		var messages notifier.Messages
+		if config.Enabled && instances != nil {
+			messages = serviceMessages(instances, current)
+		}
+		var instanceMessages notifier.Messages
+		instanceMessages, below = minHealthyMessages(below, current, config.MinHealthy)
+		messages = append(messages, instanceMessages...)
+		if len(messages) > 0 {
+			sort.SliceStable(messages, func(i, j int) bool { return messages[i].Service < messages[j].Service })
+			notifyServiceChanges(messages)
+		}
+		if config.Enabled {
+			instances = current
+		}
+		time.Sleep(interval)
+	}
+}
+
+// serviceInstances returns the registered monitored services with their
+// healthy instance count. The instances are only counted for the services
+// with a minimum, which are watched even when not in the monitored ones.
+func serviceInstances(config *consul.ServicesConfig) (map[string]int, error) {
+	services, err := consulClient.Services()
+	if err != nil {
+		return nil, err
+	}
+	monitored := make(map[string]bool, len(config.Monitored))
+	for _, service := range config.Monitored {
+		monitored[service] = true
+	}
+	instances := make(map[string]int)
+	for _, service := range services {
+		if len(monitored) > 0 && !monitored[service] && config.MinHealthy[service] == 0 {
+			continue
+		}
+		instances[service] = 0
+		if config.MinHealthy[service] > 0 {
+			if instances[service], err = consulClient.HealthyInstances(service); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return instances, nil
+}
+
+// serviceMessages returns an alert for each service registered or
+// deregistered between the previous and current polls, sorted by service.
+func serviceMessages(previous, current map[string]int) notifier.Messages {
+	var messages notifier.Messages
+	for service := range current {
+		if _, found := previous[service]; !found {
+			messages = append(messages, serviceMessage(service, "service-registration", "Service registration", "passing",
+				fmt.Sprintf("Service %s is registered.", service)))
+		}
+	}
+	for service := range previous {
+		if _, found := current[service]; !found {
+			messages = append(messages, serviceMessage(service, "service-registration", "Service registration", "critical",
+				fmt.Sprintf("Service %s is no longer registered.", service)))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Service < messages[j].Service })
+	return messages
+}
+
+// minHealthyMessages returns an alert for each service falling below or
+// getting back to its minimum healthy instance count, and the services now
+// below it. The services already below their minimum on the first poll are
+// alerted. A deregistered service is below any minimum.
+func minHealthyMessages(below map[string]bool, current, minHealthy map[string]int) (notifier.Messages, map[string]bool) {
+	var messages notifier.Messages
+	nowBelow := make(map[string]bool)
+	for service, minimum := range minHealthy {
+		if minimum <= 0 {
+			continue
+		}
+		healthy := current[service]
+		switch {
+		case healthy < minimum:
+			nowBelow[service] = true
+			if !below[service] {
+				messages = append(messages, serviceMessage(service, "service-instances", "Healthy instances", "critical",
+					fmt.Sprintf("Service %s has %d healthy instance(s), below the minimum of %d.", service, healthy, minimum)))
+			}
+		case below[service]:
+			messages = append(messages, serviceMessage(service, "service-instances", "Healthy instances", "passing",
+				fmt.Sprintf("Service %s has %d healthy instance(s), the minimum is %d.", service, healthy, minimum)))
+		}
+	}
+	// a service no longer having a minimum is back to normal
+	for service := range below {
+		if minHealthy[service] <= 0 {
+			messages = append(messages, serviceMessage(service, "service-instances", "Healthy instances", "passing",
+				fmt.Sprintf("Service %s no longer has a minimum of healthy instances.", service)))
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Service < messages[j].Service })
+	return messages, nowBelow
+}
+
+func serviceMessage(service, checkId, check, status, output string) notifier.Message {
+	return notifier.Message{
+		ServiceId: service,
+		Service:   service,
+		CheckId:   checkId,
+		Check:     check,
+		Status:    status,
+		Output:    output,
+		Timestamp: time.Now(),
+	}
+}
+
+// notifyServiceChanges sends the service alerts. The catalog is the same on
+// every agent so only the leader notifies.
+func notifyServiceChanges(messages notifier.Messages) {
+	if leaderCandidate != nil && !leaderCandidate.IsLeader() {
+		log.Debugf("Currently not the leader. Ignoring %d service change(s).", len(messages))
+		return
+	}
+	for _, message := range messages {
+		log.WithField("service", message.Service).Infoln(message.Output)
+	}
+	sendMessages(messages)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+type expectedMessage struct{ service, check, status, output string }
+
+func checkMessages(t *testing.T, messages notifier.Messages, expected []expectedMessage) {
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %v", len(expected), messages)
+	}
+	for i, e := range expected {
+		m := messages[i]
+		if m.Service != e.service || m.CheckId != e.check || m.Status != e.status || m.Output != e.output {
+			t.Errorf("unexpected message %d: %+v", i, m)
+		}
+	}
+}
+
+func TestServiceMessages(t *testing.T) {
+	previous := map[string]int{"web": 3, "api": 1, "db": 0, "cache": 2}
+	current := map[string]int{"web": 1, "api": 2, "db": 0, "queue": 0}
+
+	checkMessages(t, serviceMessages(previous, current), []expectedMessage{
+		{"cache", "service-registration", "critical", "Service cache is no longer registered."},
+		{"queue", "service-registration", "passing", "Service queue is registered."},
+	})
+}
+
+func TestMinHealthyMessages(t *testing.T) {
+	minHealthy := map[string]int{"web": 2, "api": 2, "db": 1}
+	below := map[string]bool{"api": true, "cache": true}
+	current := map[string]int{"web": 1, "api": 2}
+
+	messages, nowBelow := minHealthyMessages(below, current, minHealthy)
+	checkMessages(t, messages, []expectedMessage{
+		{"api", "service-instances", "passing", "Service api has 2 healthy instance(s), the minimum is 2."},
+		{"cache", "service-instances", "passing", "Service cache no longer has a minimum of healthy instances."},
+		{"db", "service-instances", "critical", "Service db has 0 healthy instance(s), below the minimum of 1."},
+		{"web", "service-instances", "critical", "Service web has 1 healthy instance(s), below the minimum of 2."},
+	})
+	if len(nowBelow) != 2 || !nowBelow["web"] || !nowBelow["db"] {
+		t.Errorf("unexpected services below their minimum %v", nowBelow)
+	}
+
+	messages, _ = minHealthyMessages(nowBelow, current, minHealthy)
+	if len(messages) != 0 {
+		t.Errorf("the services still below their minimum should not be alerted again, got %v", messages)
+	}
+}
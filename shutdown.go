@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"net/http"
+)
+
+// defaultShutdownTimeout is how long the shutdown waits for the queued checks
+// and events and the notifications being sent.
+const defaultShutdownTimeout = 30 * time.Second
+
+// pipeline counts the queued and running check processings, events and
+// reminders. Once stopping, the watch posts are refused and the shutdown waits
+// for the count to drop to 0.
+var pipeline = struct {
+	sync.Mutex
+	active   int
+	stopping bool
+}{}
+
+func pipelineBegin() {
+	pipeline.Lock()
+	defer pipeline.Unlock()
+	pipeline.active++
+}
+
+func pipelineEnd() {
+	pipeline.Lock()
+	defer pipeline.Unlock()
+	pipeline.active--
+}
+
+func pipelineActive() int {
+	pipeline.Lock()
+	defer pipeline.Unlock()
+	return pipeline.active
+}
+
+// stopping returns true once the shutdown has started.
+func stopping() bool {
+	pipeline.Lock()
+	defer pipeline.Unlock()
+	return pipeline.stopping
+}
+
+// drainPipeline refuses the new checks and events and waits until the accepted
+// ones are processed. It returns the number still running at the deadline.
+func drainPipeline(timeout time.Duration) int {
+	pipeline.Lock()
+	pipeline.stopping = true
+	pipeline.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		active := pipelineActive()
+		if active == 0 || !time.Now().Before(deadline) {
+			return active
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// refuseWhenStopping answers 503 to the watch posts once the shutdown has
+// started so the watch retries against another instance or after the restart.
+func refuseWhenStopping(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if stopping() {
+			writeJson(w, 503, map[string]string{"error": "shutting down"})
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestDrainPipeline(t *testing.T) {
+	defer func() { pipeline.stopping = false }()
+
+	pipelineBegin()
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		pipelineEnd()
+	}()
+	if remaining := drainPipeline(5 * time.Second); remaining != 0 {
+		t.Errorf("expected the pipeline to drain, %d remaining", remaining)
+	}
+	if !stopping() {
+		t.Error("the pipeline should be stopping")
+	}
+
+	pipelineBegin()
+	defer pipelineEnd()
+	if remaining := drainPipeline(100 * time.Millisecond); remaining != 1 {
+		t.Errorf("expected 1 remaining at the deadline, got %d", remaining)
+	}
+}
+
+func TestRefuseWhenStopping(t *testing.T) {
+	defer func() { pipeline.stopping = false }()
+	handler := refuseWhenStopping(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) })
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("PUT", "/v1/process/checks", nil))
+	if recorder.Code != 200 {
+		t.Errorf("expected 200 before the shutdown, got %d", recorder.Code)
+	}
+
+	pipeline.stopping = true
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("PUT", "/v1/process/checks", nil))
+	if recorder.Code != 503 {
+		t.Errorf("expected 503 while shutting down, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+// silenceMode creates a silence of the checks matching the node, service and
+// check patterns.
+func silenceMode(arguments map[string]interface{}) {
+	silence, err := cliSilence(stringOption(arguments, "--node", ""), stringOption(arguments, "--service", ""),
+		stringOption(arguments, "--check", ""), stringOption(arguments, "--duration", ""),
+		stringOption(arguments, "--comment", ""), time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	if err := consulClient.CreateSilence(&silence); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to create the silence:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Silence %s created until %s.\n", silence.ID, silence.EndsAt.Format(time.RFC3339))
+}
+
+func cliSilence(node, service, check, duration, comment string, now time.Time) (consul.Silence, error) {
+	silence := consul.Silence{Node: node, Service: service, Check: check, CreatedBy: cliUser(), Comment: comment, StartsAt: now}
+	length, err := time.ParseDuration(duration)
+	if err != nil || length <= 0 {
+		return silence, fmt.Errorf("invalid duration %q, expected a duration like 30m or 2h", duration)
+	}
+	silence.EndsAt = now.Add(length)
+	return silence, silence.Validate()
+}
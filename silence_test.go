@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCliSilence(t *testing.T) {
+	now := time.Now()
+	silence, err := cliSilence("", "redis", "", "2h", "upgrade", now)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if silence.Service != "redis" || !silence.EndsAt.Equal(now.Add(2*time.Hour)) || silence.Comment != "upgrade" {
+		t.Errorf("unexpected silence %+v", silence)
+	}
+	if _, err := cliSilence("", "redis", "", "soon", "", now); err == nil {
+		t.Error("an invalid duration should be rejected")
+	}
+	if _, err := cliSilence("", "", "", "2h", "", now); err == nil {
+		t.Error("a silence without pattern should be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"encoding/json"
+	"net/http"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+type silenceRequest struct {
+	consul.Silence
+	Duration string `json:"duration"`
+}
+
+// silencesHandler manages silences:
+//
+//	GET    /v1/silences       lists the silences
+//	POST   /v1/silences       creates a silence
+//	DELETE /v1/silences/{id}  removes a silence
+func silencesHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/silences"), "/")
+
+	switch {
+	case r.Method == "GET" && id == "":
+		silences, err := consulClient.Silences()
+		if err != nil {
+			writeJson(w, 503, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJson(w, 200, silences)
+
+	case r.Method == "POST" && id == "":
+		var req silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJson(w, 400, map[string]string{"error": err.Error()})
+			return
+		}
+		silence := req.Silence
+		if req.Duration != "" {
+			duration, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				writeJson(w, 400, map[string]string{"error": err.Error()})
+				return
+			}
+			if silence.StartsAt.IsZero() {
+				silence.StartsAt = time.Now()
+			}
+			silence.EndsAt = silence.StartsAt.Add(duration)
+		}
+		if err := consulClient.CreateSilence(&silence); err != nil {
+			writeJson(w, 400, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJson(w, 201, silence)
+
+	case r.Method == "DELETE" && id != "":
+		if err := consulClient.DeleteSilence(id); err != nil {
+			writeJson(w, 503, map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(204)
+
+	default:
+		w.WriteHeader(405)
+	}
+}
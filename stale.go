@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// notifyStale sends a warning for each check that became stale and a passing
+// notice for each stale check that changed again.
+func notifyStale(now time.Time) {
+	pipelineBegin()
+	defer pipelineEnd()
+	changes, err := consulClient.StaleChanges(now)
+	if err != nil {
+		log.Warnln("Unable to look up the stale checks:", err)
+	}
+	if len(changes) > 0 {
+		routeMessages(staleMessages(changes, now), nil)
+	}
+}
+
+func staleMessages(changes []consul.StaleChange, now time.Time) notifier.Messages {
+	messages := make(notifier.Messages, len(changes))
+	for i, change := range changes {
+		message := notifier.Message{
+			Datacenter: change.Datacenter,
+			Namespace:  change.Namespace,
+			Node:       change.Node,
+			NodeMeta:   change.NodeMeta,
+			ServiceId:  change.ServiceID,
+			Service:    change.ServiceName,
+			Tags:       change.ServiceTags,
+			CheckId:    "_stale:" + change.CheckID,
+			Check:      change.Name + " (stale)",
+			Timestamp:  now,
+		}
+		if change.Stale {
+			message.Status = "warning"
+			message.Output = fmt.Sprintf("The check hasn't changed for %s, its last status was %s: %s",
+				now.Sub(change.Since).Truncate(time.Second), change.Status, change.Output)
+		} else {
+			message.Status = "passing"
+			message.Output = fmt.Sprintf("The check is updated again, its status is %s: %s", change.Status, change.Output)
+		}
+		messages[i] = message
+	}
+	return messages
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+func TestStaleMessages(t *testing.T) {
+	now := time.Now()
+	check := consul.Check{Node: "web1", CheckID: "disk", Name: "Disk", Status: "passing", Output: "82% used"}
+	messages := staleMessages([]consul.StaleChange{
+		{Check: check, Stale: true, Since: now.Add(-20 * time.Minute)},
+		{Check: check, Stale: false},
+	}, now)
+
+	if messages[0].Status != "warning" || messages[0].CheckId != "_stale:disk" || messages[0].Check != "Disk (stale)" {
+		t.Errorf("unexpected stale message %+v", messages[0])
+	}
+	if !strings.Contains(messages[0].Output, "20m0s") || !strings.Contains(messages[0].Output, "82% used") {
+		t.Errorf("unexpected stale output %q", messages[0].Output)
+	}
+	if messages[1].Status != "passing" {
+		t.Errorf("expected a passing notice, got %s", messages[1].Status)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"encoding/json"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+)
+
+// exportStateMode writes a JSON snapshot of the check states, the
+// acknowledgements, the reminder times and the silences to a file, or to
+// stdout.
+func exportStateMode(arguments map[string]interface{}) {
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	snapshot, err := consulClient.ExportState()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to read the state:", err)
+		os.Exit(3)
+	}
+	data, _ := json.MarshalIndent(snapshot, "", "  ")
+	file, _ := arguments["<file>"].(string)
+	if file == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := ioutil.WriteFile(file, append(data, '\n'), 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to write the state:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d check state(s) and %d silence(s) to %s.\n", len(snapshot.Checks), len(snapshot.Silences), file)
+}
+
+// importStateMode stores the check states and the silences of a snapshot
+// read from a file, or from stdin.
+func importStateMode(arguments map[string]interface{}) {
+	var input io.Reader = os.Stdin
+	if file, _ := arguments["<file>"].(string); file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to read the state:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	}
+	var snapshot consul.StateSnapshot
+	if err := json.NewDecoder(input).Decode(&snapshot); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid state snapshot:", err)
+		os.Exit(1)
+	}
+
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	if err := consulClient.ImportState(&snapshot); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to import the state:", err)
+		os.Exit(3)
+	}
+	fmt.Printf("Imported %d check state(s) and %d silence(s).\n", len(snapshot.Checks), len(snapshot.Silences))
+}
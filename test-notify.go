@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AcalephStorage/consul-alerts/notifier"
+)
+
+// testNotifyMode sends a synthetic alert through the configured notifiers and
+// reports the result of each one. It exits with 1 if any notifier failed.
+func testNotifyMode(arguments map[string]interface{}) {
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	message := testMessage(arguments)
+	fmt.Printf("Sending a %s test alert for %s:%s:%s\n", message.Status, message.Node, message.Service, message.Check)
+
+	failed := false
+	report := func(name string, success bool) {
+		fmt.Printf("  %-10s %s\n", name, map[bool]string{true: "ok", false: "FAILED"}[success])
+		failed = failed || !success
+	}
+
+	notifiers := builtinNotifiers()
+	customNotifiers := consulClient.CustomNotifiers()
+	if len(notifiers) == 0 && len(customNotifiers) == 0 {
+		fmt.Println("No notifier is enabled.")
+		os.Exit(1)
+	}
+	for _, n := range notifiers {
+		if email, ok := n.Notifier.(*notifier.EmailNotifier); ok {
+			resolveEmailReceivers(email)
+		}
+		report(n.name, n.Notify(outputLimits(n.name).Apply(notifier.Messages{message})))
+	}
+	for _, n := range customNotifiers {
+		err := executeHealthNotifier(outputLimits("custom").Apply(notifier.Messages{message}), n, consulClient.NotifierTimeout("custom"))
+		report(n, err == nil)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func testMessage(arguments map[string]interface{}) notifier.Message {
+	message := notifier.Message{
+		Node:      stringOption(arguments, "--node", ""),
+		Service:   stringOption(arguments, "--service", ""),
+		Check:     stringOption(arguments, "--check", ""),
+		Status:    stringOption(arguments, "--status", ""),
+		Output:    stringOption(arguments, "--output", ""),
+		Timestamp: time.Now(),
+	}
+	if message.Node == "" {
+		message.Node, _ = os.Hostname()
+	}
+	if message.Check == "" {
+		message.Check = "consul-alerts test"
+	}
+	if message.Status == "" {
+		message.Status = "critical"
+	}
+	if message.Output == "" {
+		message.Output = "This is a test notification sent by consul-alerts test-notify."
+	}
+	message.ServiceId = message.Service
+	message.CheckId = message.Check
+	return message
+}
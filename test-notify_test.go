@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestTestMessageDefaults(t *testing.T) {
+	message := testMessage(map[string]interface{}{"--service": "web"})
+	if message.Node == "" {
+		t.Error("node should default to the hostname")
+	}
+	if message.Status != "critical" || message.Check == "" || message.Output == "" {
+		t.Errorf("unexpected defaults: %+v", message)
+	}
+	if message.ServiceId != "web" || message.CheckId != message.Check {
+		t.Errorf("ids should match the names: %+v", message)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/AcalephStorage/consul-alerts/consul"
+	"github.com/AcalephStorage/consul-alerts/notifier"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// validateMode loads the configuration from consul KV and reports the values
+// that can't be parsed, the enabled notifiers missing required settings and
+// the invalid email templates. It exits with 1 if any problem is found.
+func validateMode(arguments map[string]interface{}) {
+	client, err := connectConsul(arguments)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cluster has no leader or is unreacheable.", err)
+		os.Exit(3)
+	}
+	consulClient = client
+
+	problems := configProblems(consulClient)
+	if len(problems) == 0 {
+		fmt.Println("Configuration is valid.")
+		return
+	}
+	fmt.Printf("Found %d configuration problem(s):\n", len(problems))
+	for _, problem := range problems {
+		fmt.Println("  " + problem)
+	}
+	os.Exit(1)
+}
+
+// configProblems returns the values that couldn't be loaded and validates the
+// loaded configuration of every notifier.
+func configProblems(client consul.Consul) []string {
+	var problems []string
+	for _, err := range client.ConfigErrors() {
+		problems = append(problems, err.Error())
+	}
+
+	var notifierProblems []string
+	for name, status := range notifierStatus(client) {
+		if status != "ok" {
+			notifierProblems = append(notifierProblems, fmt.Sprintf("consul-alerts/config/notifiers/%s: %s", name, status))
+		}
+	}
+	sort.Strings(notifierProblems)
+	problems = append(problems, notifierProblems...)
+	problems = append(problems, httpProblems(client)...)
+	problems = append(problems, historyProblems(client)...)
+
+	problems = append(problems, unknownNotifiers(client, "consul-alerts/config/nodes/notifiers", client.NodesConfig().Notifiers)...)
+	for i, route := range client.Routes() {
+		problems = append(problems, unknownNotifiers(client, fmt.Sprintf("consul-alerts/config/routes: route %d", i), route.Notifiers)...)
+	}
+	var profileNames []string
+	for name := range client.ProfilesConfig().Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+	for _, name := range profileNames {
+		profile := client.ProfilesConfig().Profiles[name]
+		problems = append(problems, unknownNotifiers(client, "consul-alerts/config/profiles/"+name, profile.Notifiers)...)
+	}
+
+	emailConfig := client.EmailConfig()
+	email := &notifier.EmailNotifier{
+		Template:        emailConfig.Template,
+		SubjectTemplate: emailConfig.SubjectTemplate,
+	}
+	if err := email.ValidateTemplates(); err != nil {
+		problems = append(problems, "consul-alerts/config/notifiers/email/"+err.Error())
+	}
+
+	var handlers []string
+	for _, handler := range client.EventHandlers("") {
+		if !isWebhook(handler.Command) {
+			handlers = append(handlers, handler.Command)
+		}
+	}
+	problems = append(problems, commandProblems("consul-alerts/config/events/handlers", handlers)...)
+	var keyHandlers []string
+	for _, handler := range client.KeyHandlers() {
+		if !isWebhook(handler.Command) {
+			keyHandlers = append(keyHandlers, handler.Command)
+		}
+	}
+	problems = append(problems, commandProblems("consul-alerts/config/keys/handlers", keyHandlers)...)
+	var remediationHandlers []string
+	for _, handler := range client.RemediationConfig().Handlers {
+		if !isWebhook(handler.Command) {
+			remediationHandlers = append(remediationHandlers, handler.Command)
+		}
+	}
+	problems = append(problems, commandProblems("consul-alerts/config/remediation/handlers", remediationHandlers)...)
+	problems = append(problems, commandProblems("consul-alerts/config/notifiers/custom", client.CustomNotifiers())...)
+	return problems
+}
+
+// unknownNotifiers reports the names that are not a builtin notifier or
+// "custom".
+func unknownNotifiers(client consul.Consul, key string, names []string) []string {
+	statuses := notifierStatus(client)
+	var problems []string
+	for _, name := range names {
+		if _, found := statuses[name]; !found && name != "custom" {
+			problems = append(problems, fmt.Sprintf("%s: unknown notifier %s", key, name))
+		}
+	}
+	return problems
+}
+
+// commandProblems reports the commands that can't be parsed or found.
+func commandProblems(key string, commands []string) []string {
+	var problems []string
+	for _, command := range commands {
+		args, err := parseCommand(command)
+		if err == nil {
+			_, err = exec.LookPath(args[0])
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+		}
+	}
+	return problems
+}
+
+// reloadConfig loads the config again after a KV change or a SIGHUP. The new
+// config is validated with configProblems before it's used, and the current
+// config is kept when problems are found.
+func reloadConfig() {
+	_, err := consulClient.ReloadConfig()
+	var problems *consul.ConfigProblemsError
+	switch {
+	case errors.As(err, &problems):
+		configReloads.Inc("failure")
+		log.Errorf("Configuration not reloaded, keeping the current one. Found %d problem(s): %s", len(problems.Problems), strings.Join(problems.Problems, "; "))
+		return
+	case err != nil:
+		configReloads.Inc("failure")
+		log.Errorln("Unable to reload the configuration from consul KV:", err)
+		return
+	}
+	useHistoryBackend()
+	configReloads.Inc("success")
+	log.Infoln("Configuration reloaded.")
+}
+
+// reloadOnHangup reloads the config file and the KV config on SIGHUP.
+func reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		log.Infoln("SIGHUP received, reloading the configuration.")
+		reloadConfig()
+	}
+}
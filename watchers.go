@@ -1,58 +1,156 @@
 package main
 
 import (
+	"errors"
 	"io"
-	"os"
-	"syscall"
+	"sync"
+	"time"
 
 	"encoding/json"
 	"io/ioutil"
-	"os/exec"
 
-	log "github.com/Sirupsen/logrus"
+	"github.com/AcalephStorage/consul-alerts/consul"
+
+	log "github.com/AcalephStorage/consul-alerts/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
 
-func runWatcher(address, datacenter, watchType string) {
-	consulAlert := os.Args[0]
-	cmd := exec.Command(
-		"consul", "watch",
-		"-http-addr", address,
-		"-datacenter", datacenter,
-		"-type", watchType,
-		consulAlert, "watch", watchType)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		var exitCode int
-		switch err.(type) {
-		case *exec.ExitError:
-			exitError, _ := err.(*exec.ExitError)
-			status, _ := exitError.Sys().(syscall.WaitStatus)
-			exitCode = status.ExitStatus()
-			log.Println("Shutting down watcher --> Exit Code: ", exitCode)
-		case *exec.Error:
-			exitCode = 1
-			log.Println("Shutting down watcher --> Something went wrong running consul watch: ", err.Error())
+// watchRetryInterval is the delay before retrying a failed blocking query.
+const watchRetryInterval = 5 * time.Second
+
+// datacentersRefreshInterval is how often the watched datacenters are matched
+// against the configured ones.
+const datacentersRefreshInterval = time.Minute
+
+// watchers tracks which watchers are currently running.
+var watchers = struct {
+	sync.Mutex
+	running map[string]bool
+}{running: make(map[string]bool)}
+
+func setWatcherRunning(watchType string, running bool) {
+	watchers.Lock()
+	defer watchers.Unlock()
+	watchers.running[watchType] = running
+}
+
+func watcherStatus() map[string]bool {
+	watchers.Lock()
+	defer watchers.Unlock()
+	status := make(map[string]bool, len(watchers.running))
+	for watchType, running := range watchers.running {
+		status[watchType] = running
+	}
+	return status
+}
+
+// runChecksWatchers watches the checks of each monitored datacenter with its
+// own blocking query, starting and stopping the watchers as the monitored
+// datacenters change.
+func runChecksWatchers() {
+	setWatcherRunning("checks", true)
+	defer setWatcherRunning("checks", false)
+
+	watching := make(map[string]chan struct{})
+	for {
+		loopAlive("checks watcher")
+		monitored := make(map[string]bool)
+		for _, dc := range consulClient.Datacenters() {
+			monitored[dc] = true
+			if watching[dc] == nil {
+				watching[dc] = make(chan struct{})
+				go watchChecks(dc, watching[dc])
+			}
+		}
+		for dc, stop := range watching {
+			if !monitored[dc] {
+				close(stop)
+				delete(watching, dc)
+			}
+		}
+		time.Sleep(datacentersRefreshInterval)
+	}
+}
+
+// watchChecks watches the checks of a datacenter until stop is closed.
+func watchChecks(dc string, stop chan struct{}) {
+	log.Infof("Starting checks watcher of datacenter %q.", dc)
+	var index uint64
+	for {
+		select {
+		case <-stop:
+			log.Infof("Stopping checks watcher of datacenter %q.", dc)
+			return
+		default:
+		}
+		loopAlive("checks watcher")
+		checks, lastIndex, err := consulClient.WatchChecks(dc, index)
+		if err != nil {
+			logf := log.Warnf
+			if errors.Is(err, consul.ErrCircuitOpen) {
+				logf = log.Debugf
+			}
+			logf("Unable to watch the checks of datacenter %q, retrying in %s: %s", dc, watchRetryInterval, err)
+			time.Sleep(watchRetryInterval)
+			continue
+		}
+		if lastIndex != index {
+			handleChecks(checks)
+		}
+		index = lastIndex
+	}
+}
+
+// runWatcher watches the events or config using consul blocking queries and
+// hands every change to the same processing used by the watch handlers.
+func runWatcher(watchType string) {
+	setWatcherRunning(watchType, true)
+	defer setWatcherRunning(watchType, false)
+
+	log.Infof("Starting %s watcher.", watchType)
+	var index uint64
+	for {
+		loopAlive(watchType + " watcher")
+		var err error
+		var lastIndex uint64
+		switch watchType {
+		case "event":
+			var events []consul.Event
+			if events, lastIndex, err = consulClient.WatchEvents(index); err == nil && lastIndex != index {
+				handleEvents(events)
+			}
+		case "config":
+			// the config is already loaded when the first query returns
+			if lastIndex, err = consulClient.WatchConfig(index); err == nil && index != 0 && lastIndex != index {
+				reloadConfig()
+			}
 		default:
-			exitCode = 127
-			log.Println("Shutting down watcher --> Unknown error: ", err.Error())
+			log.Errorln("Unknown watch type:", watchType)
+			return
+		}
+
+		if err != nil {
+			// the open circuit breaker is notified once
+			logf := log.Warnf
+			if errors.Is(err, consul.ErrCircuitOpen) {
+				logf = log.Debugf
+			}
+			logf("Unable to watch %s, retrying in %s: %s", watchType, watchRetryInterval, err)
+			time.Sleep(watchRetryInterval)
+			continue
 		}
-		os.Exit(exitCode)
-	} else {
-		log.Printf("Execution complete.")
+		index = lastIndex
 	}
 }
 
 func toWatchObject(reader io.Reader, v interface{}) {
 	data, err := ioutil.ReadAll(reader)
 	if err != nil {
-		log.Println("stdin read error: ", err)
+		log.Errorln("stdin read error:", err)
 		// todo: what to do when can't read?
 	}
 	err = json.Unmarshal(data, v)
 	if err != nil {
-		log.Println("json unmarshall error: ", err)
+		log.Errorln("json unmarshall error:", err)
 		// todo: what if we can't serialise?
 	}
 }
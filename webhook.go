@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"net/http"
+)
+
+// webhookRequestTimeout bounds each webhook request when the handlers have no
+// timeout.
+const webhookRequestTimeout = 30 * time.Second
+
+// webhookRetryDelay is the delay before the first retry, doubled after each
+// failed attempt.
+var webhookRetryDelay = time.Second
+
+func isWebhook(handler string) bool {
+	return strings.HasPrefix(handler, "http://") || strings.HasPrefix(handler, "https://")
+}
+
+// errPermanent is a webhook failure that retrying won't fix.
+type errPermanent struct {
+	error
+}
+
+// postWebhook posts data to url with the client, retrying up to retries times
+// on network errors, 5xx and 429 responses. The attempts and the delays
+// between them can't exceed timeout.
+func postWebhook(client *http.Client, url string, data []byte, timeout time.Duration, retries int) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := postWebhookOnce(ctx, client, url, data)
+		if _, permanent := err.(errPermanent); err == nil || permanent || attempt >= retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func postWebhookOnce(ctx context.Context, client *http.Client, url string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return errPermanent{err}
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == 429:
+		return fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	default:
+		return errPermanent{fmt.Errorf("unexpected response code: %d", resp.StatusCode)}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestPostWebhookRetries(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if body, _ := ioutil.ReadAll(r.Body); string(body) != `{"Name":"deploy"}` {
+			t.Errorf("unexpected body: %s", body)
+		}
+		if attempts < 3 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer server.Close()
+
+	if err := postWebhook(http.DefaultClient, server.URL, []byte(`{"Name":"deploy"}`), time.Second, 3); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	if err := postWebhook(http.DefaultClient, server.URL, []byte(`{"Name":"deploy"}`), time.Second, 1); err == nil {
+		t.Error("expected an error once the retries are exhausted")
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestPostWebhookPermanentFailure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(400)
+	}))
+	defer server.Close()
+
+	if err := postWebhook(http.DefaultClient, server.URL, nil, time.Second, 3); err == nil {
+		t.Error("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("a 4xx response should not be retried, got %d attempts", attempts)
+	}
+}